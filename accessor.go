@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Accessor Implemented by the session stores, to read and write the values of a session at once
+// (debug pages, copies to another store)
+type Accessor interface {
+	// Keys The keys of the values of the session, sorted
+	Keys() []string
+	// Len The number of values of the session
+	Len() int
+	// Snapshot A copy of the values of the session, their maps and slices copied deeply (the
+	// other values, such as structs behind pointers, are shared with the session)
+	Snapshot() map[string]interface{}
+	// SetMulti Set the values at once, other goroutines never see part of them; none is set
+	// when WithStrictTypes rejects one
+	SetMulti(values map[string]interface{})
+}
+
+// decodeAll Decode all the values of a lazily decoded store
+func (s *store) decodeAll() {
+	s.RLock()
+	lazy := s.lazy != nil
+	s.RUnlock()
+	if lazy {
+		s.Lock()
+		s.materialize()
+		s.Unlock()
+	}
+}
+
+func (s *store) Keys() []string {
+	s.decodeAll()
+	s.RLock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	s.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *store) Len() int {
+	s.decodeAll()
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.values)
+}
+
+func (s *store) Snapshot() map[string]interface{} {
+	s.decodeAll()
+	s.RLock()
+	defer s.RUnlock()
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = deepCopy(v)
+	}
+	return values
+}
+
+func (s *store) SetMulti(values map[string]interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	values = s.admitKeys(values)
+	for k, v := range values {
+		if !s.admit(k, v) {
+			return
+		}
+	}
+	s.Lock()
+	s.materialize()
+	for k, v := range values {
+		s.values[k] = v
+		s.markDirty(k)
+	}
+	s.Unlock()
+
+	if s.diag != nil {
+		for k := range values {
+			s.diag.dirty(k)
+		}
+	}
+	if s.trace != nil {
+		s.trace.record(1, "set_multi", "", nil)
+	}
+}
+
+// deepCopy A copy of v with its maps and slices copied
+func deepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case bson.M:
+		m := make(bson.M, len(v))
+		for k, e := range v {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = deepCopy(e)
+		}
+		return a
+	case bson.A:
+		a := make(bson.A, len(v))
+		for i, e := range v {
+			a[i] = deepCopy(e)
+		}
+		return a
+	case bson.D:
+		d := make(bson.D, len(v))
+		for i, e := range v {
+			d[i] = bson.E{Key: e.Key, Value: deepCopy(e.Value)}
+		}
+		return d
+	case []string:
+		return append([]string(nil), v...)
+	case []byte:
+		return append([]byte(nil), v...)
+	}
+	return v
+}
@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccessor(t *testing.T) {
+	mstore := newOfflineStore(t)
+
+	Convey("Test the bulk accessors of the values", t, func() {
+		store := newStore(context.Background(), mstore, "test_accessor", 10, map[string]interface{}{
+			"b":    1,
+			"a":    map[string]interface{}{"x": []interface{}{"y"}},
+			"tags": []string{"t"},
+		})
+		So(store.Keys(), ShouldResemble, []string{"a", "b", "tags"})
+		So(store.Len(), ShouldEqual, 3)
+
+		Convey("copied deeply by the snapshot", func() {
+			snap := store.Snapshot()
+			snap["a"].(map[string]interface{})["x"].([]interface{})[0] = "z"
+			snap["tags"].([]string)[0] = "u"
+			snap["c"] = true
+			a, _ := store.Get("a")
+			So(a, ShouldResemble, map[string]interface{}{"x": []interface{}{"y"}})
+			tags, _ := store.Get("tags")
+			So(tags, ShouldResemble, []string{"t"})
+			So(store.Len(), ShouldEqual, 3)
+		})
+
+		Convey("set at once", func() {
+			store.SetMulti(map[string]interface{}{"b": 2, "c": "x"})
+			b, _ := store.Get("b")
+			So(b, ShouldEqual, 2)
+			So(store.Keys(), ShouldResemble, []string{"a", "b", "c", "tags"})
+			So(store.dirty, ShouldHaveLength, 2)
+			So(store.dirty, ShouldContainKey, "c")
+		})
+	})
+
+	Convey("Test the bulk accessors of a lazily decoded session", t, func() {
+		lstore := newOfflineStore(t, WithLazyDecode(true))
+		value, err := lstore.encodeValues(map[string]interface{}{"a": 1.0, "b": "x"})
+		So(err, ShouldBeNil)
+		store, err := newLoadedStore(lstore, &sessionItem{Value: value}, newStore(context.Background(), lstore, "test_accessor", 10, nil))
+		So(err, ShouldBeNil)
+		So(store.Keys(), ShouldResemble, []string{"a", "b"})
+		So(store.lazy, ShouldBeNil)
+		So(store.Snapshot(), ShouldResemble, map[string]interface{}{"a": 1.0, "b": "x"})
+	})
+}
@@ -0,0 +1,181 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// SessionInfo The description of a stored session
+type SessionInfo struct {
+	// SID The session id, hashed with WithHashedIDs
+	SID       string
+	CreatedAt time.Time
+	ExpiredAt time.Time
+	// UpdatedAt The time of the last write of the session, zero for a session not written
+	// since the time was recorded
+	UpdatedAt time.Time
+	// Size Size in bytes of the stored value
+	Size int64
+}
+
+// ManagerStoreExt Implemented by the stores, to enumerate the sessions (admin views, tooling)
+type ManagerStoreExt interface {
+	session.ManagerStore
+	// the administrative reads go to the primary, whatever the read preference of the store
+
+	// Count Count the active sessions
+	Count(ctx context.Context) (int64, error)
+	// List Get the active sessions from offset, limit at most (no limit if not positive),
+	// ordered by session id
+	List(ctx context.Context, offset, limit int64) ([]SessionInfo, error)
+	// Iterate Call fn with each active session until it returns an error, which is returned
+	Iterate(ctx context.Context, fn func(SessionInfo) error) error
+	// CreationCounts Count the sessions created since, per bucket (e.g. time.Minute),
+	// the sessions already removed are not counted
+	CreationCounts(ctx context.Context, since time.Time, bucket time.Duration) ([]BucketCount, error)
+	// ExpiryCounts Count the active sessions per range of remaining lifetimes, split at bounds
+	// (DefaultExpiryBounds if empty), e.g. to forecast the TTL deletions and the logins to come
+	ExpiryCounts(ctx context.Context, bounds []time.Duration) ([]ExpiryCount, error)
+}
+
+// sessionInfoDoc The projection of the stored session described by SessionInfo
+type sessionInfoDoc struct {
+	ID        string    `bson:"_id"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiredAt time.Time `bson:"expired_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+	Size      int64     `bson:"size"`
+}
+
+// infoProjection The projection of the stored sessions into sessionInfoDoc
+var infoProjection = bson.M{
+	"created_at": 1,
+	"expired_at": 1,
+	"updated_at": 1,
+	"size": bson.M{"$ifNull": bson.A{"$size", bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": "$value"}, "object"}},
+		bson.M{"$bsonSize": "$value"},
+		bson.M{"$ifNull": bson.A{bson.M{"$binarySize": "$value"}, 0}},
+	}}}},
+}
+
+// activeScope Query matching the active sessions of the store
+func (s *managerStore) activeScope() bson.M {
+	q := s.scope()
+	q["expired_at"] = bson.M{"$gt": s.notBefore(0)}
+	return q
+}
+
+// info The description of a projected session document
+func (s *managerStore) info(doc sessionInfoDoc) SessionInfo {
+	sid := doc.ID
+	if s.namespace != "" {
+		sid = strings.TrimPrefix(sid, s.namespace+":")
+	}
+	return SessionInfo{
+		SID:       sid,
+		CreatedAt: doc.CreatedAt,
+		ExpiredAt: doc.ExpiredAt,
+		UpdatedAt: doc.UpdatedAt,
+		Size:      doc.Size,
+	}
+}
+
+func (s *managerStore) List(ctx context.Context, offset, limit int64) ([]SessionInfo, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	infos, err := s.list(dbctx, offset, limit)
+	if err != nil || s.anon == nil || (limit > 0 && int64(len(infos)) >= limit) {
+		return infos, err
+	}
+
+	// continue with the anonymous sessions after the authenticated ones
+	if len(infos) == 0 && offset > 0 {
+		n, err := s.adminCollection().CountDocuments(dbctx, s.activeScope())
+		if err != nil {
+			return nil, err
+		}
+		if offset -= n; offset < 0 {
+			offset = 0
+		}
+	} else {
+		offset = 0
+	}
+	if limit > 0 {
+		limit -= int64(len(infos))
+	}
+	more, err := s.anon.list(dbctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(infos, more...), nil
+}
+
+// list List the active sessions of the collection of s
+func (s *managerStore) list(ctx context.Context, offset, limit int64) ([]SessionInfo, error) {
+	opts := mopts.Find().
+		SetProjection(infoProjection).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetSkip(offset)
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cur, err := s.adminCollection().Find(ctx, s.activeScope(), opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []sessionInfoDoc
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, len(docs))
+	for i, doc := range docs {
+		infos[i] = s.info(doc)
+	}
+	return infos, nil
+}
+
+func (s *managerStore) Iterate(ctx context.Context, fn func(SessionInfo) error) error {
+	if err := s.iterate(ctx, fn); err != nil || s.anon == nil {
+		return err
+	}
+	return s.anon.iterate(ctx, fn)
+}
+
+// iterate Iterate over the active sessions of the collection of s
+func (s *managerStore) iterate(ctx context.Context, fn func(SessionInfo) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cur, err := s.adminCollection().Find(ctx, s.activeScope(), mopts.Find().SetProjection(infoProjection))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	return eachInfo(ctx, cur, func(doc sessionInfoDoc) error {
+		return fn(s.info(doc))
+	})
+}
+
+// eachInfo Decode each document of cur
+func eachInfo(ctx context.Context, cur *mongo.Cursor, fn func(sessionInfoDoc) error) error {
+	for cur.Next(ctx) {
+		var doc sessionInfoDoc
+		if err := cur.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSessionInfo(t *testing.T) {
+	Convey("Test the description of the stored sessions", t, func() {
+		now := time.Now()
+		mstore := &managerStore{opts: newOptions(), namespace: "web"}
+		info := mstore.info(sessionInfoDoc{ID: "web:abc", ExpiredAt: now, UpdatedAt: now, Size: 42})
+		So(info, ShouldResemble, SessionInfo{SID: "abc", ExpiredAt: now, UpdatedAt: now, Size: 42})
+
+		q := mstore.activeScope()
+		So(q["ns"], ShouldEqual, "web")
+		So(q, ShouldContainKey, "expired_at")
+	})
+}
+
+func TestCreationCounts(t *testing.T) {
+	Convey("Test the aggregation of the session creations per bucket", t, func() {
+		since := time.Now().Add(-time.Hour)
+		mstore := &managerStore{opts: newOptions(), namespace: "web"}
+		pipeline := mstore.creationPipeline(since, time.Minute)
+		So(pipeline, ShouldHaveLength, 2)
+
+		match := pipeline[0][0].Value.(bson.M)
+		So(match["ns"], ShouldEqual, "web")
+		So(match["created_at"], ShouldResemble, bson.M{"$gte": since})
+
+		group := pipeline[1][0].Value.(bson.M)
+		mod := group["_id"].(bson.M)["$subtract"].(bson.A)[1].(bson.M)["$mod"].(bson.A)
+		So(mod[1], ShouldEqual, int64(60000))
+	})
+}
+
+func TestExpiryCounts(t *testing.T) {
+	Convey("Test the aggregation of the sessions per remaining lifetime", t, func() {
+		So(expiryBounds(nil), ShouldResemble, DefaultExpiryBounds)
+		So(expiryBounds([]time.Duration{24 * time.Hour, 0, time.Hour, time.Hour}), ShouldResemble, []time.Duration{time.Hour, 24 * time.Hour})
+
+		now := time.UnixMilli(time.Now().UnixMilli())
+		mstore := &managerStore{opts: newOptions(), namespace: "web"}
+		pipeline := mstore.expiryPipeline(now, DefaultExpiryBounds)
+		So(pipeline, ShouldHaveLength, 2)
+
+		match := pipeline[0][0].Value.(bson.M)
+		So(match["ns"], ShouldEqual, "web")
+		So(match["expired_at"], ShouldResemble, bson.M{"$gt": now})
+
+		bucket := pipeline[1][0].Value.(bson.M)
+		So(bucket["boundaries"], ShouldResemble, bson.A{now, now.Add(time.Hour), now.Add(24 * time.Hour)})
+		So(bucket["default"], ShouldEqual, "beyond")
+	})
+}
@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"context"
+	"hash/fnv"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/tag"
+)
+
+// WithSessionAffinity Spread the session reads over the replica set members matching
+// tagSets by hashing the session id, so that the reads of one session tend to hit the
+// same member and its cache, members not matching any tag set are used when none match
+// (the mode of the read preference of the store is kept, secondaryPreferred by default)
+func WithSessionAffinity(tagSets ...tag.Set) Option {
+	return func(o *options) {
+		o.affinity = tagSets
+	}
+}
+
+// affinityReadPrefs The read preferences of each of the affinity tag sets
+func (o *options) affinityReadPrefs() ([]*readpref.ReadPref, error) {
+	mode := readpref.SecondaryPreferredMode
+	if o.readPref != nil && o.readPref.Mode() != readpref.PrimaryMode {
+		mode = o.readPref.Mode()
+	}
+	rps := make([]*readpref.ReadPref, len(o.affinity))
+	for i, set := range o.affinity {
+		opts := []readpref.Option{readpref.WithTagSets(set, tag.Set{})}
+		if o.hedged {
+			opts = append(opts, readpref.WithHedgeEnabled(true))
+		}
+		rp, err := readpref.New(mode, opts...)
+		if err != nil {
+			return nil, err
+		}
+		rps[i] = rp
+	}
+	return rps, nil
+}
+
+// affinityCollections Handles of c reading from each of the affinity tag sets
+func (o *options) affinityCollections(c *mongo.Collection) ([]*mongo.Collection, error) {
+	rps, err := o.affinityReadPrefs()
+	if err != nil || len(rps) == 0 {
+		return nil, err
+	}
+	cs := make([]*mongo.Collection, len(rps))
+	for i, rp := range rps {
+		cs[i] = c.Clone(mopts.Collection().SetReadPreference(rp))
+	}
+	return cs, nil
+}
+
+// sessionCollection The collection to read the session document of sid from with ctx
+func (s *managerStore) sessionCollection(ctx context.Context, sid string) *mongo.Collection {
+	if o := callOptionsFromContext(ctx); len(s.affinity) == 0 || o.primaryRead || o.freshRead {
+		return s.readCollection(ctx)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(sid))
+	return s.affinity[h.Sum32()%uint32(len(s.affinity))]
+}
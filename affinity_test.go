@@ -0,0 +1,36 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/tag"
+)
+
+func TestSessionAffinity(t *testing.T) {
+	mstore := newOfflineStore(t, WithSessionAffinity(tag.Set{{Name: "zone", Value: "a"}}, tag.Set{{Name: "zone", Value: "b"}}))
+
+	Convey("Test the session reads spread by session id", t, func() {
+		affinity, err := mstore.opts.affinityCollections(mstore.c)
+		So(err, ShouldBeNil)
+		So(affinity, ShouldHaveLength, 2)
+		rps, err := mstore.opts.affinityReadPrefs()
+		So(err, ShouldBeNil)
+		rp := rps[1]
+		So(rp.Mode(), ShouldEqual, readpref.SecondaryPreferredMode)
+		So(rp.TagSets(), ShouldResemble, []tag.Set{{{Name: "zone", Value: "b"}}, {}})
+		mstore.affinity = affinity
+
+		ctx := context.Background()
+		seen := map[interface{}]bool{}
+		for _, sid := range []string{"a", "b", "c", "d", "e", "f"} {
+			c := mstore.sessionCollection(ctx, sid)
+			So(mstore.sessionCollection(ctx, sid), ShouldEqual, c)
+			seen[c] = true
+		}
+		So(seen, ShouldHaveLength, 2)
+		So(mstore.sessionCollection(WithPrimaryRead(ctx), "a"), ShouldEqual, mstore.cPrimary)
+	})
+}
@@ -0,0 +1,120 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// DefaultAgeCheckInterval The default interval of the checks of WithAgeAlert
+const DefaultAgeCheckInterval = 10 * time.Minute
+
+// AgeReporter Implemented by the stores, to tell how old their sessions get
+type AgeReporter interface {
+	// OldestSessionAge The age of the oldest unexpired session, from its creation, 0 if there
+	// is none; an age growing without bound reveals the sessions never expiring, e.g. through
+	// a misconfigured TTL index or expiration
+	OldestSessionAge(ctx context.Context) (time.Duration, error)
+}
+
+// AgeAlert The report of WithAgeAlert
+type AgeAlert struct {
+	// Collection The session collection
+	Collection string
+	// Age The age of its oldest unexpired session, Bound the one of WithAgeAlert
+	Age   time.Duration
+	Bound time.Duration
+}
+
+// WithAgeAlert Check every interval (DefaultAgeCheckInterval if not positive) the age of the
+// oldest unexpired session (see AgeReporter), calling alert when it exceeds bound, until the
+// store is closed; alert is called from the goroutine of the checks
+func WithAgeAlert(bound, interval time.Duration, alert func(AgeAlert)) Option {
+	return func(o *options) {
+		if interval <= 0 {
+			interval = DefaultAgeCheckInterval
+		}
+		o.ageBound = bound
+		o.ageInterval = interval
+		o.ageAlert = alert
+	}
+}
+
+// oldestCreation The creation time of the oldest unexpired session of the collection of s,
+// zero if there is none
+func (s *managerStore) oldestCreation(ctx context.Context) (time.Time, error) {
+	var doc sessionInfoDoc
+	opts := mopts.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetProjection(bson.M{"created_at": 1})
+	q := s.activeScope()
+	q["created_at"] = bson.M{"$gt": time.Time{}}
+	err := s.adminCollection().FindOne(ctx, q, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	return doc.CreatedAt, err
+}
+
+func (s *managerStore) OldestSessionAge(ctx context.Context) (time.Duration, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	oldest, err := s.oldestCreation(dbctx)
+	if err != nil {
+		return 0, err
+	}
+	if s.anon != nil {
+		at, err := s.anon.oldestCreation(dbctx)
+		if err != nil {
+			return 0, err
+		}
+		if oldest.IsZero() || (!at.IsZero() && at.Before(oldest)) {
+			oldest = at
+		}
+	}
+	if oldest.IsZero() {
+		return 0, nil
+	}
+	return s.now().Sub(oldest), nil
+}
+
+// checkAge Call the alert of WithAgeAlert if the oldest session is older than its bound
+func (s *managerStore) checkAge(ctx context.Context) {
+	age, err := s.OldestSessionAge(ctx)
+	if err != nil {
+		s.log(LevelWarn, "session age check failed", "collection", s.collectionName(), "error", err)
+		return
+	}
+	if age > s.opts.ageBound {
+		s.log(LevelWarn, "oldest session older than the bound", "collection", s.collectionName(), "age", age, "bound", s.opts.ageBound)
+		s.opts.ageAlert(AgeAlert{Collection: s.collectionName(), Age: age, Bound: s.opts.ageBound})
+	}
+}
+
+// startAgeCheck Start the checks of WithAgeAlert, stopped by Close
+func (s *managerStore) startAgeCheck() {
+	if s.opts.ageAlert == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.stopAgeCheck = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		t := time.NewTicker(s.opts.ageInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				s.checkAge(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAgeAlert(t *testing.T) {
+	Convey("Test the checks of the age of the oldest session", t, func() {
+		o := newOptions(WithAgeAlert(time.Hour, 0, func(AgeAlert) {}))
+		So(o.ageBound, ShouldEqual, time.Hour)
+		So(o.ageInterval, ShouldEqual, DefaultAgeCheckInterval)
+
+		var alerts []AgeAlert
+		var logged []string
+		mstore := newOfflineStore(t, WithOperationTimeout(time.Millisecond), WithAgeAlert(time.Hour, time.Millisecond, func(a AgeAlert) {
+			alerts = append(alerts, a)
+		}), WithLogger(func(_ LogLevel, msg string, _ ...interface{}) {
+			logged = append(logged, msg)
+		}))
+		mstore.checkAge(context.Background())
+		So(alerts, ShouldBeEmpty)
+		So(logged, ShouldContain, "session age check failed")
+
+		mstore.startAgeCheck()
+		So(mstore.stopAgeCheck, ShouldNotBeNil)
+		mstore.stopAgeCheck()
+	})
+}
@@ -0,0 +1,159 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// SessionClassifier Tell whether the values belong to an authenticated session
+type SessionClassifier func(values map[string]interface{}) bool
+
+// KeyClassifier Classify as authenticated the sessions having any of keys (e.g. the user id)
+func KeyClassifier(keys ...string) SessionClassifier {
+	return func(values map[string]interface{}) bool {
+		for _, key := range keys {
+			if _, ok := values[key]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithAnonymousCollection Store the sessions that authenticated doesn't classify as
+// authenticated in the collection name, apart from the durable collection of the others,
+// so that both get their own indexes and TTL churn, for a positive maxAge the anonymous
+// sessions expire after maxAge at most; a session moves to the collection of its class
+// when it's saved and is looked up in both collections
+func WithAnonymousCollection(name string, maxAge time.Duration, authenticated SessionClassifier) Option {
+	return func(o *options) {
+		o.anonCollection = name
+		o.anonMaxAge = maxAge
+		o.classifier = authenticated
+	}
+}
+
+// root The store of the authenticated collection of s
+func (s *managerStore) root() *managerStore {
+	if s.auth != nil {
+		return s.auth
+	}
+	return s
+}
+
+// locate Load the document of sid like getItem from the authenticated then the anonymous collection,
+// returning it with the store of its collection
+func (s *managerStore) locate(ctx context.Context, sid string, withValue bool, grace time.Duration) (*managerStore, *sessionItem, error) {
+	item, err := s.getItem(ctx, sid, withValue, grace)
+	if err != nil || item != nil || s.anon == nil {
+		return s, item, err
+	}
+	item, err = s.anon.getItem(ctx, sid, withValue, grace)
+	return s.anon, item, err
+}
+
+// classMove The switch of a store to the collection of another class
+type classMove struct {
+	from    *managerStore
+	loaded  bool
+	partial bool
+	version int64
+}
+
+// classify Switch the store to the collection of the class of its values,
+// returning the switch if any, s must be locked
+func (s *store) classify() *classMove {
+	root := s.mstore.root()
+	if root.anon == nil || root.opts.classifier == nil || s.lazy != nil {
+		return nil
+	}
+	target := root.anon
+	if root.opts.classifier(s.values) {
+		target = root
+	}
+	if target == s.mstore {
+		return nil
+	}
+
+	m := &classMove{from: s.mstore, loaded: s.loaded, partial: s.partial, version: s.version}
+	s.mstore = target
+	s.loaded, s.partial, s.version = false, false, 0
+	return m
+}
+
+// undo Switch the store back after a failed write, s must be locked
+func (m *classMove) undo(s *store) {
+	s.mstore = m.from
+	s.loaded, s.partial, s.version = m.loaded, m.partial, m.version
+}
+
+// leave Remove the document of sid from the collection the store switched from
+func (m *classMove) leave(ctx context.Context, sid string) error {
+	if !m.loaded {
+		return nil
+	}
+	if err := m.from.remove(ctx, sid); err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	return nil
+}
+
+// Promoter Implemented by the stores, to move the sessions gaining a user binding
+// (e.g. at login) to the authenticated collection
+type Promoter interface {
+	// Promote Move the session oldsid to the authenticated collection under sid (oldsid to
+	// keep it, a new id to rotate it), removing its source document in a transaction when
+	// supported, and return its store
+	Promote(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error)
+}
+
+func (s *managerStore) Promote(ctx context.Context, oldsid, sid string, expired int64) (st session.Store, err error) {
+	defer trackLoad(ctx, time.Now())
+	tctx, op := s.begin(ctx, OpPromote, oldsid)
+	defer func() { err = op.end(valueSize(st), err) }()
+	if err := s.injectFault(ctx, OpPromote); err != nil {
+		return nil, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	return s.retryLoad(dbctx, OpPromote, func() (*store, error) {
+		return s.promote(ctx, dbctx, oldsid, sid, expired)
+	})
+}
+
+// promote Move the session oldsid to the authenticated collection under sid, dbctx being
+// the context of the database calls
+func (s *managerStore) promote(ctx, dbctx context.Context, oldsid, sid string, expired int64) (*store, error) {
+	root := s.root()
+	from, item, err := root.locate(dbctx, oldsid, true, 0)
+	if err != nil {
+		return nil, err
+	} else if item == nil {
+		return newStore(ctx, root, sid, expired, nil), nil
+	}
+
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = root.now()
+	}
+	err = root.transfer(dbctx, from, oldsid, sid, &sessionItem{
+		Value:       item.Value,
+		ValueNext:   item.ValueNext,
+		ExpiredAt:   root.expiration(expired, item.CreatedAt),
+		CreatedAt:   item.CreatedAt,
+		Version:     item.Version,
+		Indexed:     item.Indexed,
+		Size:        item.Size,
+		Fingerprint: item.Fingerprint,
+		ASN:         item.ASN,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoadedStore(root, item, newStore(ctx, root, sid, expired, nil))
+}
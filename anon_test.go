@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAnonymousCollection(t *testing.T) {
+	mstore := newOfflineStore(t, WithAnonymousCollection("session_anon", time.Minute, KeyClassifier("uid")))
+	c := mstore.c.Database().Collection("session_anon")
+	mstore.anon = &managerStore{client: mstore.client, c: c, cPrimary: c, opts: mstore.opts, auth: mstore}
+
+	Convey("Test the routing of the anonymous sessions", t, func() {
+		So(KeyClassifier("uid")(map[string]interface{}{"uid": 1}), ShouldBeTrue)
+		So(KeyClassifier("uid")(map[string]interface{}{"cart": 1}), ShouldBeFalse)
+
+		now := time.Now()
+		So(mstore.expiration(3600, time.Time{}).Sub(now), ShouldBeGreaterThan, time.Hour-time.Second)
+		So(mstore.anon.expiration(3600, time.Time{}).Sub(now), ShouldBeLessThan, time.Minute+time.Second)
+
+		store := newStore(context.Background(), mstore, "test_anon", 3600, nil)
+		store.Set("cart", "book")
+		moved := store.classify()
+		So(moved.from, ShouldEqual, mstore)
+		So(moved.loaded, ShouldBeFalse)
+		So(store.mstore, ShouldEqual, mstore.anon)
+		So(store.classify(), ShouldBeNil)
+
+		store.loaded, store.version = true, 3
+		store.Set("uid", 42)
+		moved = store.classify()
+		So(moved, ShouldNotBeNil)
+		So(moved.from, ShouldEqual, mstore.anon)
+		So(store.mstore, ShouldEqual, mstore)
+		So(store.loaded, ShouldBeFalse)
+
+		moved.undo(store)
+		So(store.mstore, ShouldEqual, mstore.anon)
+		So(store.version, ShouldEqual, 3)
+
+		Convey("promotion", func() {
+			var p Promoter = newOfflineStore(t, WithFaultInjection(Fault{Op: OpPromote, Rate: 1, Err: ErrInjectedFault}))
+			_, err := p.Promote(context.Background(), "test_anon", "test_auth", 10)
+			So(errors.Is(err, ErrInjectedFault), ShouldBeTrue)
+		})
+
+		Convey("in namespaces", func() {
+			ns := mstore.namespaceView("ns")
+			So(ns.anon, ShouldNotBeNil)
+			So(ns.anon.namespace, ShouldEqual, "ns")
+			So(ns.anon.root(), ShouldEqual, ns)
+		})
+	})
+}
@@ -0,0 +1,299 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// ErrArchiveDisabled The archive is not enabled with WithArchive
+var ErrArchiveDisabled = errors.New("session archive is not enabled")
+
+// ArchiveReason Why a session was archived
+type ArchiveReason string
+
+// Reasons of the archives
+const (
+	// ArchiveDeleted The session was deleted (e.g. a logout)
+	ArchiveDeleted ArchiveReason = "deleted"
+	// ArchiveRefreshed The session was moved to a new id
+	ArchiveRefreshed ArchiveReason = "refreshed"
+	// ArchiveExpired The expired session was removed by the store
+	ArchiveExpired ArchiveReason = "expired"
+)
+
+// WithArchive Keep the documents of the sessions removed by Delete, Refresh and the store
+// itself (DeleteExpired, WithCleanupInterval, WithExpiredDeletion) for retention in the
+// collection named after the session one with an "_archive" suffix, to investigate them after
+// the logouts. The documents removed by the TTL monitor are not archived, use
+// WithCleanupInterval to archive the expired sessions as well. The values stored apart with
+// WithSpillover stay apart, their chunks being copied until the retention ends; the failures
+// to archive a document are logged
+func WithArchive(retention time.Duration) Option {
+	return func(o *options) {
+		o.archiveRetention = retention
+	}
+}
+
+// ArchivedSession The state of a session when it was archived
+type ArchivedSession struct {
+	// SID The session id, hashed with WithHashedIDs
+	SID    string
+	Reason ArchiveReason
+	// NextSID The id the session was moved to for ArchiveRefreshed, hashed with WithHashedIDs
+	NextSID     string
+	ArchivedAt  time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ExpiredAt   time.Time
+	Values      map[string]interface{}
+	Indexed     bson.M
+	Fingerprint string
+	ASN         uint32
+}
+
+// Archiver Implemented by the stores, to look up the archived sessions (see WithArchive)
+type Archiver interface {
+	// Archived The archived states of the session sid, oldest first, none if it wasn't
+	// archived or the archive was purged; the error is ErrArchiveDisabled without WithArchive
+	Archived(ctx context.Context, sid string) ([]ArchivedSession, error)
+}
+
+// archiveDoc The archive of a session document
+type archiveDoc struct {
+	ID         bson.ObjectID `bson:"_id"`
+	SID        string        `bson:"sid"`
+	Owner      string        `bson:"owner,omitempty"`
+	Namespace  string        `bson:"ns,omitempty"`
+	Reason     ArchiveReason `bson:"reason"`
+	Next       string        `bson:"next,omitempty"`
+	ArchivedAt time.Time     `bson:"archived_at"`
+	PurgeAt    time.Time     `bson:"purge_at"`
+	Session    sessionItem   `bson:"session"`
+	// Chunks The document of the copies of the chunks of a spilled value, in the chunk
+	// collection, following the spill reference of Session
+	Chunks string `bson:"chunks,omitempty"`
+}
+
+// archiveChunksPrefix The prefix of the documents of the archived chunks, which the ids of
+// the session documents don't have
+const archiveChunksPrefix = "archive:"
+
+// archiveCollection The archive collection of the session collection c
+func (o *options) archiveCollection(c *mongo.Collection) *mongo.Collection {
+	if o.archiveRetention <= 0 {
+		return nil
+	}
+	return c.Database().Collection(c.Name()+"_archive", o.collectionOptions().SetReadPreference(readpref.Primary()))
+}
+
+// archiveIndexModels The indexes to create on the archive collection
+func archiveIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "sid", Value: 1}, {Key: "archived_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "purge_at", Value: 1}},
+			Options: mopts.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+// archiveItem Archive the removed document item, with the id next it was moved to if any; the
+// value of a spilled item stays in chunks, copied for the archive, the reassembled value not
+// fitting a document
+func (s *managerStore) archiveItem(ctx context.Context, item *sessionItem, reason ArchiveReason, next string) {
+	now := s.now()
+	doc := &archiveDoc{
+		ID:         bson.NewObjectID(),
+		SID:        item.ID,
+		Owner:      item.Owner,
+		Namespace:  item.Namespace,
+		Reason:     reason,
+		ArchivedAt: now,
+		PurgeAt:    now.Add(s.opts.archiveRetention),
+		Session:    *item,
+	}
+	if next != "" {
+		doc.Next = s.docID(next)
+	}
+	if item.Spill != nil {
+		doc.Chunks = archiveChunksPrefix + doc.ID.Hex()
+		if err := s.copyChunks(ctx, item, doc.Chunks, doc.PurgeAt); err != nil {
+			s.log(LevelError, "session archive failed", "sid_hash", s.sidHash(item.ID), "error", err)
+			return
+		}
+	}
+	if _, err := s.archive.InsertOne(ctx, doc); err != nil {
+		s.log(LevelError, "session archive failed", "sid_hash", s.sidHash(item.ID), "error", err)
+		if doc.Chunks != "" {
+			s.discardChunks(ctx, doc.Chunks, item.Spill.Gen)
+		}
+	}
+}
+
+// copyChunks Copy the chunks of the spilled item to the document to, expiring at expiredAt
+func (s *managerStore) copyChunks(ctx context.Context, item *sessionItem, to string, expiredAt time.Time) error {
+	if s.chunks == nil {
+		return ErrChunksMissing
+	}
+	cur, err := s.chunks.Find(ctx, bson.M{"doc": item.ID, "gen": item.Spill.Gen}, mopts.Find().SetSort(bson.D{{Key: "n", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	n := 0
+	for cur.Next(ctx) {
+		var chunk chunkDoc
+		if err := cur.Decode(&chunk); err != nil {
+			return err
+		}
+		chunk.Doc, chunk.ExpiredAt = to, expiredAt
+		// one at a time, the chunks of a value may not fit a batch
+		if _, err := s.chunks.InsertOne(ctx, chunk); err != nil {
+			s.discardChunks(ctx, to, item.Spill.Gen)
+			return err
+		}
+		n++
+	}
+	if err := cur.Err(); err != nil {
+		s.discardChunks(ctx, to, item.Spill.Gen)
+		return err
+	}
+	if n != item.Spill.Chunks {
+		s.discardChunks(ctx, to, item.Spill.Gen)
+		return ErrChunksMissing
+	}
+	return nil
+}
+
+// retire Remove the session document like remove, archiving it for reason with WithArchive
+// and calling the cleanups of WithCleanup
+func (s *managerStore) retire(ctx context.Context, sid string, reason ArchiveReason, next string) error {
+	if !s.keepsRemoved(reason) {
+		return s.remove(ctx, sid)
+	}
+	s.uncache(sid)
+	var item sessionItem
+	if err := s.c.FindOneAndDelete(ctx, s.selector(sid)).Decode(&item); err != nil {
+		return err
+	}
+	s.removed(ctx, &item, reason, next)
+	s.dropChunks(ctx, item.ID)
+	s.dropScratch(ctx, s.docID(sid))
+	s.unpin(ctx, sid)
+	return nil
+}
+
+// retireExpired Remove the expired documents matching q one by one like retire, returning how
+// many were removed
+func (s *managerStore) retireExpired(ctx context.Context, q bson.M) (int64, error) {
+	cur, err := s.c.Find(ctx, q, mopts.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var n int64
+	for cur.Next(ctx) {
+		var doc struct {
+			ID string `bson:"_id"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return n, err
+		}
+		one := bson.M{"_id": doc.ID}
+		for k, v := range q {
+			one[k] = v
+		}
+		var item sessionItem
+		if err := s.c.FindOneAndDelete(ctx, one).Decode(&item); err == mongo.ErrNoDocuments {
+			// extended or removed since
+			continue
+		} else if err != nil {
+			return n, err
+		}
+		s.uncacheID(item.ID)
+		s.removed(ctx, &item, ArchiveExpired, "")
+		s.dropChunks(ctx, item.ID)
+		s.dropScratch(ctx, item.ID)
+		n++
+	}
+	return n, cur.Err()
+}
+
+func (s *managerStore) Archived(ctx context.Context, sid string) ([]ArchivedSession, error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Archived(ctx, sid)
+	}
+	if s.archive == nil {
+		return nil, ErrArchiveDisabled
+	}
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	q := s.scope()
+	q["sid"] = s.idFilter(sid)
+	cur, err := s.archive.Find(dbctx, q, mopts.Find().SetSort(bson.D{{Key: "archived_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(dbctx)
+
+	var archived []ArchivedSession
+	for cur.Next(dbctx) {
+		var doc archiveDoc
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if doc.Session.Spill != nil {
+			chunked := doc.Session
+			chunked.ID = doc.Chunks
+			if err := s.unspill(dbctx, &chunked); err != nil {
+				return nil, err
+			}
+			doc.Session.Value, doc.Session.Spill = chunked.Value, nil
+		}
+		values, err := s.decodeValues(&doc.Session)
+		if err != nil {
+			return nil, err
+		}
+		a := ArchivedSession{
+			SID:         s.info(sessionInfoDoc{ID: doc.SID}).SID,
+			Reason:      doc.Reason,
+			ArchivedAt:  doc.ArchivedAt,
+			CreatedAt:   doc.Session.CreatedAt,
+			UpdatedAt:   doc.Session.UpdatedAt,
+			ExpiredAt:   doc.Session.ExpiredAt,
+			Values:      values,
+			Fingerprint: doc.Session.Fingerprint,
+			ASN:         doc.Session.ASN,
+		}
+		if doc.Next != "" {
+			a.NextSID = s.info(sessionInfoDoc{ID: doc.Next}).SID
+		}
+		for field, value := range doc.Session.Indexed {
+			if s.opts.isIndexed(field) {
+				if a.Indexed == nil {
+					a.Indexed = bson.M{}
+				}
+				a.Indexed[field] = value
+			}
+		}
+		archived = append(archived, a)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
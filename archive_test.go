@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestArchiveOptions(t *testing.T) {
+	Convey("Test the archive collection", t, func() {
+		mstore := newOfflineStore(t)
+		So(mstore.opts.archiveCollection(mstore.c), ShouldBeNil)
+		_, err := mstore.Archived(context.Background(), "test_archive")
+		So(err, ShouldEqual, ErrArchiveDisabled)
+
+		o := newOptions(WithArchive(90 * 24 * time.Hour))
+		c := o.archiveCollection(mstore.c)
+		So(c, ShouldNotBeNil)
+		So(c.Name(), ShouldEqual, mstore.c.Name()+"_archive")
+
+		indexes := archiveIndexModels()
+		So(indexes, ShouldHaveLength, 2)
+		var io mopts.IndexOptions
+		for _, set := range indexes[1].Options.List() {
+			So(set(&io), ShouldBeNil)
+		}
+		So(*io.ExpireAfterSeconds, ShouldEqual, 0)
+	})
+}
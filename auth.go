@@ -0,0 +1,99 @@
+package mongo
+
+import (
+	"crypto/tls"
+
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Authentication mechanisms
+const (
+	AuthSCRAMSHA256 = "SCRAM-SHA-256"
+	AuthSCRAMSHA1   = "SCRAM-SHA-1"
+	AuthX509        = "MONGODB-X509"
+)
+
+// WithTLSConfig Set the TLS configuration of the connections, enabling TLS
+// (only for the stores creating their client)
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithClientCertificate Present cert to the server in the TLS handshake, enabling TLS,
+// e.g. for X.509 authentication (only for the stores creating their client)
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *options) {
+		o.clientCerts = append(o.clientCerts, cert)
+	}
+}
+
+// WithCredentials Set the user name and password to authenticate with, overriding those of
+// the connection string (only for the stores creating their client)
+func WithCredentials(username, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithAuthMechanism Set the authentication mechanism (e.g. AuthSCRAMSHA256 or AuthX509),
+// negotiated with the server by default (only for the stores creating their client)
+func WithAuthMechanism(mechanism string) Option {
+	return func(o *options) {
+		o.authMechanism = mechanism
+	}
+}
+
+// WithAuthSource Set the database of the user credentials, the database of the connection
+// string or admin by default (only for the stores creating their client)
+func WithAuthSource(db string) Option {
+	return func(o *options) {
+		o.authSource = db
+	}
+}
+
+// applyTLS Set the TLS configuration of the options onto opts
+func (o *options) applyTLS(opts *mopts.ClientOptions) {
+	if o.tlsConfig == nil && len(o.clientCerts) == 0 {
+		return
+	}
+	cfg := opts.TLSConfig
+	if o.tlsConfig != nil {
+		cfg = o.tlsConfig
+	}
+	if cfg == nil {
+		cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+	} else {
+		cfg = cfg.Clone()
+	}
+	cfg.Certificates = append(cfg.Certificates, o.clientCerts...)
+	opts.SetTLSConfig(cfg)
+}
+
+// applyAuth Set the credentials of the options onto opts, over those of the connection string
+func (o *options) applyAuth(opts *mopts.ClientOptions) {
+	if o.username == "" && o.authMechanism == "" && o.authSource == "" {
+		return
+	}
+	var cred mopts.Credential
+	if opts.Auth != nil {
+		cred = *opts.Auth
+	}
+	if o.username != "" {
+		cred.Username = o.username
+		cred.Password = o.password
+		cred.PasswordSet = o.password != ""
+	}
+	if o.authMechanism != "" {
+		cred.AuthMechanism = o.authMechanism
+	}
+	if o.authSource != "" {
+		cred.AuthSource = o.authSource
+	} else if cred.AuthMechanism == AuthX509 {
+		// the X.509 users are external to the databases
+		cred.AuthSource = "$external"
+	}
+	opts.SetAuth(cred)
+}
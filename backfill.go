@@ -0,0 +1,142 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// DefaultBackfillBatch The number of documents updated per batch by default by Backfill
+const DefaultBackfillBatch = 500
+
+// FieldsFunc Derive the indexed fields of a session from its values
+type FieldsFunc func(values map[string]interface{}) map[string]interface{}
+
+// KeyFields Derive the indexed fields from the session values under the keys of fieldKeys
+// (e.g. {"uid": "user_id"} for the uid field set from the user_id value)
+func KeyFields(fieldKeys map[string]string) FieldsFunc {
+	return func(values map[string]interface{}) map[string]interface{} {
+		fields := make(map[string]interface{}, len(fieldKeys))
+		for field, key := range fieldKeys {
+			if v, ok := values[key]; ok {
+				fields[field] = v
+			}
+		}
+		return fields
+	}
+}
+
+// Backfiller Implemented by the stores, to set the indexed fields of the sessions written
+// before the fields were declared
+type Backfiller interface {
+	// Backfill Decode the values of the active sessions missing any of the indexed fields
+	// and set the indexed fields derived by fn that they miss, batch documents at a time
+	// (DefaultBackfillBatch if not positive), returning the number of updated documents,
+	// the documents modified meanwhile are skipped
+	Backfill(ctx context.Context, batch int, fn FieldsFunc) (int64, error)
+}
+
+func (s *managerStore) Backfill(ctx context.Context, batch int, fn FieldsFunc) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if batch <= 0 {
+		batch = DefaultBackfillBatch
+	}
+
+	n, err := s.backfill(ctx, batch, fn)
+	if err == nil && s.anon != nil {
+		var more int64
+		more, err = s.anon.backfill(ctx, batch, fn)
+		n += more
+	}
+	s.uncacheAll()
+	return n, err
+}
+
+// backfillQuery Query matching the active sessions of the store missing an indexed field
+func (s *managerStore) backfillQuery() bson.M {
+	q := s.activeScope()
+	missing := make(bson.A, 0, len(s.opts.indexFields))
+	for _, field := range s.opts.indexFields {
+		missing = append(missing, bson.M{field: bson.M{"$exists": false}})
+	}
+	q["$or"] = missing
+	return q
+}
+
+// backfillModel The update setting the missing indexed fields of item, nil if none
+func (s *managerStore) backfillModel(item *sessionItem, fn FieldsFunc) (mongo.WriteModel, error) {
+	values, err := s.decodeValues(item)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	for field, value := range fn(values) {
+		if _, ok := item.Indexed[field]; !ok && s.opts.isIndexed(field) {
+			set[field] = value
+		}
+	}
+	if len(set) == 0 {
+		return nil, nil
+	}
+
+	q := bson.M{"_id": item.ID, "version": item.Version}
+	if item.Version == 0 {
+		q["version"] = bson.M{"$exists": false}
+	}
+	return mongo.NewUpdateOneModel().SetFilter(q).SetUpdate(bson.M{"$set": set}), nil
+}
+
+// backfill Backfill the collection of s
+func (s *managerStore) backfill(ctx context.Context, batch int, fn FieldsFunc) (int64, error) {
+	if len(s.opts.indexFields) == 0 {
+		return 0, nil
+	}
+	cur, err := s.cPrimary.Find(ctx, s.backfillQuery(), mopts.Find().SetBatchSize(int32(batch)))
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var n int64
+	models := make([]mongo.WriteModel, 0, batch)
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		res, err := s.c.BulkWrite(ctx, models, mopts.BulkWrite().SetOrdered(false))
+		if res != nil {
+			n += res.ModifiedCount
+		}
+		models = models[:0]
+		return err
+	}
+
+	for cur.Next(ctx) {
+		var item sessionItem
+		if err := cur.Decode(&item); err != nil {
+			return n, err
+		}
+		if err := s.unspill(ctx, &item); err != nil {
+			return n, err
+		}
+		model, err := s.backfillModel(&item, fn)
+		if err != nil {
+			return n, err
+		} else if model == nil {
+			continue
+		}
+		if models = append(models, model); len(models) >= batch {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return n, err
+	}
+	return n, flush()
+}
@@ -0,0 +1,37 @@
+package mongo
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestBackfill(t *testing.T) {
+	Convey("Test the backfill of the indexed fields", t, func() {
+		mstore := &managerStore{opts: newOptions(WithIndexedFields("uid", "tenant"))}
+		fn := KeyFields(map[string]string{"uid": "user_id", "tenant": "tenant_id", "other": "user_id"})
+		So(fn(map[string]interface{}{"user_id": "u1"}), ShouldResemble, map[string]interface{}{"uid": "u1", "other": "u1"})
+
+		q := mstore.backfillQuery()
+		So(q["$or"], ShouldResemble, bson.A{
+			bson.M{"uid": bson.M{"$exists": false}},
+			bson.M{"tenant": bson.M{"$exists": false}},
+		})
+
+		value, err := mstore.encodeValues(map[string]interface{}{"user_id": "u1", "tenant_id": "t1"})
+		So(err, ShouldBeNil)
+		model, err := mstore.backfillModel(&sessionItem{ID: "abc", Value: value, Version: 2, Indexed: bson.M{"tenant": "t0"}}, fn)
+		So(err, ShouldBeNil)
+		update := model.(*mongo.UpdateOneModel)
+		So(update.Filter, ShouldResemble, bson.M{"_id": "abc", "version": int64(2)})
+		So(update.Update, ShouldResemble, bson.M{"$set": bson.M{"uid": "u1"}})
+
+		Convey("nothing to set", func() {
+			model, err := mstore.backfillModel(&sessionItem{ID: "abc", Value: value, Indexed: bson.M{"uid": "u1", "tenant": "t0"}}, fn)
+			So(err, ShouldBeNil)
+			So(model, ShouldBeNil)
+		})
+	})
+}
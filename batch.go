@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// BatchChecker Implemented by the stores, to check many sessions at once
+type BatchChecker interface {
+	// CheckMulti Tell for each of sids whether the session exists, with a single query
+	// (per collection)
+	CheckMulti(ctx context.Context, sids []string) (map[string]bool, error)
+}
+
+func (s *managerStore) CheckMulti(ctx context.Context, sids []string) (_ map[string]bool, err error) {
+	tctx, op := s.begin(ctx, OpCheck, "")
+	defer func() { err = op.end(0, err) }()
+	if err := s.injectFault(ctx, OpCheck); err != nil {
+		return nil, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	exists := make(map[string]bool, len(sids))
+	for _, sid := range sids {
+		exists[sid] = false
+	}
+	err = s.retry(dbctx, OpCheck, func() error {
+		if err := s.checkMulti(dbctx, exists); err != nil {
+			return err
+		}
+		if s.anon != nil {
+			return s.anon.checkMulti(dbctx, exists)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exists, nil
+}
+
+// checkMulti Set to true the sessions of exists having an unexpired document in the collection
+func (s *managerStore) checkMulti(ctx context.Context, exists map[string]bool) error {
+	notBefore := s.notBefore(0)
+	fresh := callOptionsFromContext(ctx).freshRead
+	ids := make([]string, 0, len(exists))
+	sids := make(map[string]string, len(exists))
+	for sid, ok := range exists {
+		if ok {
+			continue
+		}
+		if item, pinned := s.pinned(ctx, sid); pinned && !fresh {
+			exists[sid] = !item.ExpiredAt.Before(notBefore)
+			continue
+		}
+		id := s.docID(sid)
+		ids = append(ids, id)
+		sids[id] = sid
+		if legacy := s.legacyID(sid); legacy != "" {
+			ids = append(ids, legacy)
+			sids[legacy] = sid
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	q := s.scope()
+	q["_id"] = bson.M{"$in": ids}
+	q["expired_at"] = bson.M{"$gte": notBefore}
+	cur, err := s.readCollection(ctx).Find(ctx, q, mopts.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var item struct {
+			ID string `bson:"_id"`
+		}
+		if err := cur.Decode(&item); err != nil {
+			return err
+		}
+		if sid, ok := sids[item.ID]; ok {
+			exists[sid] = true
+		}
+	}
+	return cur.Err()
+}
@@ -0,0 +1,34 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckMulti(t *testing.T) {
+	mstore := newOfflineStore(t)
+
+	Convey("Test checking pinned sessions at once", t, func() {
+		ctx := WithReadAfterWrite(context.Background())
+		mstore.pin(ctx, "a", sessionItem{ExpiredAt: time.Now().Add(time.Minute)})
+		mstore.pin(ctx, "b", sessionItem{ExpiredAt: time.Now().Add(-time.Minute)})
+
+		exists, err := mstore.CheckMulti(ctx, []string{"a", "b"})
+		So(err, ShouldBeNil)
+		So(exists, ShouldResemble, map[string]bool{"a": true, "b": false})
+	})
+
+	Convey("Test checking pinned sessions within the expiry skew", t, func() {
+		skewed := newOfflineStore(t, WithExpirySkew(time.Minute))
+		ctx := WithReadAfterWrite(context.Background())
+		skewed.pin(ctx, "a", sessionItem{ExpiredAt: time.Now().Add(-30 * time.Second)})
+		skewed.pin(ctx, "b", sessionItem{ExpiredAt: time.Now().Add(-2 * time.Minute)})
+
+		exists, err := skewed.CheckMulti(ctx, []string{"a", "b"})
+		So(err, ShouldBeNil)
+		So(exists, ShouldResemble, map[string]bool{"a": true, "b": false})
+	})
+}
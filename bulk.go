@@ -0,0 +1,111 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// BulkDeleter Implemented by the stores, to remove many sessions at once (maintenance jobs,
+// test teardown), within the namespace and owner of the store
+type BulkDeleter interface {
+	// DeleteExpired Remove the expired sessions not yet removed by the TTL monitor,
+	// returning how many were removed
+	DeleteExpired(ctx context.Context) (int64, error)
+	// DeleteAll Remove all the sessions
+	DeleteAll(ctx context.Context) error
+	// DeleteMany Remove the sessions sids with a bulk write, returning how many were removed,
+	// the sessions without document are ignored
+	DeleteMany(ctx context.Context, sids []string) (int64, error)
+}
+
+func (s *managerStore) DeleteExpired(ctx context.Context) (n int64, err error) {
+	tctx, op := s.begin(ctx, OpDelete, "")
+	defer func() { err = op.end(0, err) }()
+	if err := s.injectFault(ctx, OpDelete); err != nil {
+		return 0, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	q := s.scope()
+	q["expired_at"] = bson.M{"$lt": s.notBefore(0)}
+	err = s.retry(dbctx, OpDelete, func() error {
+		n = 0
+		stores := []*managerStore{s}
+		if s.anon != nil {
+			stores = append(stores, s.anon)
+		}
+		for _, m := range stores {
+			if m.keepsRemoved(ArchiveExpired) {
+				more, err := m.retireExpired(dbctx, q)
+				n += more
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			res, err := m.c.DeleteMany(dbctx, q)
+			if err != nil {
+				return err
+			}
+			n += res.DeletedCount
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (s *managerStore) DeleteMany(ctx context.Context, sids []string) (n int64, err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.DeleteMany(ctx, sids)
+	}
+	tctx, op := s.begin(ctx, OpDelete, "")
+	defer func() { err = op.end(0, err) }()
+	if len(sids) == 0 {
+		return 0, nil
+	}
+	if err := s.injectFault(ctx, OpDelete); err != nil {
+		return 0, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	err = s.retry(dbctx, OpDelete, func() error {
+		var err error
+		if n, err = s.deleteMany(dbctx, sids); err != nil || s.anon == nil {
+			return err
+		}
+		more, err := s.anon.deleteMany(dbctx, sids)
+		n += more
+		return err
+	})
+	return n, err
+}
+
+// deleteMany Remove the documents of sids from the collection of s with a single bulk write
+func (s *managerStore) deleteMany(ctx context.Context, sids []string) (int64, error) {
+	models := make([]mongo.WriteModel, 0, len(sids))
+	ids := make([]string, 0, len(sids))
+	for _, sid := range sids {
+		s.uncache(sid)
+		s.unpin(ctx, sid)
+		models = append(models, mongo.NewDeleteOneModel().SetFilter(s.selector(sid)))
+		ids = append(ids, s.docID(sid))
+	}
+	res, err := s.c.BulkWrite(ctx, models, mopts.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	if s.spills() {
+		if _, err := s.chunks.DeleteMany(ctx, bson.M{"doc": bson.M{"$in": ids}}); err != nil {
+			s.log(LevelWarn, "session chunk cleanup failed", "collection", s.chunks.Name(), "error", err)
+		}
+	}
+	s.dropScratch(ctx, ids...)
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,28 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBulkDeleter(t *testing.T) {
+	Convey("Test the bulk deletes without database", t, func() {
+		ctx := context.Background()
+		n, err := newOfflineStore(t).DeleteMany(ctx, nil)
+		So(err, ShouldBeNil)
+		So(n, ShouldBeZeroValue)
+
+		fault := errors.New("fault")
+		mstore := newOfflineStore(t, WithFaultInjection(Fault{Op: OpDelete, Rate: 1, Err: fault}))
+		_, err = mstore.DeleteMany(ctx, []string{"sid"})
+		So(errors.Is(err, fault), ShouldBeTrue)
+		_, err = mstore.DeleteExpired(ctx)
+		So(errors.Is(err, fault), ShouldBeTrue)
+		var se *StoreError
+		So(errors.As(err, &se), ShouldBeTrue)
+		So(se.Op, ShouldEqual, OpDelete)
+	})
+}
@@ -0,0 +1,187 @@
+package mongo
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL The time the cached session documents are used for by default
+const DefaultCacheTTL = 10 * time.Second
+
+// WithCache Keep the size most recently used session documents in memory for ttl at most
+// (DefaultCacheTTL if not positive), so that Check and Update of the hot sessions don't reach
+// the database: Save writes through the cache, Delete and Refresh invalidate it. The stored
+// expiration of a session loaded from the cache is extended when its entry expires, ttl must
+// hence be shorter than the session lifetime. Each instance of a deployment has its own cache,
+// so an instance may serve the state of a session modified by another one for ttl at most
+// (its saves still detect the conflict with a ConflictPolicy), use WithFreshRead for the
+// reads that can't be stale
+func WithCache(size int, ttl time.Duration) Option {
+	return func(o *options) {
+		o.cache = nil
+		if size > 0 {
+			if ttl <= 0 {
+				ttl = DefaultCacheTTL
+			}
+			o.cache = newItemCache(size, ttl)
+		}
+	}
+}
+
+// itemCache LRU cache of session documents
+type itemCache struct {
+	sync.Mutex
+	size  int
+	ttl   time.Duration
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key      string
+	item     sessionItem
+	cachedAt time.Time
+}
+
+func newItemCache(size int, ttl time.Duration) *itemCache {
+	return &itemCache{
+		size:  size,
+		ttl:   ttl,
+		lru:   list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *itemCache) get(key string, now time.Time) (sessionItem, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return sessionItem{}, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if now.Sub(entry.cachedAt) >= c.ttl {
+		c.lru.Remove(e)
+		delete(c.items, key)
+		return sessionItem{}, false
+	}
+	c.lru.MoveToFront(e)
+	return entry.item, true
+}
+
+func (c *itemCache) put(key string, item sessionItem, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.Value = &cacheEntry{key: key, item: item, cachedAt: now}
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&cacheEntry{key: key, item: item, cachedAt: now})
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// setExpiration Update the expiration of the cached document of key, if any
+func (c *itemCache) setExpiration(key string, expiredAt time.Time) {
+	c.Lock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*cacheEntry).item.ExpiredAt = expiredAt
+	}
+	c.Unlock()
+}
+
+func (c *itemCache) remove(key string) {
+	c.Lock()
+	if e, ok := c.items[key]; ok {
+		c.lru.Remove(e)
+		delete(c.items, key)
+	}
+	c.Unlock()
+}
+
+func (c *itemCache) clear() {
+	c.Lock()
+	c.lru.Init()
+	c.items = make(map[string]*list.Element, c.size)
+	c.Unlock()
+}
+
+// useCache Whether the reads done with ctx may be served by the cache
+func (s *managerStore) useCache(ctx context.Context) bool {
+	if s.opts.cache == nil {
+		return false
+	}
+	o := callOptionsFromContext(ctx)
+	return !o.freshRead && !o.noCache
+}
+
+// cached Get the cached document of sid
+func (s *managerStore) cached(sid string) (sessionItem, bool) {
+	if s.opts.cache == nil {
+		return sessionItem{}, false
+	}
+	return s.opts.cache.get(s.pinKey(sid), s.now())
+}
+
+// cache Remember the document of sid read or written with its value
+func (s *managerStore) cache(sid string, item sessionItem) {
+	if s.opts.cache != nil {
+		s.opts.cache.put(s.pinKey(sid), item, s.now())
+	}
+}
+
+// cacheExpiration Update the expiration of the cached document of sid, if any
+func (s *managerStore) cacheExpiration(sid string, expiredAt time.Time) {
+	if s.opts.cache != nil {
+		s.opts.cache.setExpiration(s.pinKey(sid), expiredAt)
+	}
+}
+
+// uncache Forget the cached document of sid after it was modified or removed
+func (s *managerStore) uncache(sid string) {
+	if s.opts.cache != nil {
+		s.opts.cache.remove(s.pinKey(sid))
+	}
+}
+
+// uncacheAll Forget all the cached documents, after removing many
+func (s *managerStore) uncacheAll() {
+	if s.opts.cache != nil {
+		s.opts.cache.clear()
+	}
+}
+
+// locateCached Get the cached document of sid from the authenticated then the anonymous
+// collection with the store of its collection, unless the document is pinned
+func (s *managerStore) locateCached(ctx context.Context, sid string) (*managerStore, *sessionItem) {
+	if !s.useCache(ctx) {
+		return nil, nil
+	}
+	for _, m := range []*managerStore{s, s.anon} {
+		if m == nil {
+			continue
+		}
+		if _, ok := m.pinned(ctx, sid); ok {
+			return nil, nil
+		}
+		if item, ok := m.cached(sid); ok && !item.CreatedAt.IsZero() && !item.ExpiredAt.Before(m.now()) {
+			return m, &item
+		}
+	}
+	return nil, nil
+}
+
+// withoutCache Returns a context making the reads done with it ignore the cache
+func withoutCache(ctx context.Context) context.Context {
+	return withCallOptions(ctx, func(o *callOptions) {
+		o.noCache = true
+	})
+}
@@ -0,0 +1,80 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestItemCache(t *testing.T) {
+	Convey("Test the LRU cache of session documents", t, func() {
+		now := time.Now()
+		c := newItemCache(2, time.Minute)
+		c.put("a", sessionItem{ID: "a"}, now)
+		c.put("b", sessionItem{ID: "b"}, now)
+		_, ok := c.get("a", now)
+		So(ok, ShouldBeTrue)
+
+		c.put("c", sessionItem{ID: "c"}, now)
+		_, ok = c.get("b", now)
+		So(ok, ShouldBeFalse)
+		item, ok := c.get("c", now)
+		So(ok, ShouldBeTrue)
+		So(item.ID, ShouldEqual, "c")
+
+		_, ok = c.get("a", now.Add(time.Minute))
+		So(ok, ShouldBeFalse)
+		So(c.lru.Len(), ShouldEqual, 1)
+
+		c.clear()
+		_, ok = c.get("c", now)
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestCache(t *testing.T) {
+	mstore := newOfflineStore(t, WithCache(10, time.Minute), WithOperationTimeout(50*time.Millisecond))
+
+	Convey("Test the sessions served from the cache", t, func() {
+		ctx := context.Background()
+		value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+		So(err, ShouldBeNil)
+		now := mstore.now()
+		mstore.cache("test_cache", sessionItem{Value: value, CreatedAt: now, ExpiredAt: now.Add(time.Second)})
+
+		ok, err := mstore.Check(ctx, "test_cache")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		store, err := mstore.Update(ctx, "test_cache", 100)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "bar")
+		item, _ := mstore.cached("test_cache")
+		So(item.ExpiredAt, ShouldHappenAfter, now.Add(time.Minute))
+
+		Convey("fresh reads bypass the cache", func() {
+			_, err := mstore.Check(WithFreshRead(ctx), "test_cache")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("refreshes bypass the cache", func() {
+			_, err := mstore.Refresh(ctx, "test_cache", "test_cache2", 100)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("deletes invalidate the cache", func() {
+			So(mstore.Delete(ctx, "test_cache"), ShouldNotBeNil)
+			_, ok := mstore.cached("test_cache")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("namespaces have their own entries", func() {
+			ns := mstore.Namespace("ns").(*managerStore)
+			_, ok := ns.cached("test_cache")
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
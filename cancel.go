@@ -0,0 +1,89 @@
+package mongo
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// SaveCancelPolicy How Save behaves when the context of the request gets canceled
+// (e.g. the client went away) before the session is persisted
+type SaveCancelPolicy int
+
+// Save cancel policies
+const (
+	// SaveAbort Fail the Save with the error of the context, keeping the modifications of
+	// the store for a later Save (default); the document is never partially written but the
+	// write may or may not have been applied when the cancellation arrives
+	SaveAbort SaveCancelPolicy = iota
+	// SaveDetach Complete the Save on a context detached from the cancellation of the
+	// request, bounded by the detach timeout, Save returns once the write is done
+	SaveDetach
+	// SaveQueue Like SaveDetach, but Save returns as soon as the context of the request is
+	// done, leaving the write to complete in the background (its failure is only logged);
+	// Close waits for the queued writes
+	SaveQueue
+)
+
+// DefaultDetachTimeout Bound of the saves detached from the request with SaveDetach or SaveQueue
+const DefaultDetachTimeout = 5 * time.Second
+
+// WithSaveCancelPolicy Set how Save behaves when the context of the request gets canceled
+// (SaveAbort by default), timeout bounding the detached saves (DefaultDetachTimeout if not positive)
+func WithSaveCancelPolicy(policy SaveCancelPolicy, timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout <= 0 {
+			timeout = DefaultDetachTimeout
+		}
+		o.saveCancel = policy
+		o.detachTimeout = timeout
+	}
+}
+
+// detachedContext A context carrying the values of its parent but not its deadline or cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// saveContext The context of the database calls of a Save done with ctx
+func (s *managerStore) saveContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.opts.saveCancel == SaveAbort || ctx == nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(detachedContext{ctx}, s.opts.detachTimeout)
+}
+
+// queueSave Run the save in the background, returning its error if it completes
+// before the context of the request is done
+func (s *store) queueSave() error {
+	const (
+		pending int32 = iota
+		completed
+		abandoned
+	)
+	var state atomic.Int32
+	done := make(chan error, 1)
+	s.mstore.opts.queued.Add(1)
+	go func() {
+		defer s.mstore.opts.queued.Done()
+		err := s.save()
+		if !state.CompareAndSwap(pending, completed) && err != nil {
+			s.mstore.log(LevelError, "queued session save failed", "sid_hash", s.mstore.sidHash(s.sid), "error", err)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-s.ctx.Done():
+		if state.CompareAndSwap(pending, abandoned) {
+			return nil
+		}
+		return <-done
+	}
+}
@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type cancelKey struct{}
+
+func TestSaveCancelPolicy(t *testing.T) {
+	Convey("Test saves of canceled requests", t, func() {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), cancelKey{}, "v"))
+		cancel()
+
+		Convey("detached contexts keep the values only", func() {
+			d := detachedContext{ctx}
+			So(d.Done(), ShouldBeNil)
+			So(d.Err(), ShouldBeNil)
+			_, ok := d.Deadline()
+			So(ok, ShouldBeFalse)
+			So(d.Value(cancelKey{}), ShouldEqual, "v")
+		})
+
+		Convey("aborted by default", func() {
+			mstore := newOfflineStore(t)
+			store := newStore(ctx, mstore, "test_cancel", 10, nil)
+			store.Set("foo", "bar")
+			So(errors.Is(store.Save(), context.Canceled), ShouldBeTrue)
+			So(store.dirty, ShouldHaveLength, 1)
+		})
+
+		Convey("detached from the request", func() {
+			mstore := newOfflineStore(t, WithSaveCancelPolicy(SaveDetach, 50*time.Millisecond))
+			So(mstore.opts.detachTimeout, ShouldEqual, 50*time.Millisecond)
+			store := newStore(ctx, mstore, "test_cancel", 10, nil)
+			store.Set("foo", "bar")
+			err := store.Save()
+			So(err, ShouldNotBeNil)
+			So(errors.Is(err, context.Canceled), ShouldBeFalse)
+		})
+
+		Convey("queued in the background", func() {
+			var failed bool
+			mstore := newOfflineStore(t, WithSaveCancelPolicy(SaveQueue, 50*time.Millisecond), WithLogger(func(level LogLevel, msg string, kv ...interface{}) {
+				failed = failed || msg == "queued session save failed"
+			}))
+			store := newStore(ctx, mstore, "test_cancel", 10, nil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+			mstore.opts.queued.Wait()
+			So(failed, ShouldBeTrue)
+			So(store.dirty, ShouldHaveLength, 1)
+		})
+
+		Convey("default detach timeout", func() {
+			So(newOptions(WithSaveCancelPolicy(SaveDetach, 0)).detachTimeout, ShouldEqual, DefaultDetachTimeout)
+		})
+	})
+}
@@ -0,0 +1,106 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCapacityExceeded The store holds as many sessions as the ceiling of WithCapacityLimit
+var ErrCapacityExceeded = errors.New("session capacity exceeded")
+
+// DefaultCapacityInterval The default interval of the counts of the sessions of WithCapacityLimit
+const DefaultCapacityInterval = 10 * time.Second
+
+// WithCapacityLimit Reject the new sessions (those of Create and of Update for an unknown id)
+// with ErrCapacityExceeded once the collections hold ceiling sessions, protecting the cluster
+// from the floods of sessions opened by bots; the sessions are counted approximately (with the
+// expired ones not yet removed) every interval (DefaultCapacityInterval if not positive), the
+// sessions created since being added, and the OnReject hook is called for the rejected ones;
+// a single request counts at a time, the others deciding with the previous count meanwhile
+func WithCapacityLimit(ceiling int64, interval time.Duration) Option {
+	return func(o *options) {
+		if interval <= 0 {
+			interval = DefaultCapacityInterval
+		}
+		o.capacity = &capacityLimiter{ceiling: ceiling, interval: interval}
+	}
+}
+
+// capacityLimiter The approximate count of the sessions of WithCapacityLimit
+type capacityLimiter struct {
+	ceiling  int64
+	interval time.Duration
+
+	sync.Mutex
+	count    int64
+	measured time.Time
+	// counting Whether a request is counting the sessions, added counting those it admitted
+	// since
+	counting bool
+	added    int64
+}
+
+// countSessions The approximate number of the documents of the session collections of s
+func (s *managerStore) countSessions(ctx context.Context) (int64, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	root := s.root()
+	n, err := root.c.EstimatedDocumentCount(dbctx)
+	if err != nil || root.anon == nil {
+		return n, err
+	}
+	more, err := root.anon.c.EstimatedDocumentCount(dbctx)
+	return n + more, err
+}
+
+// recount Count the sessions if the count is due and no other request is counting them,
+// without holding the lock; a failed count keeps the previous one
+func (s *managerStore) recount(ctx context.Context, l *capacityLimiter) {
+	now := s.now()
+	l.Lock()
+	due := !l.counting && now.Sub(l.measured) >= l.interval
+	if due {
+		l.counting, l.added = true, 0
+	}
+	l.Unlock()
+	if !due {
+		return
+	}
+
+	n, err := s.countSessions(ctx)
+	if err != nil {
+		s.log(LevelWarn, "session count failed", "collection", s.collectionName(), "error", err)
+	}
+	l.Lock()
+	if err == nil {
+		l.count = n + l.added
+	}
+	l.counting, l.measured = false, now
+	l.Unlock()
+}
+
+// admitSession Account the new session sid, ErrCapacityExceeded if the store is full
+func (s *managerStore) admitSession(ctx context.Context, sid string) error {
+	l := s.opts.capacity
+	if l == nil {
+		return nil
+	}
+	s.recount(ctx, l)
+	l.Lock()
+	full := l.count >= l.ceiling
+	if !full {
+		l.count++
+		if l.counting {
+			l.added++
+		}
+	}
+	l.Unlock()
+	if full {
+		s.fireHook(ctx, EventReject, s.opts.hooks.OnReject, SessionEvent{SID: sid})
+		return ErrCapacityExceeded
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCapacityLimit(t *testing.T) {
+	now := time.Now()
+	rejected := make(chan SessionEvent, 1)
+	mstore := newOfflineStore(t,
+		WithClock(ClockFunc(func() time.Time { return now })),
+		WithCapacityLimit(2, time.Minute),
+		WithOperationTimeout(10*time.Millisecond),
+		WithLifecycleHooks(LifecycleHooks{OnReject: func(_ context.Context, event SessionEvent) { rejected <- event }}),
+	)
+	// counted already
+	mstore.opts.capacity.measured = now
+	mstore.opts.capacity.count = 1
+
+	Convey("Test the ceiling of the sessions", t, func() {
+		ctx := context.Background()
+		_, err := mstore.Create(ctx, "test_capacity_1", 10)
+		So(err, ShouldBeNil)
+
+		_, err = mstore.Create(ctx, "test_capacity_2", 10)
+		So(errors.Is(err, ErrCapacityExceeded), ShouldBeTrue)
+		event := <-rejected
+		So(event.Kind, ShouldEqual, EventReject)
+		So(event.SID, ShouldEqual, "test_capacity_2")
+
+		mstore.opts.capacity.count = 0
+		_, err = mstore.Create(ctx, "test_capacity_2", 10)
+		So(err, ShouldBeNil)
+
+		Convey("with the previous count while another request counts", func() {
+			l := mstore.opts.capacity
+			l.measured, l.counting, l.count = now.Add(-time.Hour), true, 0
+			start := time.Now()
+			_, err := mstore.Create(ctx, "test_capacity_3", 10)
+			So(err, ShouldBeNil)
+			So(time.Since(start), ShouldBeLessThan, 100*time.Millisecond)
+			So(l.count, ShouldEqual, 1)
+			So(l.added, ShouldEqual, 1)
+			l.counting = false
+		})
+
+		Convey("keeping the previous count if the count fails", func() {
+			l := mstore.opts.capacity
+			l.measured, l.count = now.Add(-time.Hour), 1
+			_, err := mstore.Create(ctx, "test_capacity_4", 10)
+			So(err, ShouldBeNil)
+			So(l.count, ShouldEqual, 2)
+			So(l.counting, ShouldBeFalse)
+			So(l.measured, ShouldEqual, now)
+		})
+	})
+
+	Convey("Test the sessions not counted when their lock fails", t, func() {
+		locked := newOfflineStore(t,
+			WithClock(ClockFunc(func() time.Time { return now })),
+			WithCapacityLimit(2, time.Minute),
+			WithSessionLock(time.Minute, time.Second),
+			WithOperationTimeout(10*time.Millisecond),
+		)
+		locked.locks = locked.opts.lockCollection(locked.c)
+		l := locked.opts.capacity
+		l.measured, l.count = now, 1
+
+		_, err := locked.Create(context.Background(), "test_capacity_locked", 10)
+		So(err, ShouldNotBeNil)
+		So(errors.Is(err, ErrCapacityExceeded), ShouldBeFalse)
+		So(l.count, ShouldEqual, 1)
+	})
+}
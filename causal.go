@@ -0,0 +1,29 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// CausalRunner Implemented by the stores, to chain operations in a causally consistent session
+type CausalRunner interface {
+	// WithSession Run fn with a context bound to a causally consistent session of MongoDB,
+	// so that the operations done with it (e.g. Update, Save then Refresh) see the writes of
+	// the previous ones, even when reading from the secondaries with the majority read and
+	// write concerns; the calls with a context already bound to a session join it. The context
+	// can't be used concurrently nor after fn returns, so the stores loaded with it have to be
+	// saved within fn, and without the SaveQueue policy
+	WithSession(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+func (s *managerStore) WithSession(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	return s.client.UseSessionWithOptions(ctx, mopts.Session().SetCausalConsistency(true), fn)
+}
@@ -0,0 +1,33 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestCausalRunner(t *testing.T) {
+	Convey("Test the operations chained in a causally consistent session", t, func() {
+		mstore := newOfflineStore(t)
+		var outer, inner *mongo.Session
+		err := mstore.WithSession(context.Background(), func(ctx context.Context) error {
+			outer = mongo.SessionFromContext(ctx)
+			return mstore.WithSession(ctx, func(ctx context.Context) error {
+				inner = mongo.SessionFromContext(ctx)
+				return nil
+			})
+		})
+		So(err, ShouldBeNil)
+		So(outer, ShouldNotBeNil)
+		So(inner, ShouldEqual, outer)
+
+		fault := errors.New("fault")
+		err = mstore.WithSession(context.Background(), func(ctx context.Context) error {
+			return fault
+		})
+		So(err, ShouldEqual, fault)
+	})
+}
@@ -0,0 +1,104 @@
+package mongo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+// Errors of the encrypted session values
+var (
+	ErrNoCipher   = errors.New("session value is encrypted but no cipher is configured")
+	ErrUnknownKey = errors.New("session value is encrypted with an unknown key")
+	ErrCiphertext = errors.New("malformed encrypted session value")
+)
+
+// Cipher Encrypts the serialized session values before they are stored
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// WithCipher Encrypt the session values with cipher, the values are serialized by the codec
+// then encrypted and stored as binary data, the unencrypted documents remain readable
+func WithCipher(cipher Cipher) Option {
+	return func(o *options) {
+		o.cipher = cipher
+		o.nilCipher = cipher == nil
+	}
+}
+
+// aesGCMKeyIDSize Size of the key id prefixing the AES-GCM ciphertexts
+const aesGCMKeyIDSize = 4
+
+type aesGCMCipher struct {
+	id    []byte
+	aead  cipher.AEAD
+	keys  map[string]cipher.AEAD
+	nonce io.Reader
+}
+
+// NewAESGCMCipher Create a Cipher encrypting with AES-GCM and key (16, 24 or 32 bytes),
+// decrypting with key or any of the oldKeys, for key rotation: values encrypted with an
+// old key are re-encrypted with key when their session is saved again
+func NewAESGCMCipher(key []byte, oldKeys ...[]byte) (Cipher, error) {
+	c := &aesGCMCipher{
+		keys:  make(map[string]cipher.AEAD),
+		nonce: rand.Reader,
+	}
+	for i, k := range append([][]byte{key}, oldKeys...) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := aesGCMKeyID(k)
+		if i == 0 {
+			c.id, c.aead = id, aead
+		}
+		if _, ok := c.keys[string(id)]; !ok {
+			c.keys[string(id)] = aead
+		}
+	}
+	return c, nil
+}
+
+// aesGCMKeyID Identify key among the keys of a cipher without revealing it
+func aesGCMKeyID(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:aesGCMKeyIDSize]
+}
+
+// Encrypt Seal plaintext as key id | nonce | ciphertext
+func (c *aesGCMCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	out := make([]byte, aesGCMKeyIDSize+c.aead.NonceSize(), aesGCMKeyIDSize+c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	copy(out, c.id)
+	nonce := out[aesGCMKeyIDSize:]
+	if _, err := io.ReadFull(c.nonce, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(out, nonce, plaintext, c.id), nil
+}
+
+// Decrypt Open a ciphertext produced by Encrypt with the current or an old key
+func (c *aesGCMCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aesGCMKeyIDSize {
+		return nil, ErrCiphertext
+	}
+	id := ciphertext[:aesGCMKeyIDSize]
+	aead, ok := c.keys[string(id)]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	ciphertext = ciphertext[aesGCMKeyIDSize:]
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrCiphertext
+	}
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], id)
+}
@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCipher(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	Convey("Test encrypted session values", t, func() {
+		oldCipher, err := NewAESGCMCipher(oldKey)
+		So(err, ShouldBeNil)
+		mstore := &managerStore{opts: newOptions(WithCipher(oldCipher), WithDocumentValues(true))}
+
+		value, err := mstore.encodeValues(map[string]interface{}{"token": "secret"})
+		So(err, ShouldBeNil)
+		So(value.Type, ShouldEqual, bson.TypeBinary)
+		subtype, data := value.Binary()
+		So(subtype, ShouldEqual, EncryptedSubtype)
+		So(bytes.Contains(data, []byte("secret")), ShouldBeFalse)
+
+		values, err := mstore.decodeValues(&sessionItem{Value: value})
+		So(err, ShouldBeNil)
+		So(values["token"], ShouldEqual, "secret")
+
+		Convey("with a rotated key", func() {
+			rotated, err := NewAESGCMCipher(newKey, oldKey)
+			So(err, ShouldBeNil)
+			mstore := &managerStore{opts: newOptions(WithCipher(rotated))}
+			values, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(values["token"], ShouldEqual, "secret")
+
+			newCipher, err := NewAESGCMCipher(newKey)
+			So(err, ShouldBeNil)
+			mstore = &managerStore{opts: newOptions(WithCipher(newCipher))}
+			_, err = mstore.decodeValues(&sessionItem{Value: value})
+			So(errors.Is(err, ErrUnknownKey), ShouldBeTrue)
+			So(errors.Is(err, ErrDecode), ShouldBeTrue)
+		})
+
+		Convey("without cipher", func() {
+			mstore := &managerStore{opts: newOptions()}
+			_, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(errors.Is(err, ErrNoCipher), ShouldBeTrue)
+			So(errors.Is(err, ErrDecode), ShouldBeTrue)
+
+			plain, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			mstore = &managerStore{opts: newOptions(WithCipher(oldCipher))}
+			values, err := mstore.decodeValues(&sessionItem{Value: plain})
+			So(err, ShouldBeNil)
+			So(values["foo"], ShouldEqual, "bar")
+		})
+
+		Convey("with invalid keys and data", func() {
+			_, err := NewAESGCMCipher([]byte("short"))
+			So(err, ShouldNotBeNil)
+			_, err = oldCipher.Decrypt([]byte{1})
+			So(err, ShouldEqual, ErrCiphertext)
+			tampered := append([]byte(nil), data...)
+			tampered[len(tampered)-1] ^= 1
+			_, err = oldCipher.Decrypt(tampered)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
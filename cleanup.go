@@ -0,0 +1,106 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SessionCleanup The final state of a removed session, given to the functions of WithCleanup
+type SessionCleanup struct {
+	// Kind EventDelete for a session removed by Delete, EventExpire for an expired one
+	Kind EventKind
+	// SID The session id, hashed with WithHashedIDs
+	SID string
+	// SIDHash The session id obfuscated with the SIDHasher of the store, to log the cleanup
+	SIDHash   string
+	Namespace string
+	ExpiredAt time.Time
+	// Values The last saved values of the session
+	Values map[string]interface{}
+}
+
+// WithCleanup Register fn to be called with the final values of the sessions removed by Delete
+// or expired and removed by the store (WithCleanupInterval, WithExpiredDeletion, DeleteExpired),
+// to release the resources tied to them (locks, temporary files, carts). The functions are
+// called in their order of registration like the lifecycle hooks, asynchronously with a context
+// detached from the one of the operation, and Close waits for them; the sessions removed by the
+// TTL monitor are not seen, use WithCleanupInterval, and the refreshed sessions live on under
+// their new id
+func WithCleanup(fn func(ctx context.Context, c SessionCleanup)) Option {
+	return func(o *options) {
+		o.cleanups = append(o.cleanups, fn)
+	}
+}
+
+// keepsRemoved Tell whether the documents removed for reason are needed once removed, by the
+// archive or the cleanups
+func (s *managerStore) keepsRemoved(reason ArchiveReason) bool {
+	return s.archive != nil || (reason != ArchiveRefreshed && len(s.opts.cleanups) > 0)
+}
+
+// removed Archive the removed document item and call the cleanups for reason, with the id
+// next it was moved to if any
+func (s *managerStore) removed(ctx context.Context, item *sessionItem, reason ArchiveReason, next string) {
+	if s.archive != nil {
+		s.archiveItem(ctx, item, reason, next)
+	}
+	if reason == ArchiveRefreshed || len(s.opts.cleanups) == 0 {
+		return
+	}
+	if err := s.unspill(ctx, item); err != nil {
+		s.log(LevelError, "removed session unreadable", "sid_hash", s.sidHash(item.ID), "error", err)
+		return
+	}
+	item.Spill = nil
+	switch reason {
+	case ArchiveDeleted:
+		s.cascade(ctx, item, EventDelete)
+	case ArchiveExpired:
+		s.cascade(ctx, item, EventExpire)
+	}
+}
+
+// cascade Call the cleanups of WithCleanup with the removed document item on their own goroutine
+func (s *managerStore) cascade(ctx context.Context, item *sessionItem, kind EventKind) {
+	if len(s.opts.cleanups) == 0 {
+		return
+	}
+	values, err := s.decodeValues(item)
+	if err != nil {
+		s.log(LevelError, "session cleanup failed", "sid_hash", s.sidHash(item.ID), "error", err)
+		return
+	}
+	// the store cleaning up the expired sessions sees all the namespaces
+	sid := item.ID
+	if item.Namespace != "" {
+		sid = strings.TrimPrefix(sid, item.Namespace+":")
+	}
+	c := SessionCleanup{
+		Kind:      kind,
+		SID:       sid,
+		Namespace: item.Namespace,
+		ExpiredAt: item.ExpiredAt,
+		Values:    values,
+	}
+	c.SIDHash = s.sidHash(c.SID)
+
+	s.opts.hookCalls.Add(1)
+	go func() {
+		defer s.opts.hookCalls.Done()
+		for _, fn := range s.opts.cleanups {
+			s.runCleanup(detachedContext{ctx}, fn, c)
+		}
+	}()
+}
+
+// runCleanup Call fn with c, logging its panic
+func (s *managerStore) runCleanup(ctx context.Context, fn func(context.Context, SessionCleanup), c SessionCleanup) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log(LevelError, "session cleanup panicked", "sid_hash", c.SIDHash, "panic", fmt.Sprint(r))
+		}
+	}()
+	fn(ctx, c)
+}
@@ -0,0 +1,62 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCleanup(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	ctx := context.Background()
+
+	Convey("Test the cleanups of the removed sessions", t, func() {
+		cleanups := make(chan SessionCleanup, 4)
+		var logged []string
+		mstore := NewMemoryStore(clock,
+			WithCleanup(func(ctx context.Context, c SessionCleanup) {
+				panic("cleanup failed")
+			}),
+			WithCleanup(func(ctx context.Context, c SessionCleanup) {
+				cleanups <- c
+			}),
+			WithLogger(func(level LogLevel, msg string, kv ...interface{}) {
+				logged = append(logged, msg)
+			}),
+		)
+		st, err := mstore.Create(ctx, "test_cleanup", 10)
+		So(err, ShouldBeNil)
+		st.Set("cart", "c1")
+		So(st.Save(), ShouldBeNil)
+
+		Convey("deleted", func() {
+			So(mstore.Delete(ctx, "test_cleanup"), ShouldBeNil)
+			c := <-cleanups
+			So(c.Kind, ShouldEqual, EventDelete)
+			So(c.SID, ShouldEqual, "test_cleanup")
+			So(c.SIDHash, ShouldEqual, SHA256SIDHasher("test_cleanup"))
+			So(c.Values["cart"], ShouldEqual, "c1")
+			So(mstore.Close(), ShouldBeNil)
+			So(logged, ShouldContain, "session cleanup panicked")
+		})
+
+		Convey("expired", func() {
+			now = now.Add(time.Minute)
+			_, err := mstore.Create(ctx, "test_cleanup_next", 10)
+			So(err, ShouldBeNil)
+			c := <-cleanups
+			So(c.Kind, ShouldEqual, EventExpire)
+			So(c.Values["cart"], ShouldEqual, "c1")
+		})
+
+		Convey("refreshed", func() {
+			_, err := mstore.Refresh(ctx, "test_cleanup", "test_cleanup_next", 10)
+			So(err, ShouldBeNil)
+			So(mstore.Close(), ShouldBeNil)
+			So(cleanups, ShouldBeEmpty)
+		})
+	})
+}
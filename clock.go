@@ -0,0 +1,62 @@
+package mongo
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock Source of the current time used for expiration
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc Adapter to use an ordinary function as a Clock
+type ClockFunc func() time.Time
+
+// Now Get the current time
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// WithClock Set the clock used to compute and check expirations (time.Now by default),
+// tests can provide a fixed or manually advanced clock
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithRandSource Set the source of randomness of the store (fault rates, jitters),
+// a fixed seed makes the behavior reproducible in tests
+func WithRandSource(src rand.Source) Option {
+	return func(o *options) {
+		o.rand = newLockedRand(src)
+	}
+}
+
+// lockedRand A rand.Rand safe for concurrent use
+type lockedRand struct {
+	sync.Mutex
+	r *rand.Rand
+}
+
+func newLockedRand(src rand.Source) *lockedRand {
+	return &lockedRand{r: rand.New(src)}
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.Lock()
+	defer r.Unlock()
+	return r.r.Float64()
+}
+
+func (r *lockedRand) Int63n(n int64) int64 {
+	r.Lock()
+	defer r.Unlock()
+	return r.r.Int63n(n)
+}
+
+func (s *managerStore) now() time.Time {
+	return s.opts.clock.Now()
+}
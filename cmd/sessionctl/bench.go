@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-session/mongo/v3"
+	session "github.com/go-session/session/v3"
+)
+
+// Operations of the bench
+const (
+	benchCreate  = "create"
+	benchRead    = "read"
+	benchSave    = "save"
+	benchRefresh = "refresh"
+)
+
+// benchOps The operations of the bench in the order of the report
+var benchOps = []string{benchCreate, benchRead, benchSave, benchRefresh}
+
+// benchConfig The workload of the bench
+type benchConfig struct {
+	duration    time.Duration
+	concurrency int
+	mix         map[string]int
+	size        int
+	sessions    int
+	expired     int64
+}
+
+// parseMix Parse the weights of the operations, e.g. "create=10,read=60,save=25,refresh=5"
+func parseMix(s string) (map[string]int, error) {
+	mix := make(map[string]int)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		op, weight, ok := strings.Cut(part, "=")
+		n, err := strconv.Atoi(weight)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid mix %q", part)
+		}
+		known := false
+		for _, o := range benchOps {
+			known = known || o == op
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown operation %q in the mix", op)
+		}
+		mix[op] += n
+	}
+	total := 0
+	for _, n := range mix {
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("empty mix")
+	}
+	return mix, nil
+}
+
+// benchPool The sessions of the bench
+type benchPool struct {
+	sync.Mutex
+	sids []string
+}
+
+func (p *benchPool) add(sid string) {
+	p.Lock()
+	p.sids = append(p.sids, sid)
+	p.Unlock()
+}
+
+// pick Take a session out of the pool, false if it's empty
+func (p *benchPool) pick(rnd *mrand.Rand) (string, bool) {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.sids) == 0 {
+		return "", false
+	}
+	i := rnd.Intn(len(p.sids))
+	sid := p.sids[i]
+	p.sids[i] = p.sids[len(p.sids)-1]
+	p.sids = p.sids[:len(p.sids)-1]
+	return sid, true
+}
+
+// benchResult The latencies and errors of an operation
+type benchResult struct {
+	latencies []time.Duration
+	errors    int
+}
+
+// benchWorker A worker of the bench
+type benchWorker struct {
+	store   session.ManagerStore
+	c       *benchConfig
+	pool    *benchPool
+	rnd     *mrand.Rand
+	payload string
+	results map[string]*benchResult
+}
+
+// newSID A random session id
+func newSID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "bench_" + hex.EncodeToString(b)
+}
+
+// choose Pick an operation according to the mix
+func (w *benchWorker) choose() string {
+	total := 0
+	for _, n := range w.c.mix {
+		total += n
+	}
+	r := w.rnd.Intn(total)
+	for _, op := range benchOps {
+		if r -= w.c.mix[op]; r < 0 {
+			return op
+		}
+	}
+	return benchCreate
+}
+
+// run Run the operation op, returning the session to put back into the pool if any
+func (w *benchWorker) run(ctx context.Context, op string) (string, error) {
+	if op == benchCreate {
+		sid := newSID()
+		st, err := w.store.Create(ctx, sid, w.c.expired)
+		if err != nil {
+			return "", err
+		}
+		st.Set("payload", w.payload)
+		return sid, st.Save()
+	}
+
+	sid, ok := w.pool.pick(w.rnd)
+	if !ok {
+		return w.run(ctx, benchCreate)
+	}
+	switch op {
+	case benchRead:
+		st, err := w.store.Update(ctx, sid, w.c.expired)
+		if err != nil {
+			return sid, err
+		}
+		st.Get("payload")
+	case benchSave:
+		st, err := w.store.Update(ctx, sid, w.c.expired)
+		if err != nil {
+			return sid, err
+		}
+		st.Set("seen", time.Now().UnixNano())
+		return sid, st.Save()
+	case benchRefresh:
+		newsid := newSID()
+		if _, err := w.store.Refresh(ctx, sid, newsid, w.c.expired); err != nil {
+			return sid, err
+		}
+		return newsid, nil
+	}
+	return sid, nil
+}
+
+// loop Run operations until ctx is done
+func (w *benchWorker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		op := w.choose()
+		start := time.Now()
+		sid, err := w.run(ctx, op)
+		took := time.Since(start)
+		if ctx.Err() != nil {
+			if sid != "" {
+				w.pool.add(sid)
+			}
+			return
+		}
+		r := w.results[op]
+		r.latencies = append(r.latencies, took)
+		if err != nil {
+			r.errors++
+		}
+		if sid != "" {
+			w.pool.add(sid)
+		}
+	}
+}
+
+// percentile The latency under which lie p percents of the sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// bench Run the workload c against store and write the report to out, the sessions of the
+// bench are removed at the end
+func bench(ctx context.Context, store session.ManagerStore, c benchConfig, out io.Writer) error {
+	if c.concurrency <= 0 {
+		c.concurrency = 1
+	}
+	payload := make([]byte, (c.size+1)/2)
+	_, _ = rand.Read(payload)
+	pool := &benchPool{}
+
+	seed := &benchWorker{store: store, c: &c, pool: pool, payload: hex.EncodeToString(payload)[:c.size]}
+	for i := 0; i < c.sessions; i++ {
+		sid, err := seed.run(ctx, benchCreate)
+		if err != nil {
+			return fmt.Errorf("seeding the sessions: %w", err)
+		}
+		pool.add(sid)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, c.duration)
+	defer cancel()
+	workers := make([]*benchWorker, c.concurrency)
+	var wg sync.WaitGroup
+	for i := range workers {
+		w := &benchWorker{
+			store:   store,
+			c:       &c,
+			pool:    pool,
+			rnd:     mrand.New(mrand.NewSource(time.Now().UnixNano() + int64(i))),
+			payload: seed.payload,
+			results: make(map[string]*benchResult),
+		}
+		for _, op := range benchOps {
+			w.results[op] = &benchResult{}
+		}
+		workers[i] = w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(runCtx)
+		}()
+	}
+	start := time.Now()
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	fmt.Fprintf(out, "%-8s %8s %8s %10s %10s %10s %10s %10s\n", "op", "count", "errors", "ops/s", "p50", "p90", "p99", "max")
+	for _, op := range benchOps {
+		var all []time.Duration
+		errors := 0
+		for _, w := range workers {
+			all = append(all, w.results[op].latencies...)
+			errors += w.results[op].errors
+		}
+		if len(all) == 0 {
+			continue
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+		fmt.Fprintf(out, "%-8s %8d %8d %10.1f %10s %10s %10s %10s\n", op, len(all), errors,
+			float64(len(all))/elapsed.Seconds(),
+			percentile(all, 50).Round(time.Microsecond), percentile(all, 90).Round(time.Microsecond),
+			percentile(all, 99).Round(time.Microsecond), all[len(all)-1].Round(time.Microsecond))
+	}
+
+	if bd, ok := store.(mongo.BulkDeleter); ok && len(pool.sids) > 0 {
+		if _, err := bd.DeleteMany(ctx, pool.sids); err != nil {
+			return fmt.Errorf("removing the sessions: %w", err)
+		}
+	}
+	return nil
+}
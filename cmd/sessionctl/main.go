@@ -0,0 +1,179 @@
+// Command sessionctl Administer the sessions of a go-session MongoDB store
+//
+//	sessionctl [flags] export [-field uid -value 42] [-expired] [sid...] > sessions.ndjson
+//	sessionctl [flags] import < sessions.ndjson
+//	sessionctl [flags] erase (-field uid -value 42 | sid...)
+//	sessionctl [flags] bench [-duration 1m] [-concurrency 16] [-mix create=10,read=60,save=25,refresh=5]
+//	sessionctl [flags] drill [-duration 30s] [-concurrency 4] [-warmup 5s] [-stepdown 10s]
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-session/mongo/v3"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "sessionctl:", err)
+		os.Exit(1)
+	}
+}
+
+// config The flags common to the commands
+type config struct {
+	uri, db, collection, env, namespace, owner, key string
+}
+
+func (c *config) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.uri, "uri", "mongodb://127.0.0.1:27017", "MongoDB connection string")
+	fs.StringVar(&c.db, "db", mongo.DefaultDatabase, "session database")
+	fs.StringVar(&c.collection, "collection", mongo.DefaultCollection, "session collection")
+	fs.StringVar(&c.env, "env", "", "environment suffix of the session collection")
+	fs.StringVar(&c.namespace, "namespace", "", "session namespace")
+	fs.StringVar(&c.owner, "owner", "", "session store owner")
+	fs.StringVar(&c.key, "key", "", "hex AES key of the encrypted session values")
+}
+
+// open Open the store of the config with the indexed field if any
+func (c *config) open(field string) (mongo.NamespaceStore, error) {
+	opts := []mongo.Option{mongo.WithIndexCreation(false), mongo.WithEnvSuffix(c.env)}
+	if c.owner != "" {
+		opts = append(opts, mongo.WithOwner(c.owner))
+	}
+	if field != "" {
+		opts = append(opts, mongo.WithIndexedFields(field))
+	}
+	if c.key != "" {
+		key, err := hex.DecodeString(c.key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key: %w", err)
+		}
+		cipher, err := mongo.NewAESGCMCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, mongo.WithCipher(cipher))
+	}
+	s, err := mongo.NewStoreWithError(c.uri, c.db, c.collection, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ns := s.(mongo.NamespaceStore)
+	if c.namespace != "" {
+		return ns.Namespace(c.namespace), nil
+	}
+	return ns, nil
+}
+
+// parseValue The value of an indexed field given on the command line, an integer if it parses as one
+func parseValue(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	return s
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	var c config
+	fs := flag.NewFlagSet("sessionctl", flag.ContinueOnError)
+	c.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("missing command: export, import, erase, bench or drill")
+	}
+
+	cmd := flag.NewFlagSet(fs.Arg(0), flag.ContinueOnError)
+	field := cmd.String("field", "", "indexed field of the sessions (e.g. uid)")
+	value := cmd.String("value", "", "value of the indexed field")
+	expired := cmd.Bool("expired", false, "include the expired sessions (export)")
+	duration := cmd.Duration("duration", time.Minute, "duration of the bench or drill")
+	concurrency := cmd.Int("concurrency", 16, "concurrent sessions of the bench or drill")
+	mix := cmd.String("mix", "create=10,read=60,save=25,refresh=5", "weights of the operations of the bench")
+	size := cmd.Int("size", 512, "size in bytes of the session values of the bench")
+	sessions := cmd.Int("sessions", 1000, "sessions created before the bench")
+	lifetime := cmd.Duration("lifetime", time.Hour, "lifetime of the sessions of the bench")
+	warmup := cmd.Duration("warmup", mongo.DefaultDrillWarmup, "delay before the step down of the drill")
+	stepDown := cmd.Duration("stepdown", mongo.DefaultDrillStepDown, "time the stepped down primary can't be reelected")
+	if err := cmd.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := c.open(*field)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch fs.Arg(0) {
+	case "export":
+		filter := mongo.ExportFilter{SIDs: cmd.Args(), Expired: *expired}
+		if *field != "" {
+			filter.Field, filter.Value = *field, parseValue(*value)
+		}
+		r, err := store.(mongo.Exporter).Export(ctx, filter)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(stdout, r)
+		return err
+	case "import":
+		n, err := store.(mongo.Exporter).Import(ctx, stdin)
+		fmt.Fprintf(os.Stderr, "%d sessions imported\n", n)
+		return err
+	case "erase":
+		var n int64
+		switch {
+		case *field != "":
+			n, err = store.(mongo.Indexer).DeleteByIndex(ctx, *field, parseValue(*value))
+		case cmd.NArg() > 0:
+			n, err = store.(mongo.BulkDeleter).DeleteMany(ctx, cmd.Args())
+		default:
+			return fmt.Errorf("erase needs -field and -value or session ids")
+		}
+		fmt.Fprintf(os.Stderr, "%d sessions erased\n", n)
+		return err
+	case "bench":
+		weights, err := parseMix(*mix)
+		if err != nil {
+			return err
+		}
+		return bench(ctx, store, benchConfig{
+			duration:    *duration,
+			concurrency: *concurrency,
+			mix:         weights,
+			size:        *size,
+			sessions:    *sessions,
+			expired:     int64(lifetime.Seconds()),
+		}, stdout)
+	case "drill":
+		r, err := store.(mongo.Driller).Drill(ctx, mongo.DrillOptions{
+			Duration:    *duration,
+			Warmup:      *warmup,
+			StepDown:    *stepDown,
+			Concurrency: *concurrency,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "operations %d, errors %d (%.2f%%), recovery %s, max latency %s\n",
+			r.Operations, r.Errors, 100*r.ErrorRate(), r.Recovery.Round(time.Millisecond), r.MaxLatency.Round(time.Millisecond))
+		for label, n := range r.ErrorLabels {
+			fmt.Fprintf(stdout, "  %s: %d\n", label, n)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+}
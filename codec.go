@@ -0,0 +1,197 @@
+package mongo
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrUnknownFormat The session value is stored in a format the store can't decode
+var ErrUnknownFormat = errors.New("unknown session value format")
+
+// Codec Serializes the session values
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// WithCodec Set the codec of the session values (JSONCodec by default),
+// values encoded by other codecs than JSONCodec are stored as binary data
+// and the documents written as JSON strings remain readable
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithDocumentValues Set whether to store the session values as a native BSON subdocument
+// instead of a serialized blob (the codec is then not used), which makes the sessions
+// inspectable with ordinary queries (e.g. {"value.user_id": 42}), the documents written
+// in the other formats remain readable (ignored when a cipher is set)
+func WithDocumentValues(enabled bool) Option {
+	return func(o *options) {
+		o.documents = enabled
+	}
+}
+
+// JSONCodec Encodes the values as JSON (numbers are decoded as float64, times as strings)
+type JSONCodec struct{}
+
+// Marshal Encode v as JSON
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return jsoniter.Marshal(v)
+}
+
+// Unmarshal Decode the JSON data into v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return jsoniter.Unmarshal(data, v)
+}
+
+// GobCodec Encodes the values with encoding/gob, keeping their Go types
+// (the concrete types stored in the session must be registered with gob.Register,
+// time.Time is registered by this package)
+type GobCodec struct{}
+
+// Marshal Encode v with gob
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal Decode the gob data into v
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// rawValue Marshal v as a BSON value
+func rawValue(v interface{}) (bson.RawValue, error) {
+	t, data, err := bson.MarshalValue(v)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	return bson.RawValue{Type: t, Value: data}, nil
+}
+
+// encodeValues Encode the session values into the value of the document
+func (s *managerStore) encodeValues(values map[string]interface{}) (bson.RawValue, error) {
+	values, err := s.opts.encodeTypes(values)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	if s.opts.documents && s.opts.cipher == nil {
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		return rawValue(values)
+	}
+	if len(values) == 0 {
+		return rawValue("")
+	}
+
+	if _, ok := s.opts.codec.(JSONCodec); ok {
+		return marshalJSON(values, s.encodeData)
+	}
+	buf, err := s.opts.codec.Marshal(values)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	return s.encodeData(buf)
+}
+
+// encodeData Compress and encrypt the serialized values data into the value of the document,
+// data is not kept
+func (s *managerStore) encodeData(buf []byte) (bson.RawValue, error) {
+	buf, algo, err := s.opts.compress(buf)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	if s.opts.cipher != nil {
+		if buf, err = s.opts.cipher.Encrypt(buf); err != nil {
+			return bson.RawValue{}, err
+		}
+		return rawValue(bson.Binary{Subtype: valueSubtype(algo, true), Data: buf})
+	}
+	if algo != NoCompression {
+		return rawValue(bson.Binary{Subtype: valueSubtype(algo, false), Data: buf})
+	}
+	if _, ok := s.opts.codec.(JSONCodec); ok {
+		return stringValue(buf), nil
+	}
+	return rawValue(bson.Binary{Data: buf})
+}
+
+// decodeValues Decode the session values of a document, in a single pass over its value,
+// the error wraps ErrDecode
+func (s *managerStore) decodeValues(item *sessionItem) (map[string]interface{}, error) {
+	values, err := s.decodeItem(item)
+	if err != nil {
+		return nil, &kindError{kinds: []error{ErrDecode}, err: err}
+	}
+	return values, nil
+}
+
+// decodeItem Decode the session values of a document
+func (s *managerStore) decodeItem(item *sessionItem) (map[string]interface{}, error) {
+	values := s.newValues()
+
+	value := item.currentValue()
+	switch value.Type {
+	case 0, bson.TypeNull:
+	case bson.TypeString:
+		// JSON string of the documents written before other codecs were supported
+		if value := value.StringValue(); value != "" {
+			if err := jsonUnmarshalString(value, &values); err != nil {
+				return nil, err
+			}
+		}
+	case bson.TypeBinary:
+		subtype, data := value.Binary()
+		if algo, encrypted, ok := parseSubtype(subtype); ok {
+			var err error
+			if encrypted {
+				if s.opts.cipher == nil {
+					return nil, ErrNoCipher
+				}
+				if data, err = s.opts.cipher.Decrypt(data); err != nil {
+					return nil, err
+				}
+			}
+			if data, err = decompress(data, algo); err != nil {
+				return nil, err
+			}
+		}
+		if len(data) > 0 {
+			if err := s.opts.codec.Unmarshal(data, &values); err != nil {
+				return nil, err
+			}
+		}
+	case bson.TypeEmbeddedDocument:
+		dec := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(value.Value)))
+		dec.DefaultDocumentM()
+		if err := dec.Decode(&values); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err := s.opts.decodeTypes(values); err != nil {
+		return nil, err
+	}
+
+	s.observeKeys(len(values))
+	return values, nil
+}
+
+func init() {
+	gob.Register(time.Time{})
+	// the stored values of the registered value types
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
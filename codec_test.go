@@ -0,0 +1,149 @@
+package mongo
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCodec(t *testing.T) {
+	Convey("Test session value codecs", t, func() {
+		Convey("JSON values are stored as strings", func() {
+			mstore := &managerStore{opts: newOptions()}
+			value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar", "n": 1})
+			So(err, ShouldBeNil)
+			So(value.Type, ShouldEqual, bson.TypeString)
+
+			values, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(values["foo"], ShouldEqual, "bar")
+			So(values["n"], ShouldEqual, float64(1))
+		})
+
+		Convey("gob values keep their types", func() {
+			mstore := &managerStore{opts: newOptions(WithCodec(GobCodec{}))}
+			now := time.Now().Round(0)
+			value, err := mstore.encodeValues(map[string]interface{}{"n": int64(1), "t": now})
+			So(err, ShouldBeNil)
+			So(value.Type, ShouldEqual, bson.TypeBinary)
+
+			values, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(values["n"], ShouldEqual, int64(1))
+			So(values["t"].(time.Time).Equal(now), ShouldBeTrue)
+
+			legacy, err := rawValue(`{"foo":"bar"}`)
+			So(err, ShouldBeNil)
+			values, err = mstore.decodeValues(&sessionItem{Value: legacy})
+			So(err, ShouldBeNil)
+			So(values["foo"], ShouldEqual, "bar")
+		})
+
+		Convey("document values are stored as subdocuments", func() {
+			mstore := &managerStore{opts: newOptions(WithDocumentValues(true))}
+			value, err := mstore.encodeValues(map[string]interface{}{
+				"foo": "bar",
+				"n":   int64(1),
+				"m":   map[string]interface{}{"a": true},
+			})
+			So(err, ShouldBeNil)
+			So(value.Type, ShouldEqual, bson.TypeEmbeddedDocument)
+			So(value.Document().Lookup("foo").StringValue(), ShouldEqual, "bar")
+
+			values, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(values["foo"], ShouldEqual, "bar")
+			So(values["n"], ShouldEqual, int64(1))
+			So(values["m"], ShouldResemble, bson.M{"a": true})
+
+			empty, err := mstore.encodeValues(nil)
+			So(err, ShouldBeNil)
+			So(empty.Type, ShouldEqual, bson.TypeEmbeddedDocument)
+			values, err = mstore.decodeValues(&sessionItem{Value: empty})
+			So(err, ShouldBeNil)
+			So(values, ShouldBeEmpty)
+
+			legacy, err := rawValue(`{"foo":"bar"}`)
+			So(err, ShouldBeNil)
+			values, err = mstore.decodeValues(&sessionItem{Value: legacy})
+			So(err, ShouldBeNil)
+			So(values["foo"], ShouldEqual, "bar")
+		})
+
+		Convey("empty and unknown values", func() {
+			mstore := &managerStore{opts: newOptions()}
+			value, err := mstore.encodeValues(nil)
+			So(err, ShouldBeNil)
+			values, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(values, ShouldBeEmpty)
+
+			unknown, _ := rawValue(int32(1))
+			_, err = mstore.decodeValues(&sessionItem{Value: unknown})
+			So(errors.Is(err, ErrUnknownFormat), ShouldBeTrue)
+			So(errors.Is(err, ErrDecode), ShouldBeTrue)
+		})
+	})
+}
+
+// benchValues Session values of a typical size
+func benchValues() map[string]interface{} {
+	values := map[string]interface{}{"user_id": 42, "name": "alice", "roles": []string{"admin", "dev"}}
+	for i := 0; i < 32; i++ {
+		values["key"+strconv.Itoa(i)] = strings.Repeat("v", 64)
+	}
+	return values
+}
+
+func benchmarkEncode(b *testing.B, opts ...Option) {
+	mstore := &managerStore{opts: newOptions(opts...)}
+	values := benchValues()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mstore.encodeValues(values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeValues(b *testing.B) {
+	benchmarkEncode(b)
+}
+
+func BenchmarkEncodeValuesGzip(b *testing.B) {
+	benchmarkEncode(b, WithCompression(GzipCompression, 0))
+}
+
+func BenchmarkEncodeValuesSnappy(b *testing.B) {
+	benchmarkEncode(b, WithCompression(SnappyCompression, 0))
+}
+
+func benchmarkDecode(b *testing.B, opts ...Option) {
+	mstore := &managerStore{opts: newOptions(opts...)}
+	value, err := mstore.encodeValues(benchValues())
+	if err != nil {
+		b.Fatal(err)
+	}
+	item := &sessionItem{Value: value}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mstore.decodeValues(item); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeValues(b *testing.B) {
+	benchmarkDecode(b)
+}
+
+func BenchmarkDecodeValuesGzip(b *testing.B) {
+	benchmarkDecode(b, WithCompression(GzipCompression, 0))
+}
@@ -0,0 +1,101 @@
+package mongo
+
+import (
+	"errors"
+
+	"github.com/klauspost/compress/s2"
+)
+
+// ErrUnknownCompression The session value is compressed with an unknown algorithm
+var ErrUnknownCompression = errors.New("session value is compressed with an unknown algorithm")
+
+// Compression Compression algorithm of the serialized session values
+type Compression byte
+
+// Compression algorithms
+const (
+	NoCompression Compression = iota
+	GzipCompression
+	SnappyCompression
+)
+
+// DefaultCompressionThreshold Size from which the values are compressed by default
+const DefaultCompressionThreshold = 1024
+
+// Binary subtypes of the enveloped session values (user defined range): 0x80 for an
+// encrypted value, 0x80 | algorithm for a compressed value and 0x90 | algorithm for a
+// value compressed then encrypted
+const (
+	EnvelopeSubtype         = 0x80
+	EnvelopeEncrypted       = 0x10
+	EnvelopeCompressionMask = 0x0f
+	EncryptedSubtype        = EnvelopeSubtype
+)
+
+// WithCompression Compress the serialized session values of at least minSize bytes
+// (DefaultCompressionThreshold if not positive) with algo, the compressed values are
+// stored as binary data and the uncompressed documents remain readable
+// (not applied to the values stored as subdocuments)
+func WithCompression(algo Compression, minSize int) Option {
+	return func(o *options) {
+		if minSize <= 0 {
+			minSize = DefaultCompressionThreshold
+		}
+		o.compression = algo
+		o.compressionMin = minSize
+	}
+}
+
+// valueSubtype The binary subtype of a value compressed with algo and encrypted or not
+func valueSubtype(algo Compression, encrypted bool) byte {
+	switch {
+	case encrypted && algo != NoCompression:
+		return EnvelopeSubtype | EnvelopeEncrypted | byte(algo)
+	case encrypted:
+		return EncryptedSubtype
+	default:
+		return EnvelopeSubtype | byte(algo)
+	}
+}
+
+// parseSubtype The compression and encryption of a value of the binary subtype,
+// false if the value is not enveloped
+func parseSubtype(subtype byte) (Compression, bool, bool) {
+	if subtype&EnvelopeSubtype == 0 || subtype&^(EnvelopeSubtype|EnvelopeEncrypted|EnvelopeCompressionMask) != 0 {
+		return NoCompression, false, false
+	}
+	algo := Compression(subtype & EnvelopeCompressionMask)
+	return algo, subtype == EncryptedSubtype || subtype&EnvelopeEncrypted != 0, true
+}
+
+// compress Compress data with the configured algorithm if it is large enough
+func (o *options) compress(data []byte) ([]byte, Compression, error) {
+	if o.compression == NoCompression || len(data) < o.compressionMin {
+		return data, NoCompression, nil
+	}
+
+	switch o.compression {
+	case GzipCompression:
+		data, err := gzipCompress(data)
+		if err != nil {
+			return nil, NoCompression, err
+		}
+		return data, GzipCompression, nil
+	case SnappyCompression:
+		return s2.EncodeSnappy(nil, data), SnappyCompression, nil
+	}
+	return nil, NoCompression, ErrUnknownCompression
+}
+
+// decompress Decompress data compressed with algo
+func decompress(data []byte, algo Compression) ([]byte, error) {
+	switch algo {
+	case NoCompression:
+		return data, nil
+	case GzipCompression:
+		return gzipDecompress(data)
+	case SnappyCompression:
+		return s2.Decode(nil, data)
+	}
+	return nil, ErrUnknownCompression
+}
@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCompression(t *testing.T) {
+	large := map[string]interface{}{"cart": strings.Repeat("item,", 1000)}
+
+	Convey("Test compressed session values", t, func() {
+		for _, algo := range []Compression{GzipCompression, SnappyCompression} {
+			mstore := &managerStore{opts: newOptions(WithCompression(algo, 0))}
+			value, err := mstore.encodeValues(large)
+			So(err, ShouldBeNil)
+			So(value.Type, ShouldEqual, bson.TypeBinary)
+			subtype, data := value.Binary()
+			So(subtype, ShouldEqual, EnvelopeSubtype|byte(algo))
+			So(len(data), ShouldBeLessThan, 1000)
+
+			values, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(values["cart"], ShouldEqual, large["cart"])
+		}
+
+		Convey("under the threshold", func() {
+			mstore := &managerStore{opts: newOptions(WithCompression(GzipCompression, 0))}
+			value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			So(value.Type, ShouldEqual, bson.TypeString)
+		})
+
+		Convey("then encrypted", func() {
+			cipher, err := NewAESGCMCipher(bytes.Repeat([]byte{1}, 16))
+			So(err, ShouldBeNil)
+			mstore := &managerStore{opts: newOptions(WithCompression(SnappyCompression, 0), WithCipher(cipher))}
+			value, err := mstore.encodeValues(large)
+			So(err, ShouldBeNil)
+			subtype, _ := value.Binary()
+			algo, encrypted, ok := parseSubtype(subtype)
+			So(ok, ShouldBeTrue)
+			So(encrypted, ShouldBeTrue)
+			So(algo, ShouldEqual, SnappyCompression)
+
+			values, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(values["cart"], ShouldEqual, large["cart"])
+		})
+
+		Convey("subtypes", func() {
+			_, _, ok := parseSubtype(bson.TypeBinaryGeneric)
+			So(ok, ShouldBeFalse)
+			algo, encrypted, ok := parseSubtype(EncryptedSubtype)
+			So(ok, ShouldBeTrue)
+			So(encrypted, ShouldBeTrue)
+			So(algo, ShouldEqual, NoCompression)
+			_, err := decompress([]byte{1}, Compression(9))
+			So(err, ShouldEqual, ErrUnknownCompression)
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrConflict The session document was modified by another request since it was loaded
+var ErrConflict = errors.New("session was modified concurrently")
+
+// ConflictPolicy How Save handles a session document modified by another request
+// since the store was loaded, detected by the version of the documents
+type ConflictPolicy int
+
+// Conflict policies
+const (
+	// LastWriteWins Overwrite the concurrent modifications (default)
+	LastWriteWins ConflictPolicy = iota
+	// ConflictError Fail the Save with ErrConflict
+	ConflictError
+	// ConflictMerge Reload the document, apply the keys modified by the store onto it
+	// and write again, failing with ErrConflict if this keeps conflicting
+	ConflictMerge
+)
+
+// conflictRetries Number of reloads of a conflicting document with ConflictMerge
+const conflictRetries = 3
+
+// WithConflictPolicy Set how Save handles the sessions modified concurrently
+// (LastWriteWins by default)
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(o *options) {
+		o.conflictPolicy = policy
+	}
+}
+
+// versionSelector Query matching the session document if it has version expect
+// (or whatever its version with LastWriteWins), 0 standing for no document
+func (s *managerStore) versionSelector(sid string, expect int64) bson.M {
+	q := s.selector(sid)
+	if s.opts.conflictPolicy == LastWriteWins {
+		return q
+	}
+	if expect == 0 {
+		q["version"] = bson.M{"$exists": false}
+	} else {
+		q["version"] = expect
+	}
+	return q
+}
+
+// writeConflict The error of an upsert of sid not matching the document with the same id
+func (s *managerStore) writeConflict(ctx context.Context, sid string, err error) error {
+	if s.opts.conflictPolicy == LastWriteWins {
+		if s.opts.owner != "" {
+			return ErrOwnerMismatch
+		}
+		return err
+	}
+	if s.opts.owner == "" {
+		return ErrConflict
+	}
+	n, err := s.c.CountDocuments(ctx, s.selector(sid))
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return ErrOwnerMismatch
+	}
+	return ErrConflict
+}
+
+// merge Reload the session document and apply onto it the keys modified by the store
+// (a flushed store keeps its values), so that the next write is based on its version
+func (s *store) merge(ctx context.Context, dirty map[string]struct{}, flushed bool) error {
+	item, err := s.mstore.findItem(ctx, s.mstore.cPrimary, s.sid, true, 0)
+	if err != nil {
+		return err
+	}
+	var version int64
+	var values map[string]interface{}
+	if item != nil {
+		version = item.Version
+		if !flushed {
+			if values, err = s.mstore.decodeValues(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.materialize()
+	if !flushed {
+		if values == nil {
+			values = s.mstore.newValues()
+		}
+		for _, keys := range []map[string]struct{}{dirty, s.dirty} {
+			for key := range keys {
+				if v, ok := s.values[key]; ok {
+					values[key] = v
+				} else {
+					delete(values, key)
+				}
+			}
+		}
+		s.values = values
+	}
+	s.version = version
+	s.loaded = item != nil
+	s.partial = item != nil && s.mstore.opts.documents && item.Value.Type == bson.TypeEmbeddedDocument
+	return nil
+}
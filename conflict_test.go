@@ -0,0 +1,29 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestConflictPolicy(t *testing.T) {
+	Convey("Test the version conditions of the session writes", t, func() {
+		mstore := &managerStore{opts: newOptions()}
+		So(mstore.versionSelector("sid", 3), ShouldNotContainKey, "version")
+		So(mstore.writeConflict(context.Background(), "sid", ErrInjectedFault), ShouldEqual, ErrInjectedFault)
+
+		mstore = &managerStore{opts: newOptions(WithConflictPolicy(ConflictError))}
+		So(mstore.versionSelector("sid", 3)["version"], ShouldEqual, 3)
+		So(mstore.versionSelector("sid", 0)["version"], ShouldResemble, bson.M{"$exists": false})
+		So(errors.Is(mstore.writeConflict(context.Background(), "sid", ErrInjectedFault), ErrConflict), ShouldBeTrue)
+
+		value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+		So(err, ShouldBeNil)
+		store, err := newLoadedStore(mstore, &sessionItem{Value: value, Version: 7}, newStore(context.Background(), mstore, "test_conflict", 10, nil))
+		So(err, ShouldBeNil)
+		So(store.version, ShouldEqual, 7)
+	})
+}
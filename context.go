@@ -0,0 +1,111 @@
+package mongo
+
+import (
+	"context"
+	"net"
+	"time"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// contextValue Get the value of key from ctx, or else from the context of the request
+// stored in ctx by the session manager (session.Start)
+func contextValue(ctx context.Context, key interface{}) interface{} {
+	if ctx == nil {
+		return nil
+	}
+	if v := ctx.Value(key); v != nil {
+		return v
+	}
+	if req, ok := session.FromReqContext(ctx); ok {
+		return req.Context().Value(key)
+	}
+	return nil
+}
+
+type callOptionsKey struct{}
+
+// callOptions Per-call overrides of the store defaults carried by a context
+type callOptions struct {
+	primaryRead bool
+	freshRead   bool
+	noCache     bool
+	timeout     time.Duration
+	fingerprint string
+	clientIP    net.IP
+	tenant      string
+}
+
+func callOptionsFromContext(ctx context.Context) callOptions {
+	o, _ := contextValue(ctx, callOptionsKey{}).(callOptions)
+	return o
+}
+
+func withCallOptions(ctx context.Context, fn func(*callOptions)) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	o := callOptionsFromContext(ctx)
+	fn(&o)
+	return context.WithValue(ctx, callOptionsKey{}, o)
+}
+
+// WithPrimaryRead Returns a context forcing the session reads done with it to go to the
+// primary, whatever the read preference of the store
+func WithPrimaryRead(ctx context.Context) context.Context {
+	return withCallOptions(ctx, func(o *callOptions) {
+		o.primaryRead = true
+	})
+}
+
+// WithFreshRead Returns a context making the session reads done with it reflect the database:
+// they go to the primary and ignore the documents saved earlier with the same context
+func WithFreshRead(ctx context.Context) context.Context {
+	return withCallOptions(ctx, func(o *callOptions) {
+		o.freshRead = true
+	})
+}
+
+// WithCallTimeout Returns a context bounding each store operation done with it to timeout,
+// instead of the default operation timeout of the store
+func WithCallTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return withCallOptions(ctx, func(o *callOptions) {
+		o.timeout = timeout
+	})
+}
+
+// WithOperationTimeout Set the default timeout of each store operation (no timeout by default)
+func WithOperationTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// callContext Derive the context of the database calls of one store operation
+func (s *managerStore) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := s.opts.timeout
+	if o := callOptionsFromContext(ctx); o.timeout > 0 {
+		timeout = o.timeout
+	}
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// readCollection The collection to read from with ctx
+func (s *managerStore) readCollection(ctx context.Context) *mongo.Collection {
+	if o := callOptionsFromContext(ctx); o.primaryRead || o.freshRead {
+		return s.cPrimary
+	}
+	return s.opts.tunedCollection(s.c)
+}
+
+// adminCollection The collection of the administrative reads, which reflect the database
+func (s *managerStore) adminCollection() *mongo.Collection {
+	return s.cPrimary
+}
@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCallOptions(t *testing.T) {
+	mstore := newOfflineStore(t, WithOperationTimeout(time.Hour))
+
+	Convey("Test per-call overrides carried by the context", t, func() {
+		ctx := context.Background()
+		So(mstore.readCollection(ctx), ShouldEqual, mstore.c)
+		So(mstore.readCollection(WithPrimaryRead(ctx)), ShouldEqual, mstore.cPrimary)
+		So(mstore.readCollection(WithFreshRead(ctx)), ShouldEqual, mstore.cPrimary)
+
+		dbctx, cancel := mstore.callContext(ctx)
+		deadline, _ := dbctx.Deadline()
+		cancel()
+		So(time.Until(deadline), ShouldBeGreaterThan, time.Minute)
+
+		start := time.Now()
+		_, err := mstore.Check(WithCallTimeout(WithPrimaryRead(ctx), 20*time.Millisecond), "test_call_options")
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+
+	Convey("Test fresh reads ignoring the pinned documents", t, func() {
+		ctx := WithReadAfterWrite(context.Background())
+		mstore.pin(ctx, "test_fresh", sessionItem{ExpiredAt: time.Now().Add(time.Minute)})
+		item, err := mstore.getItem(ctx, "test_fresh", false, 0)
+		So(err, ShouldBeNil)
+		So(item, ShouldNotBeNil)
+
+		dbctx, cancel := mstore.callContext(WithCallTimeout(WithFreshRead(ctx), 20*time.Millisecond))
+		defer cancel()
+		_, err = mstore.getItem(dbctx, "test_fresh", false, 0)
+		So(err, ShouldNotBeNil)
+	})
+}
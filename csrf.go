@@ -0,0 +1,71 @@
+package mongo
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	session "github.com/go-session/session/v3"
+)
+
+// The session keys of the CSRF token
+const (
+	csrfKey       = "_csrf"
+	csrfPrevKey   = "_csrf_prev"
+	csrfIssuedKey = "_csrf_issued"
+)
+
+// csrfTokenSize Number of random bytes of a CSRF token
+const csrfTokenSize = 32
+
+// CSRFToken Get the CSRF token bound to the session, generating a new one when it is missing
+// or was issued more than rotate ago (never rotated if not positive); the token it replaces
+// stays valid until the next rotation, for the forms rendered before. The session must be
+// saved for a new token to be kept
+func CSRFToken(store session.Store, rotate time.Duration) (string, error) {
+	now := csrfNow(store)
+	if token, ok := store.Get(csrfKey); ok {
+		if s, ok := token.(string); ok && s != "" {
+			issued, _ := store.Get(csrfIssuedKey)
+			at, ok := asInt64(issued)
+			if rotate <= 0 || (ok && now.Sub(time.Unix(at, 0)) < rotate) {
+				return s, nil
+			}
+			store.Set(csrfPrevKey, s)
+		}
+	}
+
+	b := make([]byte, csrfTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	store.Set(csrfKey, token)
+	store.Set(csrfIssuedKey, now.Unix())
+	return token, nil
+}
+
+// VerifyCSRF Tell whether token is the CSRF token bound to the session or the one it replaced,
+// comparing in constant time
+func VerifyCSRF(store session.Store, token string) bool {
+	if token == "" {
+		return false
+	}
+	ok := false
+	for _, key := range []string{csrfKey, csrfPrevKey} {
+		v, _ := store.Get(key)
+		if s, _ := v.(string); s != "" && subtle.ConstantTimeCompare([]byte(s), []byte(token)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// csrfNow The current time of the clock of the store
+func csrfNow(st session.Store) time.Time {
+	if s, ok := st.(*store); ok {
+		return s.mstore.now()
+	}
+	return time.Now()
+}
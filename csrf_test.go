@@ -0,0 +1,52 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCSRFToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	mstore := newOfflineStore(t, WithClock(ClockFunc(func() time.Time { return now })))
+
+	Convey("Test the CSRF tokens bound to the sessions", t, func() {
+		store := newStore(context.Background(), mstore, "test_csrf", 10, nil)
+		So(VerifyCSRF(store, ""), ShouldBeFalse)
+		So(VerifyCSRF(store, "forged"), ShouldBeFalse)
+
+		token, err := CSRFToken(store, time.Hour)
+		So(err, ShouldBeNil)
+		So(token, ShouldHaveLength, 43)
+		So(VerifyCSRF(store, token), ShouldBeTrue)
+		So(VerifyCSRF(store, "forged"), ShouldBeFalse)
+
+		same, err := CSRFToken(store, time.Hour)
+		So(err, ShouldBeNil)
+		So(same, ShouldEqual, token)
+
+		Convey("rotated with the previous token still valid", func() {
+			now = now.Add(2 * time.Hour)
+			rotated, err := CSRFToken(store, time.Hour)
+			So(err, ShouldBeNil)
+			So(rotated, ShouldNotEqual, token)
+			So(VerifyCSRF(store, rotated), ShouldBeTrue)
+			So(VerifyCSRF(store, token), ShouldBeTrue)
+
+			now = now.Add(2 * time.Hour)
+			again, err := CSRFToken(store, time.Hour)
+			So(err, ShouldBeNil)
+			So(VerifyCSRF(store, again), ShouldBeTrue)
+			So(VerifyCSRF(store, token), ShouldBeFalse)
+		})
+
+		Convey("issued time decoded from the stored values", func() {
+			store.Set(csrfIssuedKey, float64(now.Unix()))
+			same, err := CSRFToken(store, time.Hour)
+			So(err, ShouldBeNil)
+			So(same, ShouldEqual, token)
+		})
+	})
+}
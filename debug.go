@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response headers (or trailers) written by DebugHandler
+const (
+	HeaderLoadTime  = "X-Session-Load-Time"
+	HeaderSize      = "X-Session-Size"
+	HeaderDirtyKeys = "X-Session-Dirty-Keys"
+	HeaderSave      = "X-Session-Save"
+)
+
+type diagnosticsKey struct{}
+
+// diagnostics Session store activity collected while serving one request
+type diagnostics struct {
+	sync.Mutex
+	loadTime  time.Duration
+	size      int
+	dirtyKeys []string
+	saved     bool
+	saveErr   error
+}
+
+// DebugHandler Wrap next so that the session store activity of each request (load time,
+// saved size, dirty keys and save outcome) is reported in the X-Session-* response headers,
+// or in trailers when useTrailer is true. Headers reflect the state when the response
+// header gets written, use trailers for handlers that save after writing the body.
+// The session must be started with the request context (or the ctx given by session.Start)
+// for the activity to be collected, intended for debugging only.
+func DebugHandler(next http.Handler, useTrailer bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := &diagnostics{}
+		r = r.WithContext(context.WithValue(r.Context(), diagnosticsKey{}, d))
+
+		if useTrailer {
+			w.Header().Set("Trailer", strings.Join([]string{HeaderLoadTime, HeaderSize, HeaderDirtyKeys, HeaderSave}, ", "))
+			next.ServeHTTP(w, r)
+			d.writeTo(w.Header())
+			return
+		}
+
+		dw := &debugResponseWriter{ResponseWriter: w, diag: d}
+		next.ServeHTTP(dw, r)
+		dw.writeHeaders()
+	})
+}
+
+// diagnosticsFromContext Get the request diagnostics carried by ctx
+func diagnosticsFromContext(ctx context.Context) *diagnostics {
+	d, _ := contextValue(ctx, diagnosticsKey{}).(*diagnostics)
+	return d
+}
+
+func (d *diagnostics) loaded(took time.Duration) {
+	d.Lock()
+	d.loadTime += took
+	d.Unlock()
+}
+
+func (d *diagnostics) dirty(key string) {
+	d.Lock()
+	for _, k := range d.dirtyKeys {
+		if k == key {
+			d.Unlock()
+			return
+		}
+	}
+	d.dirtyKeys = append(d.dirtyKeys, key)
+	d.Unlock()
+}
+
+func (d *diagnostics) save(size int, err error) {
+	d.Lock()
+	d.saved = true
+	d.size = size
+	d.saveErr = err
+	d.Unlock()
+}
+
+func (d *diagnostics) writeTo(h http.Header) {
+	d.Lock()
+	defer d.Unlock()
+
+	h.Set(HeaderLoadTime, d.loadTime.String())
+	h.Set(HeaderSize, strconv.Itoa(d.size))
+	h.Set(HeaderDirtyKeys, strings.Join(d.dirtyKeys, ","))
+	switch {
+	case !d.saved:
+		h.Set(HeaderSave, "none")
+	case d.saveErr != nil:
+		h.Set(HeaderSave, "error: "+d.saveErr.Error())
+	default:
+		h.Set(HeaderSave, "ok")
+	}
+}
+
+type debugResponseWriter struct {
+	http.ResponseWriter
+	diag        *diagnostics
+	wroteHeader bool
+}
+
+func (w *debugResponseWriter) writeHeaders() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.diag.writeTo(w.ResponseWriter.Header())
+	}
+}
+
+func (w *debugResponseWriter) WriteHeader(code int) {
+	w.writeHeaders()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *debugResponseWriter) Write(b []byte) (int, error) {
+	w.writeHeaders()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *debugResponseWriter) Flush() {
+	w.writeHeaders()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// trackLoad Report the time spent loading a session since start to the request diagnostics
+func trackLoad(ctx context.Context, start time.Time) {
+	if d := diagnosticsFromContext(ctx); d != nil {
+		d.loaded(time.Since(start))
+	}
+}
@@ -0,0 +1,41 @@
+package mongo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDebugHandler(t *testing.T) {
+	Convey("Test session diagnostics reported by the debug handler", t, func() {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			d := diagnosticsFromContext(r.Context())
+			So(d, ShouldNotBeNil)
+			d.loaded(time.Millisecond)
+			d.dirty("foo")
+			d.dirty("bar")
+			d.dirty("foo")
+			d.save(42, errors.New("boom"))
+			w.Write([]byte("ok"))
+		}
+
+		Convey("in headers", func() {
+			rec := httptest.NewRecorder()
+			DebugHandler(http.HandlerFunc(handler), false).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			So(rec.Header().Get(HeaderLoadTime), ShouldEqual, "1ms")
+			So(rec.Header().Get(HeaderSize), ShouldEqual, "42")
+			So(rec.Header().Get(HeaderDirtyKeys), ShouldEqual, "foo,bar")
+			So(rec.Header().Get(HeaderSave), ShouldEqual, "error: boom")
+		})
+
+		Convey("in trailers", func() {
+			rec := httptest.NewRecorder()
+			DebugHandler(http.HandlerFunc(handler), true).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			So(rec.Result().Trailer.Get(HeaderSave), ShouldEqual, "error: boom")
+		})
+	})
+}
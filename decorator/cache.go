@@ -0,0 +1,321 @@
+package decorator
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	session "github.com/go-session/session/v3"
+)
+
+// DefaultCacheTTL The time the cached sessions are used for by default
+const DefaultCacheTTL = 10 * time.Second
+
+// snapshotter The session stores whose values can be copied at once, as mongo.Accessor
+type snapshotter interface {
+	Snapshot() map[string]interface{}
+}
+
+// cacheStore The manager store of Cache
+type cacheStore struct {
+	next session.ManagerStore
+	c    *valueCache
+}
+
+// Cache Keep the values of the size most recently used sessions in memory for ttl at most
+// (DefaultCacheTTL if not positive), so that Check and Update of the hot sessions don't reach
+// the wrapped store: the saves write through the cache, Delete and Refresh invalidate it. Only
+// the sessions whose stores implement Snapshot (as mongo.Accessor) are cached, the others pass
+// through. The expiration of a session served from the cache isn't extended until its entry
+// expires, ttl must hence be shorter than the session lifetime; each instance of a deployment
+// has its own cache, so an instance may serve the state modified by another one for ttl at most
+func Cache(size int, ttl time.Duration) Decorator {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return func(next session.ManagerStore) session.ManagerStore {
+		if size <= 0 {
+			return next
+		}
+		return &cacheStore{next: next, c: newValueCache(size, ttl)}
+	}
+}
+
+// wrap The session store of st writing through the cache, nil if st is
+func (m *cacheStore) wrap(st session.Store) session.Store {
+	if st == nil {
+		return nil
+	}
+	if _, ok := st.(snapshotter); !ok {
+		return st
+	}
+	return &cacheThroughSession{Store: st, m: m}
+}
+
+// remember Cache the values of st, if they can be copied
+func (m *cacheStore) remember(st session.Store) {
+	if sn, ok := st.(snapshotter); ok {
+		m.c.put(st.SessionID(), sn.Snapshot(), time.Now())
+	}
+}
+
+// rememberLoaded Cache the values of st loaded by Update, unless it holds none: it may be a
+// new session, not saved yet, which Check must not find
+func (m *cacheStore) rememberLoaded(st session.Store) {
+	if sn, ok := st.(snapshotter); ok {
+		if values := sn.Snapshot(); len(values) > 0 {
+			m.c.put(st.SessionID(), values, time.Now())
+		}
+	}
+}
+
+func (m *cacheStore) Check(ctx context.Context, sid string) (bool, error) {
+	if _, ok := m.c.get(sid, time.Now()); ok {
+		return true, nil
+	}
+	return m.next.Check(ctx, sid)
+}
+
+func (m *cacheStore) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	m.c.remove(sid)
+	st, err := m.next.Create(ctx, sid, expired)
+	return m.wrap(st), err
+}
+
+func (m *cacheStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	if values, ok := m.c.get(sid, time.Now()); ok {
+		return &cachedSession{ctx: ctx, m: m, sid: sid, expired: expired, values: values, changed: make(map[string]bool)}, nil
+	}
+	st, err := m.next.Update(ctx, sid, expired)
+	if err != nil {
+		return nil, err
+	}
+	m.rememberLoaded(st)
+	return m.wrap(st), nil
+}
+
+func (m *cacheStore) Delete(ctx context.Context, sid string) error {
+	m.c.remove(sid)
+	return m.next.Delete(ctx, sid)
+}
+
+func (m *cacheStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
+	m.c.remove(oldsid)
+	m.c.remove(sid)
+	st, err := m.next.Refresh(ctx, oldsid, sid, expired)
+	return m.wrap(st), err
+}
+
+func (m *cacheStore) Close() error {
+	m.c.clear()
+	return m.next.Close()
+}
+
+// cacheThroughSession The session store of Cache loaded from the wrapped store
+type cacheThroughSession struct {
+	session.Store
+	m *cacheStore
+}
+
+func (s *cacheThroughSession) Unwrap() session.Store {
+	return s.Store
+}
+
+func (s *cacheThroughSession) Save() error {
+	if err := s.Store.Save(); err != nil {
+		s.m.c.remove(s.SessionID())
+		return err
+	}
+	s.m.remember(s.Store)
+	return nil
+}
+
+func (s *cacheThroughSession) Flush() error {
+	s.m.c.remove(s.SessionID())
+	return s.Store.Flush()
+}
+
+// cachedSession The session store of Cache served from the cache, the changed keys are
+// applied to the session of the wrapped store on Save
+type cachedSession struct {
+	sync.RWMutex
+	ctx     context.Context
+	m       *cacheStore
+	sid     string
+	expired int64
+	values  map[string]interface{}
+	changed map[string]bool
+}
+
+func (s *cachedSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *cachedSession) SessionID() string {
+	return s.sid
+}
+
+func (s *cachedSession) Set(key string, value interface{}) {
+	s.Lock()
+	s.values[key] = value
+	s.changed[key] = true
+	s.Unlock()
+}
+
+func (s *cachedSession) Get(key string) (interface{}, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *cachedSession) Delete(key string) interface{} {
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.values[key]
+	if ok {
+		delete(s.values, key)
+		s.changed[key] = true
+	}
+	return v
+}
+
+// Save Write the changed values to the session of the wrapped store, nothing if none changed
+func (s *cachedSession) Save() error {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.changed) == 0 {
+		return nil
+	}
+	st, err := s.m.next.Update(s.ctx, s.sid, s.expired)
+	if err != nil {
+		s.m.c.remove(s.sid)
+		return err
+	}
+	for k := range s.changed {
+		if v, ok := s.values[k]; ok {
+			st.Set(k, v)
+		} else {
+			st.Delete(k)
+		}
+	}
+	if err := st.Save(); err != nil {
+		s.m.c.remove(s.sid)
+		return err
+	}
+	s.changed = make(map[string]bool)
+	s.m.remember(st)
+	return nil
+}
+
+func (s *cachedSession) Flush() error {
+	s.Lock()
+	defer s.Unlock()
+	s.m.c.remove(s.sid)
+	st, err := s.m.next.Update(s.ctx, s.sid, s.expired)
+	if err != nil {
+		return err
+	}
+	s.values = make(map[string]interface{})
+	s.changed = make(map[string]bool)
+	return st.Flush()
+}
+
+// valueCache LRU cache of session values
+type valueCache struct {
+	sync.Mutex
+	size  int
+	ttl   time.Duration
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+type valueEntry struct {
+	sid      string
+	values   map[string]interface{}
+	cachedAt time.Time
+}
+
+func newValueCache(size int, ttl time.Duration) *valueCache {
+	return &valueCache{
+		size:  size,
+		ttl:   ttl,
+		lru:   list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// get A copy of the cached values of sid
+func (c *valueCache) get(sid string, now time.Time) (map[string]interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.items[sid]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*valueEntry)
+	if now.Sub(entry.cachedAt) >= c.ttl {
+		c.lru.Remove(e)
+		delete(c.items, sid)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return copyValue(entry.values).(map[string]interface{}), true
+}
+
+func (c *valueCache) put(sid string, values map[string]interface{}, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	if e, ok := c.items[sid]; ok {
+		e.Value = &valueEntry{sid: sid, values: values, cachedAt: now}
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[sid] = c.lru.PushFront(&valueEntry{sid: sid, values: values, cachedAt: now})
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*valueEntry).sid)
+	}
+}
+
+func (c *valueCache) remove(sid string) {
+	c.Lock()
+	if e, ok := c.items[sid]; ok {
+		c.lru.Remove(e)
+		delete(c.items, sid)
+	}
+	c.Unlock()
+}
+
+func (c *valueCache) clear() {
+	c.Lock()
+	c.lru.Init()
+	c.items = make(map[string]*list.Element, c.size)
+	c.Unlock()
+}
+
+// copyValue A copy of v with its maps and slices copied
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = copyValue(e)
+		}
+		return a
+	}
+	return v
+}
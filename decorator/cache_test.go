@@ -0,0 +1,99 @@
+package decorator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCache(t *testing.T) {
+	Convey("Test the cache of the sessions", t, func() {
+		backend := newTestStore()
+		backend.sessions["s1"] = map[string]interface{}{"a": 1, "b": "x"}
+		store := Cache(10, time.Minute)(backend)
+		ctx := context.Background()
+
+		st, err := store.Update(ctx, "s1", 60)
+		So(err, ShouldBeNil)
+		So(backend.count(OpUpdate), ShouldEqual, 1)
+
+		st, err = store.Update(ctx, "s1", 60)
+		So(err, ShouldBeNil)
+		So(backend.count(OpUpdate), ShouldEqual, 1)
+		v, ok := st.Get("a")
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 1)
+		ok, err = store.Check(ctx, "s1")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		So(backend.count(OpCheck), ShouldEqual, 0)
+
+		Convey("saving nothing when unchanged", func() {
+			So(st.Save(), ShouldBeNil)
+			So(backend.count(OpSave), ShouldEqual, 0)
+		})
+
+		Convey("writing the changes through", func() {
+			backend.sessions["s1"]["c"] = true
+			st.Set("a", 2)
+			st.Delete("b")
+			So(st.Save(), ShouldBeNil)
+			So(backend.sessions["s1"], ShouldResemble, map[string]interface{}{"a": 2, "c": true})
+
+			st, err := store.Update(ctx, "s1", 60)
+			So(err, ShouldBeNil)
+			So(backend.count(OpUpdate), ShouldEqual, 2)
+			v, _ := st.Get("c")
+			So(v, ShouldEqual, true)
+		})
+
+		Convey("invalidated by Delete and Refresh", func() {
+			So(store.Delete(ctx, "s1"), ShouldBeNil)
+			ok, err := store.Check(ctx, "s1")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+
+			backend.sessions["s2"] = map[string]interface{}{"a": 1}
+			_, err = store.Update(ctx, "s2", 60)
+			So(err, ShouldBeNil)
+			_, err = store.Refresh(ctx, "s2", "s3", 60)
+			So(err, ShouldBeNil)
+			ok, err = store.Check(ctx, "s2")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("not caching the empty sessions, which may be new", func() {
+			_, err := store.Update(ctx, "s9", 60)
+			So(err, ShouldBeNil)
+			ok, err := store.Check(ctx, "s9")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+			So(backend.count(OpCheck), ShouldEqual, 1)
+		})
+
+		Convey("for ttl at most", func() {
+			store := Cache(10, time.Millisecond)(backend)
+			_, err := store.Update(ctx, "s1", 60)
+			So(err, ShouldBeNil)
+			time.Sleep(2 * time.Millisecond)
+			_, err = store.Update(ctx, "s1", 60)
+			So(err, ShouldBeNil)
+			So(backend.count(OpUpdate), ShouldEqual, 3)
+		})
+
+		Convey("evicting the least recently used sessions", func() {
+			store := Cache(1, time.Minute)(backend)
+			backend.sessions["s2"] = map[string]interface{}{"a": 2}
+			_, err := store.Update(ctx, "s1", 60)
+			So(err, ShouldBeNil)
+			_, err = store.Update(ctx, "s2", 60)
+			So(err, ShouldBeNil)
+			_, err = store.Update(ctx, "s1", 60)
+			So(err, ShouldBeNil)
+			So(backend.count(OpUpdate), ShouldEqual, 4)
+		})
+	})
+}
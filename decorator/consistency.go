@@ -0,0 +1,141 @@
+package decorator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Defaults of the consistency checks
+const (
+	DefaultConsistencySamples = 100
+	DefaultExpirySkew         = 5 * time.Second
+)
+
+// OpVerify The operation of the divergences found by the consistency checks
+const OpVerify = "verify"
+
+// Kinds of the divergences found by the consistency checks, with DivergenceMissing
+const (
+	// DivergenceValues The session has other values in the secondary store
+	DivergenceValues DivergenceKind = "values"
+	// DivergenceExpiry The session expires at another time in the secondary store
+	DivergenceExpiry DivergenceKind = "expiry"
+)
+
+// ConsistencyOptions The options of the consistency checks, the defaults being used for the
+// zero values
+type ConsistencyOptions struct {
+	// Samples The number of sessions of the primary store compared with the secondary one
+	// (DefaultConsistencySamples)
+	Samples int
+	// MaxExpirySkew The difference between the expirations of a session in the stores
+	// tolerated, as they are computed by each store (DefaultExpirySkew)
+	MaxExpirySkew time.Duration
+}
+
+// ConsistencyReport The outcome of a consistency check
+type ConsistencyReport struct {
+	// Sampled The number of sessions compared
+	Sampled int
+	// Missing, Different and Expiry The hashes of the sampled sessions missing from the
+	// secondary store, holding other values there or expiring at another time
+	Missing   []string
+	Different []string
+	Expiry    []string
+}
+
+// Diverged The number of sampled sessions which diverged
+func (r *ConsistencyReport) Diverged() int {
+	return len(r.Missing) + len(r.Different) + len(r.Expiry)
+}
+
+// ConsistencyChecker Implemented by the stores of Dual, to gain confidence in the secondary
+// store before a cutover
+type ConsistencyChecker interface {
+	// CheckConsistency Compare the values and expirations of samples of the sessions of the
+	// primary store with the secondary one, reporting each divergence found to the observe
+	// function of Dual with OpVerify
+	CheckConsistency(ctx context.Context, opts ConsistencyOptions) (*ConsistencyReport, error)
+	// RunConsistencyChecks Run CheckConsistency every interval until ctx is done, returning
+	// its error; the errors of the checks are reported as divergences
+	RunConsistencyChecks(ctx context.Context, interval time.Duration, opts ConsistencyOptions) error
+}
+
+var _ ConsistencyChecker = &dualStore{}
+
+// compare Compare n sessions sampled from primary with secondary, and their expirations unless
+// skew is negative, reporting the sessions by their hash
+func compare(ctx context.Context, primary, secondary Inspector, n int, skew time.Duration, hash func(sid string) string) (*ConsistencyReport, error) {
+	sids, err := primary.SampleSessions(ctx, n)
+	if err != nil {
+		return nil, fmt.Errorf("sample the primary sessions: %w", err)
+	}
+	report := &ConsistencyReport{}
+	for _, sid := range sids {
+		want, err := primary.PeekSession(ctx, sid)
+		if err != nil {
+			return nil, fmt.Errorf("read a primary session: %w", err)
+		} else if want == nil {
+			// removed since it was sampled
+			continue
+		}
+		report.Sampled++
+		got, err := secondary.PeekSession(ctx, sid)
+		if err != nil {
+			return nil, fmt.Errorf("read a secondary session: %w", err)
+		}
+		if got == nil {
+			report.Missing = append(report.Missing, hash(sid))
+		} else if !reflect.DeepEqual(got.Values, want.Values) {
+			report.Different = append(report.Different, hash(sid))
+		} else if d := got.ExpiredAt.Sub(want.ExpiredAt); skew >= 0 && (d > skew || d < -skew) {
+			report.Expiry = append(report.Expiry, hash(sid))
+		}
+	}
+	return report, nil
+}
+
+func (d *dualStore) CheckConsistency(ctx context.Context, opts ConsistencyOptions) (*ConsistencyReport, error) {
+	if opts.Samples <= 0 {
+		opts.Samples = DefaultConsistencySamples
+	}
+	if opts.MaxExpirySkew <= 0 {
+		opts.MaxExpirySkew = DefaultExpirySkew
+	}
+	pair := d.pair.Load()
+	primary, secondary, err := pair.inspectors()
+	if err != nil {
+		return nil, err
+	}
+	report, err := compare(ctx, primary, secondary, opts.Samples, opts.MaxExpirySkew, pair.hash)
+	if err != nil {
+		return nil, err
+	}
+	if pair.observe != nil {
+		for kind, hashes := range map[DivergenceKind][]string{DivergenceMissing: report.Missing, DivergenceValues: report.Different, DivergenceExpiry: report.Expiry} {
+			for _, h := range hashes {
+				pair.observe(Divergence{Op: OpVerify, Kind: kind, SIDHash: h})
+			}
+		}
+	}
+	return report, nil
+}
+
+func (d *dualStore) RunConsistencyChecks(ctx context.Context, interval time.Duration, opts ConsistencyOptions) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.CheckConsistency(ctx, opts); err != nil && ctx.Err() == nil {
+			if observe := d.pair.Load().observe; observe != nil {
+				observe(Divergence{Op: OpVerify, Kind: DivergenceError, Err: err})
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
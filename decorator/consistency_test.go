@@ -0,0 +1,84 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	Convey("Test the consistency checks of the dual writes", t, func() {
+		ctx := context.Background()
+		primary, secondary := newTestStore(), newTestStore()
+		var divergences []Divergence
+		store := Dual(secondary, func(d Divergence) {
+			divergences = append(divergences, d)
+		})(primary)
+		for _, sid := range []string{"s1", "s2", "s3", "s4"} {
+			st, err := store.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			st.Set("sid", sid)
+			So(st.Save(), ShouldBeNil)
+		}
+		checker := store.(ConsistencyChecker)
+
+		report, err := checker.CheckConsistency(ctx, ConsistencyOptions{})
+		So(err, ShouldBeNil)
+		So(report.Sampled, ShouldEqual, 4)
+		So(report.Diverged(), ShouldEqual, 0)
+		So(divergences, ShouldBeEmpty)
+
+		Convey("reporting the divergences", func() {
+			delete(secondary.sessions, "s1")
+			secondary.sessions["s2"] = map[string]interface{}{"sid": "other"}
+			now := time.Now()
+			primary.expiries["s3"] = now
+			secondary.expiries["s3"] = now.Add(time.Minute)
+			secondary.expiries["s4"] = now.Add(time.Second)
+			primary.expiries["s4"] = now
+
+			report, err := checker.CheckConsistency(ctx, ConsistencyOptions{})
+			So(err, ShouldBeNil)
+			So(report.Missing, ShouldResemble, []string{sidHash("s1")})
+			So(report.Different, ShouldResemble, []string{sidHash("s2")})
+			So(report.Expiry, ShouldResemble, []string{sidHash("s3")})
+			So(divergences, ShouldHaveLength, 3)
+			kinds := map[DivergenceKind]string{}
+			for _, d := range divergences {
+				So(d.Op, ShouldEqual, OpVerify)
+				kinds[d.Kind] = d.SIDHash
+			}
+			So(kinds, ShouldResemble, map[DivergenceKind]string{
+				DivergenceMissing: sidHash("s1"),
+				DivergenceValues:  sidHash("s2"),
+				DivergenceExpiry:  sidHash("s3"),
+			})
+		})
+
+		Convey("hashing the sessions with the hasher of the option", func() {
+			hasher := func(sid string) string { return "keyed-" + sid }
+			store := Dual(secondary, func(d Divergence) {
+				divergences = append(divergences, d)
+			}, WithSIDHasher(hasher))(primary)
+			delete(secondary.sessions, "s1")
+
+			report, err := store.(ConsistencyChecker).CheckConsistency(ctx, ConsistencyOptions{})
+			So(err, ShouldBeNil)
+			So(report.Missing, ShouldResemble, []string{"keyed-s1"})
+			So(divergences, ShouldHaveLength, 1)
+			So(divergences[0].SIDHash, ShouldEqual, "keyed-s1")
+		})
+
+		Convey("run periodically", func() {
+			delete(secondary.sessions, "s1")
+			ctx, cancel := context.WithTimeout(ctx, 25*time.Millisecond)
+			defer cancel()
+			err := checker.RunConsistencyChecks(ctx, 10*time.Millisecond, ConsistencyOptions{})
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			So(len(divergences), ShouldBeGreaterThanOrEqualTo, 2)
+		})
+	})
+}
@@ -0,0 +1,58 @@
+// Package decorator Wrappers adding a behavior (retries, metrics, caching, dual writes,
+// read-only mode) to any session.ManagerStore, of this module or of another go-session
+// backend, composed into the stack an application needs with Chain
+package decorator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	session "github.com/go-session/session/v3"
+)
+
+// Operations of the manager stores and of their session stores
+const (
+	OpCheck   = "check"
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpDelete  = "delete"
+	OpRefresh = "refresh"
+	OpSave    = "save"
+	OpFlush   = "flush"
+)
+
+// Decorator Wrap a manager store into one adding a behavior
+type Decorator func(next session.ManagerStore) session.ManagerStore
+
+// Chain Wrap store with the decorators, the first one being the outermost, e.g.
+// Chain(store, Metrics(observe), Retry(policy)) observes the calls including their retries
+func Chain(store session.ManagerStore, decorators ...Decorator) session.ManagerStore {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		store = decorators[i](store)
+	}
+	return store
+}
+
+// Unwrapper Implemented by the session stores of the decorators, wrapping the store of the
+// inner manager store
+type Unwrapper interface {
+	Unwrap() session.Store
+}
+
+// Unwrap The innermost session store of st, to reach the extensions of the backend (e.g.
+// mongo.Accessor) through the decorators
+func Unwrap(st session.Store) session.Store {
+	for {
+		u, ok := st.(Unwrapper)
+		if !ok {
+			return st
+		}
+		st = u.Unwrap()
+	}
+}
+
+// sidHash The default hash of the session ids, the SHA256SIDHasher of the mongo store
+func sidHash(sid string) string {
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,268 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	session "github.com/go-session/session/v3"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testStore A manager store keeping the sessions in memory, counting its calls and failing
+// them with the queued errors
+type testStore struct {
+	sync.Mutex
+	sessions map[string]map[string]interface{}
+	expiries map[string]time.Time
+	calls    map[string]int
+	errs     []error
+}
+
+func newTestStore() *testStore {
+	return &testStore{sessions: map[string]map[string]interface{}{}, expiries: map[string]time.Time{}, calls: map[string]int{}}
+}
+
+// call Count a call of op, returning the next queued error
+func (m *testStore) call(op string) error {
+	m.Lock()
+	defer m.Unlock()
+	m.calls[op]++
+	if len(m.errs) == 0 {
+		return nil
+	}
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return err
+}
+
+func (m *testStore) count(op string) int {
+	m.Lock()
+	defer m.Unlock()
+	return m.calls[op]
+}
+
+func (m *testStore) Check(_ context.Context, sid string) (bool, error) {
+	if err := m.call(OpCheck); err != nil {
+		return false, err
+	}
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.sessions[sid]
+	return ok, nil
+}
+
+func (m *testStore) Create(ctx context.Context, sid string, _ int64) (session.Store, error) {
+	if err := m.call(OpCreate); err != nil {
+		return nil, err
+	}
+	return &testSession{ctx: ctx, m: m, sid: sid, values: map[string]interface{}{}}, nil
+}
+
+func (m *testStore) Update(ctx context.Context, sid string, _ int64) (session.Store, error) {
+	if err := m.call(OpUpdate); err != nil {
+		return nil, err
+	}
+	m.Lock()
+	defer m.Unlock()
+	values := map[string]interface{}{}
+	for k, v := range m.sessions[sid] {
+		values[k] = v
+	}
+	return &testSession{ctx: ctx, m: m, sid: sid, values: values}, nil
+}
+
+func (m *testStore) Delete(_ context.Context, sid string) error {
+	if err := m.call(OpDelete); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	delete(m.sessions, sid)
+	return nil
+}
+
+func (m *testStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
+	if err := m.call(OpRefresh); err != nil {
+		return nil, err
+	}
+	m.Lock()
+	values := m.sessions[oldsid]
+	delete(m.sessions, oldsid)
+	m.sessions[sid] = values
+	m.Unlock()
+	return m.Update(ctx, sid, expired)
+}
+
+func (m *testStore) Close() error { return nil }
+
+func (m *testStore) Count(context.Context) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return int64(len(m.sessions)), nil
+}
+
+func (m *testStore) SampleSessions(_ context.Context, n int) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+	var sids []string
+	for sid := range m.sessions {
+		if len(sids) < n {
+			sids = append(sids, sid)
+		}
+	}
+	return sids, nil
+}
+
+func (m *testStore) PeekSession(_ context.Context, sid string) (*SessionState, error) {
+	m.Lock()
+	defer m.Unlock()
+	values, ok := m.sessions[sid]
+	if !ok {
+		return nil, nil
+	}
+	return &SessionState{Values: values, ExpiredAt: m.expiries[sid]}, nil
+}
+
+type testSession struct {
+	ctx    context.Context
+	m      *testStore
+	sid    string
+	values map[string]interface{}
+}
+
+func (s *testSession) Context() context.Context { return s.ctx }
+func (s *testSession) SessionID() string        { return s.sid }
+func (s *testSession) Set(key string, value interface{}) {
+	s.values[key] = value
+}
+func (s *testSession) Get(key string) (interface{}, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+func (s *testSession) Delete(key string) interface{} {
+	v := s.values[key]
+	delete(s.values, key)
+	return v
+}
+func (s *testSession) Save() error {
+	if err := s.m.call(OpSave); err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.m.sessions[s.sid] = s.Snapshot()
+	return nil
+}
+func (s *testSession) Flush() error {
+	s.values = map[string]interface{}{}
+	return s.Save()
+}
+func (s *testSession) Mutate(fn func(values map[string]interface{}) error) error {
+	values := s.Snapshot()
+	if err := fn(values); err != nil {
+		return err
+	}
+	s.values = values
+	return nil
+}
+func (s *testSession) Snapshot() map[string]interface{} {
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
+
+var errTest = errors.New("test error")
+
+// timeoutError A transient error of the tests
+type timeoutError struct{ msg string }
+
+func (e *timeoutError) Error() string   { return e.msg }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+var errTransient error = &timeoutError{msg: "test timeout"}
+
+func TestChain(t *testing.T) {
+	Convey("Test the composition of the decorators", t, func() {
+		backend := newTestStore()
+		var ops []string
+		store := Chain(backend, Metrics(func(o Observation) {
+			ops = append(ops, o.Op)
+		}), Retry(RetryPolicy{InitialBackoff: 1}))
+
+		backend.errs = []error{errTest}
+		st, err := store.Create(context.Background(), "s1", 60)
+		So(err, ShouldEqual, errTest)
+		So(st, ShouldBeNil)
+		st, err = store.Create(context.Background(), "s1", 60)
+		So(err, ShouldBeNil)
+		st.Set("a", 1)
+		backend.errs = []error{errTransient}
+		So(st.Save(), ShouldBeNil)
+		So(backend.count(OpSave), ShouldEqual, 2)
+		So(backend.sessions["s1"], ShouldResemble, map[string]interface{}{"a": 1})
+		So(ops, ShouldResemble, []string{OpCreate, OpCreate, OpSave})
+
+		inner := Unwrap(st)
+		_, ok := inner.(*testSession)
+		So(ok, ShouldBeTrue)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	Convey("Test the observations of the calls", t, func() {
+		backend := newTestStore()
+		var obs []Observation
+		store := Metrics(func(o Observation) {
+			obs = append(obs, o)
+		})(backend)
+
+		_, err := store.Check(context.Background(), "s1")
+		So(err, ShouldBeNil)
+		backend.errs = []error{errTest}
+		So(store.Delete(context.Background(), "s1"), ShouldEqual, errTest)
+		st, err := store.Update(context.Background(), "s1", 60)
+		So(err, ShouldBeNil)
+		So(st.Flush(), ShouldBeNil)
+
+		So(obs, ShouldHaveLength, 4)
+		So(obs[0].Op, ShouldEqual, OpCheck)
+		So(obs[0].Err, ShouldBeNil)
+		So(obs[1].Op, ShouldEqual, OpDelete)
+		So(obs[1].Err, ShouldEqual, errTest)
+		So(obs[2].Op, ShouldEqual, OpUpdate)
+		So(obs[3].Op, ShouldEqual, OpFlush)
+	})
+}
+
+func TestReadOnly(t *testing.T) {
+	Convey("Test the read-only mode", t, func() {
+		backend := newTestStore()
+		backend.sessions["s1"] = map[string]interface{}{"a": 1}
+		store := ReadOnly()(backend)
+
+		ok, err := store.Check(context.Background(), "s1")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		st, err := store.Update(context.Background(), "s1", 60)
+		So(err, ShouldBeNil)
+		v, _ := st.Get("a")
+		So(v, ShouldEqual, 1)
+		st.Set("a", 2)
+		So(st.Save(), ShouldEqual, ErrReadOnly)
+		So(st.Flush(), ShouldEqual, ErrReadOnly)
+		So(backend.sessions["s1"], ShouldResemble, map[string]interface{}{"a": 1})
+
+		_, err = store.Create(context.Background(), "s2", 60)
+		So(err, ShouldEqual, ErrReadOnly)
+		_, err = store.Refresh(context.Background(), "s1", "s2", 60)
+		So(err, ShouldEqual, ErrReadOnly)
+		So(store.Delete(context.Background(), "s1"), ShouldEqual, ErrReadOnly)
+		So(backend.calls[OpCreate]+backend.calls[OpRefresh]+backend.calls[OpDelete]+backend.calls[OpSave], ShouldEqual, 0)
+	})
+}
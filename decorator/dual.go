@@ -0,0 +1,364 @@
+package decorator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	session "github.com/go-session/session/v3"
+)
+
+// DivergenceKind The kind of a divergence between the stores of Dual
+type DivergenceKind string
+
+// Kinds of the divergences
+const (
+	// DivergenceFallback The session was missing from the primary store and read from the
+	// secondary one
+	DivergenceFallback DivergenceKind = "fallback"
+	// DivergenceMissing The session of the primary store was missing from the secondary one
+	DivergenceMissing DivergenceKind = "missing"
+	// DivergenceError An operation of the secondary store failed
+	DivergenceError DivergenceKind = "error"
+)
+
+// Divergence A difference between the primary and secondary stores of Dual
+type Divergence struct {
+	// Op The operation (OpCheck, OpUpdate, OpSave...) finding the divergence
+	Op   string
+	Kind DivergenceKind
+	// SIDHash A hash of the session id, by the hasher of WithSIDHasher
+	SIDHash string
+	// Err The error of the secondary store for DivergenceError
+	Err error
+}
+
+// dualPair The stores of Dual in their current roles
+type dualPair struct {
+	primary, secondary session.ManagerStore
+	observe            func(Divergence)
+	hash               func(sid string) string
+}
+
+// DualOption An option of Dual
+type DualOption func(*dualPair)
+
+// WithSIDHasher Obfuscate the session ids of the divergences and of the reports of the
+// consistency checks and promotions with hasher, e.g. the keyed one of the stores
+// (mongo.WithSIDHasher), instead of the first 8 bytes of their SHA-256 in hex
+func WithSIDHasher(hasher func(sid string) string) DualOption {
+	return func(d *dualPair) {
+		if hasher != nil {
+			d.hash = hasher
+		}
+	}
+}
+
+// dualStore The manager store of Dual, forwarding to the pair of its current roles swapped
+// by Promote
+type dualStore struct {
+	pair      atomic.Pointer[dualPair]
+	promoting sync.Mutex
+}
+
+// Dual Write the sessions to the wrapped store, the primary one, and to secondary, for the moves
+// between backends (e.g. between clusters or from another go-session backend): the sessions
+// are read from primary, falling back to secondary for the sessions primary is missing, which
+// are copied to primary when their store implements Mutate like mongo.Mutator (the others keep
+// being served by secondary alone). The errors of primary are returned and those of secondary
+// are only reported to observe (if not nil) with the other divergences, so that the cutover can
+// wait for them to stop
+func Dual(secondary session.ManagerStore, observe func(Divergence), opts ...DualOption) Decorator {
+	return func(primary session.ManagerStore) session.ManagerStore {
+		pair := &dualPair{primary: primary, secondary: secondary, observe: observe, hash: sidHash}
+		for _, opt := range opts {
+			opt(pair)
+		}
+		d := &dualStore{}
+		d.pair.Store(pair)
+		return d
+	}
+}
+
+// mutator The session stores whose values can be read at once, as mongo.Mutator
+type mutator interface {
+	Mutate(fn func(values map[string]interface{}) error) error
+}
+
+// diverge Report a divergence of the session sid
+func (d *dualPair) diverge(op string, kind DivergenceKind, sid string, err error) {
+	if d.observe != nil {
+		d.observe(Divergence{Op: op, Kind: kind, SIDHash: d.hash(sid), Err: err})
+	}
+}
+
+// shadow Tell whether the secondary store has the session sid, reporting its errors
+func (d *dualPair) shadow(ctx context.Context, op, sid string) bool {
+	ok, err := d.secondary.Check(ctx, sid)
+	if err != nil {
+		d.diverge(op, DivergenceError, sid, err)
+	} else if !ok {
+		d.diverge(op, DivergenceMissing, sid, nil)
+	}
+	return ok
+}
+
+func (d *dualPair) Check(ctx context.Context, sid string) (bool, error) {
+	ok, err := d.primary.Check(ctx, sid)
+	if err != nil || ok {
+		return ok, err
+	}
+	if ok, err = d.secondary.Check(ctx, sid); err != nil {
+		d.diverge(OpCheck, DivergenceError, sid, err)
+		return false, nil
+	} else if ok {
+		d.diverge(OpCheck, DivergenceFallback, sid, nil)
+	}
+	return ok, nil
+}
+
+func (d *dualPair) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	p, err := d.primary.Create(ctx, sid, expired)
+	if err != nil {
+		return nil, err
+	}
+	s, err := d.secondary.Create(ctx, sid, expired)
+	if err != nil {
+		d.diverge(OpCreate, DivergenceError, sid, err)
+		s = nil
+	}
+	return &dualSession{d: d, primary: p, secondary: s}, nil
+}
+
+func (d *dualPair) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	ok, err := d.primary.Check(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		p, err := d.primary.Update(ctx, sid, expired)
+		if err != nil {
+			return nil, err
+		}
+		return d.join(ctx, OpUpdate, p, func() (session.Store, error) {
+			return d.secondary.Update(ctx, sid, expired)
+		}, true)
+	}
+
+	found, err := d.secondary.Check(ctx, sid)
+	if err != nil {
+		d.diverge(OpUpdate, DivergenceError, sid, err)
+	}
+	if !found {
+		p, err := d.primary.Update(ctx, sid, expired)
+		if err != nil {
+			return nil, err
+		}
+		return d.join(ctx, OpUpdate, p, func() (session.Store, error) {
+			return d.secondary.Create(ctx, sid, expired)
+		}, false)
+	}
+	return d.fallback(OpUpdate, sid, func() (session.Store, error) {
+		return d.secondary.Update(ctx, sid, expired)
+	}, func() (session.Store, error) {
+		return d.primary.Create(ctx, sid, expired)
+	})
+}
+
+func (d *dualPair) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
+	ok, err := d.primary.Check(ctx, oldsid)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		p, err := d.primary.Refresh(ctx, oldsid, sid, expired)
+		if err != nil {
+			return nil, err
+		}
+		return d.join(ctx, OpRefresh, p, func() (session.Store, error) {
+			if !d.shadow(ctx, OpRefresh, oldsid) {
+				return d.secondary.Create(ctx, sid, expired)
+			}
+			return d.secondary.Refresh(ctx, oldsid, sid, expired)
+		}, false)
+	}
+
+	found, err := d.secondary.Check(ctx, oldsid)
+	if err != nil {
+		d.diverge(OpRefresh, DivergenceError, oldsid, err)
+	}
+	if !found {
+		p, err := d.primary.Refresh(ctx, oldsid, sid, expired)
+		if err != nil {
+			return nil, err
+		}
+		return d.join(ctx, OpRefresh, p, func() (session.Store, error) {
+			return d.secondary.Create(ctx, sid, expired)
+		}, false)
+	}
+	return d.fallback(OpRefresh, oldsid, func() (session.Store, error) {
+		return d.secondary.Refresh(ctx, oldsid, sid, expired)
+	}, func() (session.Store, error) {
+		return d.primary.Create(ctx, sid, expired)
+	})
+}
+
+// join Pair the store p of primary with the one of secondary opened by open, checking first
+// that secondary has the session if check is set, and copying the values of p to the store
+// of secondary missing it when they can be read at once
+func (d *dualPair) join(ctx context.Context, op string, p session.Store, open func() (session.Store, error), check bool) (session.Store, error) {
+	sid := p.SessionID()
+	missing := check && !d.shadow(ctx, op, sid)
+	s, err := open()
+	if err != nil {
+		d.diverge(op, DivergenceError, sid, err)
+		s = nil
+	} else if missing {
+		copyValues(p, s)
+	}
+	return &dualSession{d: d, primary: p, secondary: s}, nil
+}
+
+// copyValues Set the values of from to to, if from implements Mutate
+func copyValues(from, to session.Store) {
+	m, ok := from.(mutator)
+	if !ok {
+		return
+	}
+	_ = m.Mutate(func(values map[string]interface{}) error {
+		for k, v := range values {
+			to.Set(k, v)
+		}
+		return nil
+	})
+}
+
+// fallback Read the session sid missing from primary from secondary with open, copying it
+// to the store of primary created by create when its values can be read at once
+func (d *dualPair) fallback(op, sid string, open, create func() (session.Store, error)) (session.Store, error) {
+	d.diverge(op, DivergenceFallback, sid, nil)
+	s, err := open()
+	if err != nil {
+		d.diverge(op, DivergenceError, sid, err)
+		p, err := create()
+		if err != nil {
+			return nil, err
+		}
+		return &dualSession{d: d, primary: p}, nil
+	}
+	if _, ok := s.(mutator); !ok {
+		return s, nil
+	}
+	p, err := create()
+	if err != nil {
+		return nil, err
+	}
+	copyValues(s, p)
+	if err := p.Save(); err != nil {
+		return nil, err
+	}
+	return &dualSession{d: d, primary: p, secondary: s}, nil
+}
+
+func (d *dualPair) Delete(ctx context.Context, sid string) error {
+	err := d.primary.Delete(ctx, sid)
+	if serr := d.secondary.Delete(ctx, sid); serr != nil {
+		d.diverge(OpDelete, DivergenceError, sid, serr)
+	}
+	return err
+}
+
+func (d *dualPair) Close() error {
+	err := d.primary.Close()
+	if serr := d.secondary.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func (d *dualStore) Check(ctx context.Context, sid string) (bool, error) {
+	return d.pair.Load().Check(ctx, sid)
+}
+
+func (d *dualStore) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	return d.pair.Load().Create(ctx, sid, expired)
+}
+
+func (d *dualStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	return d.pair.Load().Update(ctx, sid, expired)
+}
+
+func (d *dualStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
+	return d.pair.Load().Refresh(ctx, oldsid, sid, expired)
+}
+
+func (d *dualStore) Delete(ctx context.Context, sid string) error {
+	return d.pair.Load().Delete(ctx, sid)
+}
+
+func (d *dualStore) Close() error {
+	return d.pair.Load().Close()
+}
+
+// dualSession The session store of Dual, secondary is nil when it couldn't be opened
+type dualSession struct {
+	d                  *dualPair
+	primary, secondary session.Store
+}
+
+func (s *dualSession) Unwrap() session.Store {
+	return s.primary
+}
+
+func (s *dualSession) Context() context.Context {
+	return s.primary.Context()
+}
+
+func (s *dualSession) SessionID() string {
+	return s.primary.SessionID()
+}
+
+func (s *dualSession) Set(key string, value interface{}) {
+	s.primary.Set(key, value)
+	if s.secondary != nil {
+		s.secondary.Set(key, value)
+	}
+}
+
+func (s *dualSession) Get(key string) (interface{}, bool) {
+	return s.primary.Get(key)
+}
+
+func (s *dualSession) Delete(key string) interface{} {
+	v := s.primary.Delete(key)
+	if s.secondary != nil {
+		if sv := s.secondary.Delete(key); v == nil {
+			v = sv
+		}
+	}
+	return v
+}
+
+func (s *dualSession) Save() error {
+	if err := s.primary.Save(); err != nil {
+		return err
+	}
+	if s.secondary != nil {
+		if err := s.secondary.Save(); err != nil {
+			s.d.diverge(OpSave, DivergenceError, s.SessionID(), err)
+		}
+	}
+	return nil
+}
+
+func (s *dualSession) Flush() error {
+	if err := s.primary.Flush(); err != nil {
+		return err
+	}
+	if s.secondary != nil {
+		if err := s.secondary.Flush(); err != nil {
+			s.d.diverge(OpSave, DivergenceError, s.SessionID(), err)
+		}
+	}
+	return nil
+}
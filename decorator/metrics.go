@@ -0,0 +1,110 @@
+package decorator
+
+import (
+	"context"
+	"time"
+
+	session "github.com/go-session/session/v3"
+)
+
+// Observation A call of a store of Metrics
+type Observation struct {
+	// Op The operation called (OpCheck, OpCreate...)
+	Op string
+	// Duration The time the call took
+	Duration time.Duration
+	// Err The error of the call, nil if it succeeded
+	Err error
+}
+
+// metricsStore The manager store of Metrics
+type metricsStore struct {
+	next    session.ManagerStore
+	observe func(Observation)
+}
+
+// Metrics Report the calls of the wrapped store, and the saves and flushes of its session
+// stores, to observe (e.g. to feed a histogram per operation), which must not block
+func Metrics(observe func(Observation)) Decorator {
+	return func(next session.ManagerStore) session.ManagerStore {
+		return &metricsStore{next: next, observe: observe}
+	}
+}
+
+// record Report the call of op started at start
+func (m *metricsStore) record(op string, start time.Time, err error) {
+	if m.observe != nil {
+		m.observe(Observation{Op: op, Duration: time.Since(start), Err: err})
+	}
+}
+
+// wrap The session store of st, nil if st is
+func (m *metricsStore) wrap(st session.Store) session.Store {
+	if st == nil {
+		return nil
+	}
+	return &metricsSession{Store: st, m: m}
+}
+
+func (m *metricsStore) Check(ctx context.Context, sid string) (bool, error) {
+	start := time.Now()
+	ok, err := m.next.Check(ctx, sid)
+	m.record(OpCheck, start, err)
+	return ok, err
+}
+
+func (m *metricsStore) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	start := time.Now()
+	st, err := m.next.Create(ctx, sid, expired)
+	m.record(OpCreate, start, err)
+	return m.wrap(st), err
+}
+
+func (m *metricsStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	start := time.Now()
+	st, err := m.next.Update(ctx, sid, expired)
+	m.record(OpUpdate, start, err)
+	return m.wrap(st), err
+}
+
+func (m *metricsStore) Delete(ctx context.Context, sid string) error {
+	start := time.Now()
+	err := m.next.Delete(ctx, sid)
+	m.record(OpDelete, start, err)
+	return err
+}
+
+func (m *metricsStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
+	start := time.Now()
+	st, err := m.next.Refresh(ctx, oldsid, sid, expired)
+	m.record(OpRefresh, start, err)
+	return m.wrap(st), err
+}
+
+func (m *metricsStore) Close() error {
+	return m.next.Close()
+}
+
+// metricsSession The session store of Metrics
+type metricsSession struct {
+	session.Store
+	m *metricsStore
+}
+
+func (s *metricsSession) Unwrap() session.Store {
+	return s.Store
+}
+
+func (s *metricsSession) Save() error {
+	start := time.Now()
+	err := s.Store.Save()
+	s.m.record(OpSave, start, err)
+	return err
+}
+
+func (s *metricsSession) Flush() error {
+	start := time.Now()
+	err := s.Store.Flush()
+	s.m.record(OpFlush, start, err)
+	return err
+}
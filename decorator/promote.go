@@ -0,0 +1,122 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultPromoteSamples The number of sessions compared by Promote by default
+const DefaultPromoteSamples = 100
+
+// ErrIncomplete The secondary store of Dual is missing too many sessions of the primary one
+var ErrIncomplete = errors.New("secondary session store is incomplete")
+
+// Inspector Implemented by the manager stores which Promote can verify, as the mongo ones:
+// the sessions are read without extending their expiration
+type Inspector interface {
+	// Count Count the active sessions
+	Count(ctx context.Context) (int64, error)
+	// SampleSessions The ids of n active sessions at most, chosen randomly
+	SampleSessions(ctx context.Context, n int) ([]string, error)
+	// PeekSession The state of the active session sid, nil if there is no such session
+	PeekSession(ctx context.Context, sid string) (*SessionState, error)
+}
+
+// SessionState The state of a session read by an Inspector
+type SessionState struct {
+	Values    map[string]interface{}
+	ExpiredAt time.Time
+}
+
+// PromoteOptions The options of Promote, the defaults being used for the zero values
+type PromoteOptions struct {
+	// Samples The number of sessions of the primary store compared with the secondary one
+	// (DefaultPromoteSamples)
+	Samples int
+	// MaxMissing The share of the sessions of the primary store the secondary one may miss or
+	// hold with other values, as counted and among the samples (none by default), for the
+	// sessions written during the verification
+	MaxMissing float64
+	// DryRun Verify the secondary store without promoting it
+	DryRun bool
+}
+
+// PromoteReport The outcome of Promote
+type PromoteReport struct {
+	// PrimaryCount and SecondaryCount The numbers of active sessions of the stores
+	PrimaryCount   int64
+	SecondaryCount int64
+	// Sampled The number of sessions compared
+	Sampled int
+	// Missing and Different The hashes of the sampled sessions missing from the secondary
+	// store or holding other values there
+	Missing   []string
+	Different []string
+	// Promoted Whether the reads were flipped to the secondary store
+	Promoted bool
+}
+
+// Standby Implemented by the stores of Dual, to complete a planned move to its secondary store
+type Standby interface {
+	// Promote Verify that the secondary store is complete, comparing the counts of sessions
+	// of the stores and the values of samples of the primary one, and swap the roles of the
+	// stores: the sessions are then read from the former secondary store, and still written to
+	// both so that the move can be reverted with another Promote. The error is ErrIncomplete,
+	// with the report, when the verification fails
+	Promote(ctx context.Context, opts PromoteOptions) (*PromoteReport, error)
+}
+
+var _ Standby = &dualStore{}
+
+// inspectors The stores of the pair as Inspectors
+func (d *dualPair) inspectors() (primary, secondary Inspector, err error) {
+	primary, ok := d.primary.(Inspector)
+	if !ok {
+		return nil, nil, fmt.Errorf("primary session store %T can't be inspected", d.primary)
+	}
+	secondary, ok = d.secondary.(Inspector)
+	if !ok {
+		return nil, nil, fmt.Errorf("secondary session store %T can't be inspected", d.secondary)
+	}
+	return primary, secondary, nil
+}
+
+func (d *dualStore) Promote(ctx context.Context, opts PromoteOptions) (*PromoteReport, error) {
+	if opts.Samples <= 0 {
+		opts.Samples = DefaultPromoteSamples
+	}
+	d.promoting.Lock()
+	defer d.promoting.Unlock()
+
+	pair := d.pair.Load()
+	primary, secondary, err := pair.inspectors()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PromoteReport{}
+	if report.PrimaryCount, err = primary.Count(ctx); err != nil {
+		return nil, fmt.Errorf("count the primary sessions: %w", err)
+	}
+	if report.SecondaryCount, err = secondary.Count(ctx); err != nil {
+		return nil, fmt.Errorf("count the secondary sessions: %w", err)
+	}
+	c, err := compare(ctx, primary, secondary, opts.Samples, -1, pair.hash)
+	if err != nil {
+		return nil, err
+	}
+	report.Sampled, report.Missing, report.Different = c.Sampled, c.Missing, c.Different
+
+	missing := float64(report.PrimaryCount-report.SecondaryCount) > opts.MaxMissing*float64(report.PrimaryCount)
+	diverged := float64(len(report.Missing)+len(report.Different)) > opts.MaxMissing*float64(report.Sampled)
+	if missing || diverged {
+		return report, ErrIncomplete
+	}
+	if !opts.DryRun {
+		d.pair.Store(&dualPair{primary: pair.secondary, secondary: pair.primary, observe: pair.observe, hash: pair.hash})
+		report.Promoted = true
+	}
+	return report, nil
+}
@@ -0,0 +1,74 @@
+package decorator
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPromote(t *testing.T) {
+	Convey("Test the promotion of the secondary store", t, func() {
+		ctx := context.Background()
+		primary, secondary := newTestStore(), newTestStore()
+		store := Dual(secondary, nil)(primary)
+		for _, sid := range []string{"s1", "s2", "s3", "s4"} {
+			st, err := store.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			st.Set("sid", sid)
+			So(st.Save(), ShouldBeNil)
+		}
+		standby := store.(Standby)
+
+		Convey("verified without promoting it", func() {
+			report, err := standby.Promote(ctx, PromoteOptions{DryRun: true})
+			So(err, ShouldBeNil)
+			So(report.PrimaryCount, ShouldEqual, 4)
+			So(report.SecondaryCount, ShouldEqual, 4)
+			So(report.Sampled, ShouldEqual, 4)
+			So(report.Promoted, ShouldBeFalse)
+		})
+
+		Convey("refused when incomplete", func() {
+			delete(secondary.sessions, "s1")
+			secondary.sessions["s2"] = map[string]interface{}{"sid": "other"}
+			report, err := standby.Promote(ctx, PromoteOptions{})
+			So(err, ShouldEqual, ErrIncomplete)
+			So(report.Missing, ShouldResemble, []string{sidHash("s1")})
+			So(report.Different, ShouldResemble, []string{sidHash("s2")})
+			So(report.Promoted, ShouldBeFalse)
+
+			_, err = standby.Promote(ctx, PromoteOptions{MaxMissing: 0.5})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("filled by the dual writes", func() {
+			primary.sessions["s5"] = map[string]interface{}{"sid": "s5"}
+			st, err := store.Update(ctx, "s5", 60)
+			So(err, ShouldBeNil)
+			st.Set("n", 1)
+			So(st.Save(), ShouldBeNil)
+			So(secondary.sessions["s5"], ShouldResemble, map[string]interface{}{"sid": "s5", "n": 1})
+
+			report, err := standby.Promote(ctx, PromoteOptions{DryRun: true})
+			So(err, ShouldBeNil)
+			So(report.Sampled, ShouldEqual, 5)
+		})
+
+		Convey("flipping the reads", func() {
+			report, err := standby.Promote(ctx, PromoteOptions{Samples: 2})
+			So(err, ShouldBeNil)
+			So(report.Sampled, ShouldEqual, 2)
+			So(report.Promoted, ShouldBeTrue)
+
+			secondary.sessions["s1"] = map[string]interface{}{"sid": "promoted"}
+			st, err := store.Update(ctx, "s1", 60)
+			So(err, ShouldBeNil)
+			v, _ := st.Get("sid")
+			So(v, ShouldEqual, "promoted")
+			st.Set("n", 1)
+			So(st.Save(), ShouldBeNil)
+			So(primary.sessions["s1"]["n"], ShouldEqual, 1)
+		})
+	})
+}
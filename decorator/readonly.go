@@ -0,0 +1,71 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+
+	session "github.com/go-session/session/v3"
+)
+
+// ErrReadOnly The store of ReadOnly doesn't write the sessions
+var ErrReadOnly = errors.New("session store is read-only")
+
+// readOnlyStore The manager store of ReadOnly
+type readOnlyStore struct {
+	next session.ManagerStore
+}
+
+// ReadOnly Serve the existing sessions of the wrapped store without writing them, e.g. during
+// a maintenance of its backend: Check and Update pass through, Create, Refresh and Delete
+// fail with ErrReadOnly, as do the saves and flushes of the session stores, which keep their
+// changes in memory for the request
+func ReadOnly() Decorator {
+	return func(next session.ManagerStore) session.ManagerStore {
+		return &readOnlyStore{next: next}
+	}
+}
+
+func (r *readOnlyStore) Check(ctx context.Context, sid string) (bool, error) {
+	return r.next.Check(ctx, sid)
+}
+
+func (r *readOnlyStore) Create(context.Context, string, int64) (session.Store, error) {
+	return nil, ErrReadOnly
+}
+
+func (r *readOnlyStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	st, err := r.next.Update(ctx, sid, expired)
+	if err != nil || st == nil {
+		return st, err
+	}
+	return &readOnlySession{Store: st}, nil
+}
+
+func (r *readOnlyStore) Delete(context.Context, string) error {
+	return ErrReadOnly
+}
+
+func (r *readOnlyStore) Refresh(context.Context, string, string, int64) (session.Store, error) {
+	return nil, ErrReadOnly
+}
+
+func (r *readOnlyStore) Close() error {
+	return r.next.Close()
+}
+
+// readOnlySession The session store of ReadOnly
+type readOnlySession struct {
+	session.Store
+}
+
+func (s *readOnlySession) Unwrap() session.Store {
+	return s.Store
+}
+
+func (s *readOnlySession) Save() error {
+	return ErrReadOnly
+}
+
+func (s *readOnlySession) Flush() error {
+	return ErrReadOnly
+}
@@ -0,0 +1,258 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Defaults of Retry
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 50 * time.Millisecond
+	DefaultRetryMaxBackoff = time.Second
+)
+
+// RetryPolicy The retries of the calls failing with a transient error, of Retry and of the
+// mongo store (mongo.RetryPolicy), Retry using the defaults for the zero values
+type RetryPolicy struct {
+	// MaxAttempts The number of attempts of a call, including the first one (DefaultRetryAttempts)
+	MaxAttempts int
+	// InitialBackoff The delay before the first retry, doubled for each next one up to
+	// MaxBackoff (DefaultRetryBackoff)
+	InitialBackoff time.Duration
+	// MaxBackoff The maximum delay between two attempts (DefaultRetryMaxBackoff)
+	MaxBackoff time.Duration
+	// Jitter Fraction (0 to 1) of each delay drawn at random, to spread the retries
+	Jitter float64
+	// Retryable Tell whether a call failing with err is retried, IsTransientError if nil
+	Retryable func(err error) bool
+	// Ops The operations retried by Retry (OpCheck, OpUpdate, OpDelete, OpSave and OpFlush by
+	// default), OpCreate and OpRefresh are only safe to retry when the backend makes them
+	// idempotent; the mongo store picks the policy of each operation with
+	// mongo.WithOperationRetryPolicy instead
+	Ops []string
+}
+
+// Server error codes of the transient failures (elections, shutdowns, network)
+var transientCodes = []int{6, 7, 89, 91, 189, 262, 9001, 10107, 11600, 11602, 13435, 13436}
+
+// IsTransientError Whether err is a network error, a timeout that isn't the one of the
+// context, or a server error due to a replica set state change, that an immediate retry may
+// not hit; the permanent failures (conflicts, ownership, sizes...) aren't
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		if se.HasErrorLabel("RetryableWriteError") || se.HasErrorLabel("TransientTransactionError") {
+			return true
+		}
+		for _, code := range transientCodes {
+			if se.HasErrorCode(code) {
+				return true
+			}
+		}
+		return false
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// Backoff The delay before the retry following attempt (from 1), the jitter being drawn
+// with int63n (math/rand if nil)
+func (p RetryPolicy) Backoff(attempt int, int63n func(n int64) int64) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 && d > 0 {
+		if int63n == nil {
+			int63n = rand.Int63n
+		}
+		jitter := time.Duration(p.Jitter * float64(d))
+		d = d - jitter + time.Duration(int63n(int64(jitter)+1))
+	}
+	return d
+}
+
+// budgetBackoff The backoff d truncated so that another attempt as long as the last one,
+// which took took, still ends by the deadline of ctx, false if none can
+func budgetBackoff(ctx context.Context, d, took time.Duration) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return d, true
+	}
+	left := time.Until(deadline) - took
+	if left <= 0 {
+		return 0, false
+	} else if d > left {
+		d = left
+	}
+	return d, true
+}
+
+// RetryNotice A retry of RetryPolicy.Do about to wait for Backoff, or given up because the
+// deadline of the context is too close
+type RetryNotice struct {
+	// Attempt The attempt which failed with Err, from 1
+	Attempt int
+	Backoff time.Duration
+	Err     error
+	GivenUp bool
+}
+
+// Do Call fn until it succeeds, fails with an error that isn't retryable, or the attempts or
+// ctx are exhausted, waiting for the backoffs in between, shortened or given up so that
+// another attempt as long as the last one ends by the deadline of ctx; int63n draws the
+// jitter (as in Backoff), notify (if not nil) is told of the retries
+func (p RetryPolicy) Do(ctx context.Context, int63n func(n int64) int64, notify func(RetryNotice), fn func() error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+		d, ok := budgetBackoff(ctx, p.Backoff(attempt, int63n), time.Since(start))
+		if notify != nil {
+			notify(RetryNotice{Attempt: attempt, Backoff: d, Err: err, GivenUp: !ok})
+		}
+		if !ok {
+			return err
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryStore The manager store of Retry
+type retryStore struct {
+	next session.ManagerStore
+	p    RetryPolicy
+	ops  map[string]bool
+}
+
+// Retry Retry the calls of the wrapped store, and the saves of its session stores, failing
+// with the errors policy tells retryable (the transient ones by default) as RetryPolicy.Do
+func Retry(policy RetryPolicy) Decorator {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = DefaultRetryBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	ops := policy.Ops
+	if ops == nil {
+		ops = []string{OpCheck, OpUpdate, OpDelete, OpSave, OpFlush}
+	}
+	set := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		set[op] = true
+	}
+	return func(next session.ManagerStore) session.ManagerStore {
+		return &retryStore{next: next, p: policy, ops: set}
+	}
+}
+
+// do Run fn for op following the policy, if op is retried
+func (r *retryStore) do(ctx context.Context, op string, fn func() error) error {
+	if !r.ops[op] {
+		return fn()
+	}
+	return r.p.Do(ctx, nil, nil, fn)
+}
+
+// wrap The session store of st, nil if st is
+func (r *retryStore) wrap(st session.Store) session.Store {
+	if st == nil {
+		return nil
+	}
+	return &retrySession{Store: st, r: r}
+}
+
+func (r *retryStore) Check(ctx context.Context, sid string) (ok bool, err error) {
+	err = r.do(ctx, OpCheck, func() (err error) {
+		ok, err = r.next.Check(ctx, sid)
+		return err
+	})
+	return
+}
+
+func (r *retryStore) Create(ctx context.Context, sid string, expired int64) (st session.Store, err error) {
+	err = r.do(ctx, OpCreate, func() (err error) {
+		st, err = r.next.Create(ctx, sid, expired)
+		return err
+	})
+	return r.wrap(st), err
+}
+
+func (r *retryStore) Update(ctx context.Context, sid string, expired int64) (st session.Store, err error) {
+	err = r.do(ctx, OpUpdate, func() (err error) {
+		st, err = r.next.Update(ctx, sid, expired)
+		return err
+	})
+	return r.wrap(st), err
+}
+
+func (r *retryStore) Delete(ctx context.Context, sid string) error {
+	return r.do(ctx, OpDelete, func() error {
+		return r.next.Delete(ctx, sid)
+	})
+}
+
+func (r *retryStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (st session.Store, err error) {
+	err = r.do(ctx, OpRefresh, func() (err error) {
+		st, err = r.next.Refresh(ctx, oldsid, sid, expired)
+		return err
+	})
+	return r.wrap(st), err
+}
+
+func (r *retryStore) Close() error {
+	return r.next.Close()
+}
+
+// retrySession The session store of Retry
+type retrySession struct {
+	session.Store
+	r *retryStore
+}
+
+func (s *retrySession) Unwrap() session.Store {
+	return s.Store
+}
+
+func (s *retrySession) Save() error {
+	return s.r.do(s.Context(), OpSave, s.Store.Save)
+}
+
+func (s *retrySession) Flush() error {
+	return s.r.do(s.Context(), OpFlush, s.Store.Flush)
+}
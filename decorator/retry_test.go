@@ -0,0 +1,95 @@
+package decorator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetry(t *testing.T) {
+	Convey("Test the retries of the calls", t, func() {
+		backend := newTestStore()
+		store := Retry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})(backend)
+
+		backend.errs = []error{errTransient, errTransient}
+		_, err := store.Check(context.Background(), "s1")
+		So(err, ShouldBeNil)
+		So(backend.count(OpCheck), ShouldEqual, 3)
+
+		Convey("until the attempts are exhausted", func() {
+			backend.errs = []error{errTransient, errTransient, errTransient}
+			So(store.Delete(context.Background(), "s1"), ShouldEqual, errTransient)
+			So(backend.count(OpDelete), ShouldEqual, 3)
+		})
+
+		Convey("not for the operations which aren't idempotent", func() {
+			backend.errs = []error{errTest}
+			_, err := store.Create(context.Background(), "s1", 60)
+			So(err, ShouldEqual, errTest)
+			So(backend.count(OpCreate), ShouldEqual, 1)
+			backend.errs = []error{errTest}
+			_, err = store.Refresh(context.Background(), "s1", "s2", 60)
+			So(err, ShouldEqual, errTest)
+			So(backend.count(OpRefresh), ShouldEqual, 1)
+		})
+
+		Convey("not for the errors which aren't retryable", func() {
+			store := Retry(RetryPolicy{InitialBackoff: time.Millisecond, Retryable: func(err error) bool {
+				return err != errTest
+			}})(backend)
+			backend.errs = []error{errTest}
+			_, err := store.Update(context.Background(), "s1", 60)
+			So(err, ShouldEqual, errTest)
+			So(backend.count(OpUpdate), ShouldEqual, 1)
+		})
+
+		Convey("only for the transient errors by default", func() {
+			backend.errs = []error{errTest}
+			So(store.Delete(context.Background(), "s1"), ShouldEqual, errTest)
+			So(backend.count(OpDelete), ShouldEqual, 1)
+		})
+
+		Convey("within the deadline of the context", func() {
+			store := Retry(RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Hour})(backend)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			backend.errs = []error{errTransient}
+			_, err := store.Check(ctx, "s1")
+			So(err, ShouldBeNil)
+			So(backend.count(OpCheck), ShouldEqual, 5)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+
+			backend.errs = make([]error, 10)
+			for i := range backend.errs {
+				backend.errs[i] = errTransient
+			}
+			_, err = store.Check(ctx, "s1")
+			So(err, ShouldEqual, errTransient)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+		})
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("Test the backoffs of the retry policy", t, func() {
+		p := RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 3 * time.Millisecond}
+		So(p.Backoff(1, nil), ShouldEqual, time.Millisecond)
+		So(p.Backoff(2, nil), ShouldEqual, 2*time.Millisecond)
+		So(p.Backoff(5, nil), ShouldEqual, 3*time.Millisecond)
+
+		d, ok := budgetBackoff(context.Background(), time.Hour, time.Second)
+		So(ok, ShouldBeTrue)
+		So(d, ShouldEqual, time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, ok = budgetBackoff(ctx, time.Hour, 2*time.Second)
+		So(ok, ShouldBeFalse)
+
+		So(IsTransientError(errTransient), ShouldBeTrue)
+		So(IsTransientError(errTest), ShouldBeFalse)
+		So(IsTransientError(context.DeadlineExceeded), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// markDirty Record the modification of key since the last save, s must be locked
+func (s *store) markDirty(key string) {
+	if s.dirty == nil {
+		s.dirty = make(map[string]struct{})
+	}
+	s.dirty[key] = struct{}{}
+	delete(s.filtered, key)
+}
+
+// restoreDirty Put back the modifications taken by a failed save, s must be locked
+func (s *store) restoreDirty(dirty map[string]struct{}, flushed bool) {
+	if s.dirty == nil && len(dirty) > 0 {
+		s.dirty = make(map[string]struct{}, len(dirty))
+	}
+	// the values stay filtered
+	for key := range dirty {
+		s.dirty[key] = struct{}{}
+	}
+	s.flushed = s.flushed || flushed
+}
+
+// keyUpdate The $set and $unset of the modified keys of a session stored as a subdocument,
+// false if some key can't be addressed as a field path
+func keyUpdate(values map[string]interface{}, dirty map[string]struct{}) (bson.M, bson.M, bool) {
+	set, unset := bson.M{}, bson.M{}
+	for key := range dirty {
+		if key == "" || strings.ContainsAny(key, ".\x00") || strings.HasPrefix(key, "$") {
+			return nil, nil, false
+		}
+		if v, ok := values[key]; ok {
+			set["value."+key] = v
+		} else {
+			unset["value."+key] = ""
+		}
+	}
+	return set, unset, true
+}
+
+// updateKeys Apply the key updates to the session document of version expect,
+// false if there is no such document of sid to update
+func (s *managerStore) updateKeys(ctx context.Context, sid string, set, unset bson.M, expiredAt time.Time, expect int64) (bool, error) {
+	set["expired_at"] = expiredAt
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	s.uncache(sid)
+	res, err := s.c.UpdateOne(ctx, s.versionSelector(sid, expect), update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
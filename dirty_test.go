@@ -0,0 +1,56 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestDirtyTracking(t *testing.T) {
+	mstore := newOfflineStore(t, WithDocumentValues(true), WithOperationTimeout(50*time.Millisecond))
+
+	Convey("Test saves limited to the modified keys", t, func() {
+		value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar", "n": int64(1)})
+		So(err, ShouldBeNil)
+		store, err := newLoadedStore(mstore, &sessionItem{Value: value}, newStore(context.Background(), mstore, "test_dirty", 10, nil))
+		So(err, ShouldBeNil)
+		So(store.partial, ShouldBeTrue)
+
+		Convey("unmodified sessions are not written", func() {
+			foo, _ := store.Get("foo")
+			So(foo, ShouldEqual, "bar")
+			So(store.Save(), ShouldBeNil)
+		})
+
+		Convey("modified keys are kept after a failed save", func() {
+			store.Set("foo", "baz")
+			store.Delete("n")
+			store.Delete("missing")
+			So(store.dirty, ShouldHaveLength, 2)
+
+			set, unset, ok := keyUpdate(store.values, store.dirty)
+			So(ok, ShouldBeTrue)
+			So(set, ShouldResemble, bson.M{"value.foo": "baz"})
+			So(unset, ShouldResemble, bson.M{"value.n": ""})
+
+			So(store.Save(), ShouldNotBeNil)
+			So(store.dirty, ShouldHaveLength, 2)
+		})
+
+		Convey("flushed sessions are rewritten", func() {
+			So(store.Flush(), ShouldNotBeNil)
+			So(store.flushed, ShouldBeTrue)
+			So(store.dirty, ShouldBeEmpty)
+		})
+
+		Convey("keys that are not field paths", func() {
+			_, _, ok := keyUpdate(nil, map[string]struct{}{"a.b": {}})
+			So(ok, ShouldBeFalse)
+			_, _, ok = keyUpdate(nil, map[string]struct{}{"$a": {}})
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
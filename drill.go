@@ -0,0 +1,216 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Defaults of the failover drills
+const (
+	DefaultDrillDuration    = 30 * time.Second
+	DefaultDrillWarmup      = 5 * time.Second
+	DefaultDrillStepDown    = 10 * time.Second
+	DefaultDrillConcurrency = 4
+	DefaultDrillSlow        = time.Second
+)
+
+// DrillOptions The options of a failover drill, the defaults being used for the zero values
+type DrillOptions struct {
+	// Duration The duration of the drill (DefaultDrillDuration)
+	Duration time.Duration
+	// Warmup The delay before the step down (DefaultDrillWarmup)
+	Warmup time.Duration
+	// StepDown The time the stepped down primary can't be reelected (DefaultDrillStepDown)
+	StepDown time.Duration
+	// Concurrency The number of sessions operated concurrently (DefaultDrillConcurrency)
+	Concurrency int
+	// Slow The latency from which an operation counts as not recovered (DefaultDrillSlow)
+	Slow time.Duration
+}
+
+// DrillReport The outcome of a failover drill
+type DrillReport struct {
+	// Operations The number of operations run, Errors those which failed and ErrorLabels
+	// their number per ErrorLabel
+	Operations  int64
+	Errors      int64
+	ErrorLabels map[string]int64
+	// StepDownAt The time of the step down
+	StepDownAt time.Time
+	// Recovery The time from the step down to the end of the last operation which failed
+	// or was slow, zero if none was
+	Recovery time.Duration
+	// MaxLatency The latency of the slowest operation
+	MaxLatency time.Duration
+}
+
+// ErrorRate The share of the operations which failed
+func (r *DrillReport) ErrorRate() float64 {
+	if r.Operations == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Operations)
+}
+
+// Driller Implemented by the stores, to check in staging how the configured retries and
+// timeouts ride out a failover of the replica set
+type Driller interface {
+	// Drill Operate sessions (create, save, update, delete) while forcing the primary of the
+	// replica set to step down, reporting the errors and the recovery; the command needs the
+	// replSetStepDown privilege. The sessions of the drill are removed and left to expire
+	// when their deletion fails
+	Drill(ctx context.Context, opts DrillOptions) (*DrillReport, error)
+}
+
+// drillRecorder The recorder of the operations of a drill
+type drillRecorder struct {
+	sync.Mutex
+	report   DrillReport
+	slow     time.Duration
+	unstable time.Time
+}
+
+// record Record an operation started at start which took took
+func (r *drillRecorder) record(start time.Time, took time.Duration, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.report.Operations++
+	if took > r.report.MaxLatency {
+		r.report.MaxLatency = took
+	}
+	if err != nil {
+		r.report.Errors++
+		r.report.ErrorLabels[ErrorLabel(err)]++
+	}
+	stepDown := r.report.StepDownAt
+	if end := start.Add(took); !stepDown.IsZero() && end.After(stepDown) && (err != nil || took >= r.slow) && end.After(r.unstable) {
+		r.unstable = end
+	}
+}
+
+// stepped Record the time of the step down
+func (r *drillRecorder) stepped(at time.Time) {
+	r.Lock()
+	r.report.StepDownAt = at
+	r.Unlock()
+}
+
+// drillCycle Run the operations of a session of the drill
+func (s *managerStore) drillCycle(ctx context.Context, r *drillRecorder) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return
+	}
+	sid := "drill_" + hex.EncodeToString(b)
+	expired := int64(time.Minute.Seconds())
+
+	run := func(fn func() error) bool {
+		start := time.Now()
+		err := fn()
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return false
+		}
+		r.record(start, time.Since(start), err)
+		return err == nil
+	}
+	ok := run(func() error {
+		st, err := s.Create(ctx, sid, expired)
+		if err != nil {
+			return err
+		}
+		st.Set("drill", time.Now().UnixNano())
+		return st.Save()
+	})
+	if ok {
+		run(func() error {
+			st, err := s.Update(ctx, sid, expired)
+			if err != nil {
+				return err
+			}
+			st.Set("drill", time.Now().UnixNano())
+			return st.Save()
+		})
+	}
+	run(func() error {
+		err := s.Delete(context.Background(), sid)
+		if errors.Is(err, ErrSessionNotFound) {
+			err = nil
+		}
+		return err
+	})
+}
+
+func (s *managerStore) Drill(ctx context.Context, opts DrillOptions) (*DrillReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = DefaultDrillDuration
+	}
+	if opts.Warmup <= 0 {
+		opts.Warmup = DefaultDrillWarmup
+	}
+	if opts.StepDown <= 0 {
+		opts.StepDown = DefaultDrillStepDown
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultDrillConcurrency
+	}
+	if opts.Slow <= 0 {
+		opts.Slow = DefaultDrillSlow
+	}
+	if opts.Warmup >= opts.Duration {
+		return nil, fmt.Errorf("drill warmup %s not shorter than its duration %s", opts.Warmup, opts.Duration)
+	}
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+
+	r := &drillRecorder{slow: opts.Slow, report: DrillReport{ErrorLabels: make(map[string]int64)}}
+	runCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for runCtx.Err() == nil {
+				s.drillCycle(runCtx, r)
+			}
+		}()
+	}
+
+	timer := time.NewTimer(opts.Warmup)
+	defer timer.Stop()
+	select {
+	case <-runCtx.Done():
+	case <-timer.C:
+	}
+	at := time.Now()
+	r.stepped(at)
+	cmd := bson.D{{Key: "replSetStepDown", Value: int64(opts.StepDown.Seconds())}}
+	// the servers before 4.2 close the connections on a step down, failing the command
+	err := s.client.Database("admin").RunCommand(runCtx, cmd).Err()
+	if err != nil && !mongo.IsNetworkError(err) {
+		cancel()
+		wg.Wait()
+		return nil, fmt.Errorf("step down: %w", err)
+	}
+	wg.Wait()
+
+	r.Lock()
+	defer r.Unlock()
+	report := r.report
+	if r.unstable.After(at) {
+		report.Recovery = r.unstable.Sub(at)
+	}
+	return &report, nil
+}
@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDrill(t *testing.T) {
+	Convey("Test the failover drills", t, func() {
+		Convey("record the recovery", func() {
+			r := &drillRecorder{slow: time.Second, report: DrillReport{ErrorLabels: make(map[string]int64)}}
+			start := time.Now()
+			r.record(start, time.Millisecond, errors.New("before"))
+			So(r.unstable.IsZero(), ShouldBeTrue)
+
+			r.stepped(start)
+			r.record(start.Add(time.Second), 2*time.Second, nil)
+			r.record(start.Add(time.Second), time.Millisecond, context.DeadlineExceeded)
+			r.record(start.Add(4*time.Second), time.Millisecond, nil)
+			So(r.unstable, ShouldEqual, start.Add(3*time.Second))
+			So(r.report.Operations, ShouldEqual, 4)
+			So(r.report.Errors, ShouldEqual, 2)
+			So(r.report.ErrorLabels[ErrorLabelTimeout], ShouldEqual, 1)
+			So(r.report.MaxLatency, ShouldEqual, 2*time.Second)
+			So(r.report.ErrorRate(), ShouldEqual, 0.5)
+			So((&DrillReport{}).ErrorRate(), ShouldEqual, 0)
+		})
+
+		Convey("reject a warmup outlasting the drill", func() {
+			mstore := newOfflineStore(t)
+			_, err := mstore.Drill(context.Background(), DrillOptions{Duration: time.Second, Warmup: 2 * time.Second})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	session "github.com/go-session/session/v3"
+
+	"github.com/go-session/mongo/v3/decorator"
+)
+
+// DivergenceKind The kind of a divergence between the stores of NewDualStore
+type DivergenceKind = decorator.DivergenceKind
+
+// Kinds of the divergences
+const (
+	DivergenceFallback = decorator.DivergenceFallback
+	DivergenceMissing  = decorator.DivergenceMissing
+	DivergenceError    = decorator.DivergenceError
+)
+
+// Divergence A difference between the stores of NewDualStore
+type Divergence = decorator.Divergence
+
+// NewDualStore Create a manager store for the moves between backends (e.g. between clusters):
+// the sessions are written to both stores and read from primary, falling back to secondary for
+// the sessions primary is missing, which are copied to primary when their store implements
+// Mutator (the others keep being served by secondary alone). The errors of primary are
+// returned and those of secondary are only reported to observe (if not nil) with the other
+// divergences, so that the cutover can wait for them to stop; see decorator.Dual. The
+// divergences are hashed with the SIDHasher of primary if it is a store of this package,
+// unless opts set another one with decorator.WithSIDHasher
+func NewDualStore(primary, secondary session.ManagerStore, observe func(Divergence), opts ...decorator.DualOption) session.ManagerStore {
+	switch s := primary.(type) {
+	case *managerStore:
+		opts = append([]decorator.DualOption{decorator.WithSIDHasher(s.opts.sidHasher)}, opts...)
+	case *memoryStore:
+		opts = append([]decorator.DualOption{decorator.WithSIDHasher(s.m.opts.sidHasher)}, opts...)
+	}
+	return decorator.Dual(secondary, observe, opts...)(primary)
+}
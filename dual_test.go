@@ -0,0 +1,151 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	session "github.com/go-session/session/v3"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-session/mongo/v3/decorator"
+)
+
+// mutableStores Manager store whose stores implement Mutator over the keys
+type mutableStores struct {
+	session.ManagerStore
+	keys []string
+}
+
+func (m *mutableStores) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	st, err := m.ManagerStore.Update(ctx, sid, expired)
+	return &mutableStore{Store: st, keys: m.keys}, err
+}
+
+type mutableStore struct {
+	session.Store
+	keys []string
+}
+
+func (s *mutableStore) Mutate(fn func(values map[string]interface{}) error) error {
+	values := map[string]interface{}{}
+	for _, k := range s.keys {
+		if v, ok := s.Get(k); ok {
+			values[k] = v
+		}
+	}
+	return fn(values)
+}
+
+func TestDualStore(t *testing.T) {
+	Convey("Test the dual writes to two stores", t, func() {
+		ctx := context.Background()
+		primary, secondary := session.NewMemoryStore(), session.NewMemoryStore()
+		var divergences []Divergence
+		dual := NewDualStore(primary, secondary, func(d Divergence) {
+			divergences = append(divergences, d)
+		})
+
+		st, err := dual.Create(ctx, "both", 10)
+		So(err, ShouldBeNil)
+		st.Set("foo", "bar")
+		So(st.Save(), ShouldBeNil)
+		for _, m := range []session.ManagerStore{primary, secondary} {
+			ok, err := m.Check(ctx, "both")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+		}
+		st, err = dual.Update(ctx, "both", 10)
+		So(err, ShouldBeNil)
+		foo, _ := st.Get("foo")
+		So(foo, ShouldEqual, "bar")
+		So(divergences, ShouldBeEmpty)
+
+		Convey("missing from the secondary store", func() {
+			st, err := primary.Create(ctx, "primary_only", 10)
+			So(err, ShouldBeNil)
+			So(st.Save(), ShouldBeNil)
+			_, err = dual.Update(ctx, "primary_only", 10)
+			So(err, ShouldBeNil)
+			So(divergences, ShouldHaveLength, 1)
+			So(divergences[0].Kind, ShouldEqual, DivergenceMissing)
+			So(divergences[0].Op, ShouldEqual, OpUpdate)
+			So(divergences[0].SIDHash, ShouldEqual, SHA256SIDHasher("primary_only"))
+		})
+
+		Convey("read from the secondary store", func() {
+			st, err := secondary.Create(ctx, "secondary_only", 10)
+			So(err, ShouldBeNil)
+			st.Set("foo", "baz")
+			So(st.Save(), ShouldBeNil)
+
+			ok, err := dual.Check(ctx, "secondary_only")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			st, err = dual.Update(ctx, "secondary_only", 10)
+			So(err, ShouldBeNil)
+			foo, _ := st.Get("foo")
+			So(foo, ShouldEqual, "baz")
+			So(divergences, ShouldHaveLength, 2)
+			So(divergences[1].Kind, ShouldEqual, DivergenceFallback)
+
+			ok, err = primary.Check(ctx, "secondary_only")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("copied to the primary store", func() {
+			dual := NewDualStore(primary, &mutableStores{ManagerStore: secondary, keys: []string{"foo"}}, nil)
+			st, err := secondary.Create(ctx, "copied", 10)
+			So(err, ShouldBeNil)
+			st.Set("foo", "qux")
+			So(st.Save(), ShouldBeNil)
+
+			st, err = dual.Update(ctx, "copied", 10)
+			So(err, ShouldBeNil)
+			foo, _ := st.Get("foo")
+			So(foo, ShouldEqual, "qux")
+			st, err = primary.Update(ctx, "copied", 10)
+			So(err, ShouldBeNil)
+			foo, _ = st.Get("foo")
+			So(foo, ShouldEqual, "qux")
+		})
+
+		Convey("deleted from both stores", func() {
+			So(dual.Delete(ctx, "both"), ShouldBeNil)
+			ok, err := secondary.Check(ctx, "both")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestDualStoreSIDHasher(t *testing.T) {
+	Convey("Test the hashes of the divergences of the dual writes", t, func() {
+		ctx := context.Background()
+		hasher := func(sid string) string { return "keyed-" + sid }
+		primary, secondary := NewMemoryStore(WithSIDHasher(hasher)), NewMemoryStore()
+		var divergences []Divergence
+		dual := NewDualStore(primary, secondary, func(d Divergence) {
+			divergences = append(divergences, d)
+		})
+
+		st, err := secondary.Create(ctx, "old", 10)
+		So(err, ShouldBeNil)
+		st.Set("foo", "bar")
+		So(st.Save(), ShouldBeNil)
+		ok, err := dual.Check(ctx, "old")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		So(divergences, ShouldResemble, []Divergence{{Op: decorator.OpCheck, Kind: DivergenceFallback, SIDHash: "keyed-old"}})
+
+		Convey("unless another one is set", func() {
+			divergences = nil
+			dual := NewDualStore(primary, secondary, func(d Divergence) {
+				divergences = append(divergences, d)
+			}, decorator.WithSIDHasher(SHA256SIDHasher))
+			_, err := dual.Check(ctx, "old")
+			So(err, ShouldBeNil)
+			So(divergences[0].SIDHash, ShouldEqual, SHA256SIDHasher("old"))
+		})
+	})
+}
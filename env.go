@@ -0,0 +1,52 @@
+package mongo
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// attrEnvironment The span attribute of the environment of WithEnvSuffix
+const attrEnvironment = attribute.Key("deployment.environment")
+
+// WithEnvSuffix Append "_"+env to the names of the session collections (e.g. "session_staging",
+// and so to those of their locks, chunks, archives...), so that the applications of several
+// environments sharing a database don't share their sessions by accident, and tag the
+// measures with env: the EnvMetrics receive it, the spans have a deployment.environment
+// attribute and the log entries an "env" key. An empty env adds no suffix
+func WithEnvSuffix(env string) Option {
+	return func(o *options) {
+		o.env = env
+	}
+}
+
+// EnvMetrics Implemented by the Metrics receiving the environment of WithEnvSuffix with the
+// measures of the store operations, instead of ObserveOperation
+type EnvMetrics interface {
+	Metrics
+	// ObserveEnvOperation Record a call of op in the environment env, see ObserveOperation
+	ObserveEnvOperation(env, op string, took time.Duration, size int, err error)
+}
+
+// applyEnv Suffix the names of the session collections with the environment
+func (o *options) applyEnv() {
+	if o.env == "" {
+		return
+	}
+	o.cName += "_" + o.env
+	if o.anonCollection != "" {
+		o.anonCollection += "_" + o.env
+	}
+}
+
+// observe Report a call of op to the metrics, if any
+func (o *options) observe(op string, took time.Duration, size int, err error) {
+	if o.metrics == nil {
+		return
+	}
+	if m, ok := o.metrics.(EnvMetrics); ok && o.env != "" {
+		m.ObserveEnvOperation(o.env, op, took, size, err)
+		return
+	}
+	o.metrics.ObserveOperation(op, took, size, err)
+}
@@ -0,0 +1,48 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// envMetrics The measures received with their environment
+type envMetrics struct {
+	MetricsFunc
+	envs []string
+}
+
+func (m *envMetrics) ObserveEnvOperation(env, op string, took time.Duration, size int, err error) {
+	m.envs = append(m.envs, env+":"+op)
+}
+
+func TestEnvSuffix(t *testing.T) {
+	Convey("Test the environment suffix of the collections", t, func() {
+		o := newOptions(WithEnvSuffix("staging"), WithCollection("app"), WithAnonymousCollection("app_anon", time.Minute, nil))
+		So(o.cName, ShouldEqual, "app_staging")
+		So(o.anonCollection, ShouldEqual, "app_anon_staging")
+		So(newOptions(nameOptions("db", "app")...).cName, ShouldEqual, "app")
+		So(newOptions(append(nameOptions("db", "app"), WithEnvSuffix("prod"))...).cName, ShouldEqual, "app_prod")
+
+		Convey("tagging the measures", func() {
+			var plain []string
+			m := &envMetrics{MetricsFunc: func(op string, _ time.Duration, _ int, _ error) {
+				plain = append(plain, op)
+			}}
+			var logged []interface{}
+			mstore := newOfflineStore(t, WithEnvSuffix("staging"), WithMetrics(m), WithOperationTimeout(time.Millisecond), WithLogger(func(_ LogLevel, _ string, kv ...interface{}) {
+				logged = kv
+			}))
+			_, _ = mstore.Check(context.Background(), "test_env")
+			So(m.envs, ShouldResemble, []string{"staging:" + OpCheck})
+			So(plain, ShouldBeEmpty)
+			So(logged[len(logged)-2:], ShouldResemble, []interface{}{"env", "staging"})
+
+			mstore = newOfflineStore(t, WithMetrics(m), WithOperationTimeout(time.Millisecond))
+			_, _ = mstore.Check(context.Background(), "test_env")
+			So(plain, ShouldResemble, []string{OpCheck})
+		})
+	})
+}
@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/topology"
+)
+
+// Kinds of the errors of the operations, matched with errors.Is whatever their cause
+var (
+	// ErrSessionNotFound There is no such session, e.g. for Delete unless deletes are
+	// idempotent, the cause being mongo.ErrNoDocuments
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrExpired The session has expired but its document is still stored (Touch),
+	// it also matches ErrSessionNotFound
+	ErrExpired = errors.New("session expired")
+	// ErrDecode The session values can't be decoded (corrupt document, wrong cipher key...)
+	ErrDecode = errors.New("session values can't be decoded")
+	// ErrBackend MongoDB failed or couldn't be reached, the cause being the driver error
+	// (tell the transient ones apart with StoreError.Retryable or IsTransientError)
+	ErrBackend = errors.New("session backend failure")
+)
+
+// kindError An error of the kinds, wrapping its cause
+type kindError struct {
+	kinds []error
+	err   error
+}
+
+func (e *kindError) Error() string {
+	return e.kinds[0].Error() + ": " + e.err.Error()
+}
+
+// Is Match the kinds of the error
+func (e *kindError) Is(target error) bool {
+	for _, kind := range e.kinds {
+		if target == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap The cause of the error
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// classify Wrap the driver error err in a kind error, err is returned if it already has a kind
+func classify(err error) error {
+	switch {
+	case errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrDecode) || errors.Is(err, ErrBackend):
+		return err
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return &kindError{kinds: []error{ErrSessionNotFound}, err: err}
+	case isBackendError(err):
+		return &kindError{kinds: []error{ErrBackend}, err: err}
+	default:
+		return err
+	}
+}
+
+// isBackendError Tell whether err is a failure of MongoDB or of the connection to it
+func isBackendError(err error) bool {
+	var se mongo.ServerError
+	var sse topology.ServerSelectionError
+	return errors.As(err, &se) || errors.As(err, &sse) ||
+		mongo.IsNetworkError(err) || mongo.IsTimeout(err) ||
+		errors.Is(err, mongo.ErrClientDisconnected)
+}
+
+// missing The error for the session sid without unexpired document, ErrExpired if it's still stored
+func (s *managerStore) missing(ctx context.Context, sid string) error {
+	if s.detectExpired(ctx, sid) {
+		return &kindError{kinds: []error{ErrExpired, ErrSessionNotFound}, err: mongo.ErrNoDocuments}
+	}
+	return &kindError{kinds: []error{ErrSessionNotFound}, err: mongo.ErrNoDocuments}
+}
+
+// StoreError The error returned by the session operations, wrapping its cause
+type StoreError struct {
+	// Op The failed operation (OpCheck, OpSave...)
+	Op string
+	// SIDHash A hash of the session id, empty for the operations on many sessions
+	SIDHash string
+	// Retryable Whether the cause is transient, so that the operation may succeed if retried
+	// (e.g. worth a 503 response with Retry-After instead of a 500)
+	Retryable bool
+	Err       error
+}
+
+func (e *StoreError) Error() string {
+	return "session " + e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap The cause of the error
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// storeError Wrap the error err of op on the session of hash sidHash, nil if err is nil, with
+// its kind (ErrSessionNotFound, ErrBackend...) when it comes from the driver
+func storeError(op, sidHash string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *StoreError
+	if errors.As(err, &se) {
+		return err
+	}
+	err = classify(err)
+	e := &StoreError{Op: op, Retryable: IsTransientError(err), Err: err}
+	e.SIDHash = sidHash
+	return e
+}
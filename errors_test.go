@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestStoreError(t *testing.T) {
+	Convey("Test the errors of the store operations", t, func() {
+		mstore := &managerStore{opts: newOptions(WithFaultInjection(Fault{Op: OpDelete, Rate: 1, Err: mongo.CommandError{Code: 189}}))}
+		err := mstore.Delete(context.Background(), "test_error")
+
+		var se *StoreError
+		So(errors.As(err, &se), ShouldBeTrue)
+		So(se.Op, ShouldEqual, OpDelete)
+		So(se.SIDHash, ShouldEqual, SHA256SIDHasher("test_error"))
+		So(se.Retryable, ShouldBeTrue)
+		So(se.Error(), ShouldStartWith, "session delete: ")
+
+		Convey("wrapped once", func() {
+			So(storeError(OpSave, "sid", err), ShouldEqual, err)
+			So(storeError(OpSave, "sid", nil), ShouldBeNil)
+			So(storeError(OpSave, "", ErrConflict).(*StoreError).Retryable, ShouldBeFalse)
+		})
+
+		Convey("with kinds", func() {
+			So(errors.Is(err, ErrBackend), ShouldBeTrue)
+			var ce mongo.CommandError
+			So(errors.As(err, &ce), ShouldBeTrue)
+			So(ce.Code, ShouldEqual, 189)
+
+			notFound := storeError(OpDelete, "sid", mongo.ErrNoDocuments)
+			So(errors.Is(notFound, ErrSessionNotFound), ShouldBeTrue)
+			So(errors.Is(notFound, ErrNotFound), ShouldBeTrue)
+			So(errors.Is(notFound, mongo.ErrNoDocuments), ShouldBeTrue)
+			So(errors.Is(notFound, ErrBackend), ShouldBeFalse)
+			So(ErrorLabel(notFound), ShouldEqual, ErrorLabelNotFound)
+
+			So(errors.Is(storeError(OpSave, "sid", mongo.ErrClientDisconnected), ErrBackend), ShouldBeTrue)
+			So(errors.Is(storeError(OpSave, "sid", ErrConflict), ErrBackend), ShouldBeFalse)
+			So(errors.Is(storeError(OpSave, "sid", context.Canceled), ErrBackend), ShouldBeFalse)
+
+			expired := &kindError{kinds: []error{ErrExpired, ErrSessionNotFound}, err: mongo.ErrNoDocuments}
+			So(errors.Is(storeError(OpTouch, "sid", expired), ErrExpired), ShouldBeTrue)
+			So(errors.Is(storeError(OpTouch, "sid", expired), ErrSessionNotFound), ShouldBeTrue)
+			So(expired.Error(), ShouldStartWith, "session expired: ")
+		})
+
+		Convey("decode", func() {
+			unknown, _ := rawValue(int32(1))
+			_, err := mstore.decodeValues(&sessionItem{Value: unknown})
+			So(errors.Is(storeError(OpCheck, "sid", err), ErrDecode), ShouldBeTrue)
+			So(errors.Is(err, ErrUnknownFormat), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package mongo
+
+import (
+	"time"
+
+	session "github.com/go-session/session/v3"
+)
+
+// ExpirationMode How the expiration of the sessions is computed
+type ExpirationMode int
+
+// Expiration modes
+const (
+	// SlidingExpiration Expire the sessions after the expiration time from their last
+	// Update or Save (default)
+	SlidingExpiration ExpirationMode = iota
+	// AbsoluteExpiration Expire the sessions after the max lifetime from their creation
+	// (the expiration time if there is no max lifetime), whatever their activity
+	AbsoluteExpiration
+	// SlidingAbsoluteExpiration Expire the sessions like SlidingExpiration,
+	// and after the max lifetime from their creation at the latest
+	SlidingAbsoluteExpiration
+)
+
+// WithExpiration Set how the expiration of the sessions is computed from the creation time
+// persisted in their documents (created_at) and maxLifetime (SlidingExpiration by default)
+func WithExpiration(mode ExpirationMode, maxLifetime time.Duration) Option {
+	return func(o *options) {
+		o.expirationMode = mode
+		o.maxLifetime = maxLifetime
+	}
+}
+
+// expiration The expiration of a session created at createdAt (now if zero)
+// expiring in expired seconds
+func (s *managerStore) expiration(expired int64, createdAt time.Time) time.Time {
+	now := s.now()
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	ttl := time.Duration(expired) * time.Second
+
+	var at time.Time
+	switch s.opts.expirationMode {
+	case AbsoluteExpiration:
+		if s.opts.maxLifetime > 0 {
+			ttl = s.opts.maxLifetime
+		}
+		at = createdAt.Add(ttl)
+	case SlidingAbsoluteExpiration:
+		at = now.Add(ttl)
+		if s.opts.maxLifetime > 0 && at.After(createdAt.Add(s.opts.maxLifetime)) {
+			at = createdAt.Add(s.opts.maxLifetime)
+		}
+	default:
+		at = now.Add(ttl)
+	}
+
+	if s.auth != nil && s.opts.anonMaxAge > 0 && at.After(now.Add(s.opts.anonMaxAge)) {
+		at = now.Add(s.opts.anonMaxAge)
+	}
+	return at
+}
+
+// WithExpirySkew Keep accepting the sessions for skew after their expiration, for the
+// deployments whose nodes have drifting clocks (a session extended by a node running late
+// isn't rejected by the others); the delay of the TTL index (WithTTLIndexOptions) should
+// cover it, its monitor removing the documents regardless
+func WithExpirySkew(skew time.Duration) Option {
+	return func(o *options) {
+		o.expirySkew = skew
+	}
+}
+
+// WithExpiredDeletion Remove the expired documents the reads of the sessions come across
+// (Check, Update, Refresh and Load, and Create before a session takes over the id of an
+// expired one) rather than waiting for the TTL monitor, telling the stores of the sessions
+// found expired apart with WasExpired, at the cost of a query for each missing session
+func WithExpiredDeletion() Option {
+	return func(o *options) {
+		o.expiredDeletion = true
+	}
+}
+
+// WasExpired Tell whether the session of the store returned by Update or Refresh was found
+// expired but still stored (with WithExpiredDeletion or the OnExpireDetected hook), rather
+// than missing, e.g. to tell the user the session expired
+func WasExpired(st session.Store) bool {
+	s, ok := st.(*store)
+	if !ok {
+		return false
+	}
+	s.RLock()
+	defer s.RUnlock()
+	return s.stale
+}
+
+// notBefore The earliest expiration of the sessions still valid, grace accepting the sessions
+// expired for less than grace on top of the skew
+func (s *managerStore) notBefore(grace time.Duration) time.Time {
+	return s.now().Add(-grace - s.opts.expirySkew)
+}
+
+// seeksExpired Tell whether the missing sessions are looked up among the expired documents
+func (s *managerStore) seeksExpired() bool {
+	return s.opts.expiredDeletion || s.opts.hooks.OnExpireDetected != nil
+}
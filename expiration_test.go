@@ -0,0 +1,67 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestExpirationModes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	created := now.Add(-50 * time.Minute)
+
+	Convey("Test the sliding and absolute expirations", t, func() {
+		mstore := &managerStore{opts: newOptions(clock)}
+		So(mstore.expiration(600, created), ShouldEqual, now.Add(10*time.Minute))
+
+		mstore = &managerStore{opts: newOptions(clock, WithExpiration(AbsoluteExpiration, time.Hour))}
+		So(mstore.expiration(600, created), ShouldEqual, now.Add(10*time.Minute))
+		So(mstore.expiration(600, time.Time{}), ShouldEqual, now.Add(time.Hour))
+
+		mstore = &managerStore{opts: newOptions(clock, WithExpiration(AbsoluteExpiration, 0))}
+		So(mstore.expiration(600, created), ShouldEqual, created.Add(10*time.Minute))
+
+		mstore = &managerStore{opts: newOptions(clock, WithExpiration(SlidingAbsoluteExpiration, time.Hour))}
+		So(mstore.expiration(300, created), ShouldEqual, now.Add(5*time.Minute))
+		So(mstore.expiration(1200, created), ShouldEqual, created.Add(time.Hour))
+
+		Convey("creation time of the loaded sessions", func() {
+			value, err := mstore.encodeValues(nil)
+			So(err, ShouldBeNil)
+			store, err := newLoadedStore(mstore, &sessionItem{Value: value, CreatedAt: created}, newStore(context.Background(), mstore, "test_expiration", 600, nil))
+			So(err, ShouldBeNil)
+			So(store.createdAt, ShouldEqual, created)
+			So(newStore(context.Background(), mstore, "test_expiration", 600, nil).createdAt, ShouldEqual, now)
+		})
+	})
+}
+
+func TestExpirySkew(t *testing.T) {
+	Convey("Test the sessions accepted past their expiration", t, func() {
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		clock := WithClock(ClockFunc(func() time.Time { return now }))
+		mstore := &managerStore{opts: newOptions(clock, WithExpirySkew(30*time.Second))}
+		So(mstore.notBefore(0), ShouldEqual, now.Add(-30*time.Second))
+		So(mstore.notBefore(time.Minute), ShouldEqual, now.Add(-90*time.Second))
+		So(mstore.unexpiredSelector("sid")["expired_at"], ShouldResemble, bson.M{"$gte": now.Add(-30 * time.Second)})
+		So(mstore.activeScope()["expired_at"], ShouldResemble, bson.M{"$gt": now.Add(-30 * time.Second)})
+		So(mstore.seeksExpired(), ShouldBeFalse)
+
+		plain := &managerStore{opts: newOptions(clock)}
+		So(plain.notBefore(0), ShouldEqual, now)
+
+		Convey("report the sessions found expired", func() {
+			mstore := &managerStore{opts: newOptions(WithExpiredDeletion())}
+			So(mstore.seeksExpired(), ShouldBeTrue)
+			st := newStore(context.Background(), mstore, "sid", 10, nil)
+			So(WasExpired(st), ShouldBeFalse)
+			st.stale = true
+			So(WasExpired(st), ShouldBeTrue)
+			So(WasExpired(nil), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,277 @@
+package mongo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// maxImportLine The maximum size of a line of an import, a session of the 16MB document limit
+// taking more once encoded in JSON
+const maxImportLine = 64 << 20
+
+// ExportFilter The sessions to export
+type ExportFilter struct {
+	// Field Export the sessions whose indexed field (e.g. "uid", the subject) is Value,
+	// all the sessions if empty
+	Field string
+	Value interface{}
+	// SIDs Export these sessions only, if any
+	SIDs []string
+	// Expired Include the expired sessions not yet removed
+	Expired bool
+}
+
+// Exporter Implemented by the stores, to export the sessions (e.g. the data of a subject for
+// the GDPR) and import them (migrations, restores)
+type Exporter interface {
+	// Export Stream the sessions matching filter as newline-delimited JSON (relaxed extended
+	// JSON, keeping the types of the values), one session per line, the read fails with the
+	// error interrupting the export
+	Export(ctx context.Context, filter ExportFilter) (io.ReadCloser, error)
+	// Import Write the sessions exported by Export, replacing those with the same ids, each
+	// in the collection (authenticated or anonymous) it was exported from and within the
+	// operation timeout, returning how many were imported
+	Import(ctx context.Context, r io.Reader) (int64, error)
+}
+
+// exportRecord The export of a session
+type exportRecord struct {
+	// SID The session id, hashed with WithHashedIDs
+	SID         string                 `bson:"sid"`
+	CreatedAt   time.Time              `bson:"created_at"`
+	ExpiredAt   time.Time              `bson:"expired_at"`
+	UpdatedAt   time.Time              `bson:"updated_at,omitempty"`
+	Values      map[string]interface{} `bson:"values"`
+	Indexed     bson.M                 `bson:"indexed,omitempty"`
+	Fingerprint string                 `bson:"fp,omitempty"`
+	ASN         uint32                 `bson:"asn,omitempty"`
+	// Anonymous The session was in the collection of the anonymous sessions
+	// (WithAnonymousCollection), where it is imported back
+	Anonymous bool `bson:"anonymous,omitempty"`
+}
+
+// exportQuery The query of the session documents of s matching filter
+func (s *managerStore) exportQuery(filter ExportFilter) (bson.M, error) {
+	q := s.scope()
+	if !filter.Expired {
+		q = s.activeScope()
+	}
+	if filter.Field != "" {
+		var err error
+		if q, err = s.indexScope(q, filter.Field, filter.Value); err != nil {
+			return nil, err
+		}
+	}
+	if len(filter.SIDs) > 0 {
+		ids := make([]string, 0, len(filter.SIDs))
+		for _, sid := range filter.SIDs {
+			ids = append(ids, s.docID(sid))
+			if legacy := s.legacyID(sid); legacy != "" {
+				ids = append(ids, legacy)
+			}
+		}
+		q["_id"] = bson.M{"$in": ids}
+	}
+	return q, nil
+}
+
+func (s *managerStore) Export(ctx context.Context, filter ExportFilter) (io.ReadCloser, error) {
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+	stores := []*managerStore{s}
+	if s.anon != nil {
+		stores = append(stores, s.anon)
+	}
+	for _, m := range stores {
+		if _, err := m.exportQuery(filter); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r, w := io.Pipe()
+	go func() {
+		defer cancel()
+		var err error
+		for _, m := range stores {
+			if err = m.export(ctx, filter, w, m != s); err != nil {
+				break
+			}
+		}
+		w.CloseWithError(err)
+	}()
+	return &exportReader{PipeReader: r, cancel: cancel}, nil
+}
+
+// exportReader The stream of an export, stopping the export once closed
+type exportReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *exportReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// export Write the records of the sessions of the collection of s matching filter to w, anon
+// telling whether it is the collection of the anonymous sessions
+func (s *managerStore) export(ctx context.Context, filter ExportFilter, w io.Writer, anon bool) error {
+	q, err := s.exportQuery(filter)
+	if err != nil {
+		return err
+	}
+	cur, err := s.adminCollection().Find(ctx, q, mopts.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var item sessionItem
+		if err := cur.Decode(&item); err != nil {
+			return err
+		}
+		if err := s.unspill(ctx, &item); err != nil {
+			return err
+		}
+		values, err := s.decodeValues(&item)
+		if err != nil {
+			return fmt.Errorf("session %s: %w", s.info(sessionInfoDoc{ID: item.ID}).SID, err)
+		}
+		rec := exportRecord{
+			SID:         s.info(sessionInfoDoc{ID: item.ID}).SID,
+			CreatedAt:   item.CreatedAt,
+			ExpiredAt:   item.ExpiredAt,
+			UpdatedAt:   item.UpdatedAt,
+			Values:      values,
+			Fingerprint: item.Fingerprint,
+			ASN:         item.ASN,
+			Anonymous:   anon,
+		}
+		for field, value := range item.Indexed {
+			if s.opts.isIndexed(field) {
+				if rec.Indexed == nil {
+					rec.Indexed = bson.M{}
+				}
+				rec.Indexed[field] = value
+			}
+		}
+		line, err := bson.MarshalExtJSON(rec, false, false)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+func (s *managerStore) Import(ctx context.Context, r io.Reader) (int64, error) {
+	if err := s.ready(ctx); err != nil {
+		return 0, err
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxImportLine)
+	var n int64
+	for line := 1; sc.Scan(); line++ {
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		vr, err := bson.NewExtJSONValueReader(bytes.NewReader(data), false)
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		dec := bson.NewDecoder(vr)
+		dec.DefaultDocumentM()
+		var rec exportRecord
+		if err := dec.Decode(&rec); err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		} else if rec.SID == "" {
+			return n, fmt.Errorf("line %d: missing session id", line)
+		}
+		m := s
+		if rec.Anonymous && s.anon != nil {
+			m = s.anon
+		}
+		// each record has the operation timeout
+		dbctx, cancel := s.callContext(ctx)
+		err = s.retry(dbctx, OpSave, func() error { return m.importRecord(dbctx, &rec) })
+		cancel()
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		n++
+	}
+	return n, sc.Err()
+}
+
+// importRecord Write the session document of rec under its exported id
+func (s *managerStore) importRecord(ctx context.Context, rec *exportRecord) error {
+	values := s.newValues()
+	for k, v := range rec.Values {
+		values[k] = v
+	}
+	value, err := s.encodeValues(values)
+	if err != nil {
+		return err
+	}
+	item := &sessionItem{
+		ID:          rec.SID,
+		Value:       value,
+		ExpiredAt:   rec.ExpiredAt,
+		CreatedAt:   rec.CreatedAt,
+		UpdatedAt:   rec.UpdatedAt,
+		Owner:       s.opts.owner,
+		Namespace:   s.namespace,
+		Size:        len(value.Value),
+		Fingerprint: rec.Fingerprint,
+		ASN:         rec.ASN,
+	}
+	if s.namespace != "" {
+		item.ID = s.namespace + ":" + rec.SID
+	}
+	for field, v := range rec.Indexed {
+		if s.opts.isIndexed(field) {
+			if item.Indexed == nil {
+				item.Indexed = bson.M{}
+			}
+			item.Indexed[field] = v
+		}
+	}
+
+	stored, err := s.spill(ctx, item)
+	if err != nil {
+		return err
+	}
+	q := s.scope()
+	q["_id"] = item.ID
+	if _, err = s.c.ReplaceOne(ctx, q, stored, mopts.Replace().SetUpsert(true)); err != nil {
+		if stored.Spill != nil {
+			s.discardChunks(ctx, item.ID, stored.Spill.Gen)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrOwnerMismatch
+		}
+		return err
+	}
+	var keep string
+	if stored.Spill != nil {
+		keep = stored.Spill.Gen
+	}
+	s.cleanChunks(ctx, item.ID, keep)
+	s.uncacheID(item.ID)
+	return nil
+}
@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestExportRecords(t *testing.T) {
+	Convey("Test the export of the sessions", t, func() {
+		mstore := newOfflineStore(t, WithIndexedFields("uid"))
+
+		Convey("query", func() {
+			q, err := mstore.exportQuery(ExportFilter{Field: "uid", Value: int64(42), SIDs: []string{"a", "b"}})
+			So(err, ShouldBeNil)
+			So(q["uid"], ShouldEqual, int64(42))
+			So(q["_id"], ShouldResemble, bson.M{"$in": []string{"a", "b"}})
+			So(q["expired_at"], ShouldNotBeNil)
+
+			q, err = mstore.exportQuery(ExportFilter{Expired: true})
+			So(err, ShouldBeNil)
+			_, ok := q["expired_at"]
+			So(ok, ShouldBeFalse)
+
+			_, err = mstore.exportQuery(ExportFilter{Field: "email"})
+			So(errors.Is(err, ErrNotIndexed), ShouldBeTrue)
+			_, err = mstore.Export(context.Background(), ExportFilter{Field: "email"})
+			So(errors.Is(err, ErrNotIndexed), ShouldBeTrue)
+		})
+
+		Convey("records keeping the types", func() {
+			created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			line, err := bson.MarshalExtJSON(exportRecord{
+				SID:       "sid",
+				CreatedAt: created,
+				ExpiredAt: created.Add(time.Hour),
+				Values:    map[string]interface{}{"n": int64(1) << 40, "at": created, "tags": bson.A{"a"}},
+				Indexed:   bson.M{"uid": int32(42)},
+				Anonymous: true,
+			}, false, false)
+			So(err, ShouldBeNil)
+			So(bytes.Contains(line, []byte("\n")), ShouldBeFalse)
+
+			vr, err := bson.NewExtJSONValueReader(bytes.NewReader(line), false)
+			So(err, ShouldBeNil)
+			dec := bson.NewDecoder(vr)
+			dec.DefaultDocumentM()
+			var rec exportRecord
+			So(dec.Decode(&rec), ShouldBeNil)
+			So(rec.SID, ShouldEqual, "sid")
+			So(rec.CreatedAt.Equal(created), ShouldBeTrue)
+			So(rec.Values["n"], ShouldEqual, int64(1)<<40)
+			So(rec.Values["at"].(bson.DateTime).Time().Equal(created), ShouldBeTrue)
+			So(rec.Indexed["uid"], ShouldEqual, int32(42))
+			So(rec.Anonymous, ShouldBeTrue)
+		})
+
+		Convey("invalid imports", func() {
+			_, err := mstore.Import(context.Background(), strings.NewReader("\n{\"values\": {}}\n"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "line 2: missing session id")
+			_, err = mstore.Import(context.Background(), strings.NewReader("not json"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Operations of the store
+const (
+	OpCheck   = "check"
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpDelete  = "delete"
+	OpRefresh = "refresh"
+	OpSave    = "save"
+	OpPromote = "promote"
+	OpTouch   = "touch"
+	OpLoad    = "load"
+)
+
+// ErrInjectedFault Error to use in faults standing for a generic backend failure
+var ErrInjectedFault = errors.New("injected fault")
+
+// Fault A failure injected into an operation for chaos testing
+type Fault struct {
+	// Op Operation affected by the fault (OpSave, OpUpdate...)
+	Op string
+	// Rate Probability (0 to 1) that a call of the operation is affected
+	Rate float64
+	// Delay Latency added to the affected calls
+	Delay time.Duration
+	// Err Error returned by the affected calls instead of performing the operation,
+	// nil to only add latency
+	Err error
+}
+
+// WithFaultInjection Inject latency and errors into the store operations
+// (e.g. 5% Save failures, 200ms Update delay),
+// only meant for testing how an application handles session store failures
+func WithFaultInjection(faults ...Fault) Option {
+	return func(o *options) {
+		o.faults = append(o.faults, faults...)
+	}
+}
+
+// injectFault Finish the deferred setup of the store then apply the faults configured for op
+func (s *managerStore) injectFault(ctx context.Context, op string) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+	for _, f := range s.opts.faults {
+		if f.Op != op || s.opts.rand.Float64() >= f.Rate {
+			continue
+		}
+		if f.Delay > 0 {
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			t := time.NewTimer(f.Delay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			}
+		}
+		if f.Err != nil {
+			return f.Err
+		}
+	}
+	return nil
+}
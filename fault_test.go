@@ -0,0 +1,50 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFaultInjection(t *testing.T) {
+	Convey("Test injected faults on store operations", t, func() {
+		mstore := &managerStore{opts: newOptions(WithFaultInjection(
+			Fault{Op: OpCreate, Rate: 1, Delay: 10 * time.Millisecond},
+			Fault{Op: OpSave, Rate: 1, Err: ErrInjectedFault},
+		))}
+
+		start := time.Now()
+		store, err := mstore.Create(context.Background(), "test_fault", 10)
+		So(err, ShouldBeNil)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+
+		store.Set("foo", "bar")
+		So(errors.Is(store.Save(), ErrInjectedFault), ShouldBeTrue)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = mstore.Create(ctx, "test_fault", 10)
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+	})
+}
+
+func TestFaultInjectionSeed(t *testing.T) {
+	Convey("Test fault injection is reproducible with a fixed rand source", t, func() {
+		run := func() []bool {
+			mstore := &managerStore{opts: newOptions(
+				WithRandSource(rand.NewSource(42)),
+				WithFaultInjection(Fault{Op: OpCheck, Rate: 0.5, Err: ErrInjectedFault}),
+			)}
+			var failed []bool
+			for i := 0; i < 20; i++ {
+				failed = append(failed, mstore.injectFault(context.Background(), OpCheck) != nil)
+			}
+			return failed
+		}
+		So(run(), ShouldResemble, run())
+	})
+}
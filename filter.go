@@ -0,0 +1,42 @@
+package mongo
+
+import "context"
+
+// ValueFilter Transform the value of key before it's written (e.g. hash an email, truncate a
+// token), returning the value to store in its place
+type ValueFilter func(ctx context.Context, key string, value interface{}) interface{}
+
+// WithValueFilter Pass the values set since the last save through the filters, in order,
+// before they're written; the stores keep the filtered values, which aren't filtered again
+// (by the next saves or the retries of a failed one) until they're set again, so that the
+// filters needn't be idempotent. The filters are called under the lock of the store, which
+// they must not use
+func WithValueFilter(filters ...ValueFilter) Option {
+	return func(o *options) {
+		o.valueFilters = append(o.valueFilters, filters...)
+	}
+}
+
+// filterValues Replace the values modified since the last save by their filtered values
+func (s *store) filterValues() {
+	filters := s.mstore.opts.valueFilters
+	if len(filters) == 0 {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	for k := range s.dirty {
+		v, ok := s.values[k]
+		if _, done := s.filtered[k]; !ok || done {
+			continue
+		}
+		for _, filter := range filters {
+			v = filter(s.ctx, k, v)
+		}
+		s.values[k] = v
+		if s.filtered == nil {
+			s.filtered = make(map[string]struct{})
+		}
+		s.filtered[k] = struct{}{}
+	}
+}
@@ -0,0 +1,53 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValueFilter(t *testing.T) {
+	Convey("Test the filtering of the values before their write", t, func() {
+		calls := 0
+		redact := func(ctx context.Context, key string, v interface{}) interface{} {
+			calls++
+			if s, ok := v.(string); ok && key == "email" {
+				return "redacted:" + s[strings.Index(s, "@")+1:]
+			}
+			return v
+		}
+		truncate := func(ctx context.Context, key string, v interface{}) interface{} {
+			if s, ok := v.(string); ok && len(s) > 12 {
+				return s[:12]
+			}
+			return v
+		}
+		mstore := newOfflineStore(t, WithOperationTimeout(time.Millisecond), WithValueFilter(redact, truncate))
+		store := newStore(context.Background(), mstore, "test_filter", 10, nil)
+
+		store.Set("email", "someone@example.com")
+		store.Set("n", 1.0)
+		So(store.Save(), ShouldNotBeNil)
+		v, _ := store.Get("email")
+		So(v, ShouldEqual, "redacted:exa")
+		So(calls, ShouldEqual, 2)
+
+		// the retry of the failed save doesn't filter the values again
+		So(store.Save(), ShouldNotBeNil)
+		So(calls, ShouldEqual, 2)
+		v, _ = store.Get("email")
+		So(v, ShouldEqual, "redacted:exa")
+
+		store.Set("email", "other@example.org")
+		store.Delete("n")
+		So(store.Save(), ShouldNotBeNil)
+		So(calls, ShouldEqual, 3)
+		v, _ = store.Get("email")
+		So(v, ShouldEqual, "redacted:exa")
+		_, ok := store.Get("n")
+		So(ok, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,103 @@
+package mongo
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrFingerprintMismatch The fingerprint of the caller is not the one the session is bound to
+var ErrFingerprintMismatch = errors.New("session fingerprint mismatch")
+
+// FingerprintPolicy How Update and Refresh handle a caller whose fingerprint is not the one of the session
+type FingerprintPolicy int
+
+// Fingerprint policies
+const (
+	// FingerprintReject Fail with ErrFingerprintMismatch (default)
+	FingerprintReject FingerprintPolicy = iota
+	// FingerprintRestart Start a new empty session under the same id, replacing the stored one when saved
+	FingerprintRestart
+	// FingerprintLog Log the mismatch and load the session
+	FingerprintLog
+)
+
+// WithFingerprintBinding Bind the sessions to the fingerprint of the device that created them
+// (given with WithFingerprint, e.g. a hash of the user agent and the client hints), of which
+// only a hash is stored, Update and Refresh applying policy to the callers with another one
+// or none; the sessions stored without fingerprint get the one of their next full write
+func WithFingerprintBinding(policy FingerprintPolicy) Option {
+	return func(o *options) {
+		o.fingerprints = true
+		o.fingerprintPolicy = policy
+	}
+}
+
+// WithFingerprint Returns a context giving the fingerprint of the device of the request
+// to the sessions created or loaded with it
+func WithFingerprint(ctx context.Context, fingerprint string) context.Context {
+	return withCallOptions(ctx, func(o *callOptions) {
+		o.fingerprint = fingerprintHash(fingerprint)
+	})
+}
+
+// fingerprintHash The stored hash of fingerprint
+func fingerprintHash(fingerprint string) string {
+	if fingerprint == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fingerprint))
+	return hex.EncodeToString(sum[:])
+}
+
+// callerFingerprint The fingerprint hash given with ctx, if the sessions are bound to one
+func (s *managerStore) callerFingerprint(ctx context.Context) string {
+	if !s.opts.fingerprints {
+		return ""
+	}
+	return callOptionsFromContext(ctx).fingerprint
+}
+
+// verifyFingerprint Apply the fingerprint policy to the caller of ctx loading item,
+// true if the session has to be started over
+func (s *managerStore) verifyFingerprint(ctx context.Context, sid string, item *sessionItem) (bool, error) {
+	if !s.opts.fingerprints || item.Fingerprint == "" {
+		return false, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(s.callerFingerprint(ctx)), []byte(item.Fingerprint)) == 1 {
+		return false, nil
+	}
+
+	s.log(LevelWarn, "session fingerprint mismatch", "sid_hash", s.sidHash(sid))
+	switch s.opts.fingerprintPolicy {
+	case FingerprintRestart:
+		return true, nil
+	case FingerprintLog:
+		return false, nil
+	default:
+		return false, ErrFingerprintMismatch
+	}
+}
+
+// loadBound Create the store of the loaded item of sid once its fingerprint and the IP
+// of the caller are verified
+func (s *managerStore) loadBound(ctx context.Context, sid string, expired int64, item *sessionItem) (*store, error) {
+	restart, err := s.verifyFingerprint(ctx, sid, item)
+	if err != nil {
+		return nil, err
+	}
+	decision, ip := s.verifyIP(ctx, sid, item)
+	switch {
+	case decision == IPReject:
+		return nil, ErrIPNotAllowed
+	case restart || decision == IPRestart:
+		return newStore(ctx, s, sid, expired, nil), nil
+	}
+	st, err := newLoadedStore(s, item, newStore(ctx, s, sid, expired, nil))
+	if err == nil && decision == IPFlag {
+		st.Set(ipFlagKey, ip.String())
+	}
+	return st, err
+}
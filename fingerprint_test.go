@@ -0,0 +1,62 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFingerprintBinding(t *testing.T) {
+	Convey("Test the sessions bound to the fingerprint of their device", t, func() {
+		ctx := WithFingerprint(context.Background(), "device-a")
+		other := WithFingerprint(context.Background(), "device-b")
+		fp := fingerprintHash("device-a")
+		So(fp, ShouldHaveLength, 64)
+		So(fingerprintHash(""), ShouldBeEmpty)
+
+		Convey("recorded at the creation", func() {
+			mstore := newOfflineStore(t, WithFingerprintBinding(FingerprintReject))
+			So(newStore(ctx, mstore, "sid", 10, nil).fingerprint, ShouldEqual, fp)
+			So(newStore(context.Background(), mstore, "sid", 10, nil).fingerprint, ShouldBeEmpty)
+			So(newStore(ctx, newOfflineStore(t), "sid", 10, nil).fingerprint, ShouldBeEmpty)
+		})
+
+		Convey("verified on load", func() {
+			value, err := newOfflineStore(t).encodeValues(map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			item := func() *sessionItem { return &sessionItem{Value: value, Fingerprint: fp} }
+
+			mstore := newOfflineStore(t, WithFingerprintBinding(FingerprintReject))
+			store, err := mstore.loadBound(ctx, "sid", 10, item())
+			So(err, ShouldBeNil)
+			So(store.loaded, ShouldBeTrue)
+			So(store.fingerprint, ShouldEqual, fp)
+			_, err = mstore.loadBound(other, "sid", 10, item())
+			So(errors.Is(err, ErrFingerprintMismatch), ShouldBeTrue)
+			_, err = mstore.loadBound(context.Background(), "sid", 10, item())
+			So(errors.Is(err, ErrFingerprintMismatch), ShouldBeTrue)
+
+			mstore = newOfflineStore(t, WithFingerprintBinding(FingerprintRestart))
+			store, err = mstore.loadBound(other, "sid", 10, item())
+			So(err, ShouldBeNil)
+			So(store.loaded, ShouldBeFalse)
+			_, ok := store.Get("foo")
+			So(ok, ShouldBeFalse)
+
+			mstore = newOfflineStore(t, WithFingerprintBinding(FingerprintLog))
+			store, err = mstore.loadBound(other, "sid", 10, item())
+			So(err, ShouldBeNil)
+			foo, _ := store.Get("foo")
+			So(foo, ShouldEqual, "bar")
+			So(store.fingerprint, ShouldEqual, fp)
+
+			Convey("unless unbound", func() {
+				store, err := mstore.loadBound(other, "sid", 10, &sessionItem{Value: value})
+				So(err, ShouldBeNil)
+				So(store.fingerprint, ShouldEqual, fingerprintHash("device-b"))
+			})
+		})
+	})
+}
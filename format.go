@@ -0,0 +1,200 @@
+package mongo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// FormatVersion The version of the format of the session documents described here, raised
+// with the changes the other implementations must follow. The documents don't store it: the
+// format of the value is told by its BSON type and binary subtype
+//
+// A session document holds:
+//   - FieldID: the session id, HMAC-SHA256(sid, secret) encoded as unpadded base64url with
+//     WithHashedIDs, prefixed with "<namespace>:" for the stores of Namespace
+//   - FieldValue: the values, as a JSON string (JSONCodec, uncompressed and unencrypted), a
+//     subdocument (WithDocumentValues, unencrypted), a binary of subtype 0 holding the
+//     output of another codec, or an enveloped binary (see EnvelopeSubtype): compressed with
+//     gzip (GzipCompression) or snappy (SnappyCompression, block format) and/or encrypted;
+//     the AES-GCM values of NewAESGCMCipher being the 4 first bytes of SHA-256(key), a 12
+//     bytes nonce and the ciphertext sealed with the 4 bytes as additional data. An empty
+//     string or a missing value holds no values
+//   - FieldValueNext: the value in the new format during WithDualWrite, read instead
+//   - FieldExpiredAt: the expiration (UTC datetime, removed by the TTL index), the sessions
+//     past it being missing even before their removal
+//   - FieldVersion: incremented by each write of the values, for the optimistic locking
+//   - FieldCreatedAt, FieldUpdatedAt: the times of the creation and of the last write
+//
+// The other fields (owner, ns, size, spill, fp, asn, refresh_key, save_id and the indexed
+// fields) are kept as is by the writers which don't use them
+const FormatVersion = 1
+
+// Fields of the session documents
+const (
+	FieldID        = "_id"
+	FieldValue     = "value"
+	FieldValueNext = "value_next"
+	FieldExpiredAt = "expired_at"
+	FieldVersion   = "version"
+	FieldCreatedAt = "created_at"
+	FieldUpdatedAt = "updated_at"
+)
+
+// ErrFormatMismatch The document of a FormatVector doesn't hold its values
+var ErrFormatMismatch = errors.New("session document doesn't match its format vector")
+
+// FormatVector A session document with the options it was written with and the values it
+// holds, to check the implementations of the format in other languages: the vectors of
+// FormatVectors are read by their tests and the documents they write are checked with
+// VerifyFormatVector. Only the values of the JSON types are covered, GobCodec being Go only
+type FormatVector struct {
+	Name string `json:"name"`
+	SID  string `json:"sid"`
+	// IDSecret The hex encoded secret of WithHashedIDs, empty if the id isn't hashed
+	IDSecret string `json:"id_secret,omitempty"`
+	// Documents Whether the values are stored as a subdocument (WithDocumentValues)
+	Documents bool `json:"documents,omitempty"`
+	// Compression The compression of the values, of any size
+	Compression Compression `json:"compression,omitempty"`
+	// Key The hex encoded AES key of NewAESGCMCipher, empty if the values aren't encrypted
+	Key string `json:"key,omitempty"`
+	// Document The BSON session document, base64 encoded in JSON
+	Document []byte `json:"document"`
+	// Values The values held by the document
+	Values map[string]interface{} `json:"values"`
+	// ExpiredAt The expiration of the session
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// store A store with the options of v, without client, for the encoding of its values
+func (v *FormatVector) store() (*managerStore, error) {
+	var opts []Option
+	if v.IDSecret != "" {
+		secret, err := hex.DecodeString(v.IDSecret)
+		if err != nil {
+			return nil, fmt.Errorf("id secret: %w", err)
+		}
+		opts = append(opts, WithHashedIDs(secret, false))
+	}
+	if v.Documents {
+		opts = append(opts, WithDocumentValues(true))
+	}
+	if v.Compression != NoCompression {
+		opts = append(opts, WithCompression(v.Compression, 1))
+	}
+	if v.Key != "" {
+		key, err := hex.DecodeString(v.Key)
+		if err != nil {
+			return nil, fmt.Errorf("key: %w", err)
+		}
+		c, err := NewAESGCMCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithCipher(c))
+	}
+	return &managerStore{opts: newOptions(opts...)}, nil
+}
+
+// FormatVectors Generate the format vectors of the combinations of options, the documents
+// differing between the calls by the order of the JSON keys and the nonces
+func FormatVectors() ([]FormatVector, error) {
+	values := func() map[string]interface{} {
+		return map[string]interface{}{
+			"user_id": "u-42",
+			"name":    "Zoë",
+			"count":   3.0,
+			"admin":   false,
+			"none":    nil,
+			"roles":   []interface{}{"reader", "writer"},
+			"profile": map[string]interface{}{"lang": "fr", "scores": []interface{}{1.5, 2.0}},
+		}
+	}
+	key := hex.EncodeToString(bytes.Repeat([]byte{0x42}, 32))
+	vectors := []FormatVector{
+		{Name: "json"},
+		{Name: "json_hashed_id", IDSecret: hex.EncodeToString([]byte("vector secret"))},
+		{Name: "documents", Documents: true},
+		{Name: "gzip", Compression: GzipCompression},
+		{Name: "snappy", Compression: SnappyCompression},
+		{Name: "aes_gcm", Key: key},
+		{Name: "gzip_aes_gcm", Compression: GzipCompression, Key: key},
+		{Name: "documents_aes_gcm", Documents: true, Key: key},
+		{Name: "empty"},
+	}
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := range vectors {
+		v := &vectors[i]
+		v.SID = "vector-" + v.Name
+		v.Values = values()
+		if v.Name == "empty" {
+			v.Values = map[string]interface{}{}
+		}
+		v.ExpiredAt = createdAt.Add(time.Hour)
+
+		s, err := v.store()
+		if err != nil {
+			return nil, fmt.Errorf("format vector %s: %w", v.Name, err)
+		}
+		value, err := s.encodeValues(v.Values)
+		if err != nil {
+			return nil, fmt.Errorf("format vector %s: %w", v.Name, err)
+		}
+		item := sessionItem{
+			ID:        s.docID(v.SID),
+			Value:     value,
+			ExpiredAt: v.ExpiredAt,
+			Version:   1,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+		if v.Document, err = bson.Marshal(item); err != nil {
+			return nil, fmt.Errorf("format vector %s: %w", v.Name, err)
+		}
+	}
+	return vectors, nil
+}
+
+// VerifyFormatVector Check that the document of v, e.g. written by another implementation
+// of the format, is found under the id of the session and holds its values and expiration,
+// the error wrapping ErrFormatMismatch if it doesn't
+func VerifyFormatVector(v FormatVector) error {
+	s, err := v.store()
+	if err != nil {
+		return err
+	}
+	var item sessionItem
+	if err := bson.Unmarshal(v.Document, &item); err != nil {
+		return fmt.Errorf("%w: %v", ErrFormatMismatch, err)
+	}
+	if id := s.docID(v.SID); item.ID != id {
+		return fmt.Errorf("%w: id %q instead of %q", ErrFormatMismatch, item.ID, id)
+	}
+	if !item.ExpiredAt.Equal(v.ExpiredAt) {
+		return fmt.Errorf("%w: expiration %s instead of %s", ErrFormatMismatch, item.ExpiredAt, v.ExpiredAt)
+	}
+	values, err := s.decodeValues(&item)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFormatMismatch, err)
+	}
+	// the subdocuments are decoded as bson.M and bson.A
+	got, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFormatMismatch, err)
+	}
+	want, err := json.Marshal(v.Values)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: values %s instead of %s", ErrFormatMismatch, got, want)
+	}
+	return nil
+}
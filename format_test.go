@@ -0,0 +1,67 @@
+package mongo
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var updateVectors = flag.Bool("update-vectors", false, "rewrite testdata/format_vectors.json")
+
+const vectorsFile = "format_vectors.json"
+
+func TestFormatVectors(t *testing.T) {
+	vectors, err := FormatVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", vectorsFile)
+	if *updateVectors {
+		data, err := json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	Convey("Test the format vectors", t, func() {
+		Convey("The generated vectors hold their values", func() {
+			for _, v := range vectors {
+				So(VerifyFormatVector(v), ShouldBeNil)
+			}
+		})
+
+		Convey("The published vectors stay readable", func() {
+			data, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			var published []FormatVector
+			So(json.Unmarshal(data, &published), ShouldBeNil)
+			So(published, ShouldHaveLength, len(vectors))
+			for _, v := range published {
+				So(VerifyFormatVector(v), ShouldBeNil)
+			}
+		})
+
+		Convey("The documents which don't match are reported", func() {
+			v := vectors[0]
+			v.SID = "other"
+			So(errors.Is(VerifyFormatVector(v), ErrFormatMismatch), ShouldBeTrue)
+
+			v = vectors[0]
+			v.Values = map[string]interface{}{"user_id": "u-43"}
+			So(errors.Is(VerifyFormatVector(v), ErrFormatMismatch), ShouldBeTrue)
+
+			v = vectors[0]
+			v.Document, _ = bson.Marshal(bson.M{"_id": v.SID, "value": 1, "expired_at": v.ExpiredAt})
+			So(errors.Is(VerifyFormatVector(v), ErrFormatMismatch), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,234 @@
+// Package gorilla Adapts the session stores to the gorilla/sessions Store interface (also used
+// by the session middleware of echo-contrib), so that the applications mixing frameworks share
+// the sessions collection and its document format
+package gorilla
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-session/mongo/v3"
+	session "github.com/go-session/session/v3"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// DefaultMaxAge The lifetime in seconds of the sessions and their cookies by default
+const DefaultMaxAge = 86400 * 30
+
+// DefaultCookieMaxSize The size of the cookies holding the values of the cookie only mode
+// by default, under the 4096 bytes the browsers keep
+const DefaultCookieMaxSize = 4000
+
+// ErrCookieTooLarge The values of the session don't fit the cookie of the cookie only mode
+var ErrCookieTooLarge = errors.New("session values too large for the cookie")
+
+// Store A gorilla/sessions store keeping the values in a manager store (e.g. the mongo store),
+// the cookie holding the signed session id
+type Store struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	// CookieMaxSize The size of the cookies of the cookie only mode (DefaultCookieMaxSize if
+	// not positive), within the max length of the codecs (4096 by default)
+	CookieMaxSize int
+	store         session.ManagerStore
+	cookieOnly    atomic.Bool
+}
+
+// cookiePayload The content of the cookies of the cookie only mode
+type cookiePayload struct {
+	ID     string
+	Values map[string]interface{}
+}
+
+var _ sessions.Store = &Store{}
+
+// NewStore Create a gorilla/sessions store of the sessions of store, the cookies being signed
+// (and encrypted) with keyPairs as with sessions.NewCookieStore
+func NewStore(store session.ManagerStore, keyPairs ...[]byte) *Store {
+	return &Store{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: DefaultMaxAge,
+		},
+		store: store,
+	}
+}
+
+// SetCookieOnly Switch the emergency cookie only mode on or off, e.g. during an outage of
+// the database: the sessions are saved in their signed cookies (of CookieMaxSize bytes at
+// most, their values being of the types gob encodes without registration) instead of the
+// manager store, and the sessions of the store which can't be loaded start over. The
+// sessions of the cookies are loaded in both modes, and written back to the manager store
+// by their next save once the mode is off; as with sessions.CookieStore, the cookies can't
+// be revoked and stay valid for the max age of the codecs
+func (s *Store) SetCookieOnly(enabled bool) {
+	s.cookieOnly.Store(enabled)
+}
+
+// CookieOnly Whether the cookie only mode is on
+func (s *Store) CookieOnly() bool {
+	return s.cookieOnly.Load()
+}
+
+// Get Get the session name registered for the request
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New Load the session name of the cookie of the request, a new session if there is none;
+// the error of an invalid cookie is returned with the new session
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	sess := sessions.NewSession(s, name)
+	opts := *s.Options
+	sess.Options = &opts
+	sess.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return sess, nil
+	}
+	if err := securecookie.DecodeMulti(name, c.Value, &sess.ID, s.Codecs...); err != nil {
+		var payload cookiePayload
+		if securecookie.DecodeMulti(name, c.Value, &payload, s.Codecs...) != nil {
+			return sess, err
+		}
+		sess.ID = payload.ID
+		for k, v := range payload.Values {
+			sess.Values[k] = v
+		}
+		sess.IsNew = false
+		return sess, nil
+	}
+	ok, err := s.store.Check(r.Context(), sess.ID)
+	if err != nil && s.CookieOnly() {
+		sess.ID = ""
+		return sess, nil
+	} else if err != nil {
+		return sess, err
+	} else if !ok {
+		sess.ID = ""
+		return sess, nil
+	}
+	st, err := s.store.Update(r.Context(), sess.ID, int64(s.maxAge(sess)))
+	if err != nil {
+		return sess, err
+	}
+	if err := load(st, sess); err != nil {
+		return sess, err
+	}
+	sess.IsNew = false
+	return sess, nil
+}
+
+// Save Write the values of the session and its cookie, a negative MaxAge deleting the session
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, sess *sessions.Session) error {
+	if sess.Options.MaxAge < 0 {
+		if sess.ID != "" {
+			if err := s.store.Delete(r.Context(), sess.ID); err != nil && !s.CookieOnly() {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(sess.Name(), "", sess.Options))
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(sess.Values))
+	for k, v := range sess.Values {
+		key, ok := k.(string)
+		if !ok {
+			return fmt.Errorf("session value key %v is not a string", k)
+		}
+		values[key] = v
+	}
+	if sess.ID == "" {
+		sess.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+	}
+	if s.CookieOnly() {
+		return s.saveCookie(w, sess, values)
+	}
+	if err := s.write(r.Context(), sess.ID, int64(s.maxAge(sess)), values); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(sess.Name(), sess.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(sess.Name(), encoded, sess.Options))
+	return nil
+}
+
+// saveCookie Write the values of the session in its cookie
+func (s *Store) saveCookie(w http.ResponseWriter, sess *sessions.Session, values map[string]interface{}) error {
+	encoded, err := securecookie.EncodeMulti(sess.Name(), cookiePayload{ID: sess.ID, Values: values}, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	max := s.CookieMaxSize
+	if max <= 0 {
+		max = DefaultCookieMaxSize
+	}
+	if len(encoded) > max {
+		return fmt.Errorf("%w: %d bytes over %d", ErrCookieTooLarge, len(encoded), max)
+	}
+	http.SetCookie(w, sessions.NewCookie(sess.Name(), encoded, sess.Options))
+	return nil
+}
+
+// maxAge The lifetime in seconds of the session, DefaultMaxAge for a session cookie
+func (s *Store) maxAge(sess *sessions.Session) int {
+	if sess.Options.MaxAge > 0 {
+		return sess.Options.MaxAge
+	}
+	return DefaultMaxAge
+}
+
+// write Replace the values of the session sid with values
+func (s *Store) write(ctx context.Context, sid string, expired int64, values map[string]interface{}) error {
+	st, err := s.store.Update(ctx, sid, expired)
+	if err != nil {
+		return err
+	}
+	if m, ok := st.(mongo.Mutator); ok {
+		err = m.Mutate(func(current map[string]interface{}) error {
+			for k := range current {
+				delete(current, k)
+			}
+			for k, v := range values {
+				current[k] = v
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return st.Save()
+	}
+
+	if err := st.Flush(); err != nil {
+		return err
+	}
+	for k, v := range values {
+		st.Set(k, v)
+	}
+	return st.Save()
+}
+
+// load Copy the values of st into sess
+func load(st session.Store, sess *sessions.Session) error {
+	a, ok := st.(mongo.Accessor)
+	if !ok {
+		return fmt.Errorf("session store %T can't list its values", st)
+	}
+	for k, v := range a.Snapshot() {
+		sess.Values[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package gorilla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	session "github.com/go-session/session/v3"
+	"github.com/gorilla/securecookie"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testStore A manager store keeping the sessions in memory, their stores listing their values
+type testStore struct {
+	sync.Mutex
+	sessions map[string]map[string]interface{}
+}
+
+func (m *testStore) Check(_ context.Context, sid string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.sessions[sid]
+	return ok, nil
+}
+
+func (m *testStore) Create(ctx context.Context, sid string, _ int64) (session.Store, error) {
+	return &testSession{ctx: ctx, m: m, sid: sid, values: map[string]interface{}{}}, nil
+}
+
+func (m *testStore) Update(ctx context.Context, sid string, _ int64) (session.Store, error) {
+	m.Lock()
+	defer m.Unlock()
+	values := map[string]interface{}{}
+	for k, v := range m.sessions[sid] {
+		values[k] = v
+	}
+	return &testSession{ctx: ctx, m: m, sid: sid, values: values}, nil
+}
+
+func (m *testStore) Delete(_ context.Context, sid string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.sessions, sid)
+	return nil
+}
+
+func (m *testStore) Refresh(ctx context.Context, _, sid string, expired int64) (session.Store, error) {
+	return m.Create(ctx, sid, expired)
+}
+
+func (m *testStore) Close() error { return nil }
+
+type testSession struct {
+	ctx    context.Context
+	m      *testStore
+	sid    string
+	values map[string]interface{}
+}
+
+func (s *testSession) Context() context.Context { return s.ctx }
+func (s *testSession) SessionID() string        { return s.sid }
+func (s *testSession) Set(key string, value interface{}) {
+	s.values[key] = value
+}
+func (s *testSession) Get(key string) (interface{}, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+func (s *testSession) Delete(key string) interface{} {
+	v := s.values[key]
+	delete(s.values, key)
+	return v
+}
+func (s *testSession) Save() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.m.sessions[s.sid] = s.Snapshot()
+	return nil
+}
+func (s *testSession) Flush() error {
+	s.values = map[string]interface{}{}
+	return s.Save()
+}
+func (s *testSession) Keys() []string {
+	var keys []string
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+func (s *testSession) Len() int { return len(s.values) }
+func (s *testSession) Snapshot() map[string]interface{} {
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
+func (s *testSession) SetMulti(values map[string]interface{}) {
+	for k, v := range values {
+		s.values[k] = v
+	}
+}
+
+func TestStore(t *testing.T) {
+	Convey("Test the gorilla/sessions adapter", t, func() {
+		backend := &testStore{sessions: map[string]map[string]interface{}{}}
+		store := NewStore(backend, []byte("0123456789abcdef0123456789abcdef"))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		sess, err := store.New(r, "app")
+		So(err, ShouldBeNil)
+		So(sess.IsNew, ShouldBeTrue)
+		sess.Values["user"] = "u1"
+		sess.Values["n"] = 2
+		w := httptest.NewRecorder()
+		So(sess.Save(r, w), ShouldBeNil)
+		So(backend.sessions[sess.ID], ShouldResemble, map[string]interface{}{"user": "u1", "n": 2})
+		cookies := w.Result().Cookies()
+		So(cookies, ShouldHaveLength, 1)
+		So(cookies[0].Value, ShouldNotEqual, sess.ID)
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookies[0])
+		loaded, err := store.New(r, "app")
+		So(err, ShouldBeNil)
+		So(loaded.IsNew, ShouldBeFalse)
+		So(loaded.ID, ShouldEqual, sess.ID)
+		So(loaded.Values["user"], ShouldEqual, "u1")
+
+		delete(loaded.Values, "n")
+		So(loaded.Save(r, httptest.NewRecorder()), ShouldBeNil)
+		So(backend.sessions[sess.ID], ShouldResemble, map[string]interface{}{"user": "u1"})
+
+		Convey("deleted with a negative max age", func() {
+			loaded.Options.MaxAge = -1
+			w := httptest.NewRecorder()
+			So(loaded.Save(r, w), ShouldBeNil)
+			So(backend.sessions, ShouldBeEmpty)
+			So(w.Result().Cookies()[0].MaxAge, ShouldBeLessThan, 0)
+		})
+
+		Convey("rejecting the forged cookies", func() {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.AddCookie(&http.Cookie{Name: "app", Value: sess.ID})
+			sess, err := store.New(r, "app")
+			So(err, ShouldNotBeNil)
+			So(sess.IsNew, ShouldBeTrue)
+		})
+
+		Convey("rejecting the keys which aren't strings", func() {
+			sess, _ := store.New(httptest.NewRequest(http.MethodGet, "/", nil), "app")
+			sess.Values[1] = "x"
+			So(sess.Save(r, httptest.NewRecorder()), ShouldNotBeNil)
+		})
+	})
+}
+
+// downStore A manager store whose database is unreachable
+type downStore struct {
+	testStore
+}
+
+func (m *downStore) Check(context.Context, string) (bool, error) {
+	return false, errors.New("server selection timeout")
+}
+
+func (m *downStore) Update(context.Context, string, int64) (session.Store, error) {
+	return nil, errors.New("server selection timeout")
+}
+
+func (m *downStore) Delete(context.Context, string) error {
+	return errors.New("server selection timeout")
+}
+
+func TestCookieOnly(t *testing.T) {
+	Convey("Test the cookie only mode", t, func() {
+		backend := &testStore{sessions: map[string]map[string]interface{}{}}
+		down := &downStore{}
+		key := []byte("0123456789abcdef0123456789abcdef")
+		store := NewStore(down, key)
+
+		// a session of the database, unreachable during the outage
+		w := httptest.NewRecorder()
+		dbStore := NewStore(backend, key)
+		sess, _ := dbStore.New(httptest.NewRequest(http.MethodGet, "/", nil), "app")
+		sess.Values["user"] = "u0"
+		So(sess.Save(httptest.NewRequest(http.MethodGet, "/", nil), w), ShouldBeNil)
+		dbCookie := w.Result().Cookies()[0]
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(dbCookie)
+		_, err := store.New(r, "app")
+		So(err, ShouldNotBeNil)
+
+		store.SetCookieOnly(true)
+		So(store.CookieOnly(), ShouldBeTrue)
+		sess, err = store.New(r, "app")
+		So(err, ShouldBeNil)
+		So(sess.IsNew, ShouldBeTrue)
+
+		sess.Values["user"] = "u1"
+		w = httptest.NewRecorder()
+		So(sess.Save(r, w), ShouldBeNil)
+		cookie := w.Result().Cookies()[0]
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookie)
+		loaded, err := store.New(r, "app")
+		So(err, ShouldBeNil)
+		So(loaded.IsNew, ShouldBeFalse)
+		So(loaded.ID, ShouldEqual, sess.ID)
+		So(loaded.Values["user"], ShouldEqual, "u1")
+
+		store.CookieMaxSize = 500
+		loaded.Values["blob"] = strings.Repeat("x", 500)
+		So(errors.Is(loaded.Save(r, httptest.NewRecorder()), ErrCookieTooLarge), ShouldBeTrue)
+		delete(loaded.Values, "blob")
+
+		Convey("The sessions of the cookies move back to the database after the outage", func() {
+			recovered := NewStore(backend, key)
+			loaded, err := recovered.New(r, "app")
+			So(err, ShouldBeNil)
+			So(loaded.Values["user"], ShouldEqual, "u1")
+
+			w := httptest.NewRecorder()
+			So(loaded.Save(r, w), ShouldBeNil)
+			So(backend.sessions[sess.ID], ShouldResemble, map[string]interface{}{"user": "u1"})
+			var sid string
+			So(securecookie.DecodeMulti("app", w.Result().Cookies()[0].Value, &sid, recovered.Codecs...), ShouldBeNil)
+			So(sid, ShouldEqual, sess.ID)
+		})
+
+		Convey("The sessions are deleted despite the outage", func() {
+			loaded.Options.MaxAge = -1
+			w := httptest.NewRecorder()
+			So(loaded.Save(r, w), ShouldBeNil)
+			So(w.Result().Cookies()[0].MaxAge, ShouldBeLessThan, 0)
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	session "github.com/go-session/session/v3"
+)
+
+// ErrCreateDenied The guard of WithCreateGuard denied the new session
+var ErrCreateDenied = errors.New("session creation denied")
+
+// CreateRequest The creation of a session submitted to the guard of WithCreateGuard
+type CreateRequest struct {
+	SID string
+	// Request The request of the session manager (session.Start), nil for the direct calls
+	Request *http.Request
+	// ClientIP The IP of the client (see WithClientIP), nil if unknown
+	ClientIP net.IP
+}
+
+// CreateDecision The decision of the guard of WithCreateGuard
+type CreateDecision struct {
+	// Deny Reject the session with ErrCreateDenied
+	Deny bool
+	// Tags The values to set on the accepted session (e.g. a bot score), saved with it
+	Tags map[string]interface{}
+}
+
+// WithCreateGuard Submit the new sessions (those of Create and of Update for an unknown id)
+// to guard before anything is written, to deny those of the abusive requests (known bad user
+// agents, missing headers...) or tag them; the denied sessions fail with ErrCreateDenied and
+// the OnReject hook is called for them
+func WithCreateGuard(guard func(ctx context.Context, req CreateRequest) CreateDecision) Option {
+	return func(o *options) {
+		o.createGuard = guard
+	}
+}
+
+// guardCreate Submit the new session of st to the guard, tagging st
+func (s *managerStore) guardCreate(ctx context.Context, st *store) error {
+	if s.opts.createGuard == nil {
+		return nil
+	}
+	req := CreateRequest{SID: st.sid, ClientIP: clientIP(ctx)}
+	if ctx != nil {
+		req.Request, _ = session.FromReqContext(ctx)
+	}
+	d := s.opts.createGuard(ctx, req)
+	if d.Deny {
+		s.fireHook(ctx, EventReject, s.opts.hooks.OnReject, SessionEvent{SID: st.sid})
+		return ErrCreateDenied
+	}
+	if len(d.Tags) > 0 {
+		st.SetMulti(d.Tags)
+	}
+	return nil
+}
+
+// admitNew Submit the new session of st to the guard and the capacity limit
+func (s *managerStore) admitNew(ctx, dbctx context.Context, st *store) error {
+	if err := s.guardCreate(ctx, st); err != nil {
+		return err
+	}
+	return s.admitSession(dbctx, st.sid)
+}
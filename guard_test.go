@@ -0,0 +1,41 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	session "github.com/go-session/session/v3"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateGuard(t *testing.T) {
+	rejected := make(chan SessionEvent, 1)
+	mstore := newOfflineStore(t,
+		WithCreateGuard(func(ctx context.Context, req CreateRequest) CreateDecision {
+			if req.Request != nil && req.Request.UserAgent() == "" {
+				return CreateDecision{Deny: true}
+			}
+			return CreateDecision{Tags: map[string]interface{}{"ip": req.ClientIP.String()}}
+		}),
+		WithLifecycleHooks(LifecycleHooks{OnReject: func(_ context.Context, event SessionEvent) { rejected <- event }}),
+	)
+
+	Convey("Test the guard of the new sessions", t, func() {
+		manager := session.NewManager(session.SetStore(mstore))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		_, err := manager.Start(context.Background(), w, r)
+		So(errors.Is(err, ErrCreateDenied), ShouldBeTrue)
+		So((<-rejected).Kind, ShouldEqual, EventReject)
+
+		r.Header.Set("User-Agent", "test")
+		st, err := manager.Start(context.Background(), w, r)
+		So(err, ShouldBeNil)
+		ip, ok := st.Get("ip")
+		So(ok, ShouldBeTrue)
+		So(ip, ShouldEqual, "192.0.2.1")
+		So(st.(*store).dirty, ShouldContainKey, "ip")
+	})
+}
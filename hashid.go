@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// WithHashedIDs Store the sessions under HMAC-SHA256(sid, secret) instead of their id, so that
+// the ids read from a leaked database can't be replayed as cookies; with legacy, the documents
+// stored under the plain ids are found as well and moved under the hashed ones when loaded,
+// for the migration of a store. The secret must stay the same for the sessions to be found,
+// and SessionInfo reports the hashed ids
+func WithHashedIDs(secret []byte, legacy bool) Option {
+	return func(o *options) {
+		o.idSecret = append([]byte(nil), secret...)
+		o.legacyIDs = legacy
+	}
+}
+
+// hashID The hashed document id of sid
+func (o *options) hashID(sid string) string {
+	mac := hmac.New(sha256.New, o.idSecret)
+	mac.Write([]byte(sid))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// legacyID The document id of sid stored before the ids were hashed, empty if not looked up
+func (s *managerStore) legacyID(sid string) string {
+	if len(s.opts.idSecret) == 0 || !s.opts.legacyIDs {
+		return ""
+	}
+	if s.namespace == "" {
+		return sid
+	}
+	return s.namespace + ":" + sid
+}
+
+// idFilter The query of the _id of the document of sid
+func (s *managerStore) idFilter(sid string) interface{} {
+	if legacy := s.legacyID(sid); legacy != "" {
+		return bson.M{"$in": bson.A{s.docID(sid), legacy}}
+	}
+	return s.docID(sid)
+}
+
+// migrateID Move the loaded document of sid stored under its legacy id to the hashed one,
+// with the chunks of its value if it is stored apart
+func (s *managerStore) migrateID(ctx context.Context, sid string, item *sessionItem) error {
+	legacy := s.legacyID(sid)
+	if legacy == "" || item.ID != legacy {
+		return nil
+	}
+
+	id := s.docID(sid)
+	stored := *item
+	stored.ID = id
+	if item.Spill != nil {
+		// the value stays apart, the reassembled one may not fit a document
+		stored.Value = bson.RawValue{Type: bson.TypeNull}
+		if err := s.moveChunks(ctx, legacy, id, item.Spill.Gen); err != nil {
+			return err
+		}
+	}
+	if _, err := s.c.InsertOne(ctx, &stored); err != nil && !mongo.IsDuplicateKeyError(err) {
+		if item.Spill != nil {
+			if err := s.moveChunks(ctx, id, legacy, item.Spill.Gen); err != nil {
+				s.log(LevelError, "session chunks not moved back", "sid_hash", s.sidHash(sid), "error", err)
+			}
+		}
+		return err
+	}
+	item.ID = id
+	s.log(LevelDebug, "session moved under its hashed id", "sid_hash", s.sidHash(sid))
+	_, err := s.c.DeleteOne(ctx, bson.M{"_id": legacy})
+	return err
+}
+
+// moveChunks Move the chunks of the generation gen from the document from to the document to
+func (s *managerStore) moveChunks(ctx context.Context, from, to, gen string) error {
+	if s.chunks == nil {
+		return ErrChunksMissing
+	}
+	_, err := s.chunks.UpdateMany(ctx, bson.M{"doc": from, "gen": gen}, bson.M{"$set": bson.M{"doc": to}})
+	return err
+}
@@ -0,0 +1,41 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestHashedIDs(t *testing.T) {
+	Convey("Test the session ids hashed at rest", t, func() {
+		mstore := newOfflineStore(t, WithHashedIDs([]byte("secret"), false))
+		id := mstore.docID("sid")
+		So(id, ShouldNotEqual, "sid")
+		So(id, ShouldHaveLength, 43)
+		So(mstore.docID("sid"), ShouldEqual, id)
+		So(mstore.docID("other"), ShouldNotEqual, id)
+		So(newOfflineStore(t, WithHashedIDs([]byte("other"), false)).docID("sid"), ShouldNotEqual, id)
+		So(mstore.namespaceView("ns").docID("sid"), ShouldEqual, "ns:"+id)
+		So(mstore.selector("sid")["_id"], ShouldEqual, id)
+		So(mstore.legacyID("sid"), ShouldBeEmpty)
+
+		Convey("legacy plain ids", func() {
+			mstore := newOfflineStore(t, WithHashedIDs([]byte("secret"), true))
+			So(mstore.legacyID("sid"), ShouldEqual, "sid")
+			So(mstore.namespaceView("ns").legacyID("sid"), ShouldEqual, "ns:sid")
+			So(mstore.selector("sid")["_id"], ShouldResemble, bson.M{"$in": bson.A{id, "sid"}})
+
+			item := &sessionItem{ID: id}
+			So(mstore.migrateID(context.Background(), "sid", item), ShouldBeNil)
+			So(item.ID, ShouldEqual, id)
+		})
+
+		Convey("plain ids by default", func() {
+			mstore := newOfflineStore(t)
+			So(mstore.docID("sid"), ShouldEqual, "sid")
+			So(mstore.legacyID("sid"), ShouldBeEmpty)
+		})
+	})
+}
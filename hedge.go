@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// WithHedgedReads Set whether the session reads are hedged: a sharded cluster router sends
+// them to two members of the replica set and returns the first response, so a slow
+// secondary doesn't dominate the tail latency (MongoDB 4.4 to 7.x, deprecated by 8.0),
+// the reads use the nearest member unless another secondary read preference is set
+func WithHedgedReads(enabled bool) Option {
+	return func(o *options) {
+		o.hedged = enabled
+	}
+}
+
+// hedgedMode The read preference mode of the hedged reads
+func (o *options) hedgedMode() readpref.Mode {
+	if o.readPref != nil && o.readPref.Mode() != readpref.PrimaryMode {
+		return o.readPref.Mode()
+	}
+	return readpref.NearestMode
+}
+
+// sessionReadPref The read preference of the session reads, nil for the client one
+func (o *options) sessionReadPref() *readpref.ReadPref {
+	if !o.hedged {
+		return o.readPref
+	}
+
+	opts := []readpref.Option{readpref.WithHedgeEnabled(true)}
+	if o.readPref != nil {
+		if sets := o.readPref.TagSets(); len(sets) > 0 {
+			opts = append(opts, readpref.WithTagSets(sets...))
+		}
+		if ms, ok := o.readPref.MaxStaleness(); ok {
+			opts = append(opts, readpref.WithMaxStaleness(ms))
+		}
+	}
+	// only the primary mode rejects hedging, tag sets and staleness
+	rp, _ := readpref.New(o.hedgedMode(), opts...)
+	return rp
+}
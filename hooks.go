@@ -0,0 +1,143 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// EventKind The kind of a session event
+type EventKind string
+
+// Kinds of the session events
+const (
+	EventCreate  EventKind = "create"
+	EventUpdate  EventKind = "update"
+	EventRefresh EventKind = "refresh"
+	EventDelete  EventKind = "delete"
+	EventExpire  EventKind = "expire"
+	EventReject  EventKind = "reject"
+)
+
+// SessionEvent A change in the lifecycle of a session
+type SessionEvent struct {
+	// Kind The kind of the event
+	Kind EventKind
+	// SID The session id, OldSID the replaced one for a refresh
+	SID    string
+	OldSID string
+	// SIDHash The session id obfuscated with the SIDHasher of the store, to log the event
+	SIDHash string
+	// Namespace The namespace of the session
+	Namespace string
+	// At The time of the operation
+	At time.Time
+	// ExpiredAt The expiration of the session, the past one when its expiration is detected,
+	// zero for a deletion
+	ExpiredAt time.Time
+}
+
+// LifecycleHooks The callbacks of the lifecycle of the sessions, e.g. for audit trails,
+// each of them is optional
+type LifecycleHooks struct {
+	// OnCreate Called after Create
+	OnCreate func(ctx context.Context, event SessionEvent)
+	// OnRefresh Called after Refresh moved a session to a new id
+	OnRefresh func(ctx context.Context, event SessionEvent)
+	// OnDelete Called after Delete
+	OnDelete func(ctx context.Context, event SessionEvent)
+	// OnExpireDetected Called when Check, Update, Refresh, Touch or Load find the session
+	// expired but still stored (before the TTL monitor removes it), at the cost of a query
+	// for the missing sessions
+	OnExpireDetected func(ctx context.Context, event SessionEvent)
+	// OnReject Called when a new session is rejected by WithCapacityLimit or WithCreateGuard
+	OnReject func(ctx context.Context, event SessionEvent)
+}
+
+// WithLifecycleHooks Call the hooks after the operations of the sessions, asynchronously
+// with a context detached from the one of the operation; a panicking hook is logged
+// without affecting the store, and Close waits for the running hooks
+func WithLifecycleHooks(hooks LifecycleHooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// fireHook Call hook with the event on its own goroutine, if set
+func (s *managerStore) fireHook(ctx context.Context, kind EventKind, hook func(context.Context, SessionEvent), event SessionEvent) {
+	if hook == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	event.Kind = kind
+	event.Namespace = s.namespace
+	if event.SID != "" {
+		event.SIDHash = s.sidHash(event.SID)
+	}
+	if event.At.IsZero() {
+		event.At = s.now()
+	}
+
+	s.opts.hookCalls.Add(1)
+	go func() {
+		defer s.opts.hookCalls.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				s.log(LevelError, "session hook panicked", "hook", string(kind), "sid_hash", event.SIDHash, "panic", fmt.Sprint(r))
+			}
+		}()
+		hook(detachedContext{ctx}, event)
+	}()
+}
+
+// expiredItem The expiration of the stored but expired document of sid, false if there is none
+func (s *managerStore) expiredItem(ctx context.Context, sid string) (time.Time, bool) {
+	q := s.selector(sid)
+	q["expired_at"] = bson.M{"$lt": s.notBefore(0)}
+	var item struct {
+		ExpiredAt time.Time `bson:"expired_at"`
+	}
+	err := s.cPrimary.FindOne(ctx, q, mopts.FindOne().SetProjection(bson.M{"expired_at": 1})).Decode(&item)
+	return item.ExpiredAt, err == nil
+}
+
+// detectExpired Call the OnExpireDetected hook if the missing session sid is expired but stored,
+// removing its document with WithExpiredDeletion
+func (s *managerStore) detectExpired(ctx context.Context, sid string) bool {
+	at, ok := s.expiredItem(ctx, sid)
+	if !ok {
+		return false
+	}
+	if s.opts.expiredDeletion {
+		s.deleteExpired(ctx, sid)
+	}
+	s.fireHook(ctx, EventExpire, s.opts.hooks.OnExpireDetected, SessionEvent{SID: sid, ExpiredAt: at})
+	return true
+}
+
+// deleteExpired Remove the document of sid if it is expired
+func (s *managerStore) deleteExpired(ctx context.Context, sid string) {
+	q := s.selector(sid)
+	q["expired_at"] = bson.M{"$lt": s.notBefore(0)}
+	s.uncache(sid)
+	if s.keepsRemoved(ArchiveExpired) {
+		if _, err := s.retireExpired(ctx, q); err != nil {
+			s.log(LevelWarn, "session expired deletion failed", "sid_hash", s.sidHash(sid), "error", err)
+		}
+		return
+	}
+	res, err := s.c.DeleteOne(ctx, q)
+	if err != nil {
+		s.log(LevelWarn, "session expired deletion failed", "sid_hash", s.sidHash(sid), "error", err)
+		return
+	}
+	if res.DeletedCount > 0 {
+		s.dropChunks(ctx, s.docID(sid))
+		s.dropScratch(ctx, s.docID(sid))
+	}
+}
@@ -0,0 +1,67 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	Convey("Test the hooks of the session lifecycle", t, func() {
+		var mu sync.Mutex
+		var events []SessionEvent
+		var logged []string
+		record := func(ctx context.Context, event SessionEvent) {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}
+		logger := func(level LogLevel, msg string, kv ...interface{}) {
+			mu.Lock()
+			logged = append(logged, msg)
+			mu.Unlock()
+		}
+
+		Convey("on create", func() {
+			mstore := newOfflineStore(t, WithLogger(logger), WithLifecycleHooks(LifecycleHooks{OnCreate: record}))
+			ctx, cancel := context.WithCancel(context.Background())
+			_, err := mstore.Create(ctx, "sid", 10)
+			cancel()
+			So(err, ShouldBeNil)
+			mstore.opts.hookCalls.Wait()
+			So(events, ShouldHaveLength, 1)
+			So(events[0].SID, ShouldEqual, "sid")
+			So(events[0].At.IsZero(), ShouldBeFalse)
+			So(events[0].ExpiredAt.Sub(events[0].At), ShouldAlmostEqual, 10e9, 1e9)
+		})
+
+		Convey("isolated from panics", func() {
+			ctxErr := errors.New("not called")
+			mstore := newOfflineStore(t, WithLogger(logger), WithLifecycleHooks(LifecycleHooks{
+				OnCreate: func(ctx context.Context, event SessionEvent) {
+					ctxErr = ctx.Err()
+					panic("hook failure")
+				},
+			}))
+			ctx, cancel := context.WithCancel(context.Background())
+			_, err := mstore.Create(ctx, "sid", 10)
+			cancel()
+			So(err, ShouldBeNil)
+			mstore.opts.hookCalls.Wait()
+			So(ctxErr, ShouldBeNil)
+			So(logged, ShouldContain, "session hook panicked")
+		})
+
+		Convey("not on failures", func() {
+			fault := errors.New("fault")
+			mstore := newOfflineStore(t, WithLifecycleHooks(LifecycleHooks{OnDelete: record}),
+				WithFaultInjection(Fault{Op: OpDelete, Rate: 1, Err: fault}))
+			So(errors.Is(mstore.Delete(context.Background(), "sid"), fault), ShouldBeTrue)
+			mstore.opts.hookCalls.Wait()
+			So(events, ShouldBeEmpty)
+		})
+	})
+}
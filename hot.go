@@ -0,0 +1,151 @@
+package mongo
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+// WithHotSessionTracking Count the accesses (Check, Update, Refresh and Touch) of the most
+// accessed sessions in memory, keeping at most capacity counters (Space-Saving algorithm),
+// to report the hottest sessions with HotSessions, 0 disables the tracking
+func WithHotSessionTracking(capacity int) Option {
+	return func(o *options) {
+		o.hot = nil
+		if capacity > 0 {
+			o.hot = newHotTracker(capacity)
+		}
+	}
+}
+
+// HotSession The approximate access count of a session
+type HotSession struct {
+	Namespace string
+	// SID The session id, for the operators to act on the session (e.g. delete it); log
+	// SIDHash instead, the hash of WithSIDHasher
+	SID     string
+	SIDHash string
+	// Hits Number of accesses, overestimated by Error at most
+	Hits  uint64
+	Error uint64
+}
+
+// HotSessionReporter Implemented by the stores, to report the most accessed sessions
+// (e.g. of runaway clients or bots)
+type HotSessionReporter interface {
+	// HotSessions The n most accessed sessions of all the namespaces since the store was created,
+	// by decreasing hits, empty unless the tracking is enabled with WithHotSessionTracking
+	HotSessions(n int) []HotSession
+}
+
+func (s *managerStore) HotSessions(n int) []HotSession {
+	if s.opts.hot == nil {
+		return nil
+	}
+	sessions := s.opts.hot.top(n)
+	for i := range sessions {
+		sessions[i].SIDHash = s.sidHash(sessions[i].SID)
+	}
+	return sessions
+}
+
+// hit Account an access to the session sid
+func (s *managerStore) hit(sid string) {
+	if s.opts.hot != nil {
+		s.opts.hot.hit(hotKey{namespace: s.namespace, sid: sid})
+	}
+}
+
+type hotKey struct {
+	namespace string
+	sid       string
+}
+
+type hotCounter struct {
+	key   hotKey
+	hits  uint64
+	err   uint64
+	index int
+}
+
+// hotHeap Min-heap of the counters by hits
+type hotHeap []*hotCounter
+
+func (h hotHeap) Len() int           { return len(h) }
+func (h hotHeap) Less(i, j int) bool { return h[i].hits < h[j].hits }
+func (h hotHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *hotHeap) Push(x interface{}) {
+	c := x.(*hotCounter)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *hotHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// hotTracker Space-Saving counters of the most accessed sessions
+type hotTracker struct {
+	mu       sync.Mutex
+	capacity int
+	counters map[hotKey]*hotCounter
+	heap     hotHeap
+}
+
+func newHotTracker(capacity int) *hotTracker {
+	return &hotTracker{
+		capacity: capacity,
+		counters: make(map[hotKey]*hotCounter, capacity),
+	}
+}
+
+// hit Account an access to key, replacing the least accessed session when full
+func (t *hotTracker) hit(key hotKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c, ok := t.counters[key]; ok {
+		c.hits++
+		heap.Fix(&t.heap, c.index)
+		return
+	}
+	if len(t.heap) < t.capacity {
+		c := &hotCounter{key: key, hits: 1}
+		t.counters[key] = c
+		heap.Push(&t.heap, c)
+		return
+	}
+
+	// the new session inherits the count of the evicted one as its error
+	c := t.heap[0]
+	delete(t.counters, c.key)
+	c.key, c.err = key, c.hits
+	c.hits++
+	t.counters[key] = c
+	heap.Fix(&t.heap, 0)
+}
+
+// top The n counters of the most hits
+func (t *hotTracker) top(n int) []HotSession {
+	t.mu.Lock()
+	sessions := make([]HotSession, len(t.heap))
+	for i, c := range t.heap {
+		sessions[i] = HotSession{Namespace: c.key.namespace, SID: c.key.sid, Hits: c.hits, Error: c.err}
+	}
+	t.mu.Unlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Hits > sessions[j].Hits
+	})
+	if n >= 0 && n < len(sessions) {
+		sessions = sessions[:n]
+	}
+	return sessions
+}
@@ -0,0 +1,40 @@
+package mongo
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHotSessions(t *testing.T) {
+	Convey("Test the report of the most accessed sessions", t, func() {
+		mstore := &managerStore{opts: newOptions(WithHotSessionTracking(2))}
+		for i := 0; i < 10; i++ {
+			mstore.hit("bot")
+		}
+		for i := 0; i < 3; i++ {
+			mstore.hit("user")
+		}
+		mstore.hit("visitor")
+
+		top := mstore.HotSessions(10)
+		So(top, ShouldHaveLength, 2)
+		So(top[0], ShouldResemble, HotSession{SID: "bot", SIDHash: SHA256SIDHasher("bot"), Hits: 10})
+		So(top[1], ShouldResemble, HotSession{SID: "visitor", SIDHash: SHA256SIDHasher("visitor"), Hits: 4, Error: 3})
+		So(mstore.HotSessions(1), ShouldHaveLength, 1)
+
+		Convey("shared by the namespaces", func() {
+			ns := mstore.Namespace("api").(*managerStore)
+			for i := 0; i < 20; i++ {
+				ns.hit("bot")
+			}
+			So(mstore.HotSessions(1)[0], ShouldResemble, HotSession{Namespace: "api", SID: "bot", SIDHash: SHA256SIDHasher("bot"), Hits: 24, Error: 4})
+		})
+
+		Convey("disabled", func() {
+			mstore := &managerStore{opts: newOptions()}
+			mstore.hit("bot")
+			So(mstore.HotSessions(10), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,232 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ErrNotIndexed The field is not an indexed field of the store
+var ErrNotIndexed = errors.New("mongo: not an indexed session field")
+
+// reservedFields The fields of the session documents that can't be indexed metadata
+var reservedFields = map[string]struct{}{
+	"_id": {}, "value": {}, "value_next": {}, "expired_at": {}, "owner": {}, "ns": {}, "version": {}, "created_at": {}, "updated_at": {}, "size": {}, "spill": {}, "fp": {}, "asn": {}, "refresh_key": {}, "save_id": {},
+}
+
+// WithIndexedFields Store the metadata fields (e.g. the user id) set with SetIndexed as
+// top-level fields of the session documents with a secondary index each, to find and delete
+// the sessions by these fields, the names of the document fields of the store are ignored
+func WithIndexedFields(fields ...string) Option {
+	return func(o *options) {
+		for _, field := range fields {
+			if _, ok := reservedFields[field]; ok || field == "" || strings.ContainsAny(field, ".$\x00") {
+				continue
+			}
+			o.indexFields = append(o.indexFields, field)
+		}
+	}
+}
+
+// isIndexed Tell whether field is an indexed field of the store
+func (o *options) isIndexed(field string) bool {
+	for _, f := range o.indexFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexedStore Implemented by the session stores, to set the indexed metadata of the session
+type IndexedStore interface {
+	// SetIndexed Set the indexed field, saved with the session,
+	// Save fails with ErrNotIndexed if field is not an indexed field of the store
+	SetIndexed(field string, value interface{})
+	// GetIndexed Get the indexed field
+	GetIndexed(field string) (interface{}, bool)
+	// DeleteIndexed Remove the indexed field
+	DeleteIndexed(field string)
+}
+
+func (s *store) SetIndexed(field string, value interface{}) {
+	s.Lock()
+	if s.indexed == nil {
+		s.indexed = make(bson.M)
+	}
+	s.indexed[field] = value
+	s.indexDirty = true
+	s.Unlock()
+}
+
+func (s *store) GetIndexed(field string) (interface{}, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	value, ok := s.indexed[field]
+	return value, ok
+}
+
+func (s *store) DeleteIndexed(field string) {
+	s.Lock()
+	if _, ok := s.indexed[field]; ok {
+		delete(s.indexed, field)
+		s.indexDirty = true
+	}
+	s.Unlock()
+}
+
+// indexedFields A copy of the indexed fields of the store, s must be locked
+func (s *store) indexedFields() (bson.M, error) {
+	if len(s.indexed) == 0 {
+		return nil, nil
+	}
+	fields := make(bson.M, len(s.indexed))
+	for field, value := range s.indexed {
+		if !s.mstore.opts.isIndexed(field) {
+			return nil, ErrNotIndexed
+		}
+		fields[field] = value
+	}
+	return fields, nil
+}
+
+// indexUpdate Add the indexed fields to the $set and $unset of a partial update
+func (o *options) indexUpdate(fields, set, unset bson.M) {
+	for _, field := range o.indexFields {
+		if value, ok := fields[field]; ok {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+}
+
+// loadIndexed Keep the indexed fields of a loaded session document
+func (s *managerStore) loadIndexed(item *sessionItem, st *store) {
+	for field, value := range item.Indexed {
+		if !s.opts.isIndexed(field) {
+			continue
+		}
+		if st.indexed == nil {
+			st.indexed = make(bson.M)
+		}
+		st.indexed[field] = value
+		if field == s.opts.loginField {
+			st.bound = value
+		}
+	}
+}
+
+// indexFieldModels The secondary indexes of the indexed fields
+func (o *options) indexFieldModels() []mongo.IndexModel {
+	indexes := make([]mongo.IndexModel, 0, len(o.indexFields))
+	for _, field := range o.indexFields {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: 1}},
+			Options: mopts.Index().SetSparse(true),
+		})
+	}
+	return indexes
+}
+
+// Indexer Implemented by the stores, to find and delete the sessions by their indexed fields
+// (e.g. to log a user out everywhere)
+type Indexer interface {
+	// FindByIndex List the active sessions whose indexed field is value
+	FindByIndex(ctx context.Context, field string, value interface{}) ([]SessionInfo, error)
+	// DeleteByIndex Remove the sessions whose indexed field is value, returning their number
+	DeleteByIndex(ctx context.Context, field string, value interface{}) (int64, error)
+}
+
+// indexScope Query matching the sessions of the store whose indexed field is value
+func (s *managerStore) indexScope(q bson.M, field string, value interface{}) (bson.M, error) {
+	if !s.opts.isIndexed(field) {
+		return nil, ErrNotIndexed
+	}
+	q[field] = value
+	return q, nil
+}
+
+func (s *managerStore) FindByIndex(ctx context.Context, field string, value interface{}) ([]SessionInfo, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	infos, err := s.findByIndex(dbctx, field, value)
+	if err != nil || s.anon == nil {
+		return infos, err
+	}
+	more, err := s.anon.findByIndex(dbctx, field, value)
+	if err != nil {
+		return nil, err
+	}
+	return append(infos, more...), nil
+}
+
+// findByIndex List the active sessions of the collection of s whose indexed field is value
+func (s *managerStore) findByIndex(ctx context.Context, field string, value interface{}) ([]SessionInfo, error) {
+	q, err := s.indexScope(s.activeScope(), field, value)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := s.adminCollection().Find(ctx, q, mopts.Find().SetProjection(infoProjection))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var infos []SessionInfo
+	err = eachInfo(ctx, cur, func(doc sessionInfoDoc) error {
+		infos = append(infos, s.info(doc))
+		return nil
+	})
+	return infos, err
+}
+
+func (s *managerStore) DeleteByIndex(ctx context.Context, field string, value interface{}) (int64, error) {
+	if err := s.injectFault(ctx, OpDelete); err != nil {
+		return 0, err
+	}
+
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	n, err := s.deleteByIndex(dbctx, field, value)
+	if err != nil || s.anon == nil {
+		return n, err
+	}
+	more, err := s.anon.deleteByIndex(dbctx, field, value)
+	return n + more, err
+}
+
+// deleteByIndex Remove the sessions of the collection of s whose indexed field is value
+func (s *managerStore) deleteByIndex(ctx context.Context, field string, value interface{}) (int64, error) {
+	q, err := s.indexScope(s.scope(), field, value)
+	if err != nil {
+		return 0, err
+	}
+	if writePinsFromContext(ctx) != nil {
+		// forget the deleted documents saved with ctx
+		cur, err := s.cPrimary.Find(ctx, q, mopts.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return 0, err
+		}
+		err = eachInfo(ctx, cur, func(doc sessionInfoDoc) error {
+			s.unpinID(ctx, doc.ID)
+			return nil
+		})
+		cur.Close(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	res, err := s.c.DeleteMany(ctx, q)
+	s.uncacheAll()
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
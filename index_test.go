@@ -0,0 +1,68 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestIndexedFields(t *testing.T) {
+	mstore := newOfflineStore(t, WithIndexedFields("uid", "value", "a.b", "tenant"), WithOperationTimeout(50*time.Millisecond))
+
+	Convey("Test the indexed metadata of the sessions", t, func() {
+		So(mstore.opts.indexFields, ShouldResemble, []string{"uid", "tenant"})
+		indexes := mstore.opts.indexModels()
+		So(indexes[len(indexes)-2].Keys, ShouldResemble, bson.D{{Key: "uid", Value: 1}})
+		So(indexes[len(indexes)-1].Keys, ShouldResemble, bson.D{{Key: "tenant", Value: 1}})
+
+		Convey("stored as top-level fields", func() {
+			value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			raw, err := bson.Marshal(&sessionItem{ID: "abc", Value: value, Indexed: bson.M{"uid": "u1"}})
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("uid").StringValue(), ShouldEqual, "u1")
+
+			var item sessionItem
+			So(bson.Unmarshal(raw, &item), ShouldBeNil)
+			store, err := newLoadedStore(mstore, &item, newStore(context.Background(), mstore, "abc", 10, nil))
+			So(err, ShouldBeNil)
+			uid, ok := store.GetIndexed("uid")
+			So(ok, ShouldBeTrue)
+			So(uid, ShouldEqual, "u1")
+		})
+
+		Convey("saved with the partial updates", func() {
+			set, unset := bson.M{}, bson.M{}
+			mstore.opts.indexUpdate(bson.M{"uid": "u1"}, set, unset)
+			So(set, ShouldResemble, bson.M{"uid": "u1"})
+			So(unset, ShouldResemble, bson.M{"tenant": ""})
+		})
+
+		Convey("modifications are kept after a failed save", func() {
+			store := newStore(context.Background(), mstore, "abc", 10, nil)
+			store.loaded = true
+			store.SetIndexed("uid", "u1")
+			So(store.Save(), ShouldNotBeNil)
+			So(store.indexDirty, ShouldBeTrue)
+
+			store.DeleteIndexed("uid")
+			_, ok := store.GetIndexed("uid")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("fields that are not indexed", func() {
+			store := newStore(context.Background(), mstore, "abc", 10, nil)
+			store.SetIndexed("other", 1)
+			So(errors.Is(store.Save(), ErrNotIndexed), ShouldBeTrue)
+
+			_, err := mstore.FindByIndex(context.Background(), "other", 1)
+			So(err, ShouldEqual, ErrNotIndexed)
+			_, err = mstore.DeleteByIndex(context.Background(), "value", 1)
+			So(err, ShouldEqual, ErrNotIndexed)
+		})
+	})
+}
@@ -0,0 +1,211 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// DefaultIndexProgressInterval The default interval of the progress reports of the deferred
+// index builds
+const DefaultIndexProgressInterval = 10 * time.Second
+
+// IndexBuild The progress of the build of an index of the session collection
+type IndexBuild struct {
+	Collection string
+	// Keys The keys of the index, e.g. "uid_1"
+	Keys string
+	// Done and Total The progress of the current phase of the build reported by the server,
+	// Message its description
+	Done, Total int64
+	Message     string
+	// Finished Whether the build is over, Err its failure if any
+	Finished bool
+	Err      error
+}
+
+// WithDeferredIndexes Build the indexes missing from the session collection in the background
+// rather than when the store is created, one at a time, for the huge existing collections
+// on which adding an index (e.g. of WithIndexedFields) takes long; progress (if not nil) is
+// called every interval (DefaultIndexProgressInterval if not positive) with the progress
+// reported by the server, and when each build is over. Close stops following the builds,
+// which the servers from 4.4 complete anyway
+func WithDeferredIndexes(interval time.Duration, progress func(IndexBuild)) Option {
+	return func(o *options) {
+		if interval <= 0 {
+			interval = DefaultIndexProgressInterval
+		}
+		o.deferredIndexes = &deferredIndexes{interval: interval, progress: progress}
+	}
+}
+
+// deferredIndexes The options of WithDeferredIndexes
+type deferredIndexes struct {
+	interval time.Duration
+	progress func(IndexBuild)
+}
+
+// indexBuilds The background index builds of a store
+type indexBuilds struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newIndexBuilds() *indexBuilds {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &indexBuilds{ctx: ctx, cancel: cancel}
+}
+
+// stop Stop following the builds and wait for their goroutine
+func (b *indexBuilds) stop() {
+	b.cancel()
+	b.wg.Wait()
+}
+
+// keySignature The keys of an index specification as "field_direction" pairs
+func keySignature(keys bson.Raw) string {
+	elems, err := keys.Elements()
+	if err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(elems))
+	for _, e := range elems {
+		v := e.Value()
+		if f, ok := v.AsFloat64OK(); ok {
+			parts = append(parts, fmt.Sprintf("%s_%g", e.Key(), f))
+		} else {
+			parts = append(parts, e.Key()+"_"+v.StringValue())
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// missingIndexes The models of indexes the collection of s doesn't have
+func (s *managerStore) missingIndexes(ctx context.Context, indexes []mongo.IndexModel) ([]mongo.IndexModel, error) {
+	cur, err := s.c.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	existing := map[string]bool{}
+	for cur.Next(ctx) {
+		var spec struct {
+			Key bson.Raw `bson:"key"`
+		}
+		if err := cur.Decode(&spec); err != nil {
+			return nil, err
+		}
+		existing[keySignature(spec.Key)] = true
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []mongo.IndexModel
+	for _, model := range indexes {
+		keys, err := bson.Marshal(model.Keys)
+		if err != nil {
+			return nil, err
+		}
+		if !existing[keySignature(keys)] {
+			missing = append(missing, model)
+		}
+	}
+	return missing, nil
+}
+
+// deferIndexes Build the indexes missing from the collection of s in the background
+func (s *managerStore) deferIndexes(ctx context.Context, indexes []mongo.IndexModel) error {
+	missing, err := s.missingIndexes(ctx, indexes)
+	if err != nil {
+		s.log(LevelError, "session index listing failed", "collection", s.c.Name(), "error", err)
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	s.builds.wg.Add(1)
+	go func() {
+		defer s.builds.wg.Done()
+		for _, model := range missing {
+			if s.builds.ctx.Err() != nil {
+				return
+			}
+			s.buildIndex(s.builds.ctx, model)
+		}
+	}()
+	return nil
+}
+
+// buildIndex Build the index of model, reporting its progress
+func (s *managerStore) buildIndex(ctx context.Context, model mongo.IndexModel) {
+	keys, _ := bson.Marshal(model.Keys)
+	build := IndexBuild{Collection: s.c.Name(), Keys: keySignature(keys)}
+	report := func(b IndexBuild) {
+		if p := s.opts.deferredIndexes.progress; p != nil {
+			p(b)
+		}
+	}
+
+	s.log(LevelInfo, "session index build started", "collection", build.Collection, "keys", build.Keys)
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.c.Indexes().CreateOne(ctx, model)
+		done <- err
+	}()
+	t := time.NewTicker(s.opts.deferredIndexes.interval)
+	defer t.Stop()
+	for {
+		select {
+		case err := <-done:
+			if ctx.Err() != nil {
+				// the store is closed
+				return
+			}
+			build.Finished, build.Err = true, err
+			if err != nil {
+				s.log(LevelError, "session index build failed", "collection", build.Collection, "keys", build.Keys, "error", err)
+			} else {
+				s.log(LevelInfo, "session index built", "collection", build.Collection, "keys", build.Keys)
+			}
+			report(build)
+			return
+		case <-t.C:
+			s.indexProgress(ctx, &build)
+			report(build)
+		}
+	}
+}
+
+// indexProgress Update the progress of build from the operations of the server
+func (s *managerStore) indexProgress(ctx context.Context, build *IndexBuild) {
+	cmd := bson.D{
+		{Key: "currentOp", Value: true},
+		{Key: "command.createIndexes", Value: build.Collection},
+	}
+	var res struct {
+		InProg []struct {
+			Msg      string `bson:"msg"`
+			Progress struct {
+				Done  int64 `bson:"done,truncate"`
+				Total int64 `bson:"total,truncate"`
+			} `bson:"progress"`
+		} `bson:"inprog"`
+	}
+	if err := s.client.Database("admin").RunCommand(ctx, cmd).Decode(&res); err != nil {
+		s.log(LevelWarn, "session index progress failed", "collection", build.Collection, "error", err)
+		return
+	}
+	for _, op := range res.InProg {
+		if op.Progress.Total > 0 || op.Msg != "" {
+			build.Done, build.Total, build.Message = op.Progress.Done, op.Progress.Total, op.Msg
+			return
+		}
+	}
+}
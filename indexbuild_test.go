@@ -0,0 +1,25 @@
+package mongo
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestKeySignature(t *testing.T) {
+	Convey("Test the signatures of the index keys", t, func() {
+		keys, err := bson.Marshal(bson.D{{Key: "ns", Value: 1}, {Key: "expired_at", Value: -1}})
+		So(err, ShouldBeNil)
+		So(keySignature(keys), ShouldEqual, "ns_1_expired_at_-1")
+
+		// as created by the shell
+		keys, err = bson.Marshal(bson.D{{Key: "ns", Value: 1.0}, {Key: "expired_at", Value: int64(-1)}})
+		So(err, ShouldBeNil)
+		So(keySignature(keys), ShouldEqual, "ns_1_expired_at_-1")
+
+		keys, err = bson.Marshal(bson.D{{Key: "uid", Value: "hashed"}})
+		So(err, ShouldBeNil)
+		So(keySignature(keys), ShouldEqual, "uid_hashed")
+	})
+}
@@ -0,0 +1,145 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	session "github.com/go-session/session/v3"
+)
+
+// ErrIPNotAllowed The session is used from outside its allowed networks
+var ErrIPNotAllowed = errors.New("session used from a network that is not allowed")
+
+// ipFlagKey The session value flagging a session used from outside its allowed networks
+const ipFlagKey = "_ip_flagged"
+
+// IPDecision What Update and Refresh do with a session used from outside the allowed networks
+type IPDecision int
+
+// IP pinning decisions
+const (
+	// IPReject Fail with ErrIPNotAllowed (default)
+	IPReject IPDecision = iota
+	// IPRestart Start a new empty session under the same id, replacing the stored one when saved
+	IPRestart
+	// IPFlag Log the request and load the session, flagged with the IP (see IPFlagged)
+	IPFlag
+	// IPAllow Load the session
+	IPAllow
+)
+
+// IPCheck A request loading a session from outside its allowed networks
+type IPCheck struct {
+	// SIDHash A hash of the session id
+	SIDHash string
+	// IP The IP of the request, nil if unknown
+	IP net.IP
+	// OutOfRange Whether the IP is unknown or out of the allowed ranges
+	OutOfRange bool
+	// ASN The autonomous system of the IP, SessionASN the one the session was created from
+	ASN, SessionASN uint32
+}
+
+// IPPinning The networks the sessions may be used from, checked by Update and Refresh
+type IPPinning struct {
+	// Ranges The allowed ranges, any IP if empty
+	Ranges []*net.IPNet
+	// LookupASN The autonomous system of an IP (0 if unknown), the sessions are bound to the
+	// one of the request that creates them if set
+	LookupASN func(ip net.IP) uint32
+	// Policy The decision for the requests out of the ranges or from another autonomous system
+	Policy IPDecision
+	// Decide Overrides Policy for each of these requests if set
+	Decide func(ctx context.Context, check IPCheck) IPDecision
+}
+
+// WithIPPinning Check the IP of the requests loading the sessions with Update or Refresh
+// (given with WithClientIP, or else the remote address of the request started by the session
+// manager), applying the policy of pinning to those out of the ranges or changing of ASN
+func WithIPPinning(pinning IPPinning) Option {
+	return func(o *options) {
+		o.ipPinning = &pinning
+	}
+}
+
+// WithClientIP Returns a context giving the IP of the client of the request to the sessions
+// loaded with it, e.g. the one forwarded by a trusted proxy
+func WithClientIP(ctx context.Context, ip net.IP) context.Context {
+	return withCallOptions(ctx, func(o *callOptions) {
+		o.clientIP = ip
+	})
+}
+
+// IPFlagged The IP the session was flagged from with IPFlag, false if it's not flagged
+func IPFlagged(store session.Store) (string, bool) {
+	v, ok := store.Get(ipFlagKey)
+	ip, _ := v.(string)
+	return ip, ok && ip != ""
+}
+
+// clientIP The IP of the client of ctx, nil if unknown
+func clientIP(ctx context.Context) net.IP {
+	if ip := callOptionsFromContext(ctx).clientIP; ip != nil {
+		return ip
+	}
+	if ctx == nil {
+		return nil
+	}
+	req, ok := session.FromReqContext(ctx)
+	if !ok {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// callerASN The autonomous system of the client of ctx, if the sessions are bound to one
+func (s *managerStore) callerASN(ctx context.Context) uint32 {
+	p := s.opts.ipPinning
+	if p == nil || p.LookupASN == nil {
+		return 0
+	}
+	if ip := clientIP(ctx); ip != nil {
+		return p.LookupASN(ip)
+	}
+	return 0
+}
+
+// verifyIP Apply the IP pinning to the caller of ctx loading item
+func (s *managerStore) verifyIP(ctx context.Context, sid string, item *sessionItem) (IPDecision, net.IP) {
+	p := s.opts.ipPinning
+	if p == nil {
+		return IPAllow, nil
+	}
+	check := IPCheck{SIDHash: s.sidHash(sid), IP: clientIP(ctx), SessionASN: item.ASN}
+	if len(p.Ranges) > 0 {
+		check.OutOfRange = true
+		for _, r := range p.Ranges {
+			if check.IP != nil && r.Contains(check.IP) {
+				check.OutOfRange = false
+				break
+			}
+		}
+	}
+	if p.LookupASN != nil && check.IP != nil {
+		check.ASN = p.LookupASN(check.IP)
+	}
+	changed := check.ASN != 0 && check.SessionASN != 0 && check.ASN != check.SessionASN
+	if !check.OutOfRange && !changed {
+		return IPAllow, check.IP
+	}
+
+	decision := p.Policy
+	if p.Decide != nil {
+		decision = p.Decide(ctx, check)
+	}
+	if decision != IPAllow {
+		s.log(LevelWarn, "session used from a network that is not allowed", "sid_hash", check.SIDHash,
+			"ip", check.IP.String(), "out_of_range", check.OutOfRange, "asn", check.ASN, "session_asn", check.SessionASN)
+	}
+	return decision, check.IP
+}
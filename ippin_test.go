@@ -0,0 +1,97 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIPPinning(t *testing.T) {
+	Convey("Test the sessions pinned to IP ranges", t, func() {
+		_, office, err := net.ParseCIDR("10.0.0.0/8")
+		So(err, ShouldBeNil)
+		asns := map[string]uint32{"10.0.0.1": 64500, "10.0.0.2": 64501}
+		lookup := func(ip net.IP) uint32 { return asns[ip.String()] }
+
+		inside := WithClientIP(context.Background(), net.ParseIP("10.0.0.1"))
+		otherASN := WithClientIP(context.Background(), net.ParseIP("10.0.0.2"))
+		outside := WithClientIP(context.Background(), net.ParseIP("192.0.2.1"))
+
+		value, err := newOfflineStore(t).encodeValues(map[string]interface{}{"foo": "bar"})
+		So(err, ShouldBeNil)
+		item := func() *sessionItem { return &sessionItem{Value: value, ASN: 64500} }
+
+		Convey("client IP", func() {
+			So(clientIP(inside).String(), ShouldEqual, "10.0.0.1")
+			So(clientIP(context.Background()), ShouldBeNil)
+		})
+
+		Convey("ASN recorded at the creation", func() {
+			mstore := newOfflineStore(t, WithIPPinning(IPPinning{LookupASN: lookup}))
+			So(newStore(inside, mstore, "sid", 10, nil).asn, ShouldEqual, 64500)
+			So(newStore(context.Background(), mstore, "sid", 10, nil).asn, ShouldBeZeroValue)
+			So(newStore(inside, newOfflineStore(t), "sid", 10, nil).asn, ShouldBeZeroValue)
+		})
+
+		Convey("enforced on load", func() {
+			mstore := newOfflineStore(t, WithIPPinning(IPPinning{Ranges: []*net.IPNet{office}, LookupASN: lookup}))
+			store, err := mstore.loadBound(inside, "sid", 10, item())
+			So(err, ShouldBeNil)
+			So(store.loaded, ShouldBeTrue)
+			So(store.asn, ShouldEqual, 64500)
+			_, err = mstore.loadBound(outside, "sid", 10, item())
+			So(errors.Is(err, ErrIPNotAllowed), ShouldBeTrue)
+			_, err = mstore.loadBound(otherASN, "sid", 10, item())
+			So(errors.Is(err, ErrIPNotAllowed), ShouldBeTrue)
+			_, err = mstore.loadBound(context.Background(), "sid", 10, item())
+			So(errors.Is(err, ErrIPNotAllowed), ShouldBeTrue)
+
+			mstore = newOfflineStore(t, WithIPPinning(IPPinning{Ranges: []*net.IPNet{office}, Policy: IPRestart}))
+			store, err = mstore.loadBound(outside, "sid", 10, item())
+			So(err, ShouldBeNil)
+			So(store.loaded, ShouldBeFalse)
+			_, ok := store.Get("foo")
+			So(ok, ShouldBeFalse)
+
+			mstore = newOfflineStore(t, WithIPPinning(IPPinning{Ranges: []*net.IPNet{office}, Policy: IPFlag}))
+			store, err = mstore.loadBound(outside, "sid", 10, item())
+			So(err, ShouldBeNil)
+			foo, _ := store.Get("foo")
+			So(foo, ShouldEqual, "bar")
+			ip, ok := IPFlagged(store)
+			So(ok, ShouldBeTrue)
+			So(ip, ShouldEqual, "192.0.2.1")
+
+			store, err = mstore.loadBound(inside, "sid", 10, item())
+			So(err, ShouldBeNil)
+			_, ok = IPFlagged(store)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("with a custom decision", func() {
+			var checks []IPCheck
+			mstore := newOfflineStore(t, WithIPPinning(IPPinning{
+				LookupASN: lookup,
+				Decide: func(ctx context.Context, check IPCheck) IPDecision {
+					checks = append(checks, check)
+					return IPAllow
+				},
+			}))
+			_, err := mstore.loadBound(inside, "sid", 10, item())
+			So(err, ShouldBeNil)
+			So(checks, ShouldBeEmpty)
+
+			store, err := mstore.loadBound(otherASN, "sid", 10, item())
+			So(err, ShouldBeNil)
+			So(store.loaded, ShouldBeTrue)
+			So(checks, ShouldHaveLength, 1)
+			So(checks[0].SIDHash, ShouldEqual, SHA256SIDHasher("sid"))
+			So(checks[0].OutOfRange, ShouldBeFalse)
+			So(checks[0].ASN, ShouldEqual, 64501)
+			So(checks[0].SessionASN, ShouldEqual, 64500)
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package mongo
+
+import "strings"
+
+// KeyViolation A session key refused by the key policy of WithAllowedKeys and WithDeniedKeys
+type KeyViolation struct {
+	// Key The refused key
+	Key string
+	// SIDHash The hash of the id of the session, as logged
+	SIDHash string
+	// Denied Whether the key matched WithDeniedKeys, else it's missing from WithAllowedKeys
+	Denied bool
+	// Saved Whether the key was stripped from the values at their save (e.g. loaded from a
+	// session stored before the policy), else it was refused by Set, SetMulti or Mutate
+	Saved bool
+}
+
+// WithAllowedKeys Only store the session keys among keys, the patterns ending with "*"
+// matching the keys of their prefix, case insensitively; the other keys are refused by Set,
+// SetMulti and Mutate and stripped from the values at their save
+func WithAllowedKeys(keys ...string) Option {
+	return func(o *options) {
+		o.allowedKeys = append(o.allowedKeys, lowerKeys(keys)...)
+	}
+}
+
+// WithDeniedKeys Never store the session keys among keys (e.g. "password", "card_*"), the
+// patterns ending with "*" matching the keys of their prefix, case insensitively; the keys
+// are refused by Set, SetMulti and Mutate and stripped from the values at their save
+func WithDeniedKeys(keys ...string) Option {
+	return func(o *options) {
+		o.deniedKeys = append(o.deniedKeys, lowerKeys(keys)...)
+	}
+}
+
+// WithKeyViolation Call violation with each session key refused or stripped by the key policy
+// of WithAllowedKeys and WithDeniedKeys, e.g. to alert on the code storing them
+func WithKeyViolation(violation func(KeyViolation)) Option {
+	return func(o *options) {
+		o.keyViolation = violation
+	}
+}
+
+// lowerKeys The patterns keys in lower case
+func lowerKeys(keys []string) []string {
+	lower := make([]string, len(keys))
+	for i, k := range keys {
+		lower[i] = strings.ToLower(k)
+	}
+	return lower
+}
+
+// matchKey Tell whether key matches one of the lower case patterns
+func matchKey(patterns []string, key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range patterns {
+		if prefix := strings.TrimSuffix(p, "*"); prefix != p {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == p {
+			return true
+		}
+	}
+	return false
+}
+
+// keyPolicy Whether o restricts the session keys
+func (o *options) keyPolicy() bool {
+	return len(o.allowedKeys) > 0 || len(o.deniedKeys) > 0
+}
+
+// refuseKey Tell whether key can't be stored, and if it's for matching WithDeniedKeys
+func (o *options) refuseKey(key string) (refused, denied bool) {
+	if matchKey(o.deniedKeys, key) {
+		return true, true
+	}
+	if len(o.allowedKeys) > 0 && !matchKey(o.allowedKeys, key) {
+		return true, false
+	}
+	return false, false
+}
+
+// keyViolated Report the refused key of the session sid
+func (s *managerStore) keyViolated(sid, key string, denied, saved bool) {
+	s.log(LevelWarn, "session key refused by the key policy", "sid_hash", s.sidHash(sid), "key", key, "denied", denied)
+	if s.opts.keyViolation != nil {
+		s.opts.keyViolation(KeyViolation{Key: key, SIDHash: s.sidHash(sid), Denied: denied, Saved: saved})
+	}
+}
+
+// admitKey Tell whether key can be set, reporting a refused one
+func (s *store) admitKey(key string) bool {
+	if refused, denied := s.mstore.opts.refuseKey(key); refused {
+		s.mstore.keyViolated(s.sid, key, denied, false)
+		return false
+	}
+	return true
+}
+
+// admitKeys The values without those of the refused keys, reported, values itself if
+// none is refused
+func (s *store) admitKeys(values map[string]interface{}) map[string]interface{} {
+	if !s.mstore.opts.keyPolicy() {
+		return values
+	}
+	admitted := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if s.admitKey(k) {
+			admitted[k] = v
+		}
+	}
+	return admitted
+}
+
+// stripKeys Remove the values of the refused keys before a save, the removals being written
+// with the other modifications
+func (s *store) stripKeys() {
+	if !s.mstore.opts.keyPolicy() {
+		return
+	}
+	type strip struct {
+		key    string
+		denied bool
+	}
+	var stripped []strip
+	s.Lock()
+	s.materialize()
+	for k := range s.values {
+		if refused, denied := s.mstore.opts.refuseKey(k); refused {
+			delete(s.values, k)
+			s.markDirty(k)
+			stripped = append(stripped, strip{k, denied})
+		}
+	}
+	s.Unlock()
+	for _, st := range stripped {
+		if s.diag != nil {
+			s.diag.dirty(st.key)
+		}
+		s.mstore.keyViolated(s.sid, st.key, st.denied, true)
+	}
+}
@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKeyPolicy(t *testing.T) {
+	Convey("Test the refusal of the keys out of the key policy", t, func() {
+		var violations []KeyViolation
+		mstore := newOfflineStore(t,
+			WithAllowedKeys("user_id", "cart_*", "Password"),
+			WithDeniedKeys("password", "cart_card*"),
+			WithKeyViolation(func(v KeyViolation) { violations = append(violations, v) }),
+		)
+		store := newStore(context.Background(), mstore, "test_keys", 10, nil)
+
+		store.Set("user_id", "u1")
+		store.Set("Cart_Items", 2.0)
+		store.Set("PASSWORD", "secret")
+		store.Set("email", "a@b.c")
+		So(store.Len(), ShouldEqual, 2)
+		So(violations, ShouldResemble, []KeyViolation{
+			{Key: "PASSWORD", SIDHash: mstore.sidHash("test_keys"), Denied: true},
+			{Key: "email", SIDHash: mstore.sidHash("test_keys")},
+		})
+
+		violations = nil
+		store.SetMulti(map[string]interface{}{"cart_card_number": "4111", "cart_total": 3.0})
+		So(store.Len(), ShouldEqual, 3)
+		So(violations, ShouldHaveLength, 1)
+		So(violations[0].Key, ShouldEqual, "cart_card_number")
+		So(violations[0].Denied, ShouldBeTrue)
+
+		violations = nil
+		err := store.Mutate(func(values map[string]interface{}) error {
+			values["phone"] = "555"
+			values["cart_total"] = 4.0
+			return nil
+		})
+		So(err, ShouldBeNil)
+		_, ok := store.Get("phone")
+		So(ok, ShouldBeFalse)
+		v, _ := store.Get("cart_total")
+		So(v, ShouldEqual, 4.0)
+		So(violations, ShouldHaveLength, 1)
+		So(violations[0].Key, ShouldEqual, "phone")
+		So(violations[0].Denied, ShouldBeFalse)
+
+		Convey("The refused keys loaded with the session are stripped at its save", func() {
+			violations = nil
+			store.values["password"] = "loaded"
+			store.dirty = nil
+			store.stripKeys()
+			_, ok := store.Get("password")
+			So(ok, ShouldBeFalse)
+			So(store.dirty, ShouldContainKey, "password")
+			So(violations, ShouldResemble, []KeyViolation{
+				{Key: "password", SIDHash: mstore.sidHash("test_keys"), Denied: true, Saved: true},
+			})
+		})
+	})
+
+	Convey("Test the validation of the key policy options", t, func() {
+		So(ValidateOptions(WithKeyViolation(func(KeyViolation) {})), ShouldNotBeNil)
+		So(ValidateOptions(WithDeniedKeys("password"), WithKeyViolation(func(KeyViolation) {})), ShouldBeNil)
+	})
+}
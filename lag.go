@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// WithReadLagGuard Guard the session reads from the secondaries (WithReadPreference,
+// WithSessionAffinity, WithHedgedReads) against the replication lag: the documents last
+// modified more than maxStaleness ago, which a write not replicated yet may have replaced, and
+// the missing documents, which may not be replicated yet, are read again from the primary. The
+// sessions saved within maxStaleness are served by the secondaries, the others by the primary
+func WithReadLagGuard(maxStaleness time.Duration) Option {
+	return func(o *options) {
+		o.maxReadLag = maxStaleness
+	}
+}
+
+// lagging Tell whether item, read from c, may be older than the document on the primary
+func (s *managerStore) lagging(c *mongo.Collection, item *sessionItem) bool {
+	if s.opts.maxReadLag <= 0 || c == s.cPrimary {
+		return false
+	} else if item == nil {
+		return true
+	}
+	modified := item.UpdatedAt
+	if modified.IsZero() {
+		modified = item.CreatedAt
+	}
+	return modified.Before(s.now().Add(-s.opts.maxReadLag))
+}
@@ -0,0 +1,28 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReadLagGuard(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+
+	Convey("Test the guard of the reads from the secondaries", t, func() {
+		mstore := newOfflineStore(t, clock)
+		item := &sessionItem{CreatedAt: now.Add(-time.Hour)}
+		So(mstore.lagging(mstore.c, item), ShouldBeFalse)
+		So(mstore.lagging(mstore.c, nil), ShouldBeFalse)
+
+		mstore = newOfflineStore(t, clock, WithReadLagGuard(10*time.Second))
+		So(mstore.lagging(mstore.cPrimary, nil), ShouldBeFalse)
+		So(mstore.lagging(mstore.c, nil), ShouldBeTrue)
+		So(mstore.lagging(mstore.c, item), ShouldBeTrue)
+		item.UpdatedAt = now.Add(-5 * time.Second)
+		So(mstore.lagging(mstore.c, item), ShouldBeFalse)
+		So(mstore.lagging(mstore.c, &sessionItem{CreatedAt: now.Add(-time.Second)}), ShouldBeFalse)
+	})
+}
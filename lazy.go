@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// WithLazyDecode Set whether to decode the JSON session values on demand: until the session
+// gets modified, Get only decodes the requested key and Save writes back the stored value,
+// which saves allocations for large sessions of which few keys are read per request
+func WithLazyDecode(lazy bool) Option {
+	return func(o *options) {
+		o.lazyDecode = lazy
+	}
+}
+
+// newLoadedStore Create the store of a loaded session document,
+// deferring the decoding of its values in lazy mode
+func newLoadedStore(s *managerStore, item *sessionItem, st *store) (*store, error) {
+	st.loaded = true
+	st.size = len(item.Value.Value)
+	st.version = item.Version
+	if !item.CreatedAt.IsZero() {
+		st.createdAt = item.CreatedAt
+	}
+	st.partial = s.opts.documents && item.Value.Type == bson.TypeEmbeddedDocument && item.ValueNext.IsZero() && item.Spill == nil
+	s.loadIndexed(item, st)
+	if item.Fingerprint != "" {
+		st.fingerprint = item.Fingerprint
+	}
+	if item.ASN != 0 {
+		st.asn = item.ASN
+	}
+	if s.opts.lazyDecode && item.Value.Type == bson.TypeString && item.ValueNext.IsZero() {
+		if value := item.Value.StringValue(); value != "" {
+			st.lazy = []byte(value)
+			st.lazyValue = item.Value
+		}
+		return st, nil
+	}
+
+	values, err := s.decodeValues(item)
+	if err != nil {
+		return nil, err
+	}
+	if len(st.values) == 0 {
+		st.values = values
+		return st, nil
+	}
+	for k, v := range values {
+		st.values[k] = v
+	}
+	return st, nil
+}
+
+// lazyGet Decode the value of key only, s must be locked
+func (s *store) lazyGet(key string) (interface{}, bool) {
+	if val, ok := s.values[key]; ok || s.lazy == nil {
+		return val, ok
+	}
+
+	v := jsoniter.Get(s.lazy, key)
+	if v.LastError() != nil {
+		return nil, false
+	}
+	val, err := s.mstore.opts.decodeType(v.GetInterface())
+	if err != nil {
+		return nil, false
+	}
+	s.values[key] = val
+	return val, true
+}
+
+// materialize Decode all the values of a lazily decoded store, s must be locked
+func (s *store) materialize() {
+	if s.lazy == nil {
+		return
+	}
+
+	var values map[string]interface{}
+	if err := jsoniter.Unmarshal(s.lazy, &values); err != nil {
+		s.lazyErr = err
+	} else if err := s.mstore.opts.decodeTypes(values); err != nil {
+		s.lazyErr = err
+	}
+	for k, v := range values {
+		if _, ok := s.values[k]; !ok {
+			s.values[k] = v
+		}
+	}
+	s.lazy = nil
+	s.lazyValue = bson.RawValue{}
+}
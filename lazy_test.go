@@ -0,0 +1,45 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLazyDecode(t *testing.T) {
+	mstore := newOfflineStore(t, WithLazyDecode(true))
+
+	Convey("Test values decoded on demand", t, func() {
+		value, err := rawValue(`{"foo":"bar","n":1,"m":{"a":true}}`)
+		So(err, ShouldBeNil)
+		item := &sessionItem{Value: value}
+
+		store, err := newLoadedStore(mstore, item, newStore(context.Background(), mstore, "test_lazy", 10, nil))
+		So(err, ShouldBeNil)
+
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+		So(store.values, ShouldHaveLength, 1)
+		So(store.lazy, ShouldNotBeNil)
+
+		_, ok = store.Get("missing")
+		So(ok, ShouldBeFalse)
+
+		store.Set("foo", "baz")
+		So(store.lazy, ShouldBeNil)
+		So(store.values["foo"], ShouldEqual, "baz")
+		So(store.values["n"], ShouldEqual, float64(1))
+		So(store.values["m"], ShouldResemble, map[string]interface{}{"a": true})
+
+		Convey("with invalid stored data", func() {
+			value, err := rawValue(`{"foo":`)
+			So(err, ShouldBeNil)
+			store, err := newLoadedStore(mstore, &sessionItem{Value: value}, newStore(context.Background(), mstore, "test_lazy", 10, nil))
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldNotBeNil)
+		})
+	})
+}
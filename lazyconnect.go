@@ -0,0 +1,49 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// WithLazyConnect Set whether to defer the setup of the database (index creation and
+// connection warm-up) from the creation of the store to its first session operation, which
+// then returns the setup error, retried by the next operations; for the programs that may
+// never use the store (e.g. command line tools and tests), the client still connecting in
+// the background
+func WithLazyConnect(lazy bool) Option {
+	return func(o *options) {
+		o.lazy = nil
+		if lazy {
+			o.lazy = &lazySetup{}
+		}
+	}
+}
+
+// lazySetup The deferred setup of a store, shared by its views
+type lazySetup struct {
+	mu   sync.Mutex
+	done atomic.Bool
+	fn   func(ctx context.Context) error
+}
+
+// ready Run the deferred setup of the store unless done already
+func (s *managerStore) ready(ctx context.Context) error {
+	l := s.opts.lazy
+	if l == nil || l.done.Load() {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done.Load() || l.fn == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := l.fn(ctx); err != nil {
+		return err
+	}
+	l.done.Store(true)
+	return nil
+}
@@ -0,0 +1,24 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLazyConnect(t *testing.T) {
+	Convey("Test the setup deferred to the first use", t, func() {
+		_, err := NewStoreWithError("127.0.0.1:1", dbName, cName, WithDialTimeout(50*time.Millisecond))
+		So(err, ShouldNotBeNil)
+
+		mstore, err := NewStoreWithError("127.0.0.1:1", dbName, cName, WithDialTimeout(50*time.Millisecond), WithLazyConnect(true))
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		_, err = mstore.Create(context.Background(), "test_lazy", 10)
+		So(err, ShouldNotBeNil)
+		So(mstore.(*managerStore).opts.lazy.done.Load(), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,168 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// ErrLockTimeout The lock of the session was held by another request for longer than the wait timeout
+var ErrLockTimeout = errors.New("session lock wait timed out")
+
+// lockPoll Bounds of the interval between the attempts to take a held lock
+const (
+	lockPollMin = 10 * time.Millisecond
+	lockPollMax = 200 * time.Millisecond
+)
+
+// WithSessionLock Serialize the requests of a session across the application servers:
+// Create and Update take an advisory lock on the session, held until Save, Release or the end
+// of the context of the request, waiting up to wait for it (failing with ErrLockTimeout), the
+// lock lapsing after ttl if none of them comes; the locks are the documents of the collection
+// named after the session one with a "_locks" suffix
+func WithSessionLock(ttl, wait time.Duration) Option {
+	return func(o *options) {
+		o.lockTTL = ttl
+		o.lockWait = wait
+	}
+}
+
+// Releaser Implemented by the session stores, to release the session lock of a request which
+// doesn't save, e.g. a read-only one with a context never done
+type Releaser interface {
+	// Release Release the lock taken by the request if any, the changes can still be saved
+	// without it
+	Release()
+}
+
+// sessionLock A lock taken on a session
+type sessionLock struct {
+	locks *mongo.Collection
+	id    string
+	token string
+}
+
+// lockCollection The collection of the session locks of the store of c
+func (o *options) lockCollection(c *mongo.Collection) *mongo.Collection {
+	if o.lockTTL <= 0 {
+		return nil
+	}
+	return c.Database().Collection(c.Name()+"_locks", o.collectionOptions().SetReadPreference(readpref.Primary()))
+}
+
+// lockIndexModels The indexes to create on the lock collection
+func lockIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "expired_at", Value: 1}},
+		Options: mopts.Index().SetExpireAfterSeconds(1),
+	}}
+}
+
+// lock Take the lock of sid, waiting for the other requests holding it
+func (s *managerStore) lock(ctx context.Context, sid string) (*sessionLock, error) {
+	root := s.root()
+	if root.locks == nil {
+		return nil, nil
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	l := &sessionLock{locks: root.locks, id: s.docID(sid), token: hex.EncodeToString(token)}
+
+	// the wait is measured on the monotonic clock, the one of WithClock may not move
+	start := time.Now()
+	poll := lockPollMin
+	for {
+		now := s.now()
+		_, err := l.locks.UpdateOne(ctx,
+			bson.M{"_id": l.id, "expired_at": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"token": l.token, "expired_at": now.Add(s.opts.lockTTL)}},
+			mopts.UpdateOne().SetUpsert(true))
+		if err == nil {
+			return l, nil
+		} else if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		// held by another request
+		if time.Since(start)+poll >= s.opts.lockWait {
+			s.log(LevelWarn, "session lock wait timed out", "sid_hash", s.sidHash(sid), "wait", s.opts.lockWait)
+			return nil, ErrLockTimeout
+		}
+		timer := time.NewTimer(poll)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if poll *= 2; poll > lockPollMax {
+			poll = lockPollMax
+		}
+	}
+}
+
+// unlock Release the lock if it is still held by the request
+func (l *sessionLock) unlock(ctx context.Context) error {
+	_, err := l.locks.DeleteOne(ctx, bson.M{"_id": l.id, "token": l.token})
+	return err
+}
+
+// hold Keep the lock l taken for the store until it is released, releasing it at the end of
+// the request context, so that the requests which don't save don't hold it for its ttl
+func (s *store) hold(l *sessionLock) {
+	if l == nil {
+		return
+	}
+	s.held = l
+	if s.ctx == nil || s.ctx.Done() == nil {
+		return
+	}
+	done, released := s.ctx.Done(), make(chan struct{})
+	s.released = released
+	go func() {
+		select {
+		case <-done:
+			s.unlock()
+		case <-released:
+		}
+	}()
+}
+
+func (s *store) Release() {
+	s.unlock()
+}
+
+// unlock Release the lock taken by the store if any, whatever the state of its request
+func (s *store) unlock() {
+	s.Lock()
+	l := s.held
+	s.held = nil
+	if s.released != nil {
+		close(s.released)
+		s.released = nil
+	}
+	s.Unlock()
+	if l == nil {
+		return
+	}
+
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, DefaultDetachTimeout)
+	defer cancel()
+	if err := l.unlock(ctx); err != nil {
+		s.mstore.log(LevelWarn, "session lock release failed", "sid_hash", s.mstore.sidHash(s.sid), "error", err)
+	}
+}
@@ -0,0 +1,33 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSessionLockOptions(t *testing.T) {
+	Convey("Test the session locks", t, func() {
+		Convey("disabled by default", func() {
+			mstore := newOfflineStore(t)
+			So(mstore.opts.lockCollection(mstore.c), ShouldBeNil)
+			held, err := mstore.lock(context.Background(), "test_lock")
+			So(err, ShouldBeNil)
+			So(held, ShouldBeNil)
+		})
+
+		Convey("documents of the lock collection", func() {
+			mstore := newOfflineStore(t, WithSessionLock(time.Minute, time.Second), WithOperationTimeout(50*time.Millisecond))
+			mstore.locks = mstore.opts.lockCollection(mstore.c)
+			So(mstore.locks.Name(), ShouldEqual, cName+"_locks")
+			So(mstore.Namespace("ns").(*managerStore).locks, ShouldEqual, mstore.locks)
+
+			_, err := mstore.Create(context.Background(), "test_lock", 10)
+			So(err, ShouldNotBeNil)
+			So(errors.Is(err, ErrLockTimeout), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package mongo
+
+import (
+	"sync"
+)
+
+// WithoutStoreLocking Set whether to skip the locking of the session stores (false by default),
+// only for frameworks guaranteeing that a session store is never used by more than one
+// goroutine at a time: concurrent use of a store then corrupts its values or crashes
+func WithoutStoreLocking(disabled bool) Option {
+	return func(o *options) {
+		o.noLock = disabled
+	}
+}
+
+// rwLocker The lock of a session store
+type rwLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+// noLock A rwLocker doing nothing
+type noLock struct{}
+
+func (noLock) Lock()    {}
+func (noLock) Unlock()  {}
+func (noLock) RLock()   {}
+func (noLock) RUnlock() {}
+
+// newLocker The lock of the stores created by s
+func (s *managerStore) newLocker() rwLocker {
+	if s.opts.noLock {
+		return noLock{}
+	}
+	return &sync.RWMutex{}
+}
@@ -0,0 +1,27 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStoreLocking(t *testing.T) {
+	Convey("Test the lock of the session stores", t, func() {
+		mstore := &managerStore{opts: newOptions()}
+		store := newStore(context.Background(), mstore, "test_lock", 10, nil)
+		_, ok := store.rwLocker.(noLock)
+		So(ok, ShouldBeFalse)
+
+		mstore = &managerStore{opts: newOptions(WithoutStoreLocking(true))}
+		store = newStore(context.Background(), mstore, "test_lock", 10, nil)
+		_, ok = store.rwLocker.(noLock)
+		So(ok, ShouldBeTrue)
+
+		store.Set("foo", "bar")
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+	})
+}
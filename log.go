@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"strconv"
+	"time"
+)
+
+// LogLevel The severity of a log entry, with the values of the levels of log/slog
+type LogLevel int
+
+// Log levels
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// LogFunc Receiver of the log entries of the store, kv being alternate keys and values
+// (e.g. adapted to a slog.Logger with logger.Log(ctx, slog.Level(level), msg, kv...))
+type LogFunc func(level LogLevel, msg string, kv ...interface{})
+
+// WithLogger Report the failed operations, the conflict retries, the transaction fallback,
+// the cleanup failures and the outcome of the index creation to fn
+func WithLogger(fn LogFunc) Option {
+	return func(o *options) {
+		o.logger = fn
+	}
+}
+
+// WithSlowThreshold Log a warning for the store operations taking longer than threshold
+// (requires WithLogger), 0 disables it
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = threshold
+	}
+}
+
+// log Report an entry to the logger of the store, if any
+func (s *managerStore) log(level LogLevel, msg string, kv ...interface{}) {
+	if s.opts.logger != nil {
+		if s.opts.env != "" {
+			kv = append(kv, "env", s.opts.env)
+		}
+		s.opts.logger(level, msg, kv...)
+	}
+}
+
+// logOperation Report a slow or failed call of op
+func (s *managerStore) logOperation(op string, took time.Duration, err error) {
+	if s.opts.logger == nil {
+		return
+	}
+	if slow := s.opts.slowOperation(); slow > 0 && took > slow {
+		s.log(LevelWarn, "slow session operation", "op", op, "took", took, "collection", s.collectionName())
+	}
+	switch ErrorLabel(err) {
+	case ErrorLabelNone, ErrorLabelNotFound, ErrorLabelConflict:
+	default:
+		s.log(LevelError, "session operation failed", "op", op, "collection", s.collectionName(), "error", err)
+	}
+}
+
+// collectionName The name of the collection of s, for the log entries
+func (s *managerStore) collectionName() string {
+	if s.c == nil {
+		return ""
+	}
+	return s.c.Name()
+}
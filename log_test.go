@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogger(t *testing.T) {
+	type entry struct {
+		level LogLevel
+		msg   string
+	}
+	var entries []entry
+	logger := func(level LogLevel, msg string, kv ...interface{}) {
+		So(len(kv)%2, ShouldEqual, 0)
+		entries = append(entries, entry{level, msg})
+	}
+	mstore := newOfflineStore(t, WithLogger(logger), WithSlowThreshold(time.Nanosecond), WithOperationTimeout(50*time.Millisecond))
+
+	Convey("Test the log entries of the store", t, func() {
+		entries = nil
+		_, err := mstore.Check(context.Background(), "test_logger")
+		So(err, ShouldNotBeNil)
+		So(entries, ShouldResemble, []entry{
+			{LevelWarn, "slow session operation"},
+			{LevelError, "session operation failed"},
+		})
+
+		Convey("not found sessions are not failures", func() {
+			entries = nil
+			mstore.logOperation(OpDelete, 0, ErrNotFound)
+			So(entries, ShouldBeEmpty)
+		})
+
+		Convey("levels named like slog", func() {
+			So(LevelWarn.String(), ShouldEqual, "WARN")
+			So(LogLevel(2).String(), ShouldEqual, "LEVEL(2)")
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// LoginEvent A session newly bound to a user who has other active sessions
+type LoginEvent struct {
+	// Field The indexed field binding the sessions to their user, User its value
+	Field string
+	User  interface{}
+	// SIDHash A hash of the id of the new session
+	SIDHash string
+	// Others The other active sessions of the user
+	Others []SessionInfo
+}
+
+// WithLoginHook Call hook when a session gets bound to a user (the indexed field, e.g. "uid",
+// set with SetIndexed at the login) who already has other active sessions, e.g. to notify
+// of a new sign-in; hook is called once the session is saved, with the context of the session
+// (giving the request started by the session manager, for its user agent...)
+func WithLoginHook(field string, hook func(ctx context.Context, event LoginEvent)) Option {
+	return func(o *options) {
+		o.loginField = field
+		o.loginHook = hook
+	}
+}
+
+// boundUser The user the store is bound to and whether the binding changed since the last save
+// with the indexed fields modified if indexDirty, s must be locked
+func (s *store) boundUser(indexDirty bool) (interface{}, bool) {
+	field := s.mstore.opts.loginField
+	if s.mstore.opts.loginHook == nil || field == "" || !indexDirty {
+		return nil, false
+	}
+	user, ok := s.indexed[field]
+	return user, ok && user != nil && !sameUser(user, s.bound)
+}
+
+// sameUser Tell whether the user values are the same, whatever the type of their numbers
+// once loaded from the documents (int, int32...)
+func sameUser(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b) || (b != nil && fmt.Sprint(a) == fmt.Sprint(b))
+}
+
+// notifyLogin Call the login hook if the user newly bound to the saved store has other sessions
+func (s *store) notifyLogin(ctx context.Context, user interface{}) {
+	s.Lock()
+	s.bound = user
+	s.Unlock()
+
+	m := s.mstore
+	field := m.opts.loginField
+	infos, err := m.FindByIndex(ctx, field, user)
+	if err != nil {
+		m.log(LevelWarn, "session login hook skipped", "sid_hash", m.sidHash(s.sid), "error", err)
+		return
+	}
+	own := m.info(sessionInfoDoc{ID: m.docID(s.sid)}).SID
+	others := make([]SessionInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.SID != own {
+			others = append(others, info)
+		}
+	}
+	if len(others) == 0 {
+		return
+	}
+
+	hookCtx := s.ctx
+	if hookCtx == nil {
+		hookCtx = context.Background()
+	}
+	m.opts.loginHook(hookCtx, LoginEvent{Field: field, User: user, SIDHash: m.sidHash(s.sid), Others: others})
+}
@@ -0,0 +1,45 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestLoginHook(t *testing.T) {
+	Convey("Test the detection of the sessions newly bound to a user", t, func() {
+		hook := func(ctx context.Context, event LoginEvent) {}
+		mstore := newOfflineStore(t, WithIndexedFields("uid"), WithLoginHook("uid", hook))
+
+		store := newStore(context.Background(), mstore, "sid", 10, nil)
+		_, login := store.boundUser(false)
+		So(login, ShouldBeFalse)
+		store.SetIndexed("uid", 42)
+		user, login := store.boundUser(true)
+		So(login, ShouldBeTrue)
+		So(user, ShouldEqual, 42)
+
+		Convey("unless already bound", func() {
+			value, err := mstore.encodeValues(map[string]interface{}{})
+			So(err, ShouldBeNil)
+			loaded, err := newLoadedStore(mstore, &sessionItem{Value: value, Indexed: bson.M{"uid": int32(42)}}, newStore(context.Background(), mstore, "sid", 10, nil))
+			So(err, ShouldBeNil)
+			So(loaded.bound, ShouldEqual, int32(42))
+			loaded.SetIndexed("uid", 42)
+			_, login := loaded.boundUser(true)
+			So(login, ShouldBeFalse)
+			loaded.SetIndexed("uid", 43)
+			_, login = loaded.boundUser(true)
+			So(login, ShouldBeTrue)
+		})
+
+		Convey("without hook", func() {
+			store := newStore(context.Background(), newOfflineStore(t, WithIndexedFields("uid")), "sid", 10, nil)
+			store.SetIndexed("uid", 42)
+			_, login := store.boundUser(true)
+			So(login, ShouldBeFalse)
+		})
+	})
+}
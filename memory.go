@@ -0,0 +1,251 @@
+package mongo
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// memoryDoc The session document of the memory store
+type memoryDoc struct {
+	value     bson.RawValue
+	createdAt time.Time
+	updatedAt time.Time
+	expiredAt time.Time
+	version   int64
+}
+
+// memoryStore The manager store of NewMemoryStore
+type memoryStore struct {
+	sync.Mutex
+	// m The store computing the expirations and encoding the values with the options
+	m    *managerStore
+	docs map[string]*memoryDoc
+}
+
+// NewMemoryStore Create a manager store keeping the sessions in memory, for the tests without
+// a server: the values are encoded as in the documents of the mongo store (a number saved with
+// JSONCodec is loaded as a float64) and expire like them according to the clock (WithClock),
+// expiration, refresh grace, expiry skew, idempotent delete and cleanup options; the options
+// of the server (indexes, caches, hooks, tenants...) are ignored, and the expired sessions are
+// removed by the next Create
+func NewMemoryStore(opts ...Option) session.ManagerStore {
+	return &memoryStore{m: &managerStore{opts: newOptions(opts...)}, docs: make(map[string]*memoryDoc)}
+}
+
+// get The unexpired document of sid or expired for less than grace, nil if none,
+// the store must be locked
+func (s *memoryStore) get(sid string, grace time.Duration) *memoryDoc {
+	doc, ok := s.docs[s.m.docID(sid)]
+	if !ok || doc.expiredAt.Before(s.m.notBefore(grace)) {
+		return nil
+	}
+	return doc
+}
+
+// purge Remove the expired documents not refreshable anymore, the store must be locked
+func (s *memoryStore) purge(ctx context.Context) {
+	notBefore := s.m.notBefore(s.m.opts.refreshGrace)
+	for id, doc := range s.docs {
+		if doc.expiredAt.Before(notBefore) {
+			delete(s.docs, id)
+			s.m.cascade(ctx, doc.item(id), EventExpire)
+		}
+	}
+}
+
+// item The session document of doc stored under id
+func (doc *memoryDoc) item(id string) *sessionItem {
+	return &sessionItem{ID: id, Value: doc.value, ExpiredAt: doc.expiredAt, CreatedAt: doc.createdAt, UpdatedAt: doc.updatedAt, Version: doc.version}
+}
+
+// open The session store of sid with the values of doc, empty if nil
+func (s *memoryStore) open(ctx context.Context, sid string, expired int64, doc *memoryDoc) (*memorySession, error) {
+	st := &memorySession{ctx: ctx, s: s, sid: sid, expired: expired, createdAt: s.m.now(), values: s.m.newValues()}
+	if doc == nil {
+		return st, nil
+	}
+	values, err := s.m.decodeValues(&sessionItem{Value: doc.value})
+	if err != nil {
+		return nil, err
+	}
+	st.values = values
+	st.createdAt = doc.createdAt
+	st.version = doc.version
+	return st, nil
+}
+
+func (s *memoryStore) Check(_ context.Context, sid string) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.get(sid, 0) != nil, nil
+}
+
+func (s *memoryStore) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	s.Lock()
+	s.purge(ctx)
+	s.Unlock()
+	return s.open(ctx, sid, expired, nil)
+}
+
+func (s *memoryStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	s.Lock()
+	defer s.Unlock()
+	doc := s.get(sid, 0)
+	if doc != nil {
+		doc.expiredAt = s.m.expiration(expired, doc.createdAt)
+	}
+	return s.open(ctx, sid, expired, doc)
+}
+
+func (s *memoryStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
+	s.Lock()
+	defer s.Unlock()
+	doc := s.get(oldsid, s.m.opts.refreshGrace)
+	if doc == nil {
+		return s.open(ctx, sid, expired, nil)
+	}
+	delete(s.docs, s.m.docID(oldsid))
+	moved := *doc
+	moved.expiredAt = s.m.expiration(expired, doc.createdAt)
+	moved.updatedAt = s.m.now()
+	s.docs[s.m.docID(sid)] = &moved
+	return s.open(ctx, sid, expired, &moved)
+}
+
+func (s *memoryStore) Delete(ctx context.Context, sid string) error {
+	s.Lock()
+	defer s.Unlock()
+	id := s.m.docID(sid)
+	doc, ok := s.docs[id]
+	if !ok {
+		if s.m.opts.idempotentDelete {
+			return nil
+		}
+		return storeError(OpDelete, s.m.sidHash(sid), mongo.ErrNoDocuments)
+	}
+	delete(s.docs, id)
+	s.m.cascade(ctx, doc.item(id), EventDelete)
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	s.m.opts.hookCalls.Wait()
+	return nil
+}
+
+// memorySession The session store of NewMemoryStore
+type memorySession struct {
+	sync.RWMutex
+	ctx       context.Context
+	s         *memoryStore
+	sid       string
+	expired   int64
+	createdAt time.Time
+	version   int64
+	values    map[string]interface{}
+}
+
+func (s *memorySession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *memorySession) SessionID() string {
+	return s.sid
+}
+
+func (s *memorySession) Set(key string, value interface{}) {
+	s.Lock()
+	s.values[key] = value
+	s.Unlock()
+}
+
+func (s *memorySession) Get(key string) (interface{}, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *memorySession) Delete(key string) interface{} {
+	s.Lock()
+	defer s.Unlock()
+	v := s.values[key]
+	delete(s.values, key)
+	return v
+}
+
+func (s *memorySession) Save() error {
+	s.Lock()
+	defer s.Unlock()
+	value, err := s.s.m.encodeValues(s.values)
+	if err != nil {
+		return err
+	}
+	s.s.Lock()
+	defer s.s.Unlock()
+	m := s.s.m
+	s.version++
+	s.s.docs[m.docID(s.sid)] = &memoryDoc{
+		value:     value,
+		createdAt: s.createdAt,
+		updatedAt: m.now(),
+		expiredAt: m.expiration(s.expired, s.createdAt),
+		version:   s.version,
+	}
+	return nil
+}
+
+func (s *memorySession) Flush() error {
+	s.Lock()
+	s.values = s.s.m.newValues()
+	s.Unlock()
+	return s.Save()
+}
+
+func (s *memorySession) Keys() []string {
+	s.RLock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	s.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *memorySession) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.values)
+}
+
+func (s *memorySession) Snapshot() map[string]interface{} {
+	s.RLock()
+	defer s.RUnlock()
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = deepCopy(v)
+	}
+	return values
+}
+
+func (s *memorySession) SetMulti(values map[string]interface{}) {
+	s.Lock()
+	for k, v := range values {
+		s.values[k] = v
+	}
+	s.Unlock()
+}
+
+func (s *memorySession) Revision() string {
+	s.RLock()
+	defer s.RUnlock()
+	return strconv.FormatInt(s.version, 10)
+}
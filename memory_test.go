@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	ctx := context.Background()
+
+	Convey("Test the sessions kept in memory", t, func() {
+		mstore := NewMemoryStore(clock)
+		st, err := mstore.Create(ctx, "test_memory", 10)
+		So(err, ShouldBeNil)
+		st.Set("n", 1)
+		st.Set("user", "u1")
+		ok, err := mstore.Check(ctx, "test_memory")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+		So(st.Save(), ShouldBeNil)
+
+		st, err = mstore.Update(ctx, "test_memory", 10)
+		So(err, ShouldBeNil)
+		n, _ := st.Get("n")
+		So(n, ShouldEqual, 1.0)
+		So(st.(Revisioner).Revision(), ShouldEqual, "1")
+
+		Convey("expired with the clock", func() {
+			now = now.Add(9 * time.Second)
+			ok, _ := mstore.Check(ctx, "test_memory")
+			So(ok, ShouldBeTrue)
+			_, err := mstore.Update(ctx, "test_memory", 10)
+			So(err, ShouldBeNil)
+			now = now.Add(9 * time.Second)
+			ok, _ = mstore.Check(ctx, "test_memory")
+			So(ok, ShouldBeTrue)
+			now = now.Add(2 * time.Second)
+			ok, _ = mstore.Check(ctx, "test_memory")
+			So(ok, ShouldBeFalse)
+			st, err := mstore.Update(ctx, "test_memory", 10)
+			So(err, ShouldBeNil)
+			_, found := st.Get("user")
+			So(found, ShouldBeFalse)
+		})
+
+		Convey("refreshed to a new id", func() {
+			st, err := mstore.Refresh(ctx, "test_memory", "test_memory_next", 10)
+			So(err, ShouldBeNil)
+			user, _ := st.Get("user")
+			So(user, ShouldEqual, "u1")
+			ok, _ := mstore.Check(ctx, "test_memory")
+			So(ok, ShouldBeFalse)
+			ok, _ = mstore.Check(ctx, "test_memory_next")
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("deleted", func() {
+			So(mstore.Delete(ctx, "test_memory"), ShouldBeNil)
+			err := mstore.Delete(ctx, "test_memory")
+			So(errors.Is(err, ErrSessionNotFound), ShouldBeTrue)
+			So(NewMemoryStore(WithIdempotentDelete(true)).Delete(ctx, "test_memory"), ShouldBeNil)
+		})
+	})
+
+	Convey("Test the expiration options of the sessions kept in memory", t, func() {
+		mstore := NewMemoryStore(clock, WithExpiration(AbsoluteExpiration, 15*time.Second), WithRefreshGrace(time.Minute))
+		st, err := mstore.Create(ctx, "test_memory", 10)
+		So(err, ShouldBeNil)
+		st.Set("user", "u1")
+		So(st.Save(), ShouldBeNil)
+
+		now = now.Add(10 * time.Second)
+		_, err = mstore.Update(ctx, "test_memory", 10)
+		So(err, ShouldBeNil)
+		now = now.Add(10 * time.Second)
+		ok, _ := mstore.Check(ctx, "test_memory")
+		So(ok, ShouldBeFalse)
+
+		st, err = mstore.Refresh(ctx, "test_memory", "test_memory_next", 10)
+		So(err, ShouldBeNil)
+		user, _ := st.Get("user")
+		So(user, ShouldEqual, "u1")
+	})
+}
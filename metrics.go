@@ -0,0 +1,109 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Metrics Receiver of the measures of the store operations, e.g. to feed Prometheus collectors
+type Metrics interface {
+	// ObserveOperation Record a call of op (OpCheck, OpCreate, OpUpdate, OpRefresh, OpDelete,
+	// OpTouch, OpPromote, OpLoad or OpSave) lasting took, with the size of the loaded or saved
+	// value (0 if none) and the returned error, nil on success
+	ObserveOperation(op string, took time.Duration, size int, err error)
+}
+
+// MetricsFunc Function implementing Metrics
+type MetricsFunc func(op string, took time.Duration, size int, err error)
+
+// ObserveOperation Call f
+func (f MetricsFunc) ObserveOperation(op string, took time.Duration, size int, err error) {
+	f(op, took, size, err)
+}
+
+// WithMetrics Report the count, duration, value size and error of the store operations to m
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// Error labels of ErrorLabel
+const (
+	ErrorLabelNone      = ""
+	ErrorLabelNotFound  = "not_found"
+	ErrorLabelConflict  = "conflict"
+	ErrorLabelTimeout   = "timeout"
+	ErrorLabelNetwork   = "network"
+	ErrorLabelDuplicate = "duplicate_key"
+	ErrorLabelServer    = "server"
+	ErrorLabelOther     = "other"
+)
+
+// ErrorLabel Classify err for the metrics, telling the MongoDB errors apart
+// (ErrorLabelNetwork, ErrorLabelServer...) from the others
+func ErrorLabel(err error) string {
+	var se mongo.ServerError
+	switch {
+	case err == nil:
+		return ErrorLabelNone
+	case errors.Is(err, ErrSessionNotFound) || errors.Is(err, mongo.ErrNoDocuments):
+		return ErrorLabelNotFound
+	case errors.Is(err, ErrConflict):
+		return ErrorLabelConflict
+	case mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded):
+		return ErrorLabelTimeout
+	case mongo.IsNetworkError(err):
+		return ErrorLabelNetwork
+	case mongo.IsDuplicateKeyError(err):
+		return ErrorLabelDuplicate
+	case errors.As(err, &se):
+		return ErrorLabelServer
+	default:
+		return ErrorLabelOther
+	}
+}
+
+// operation A call of a store operation, measured and traced
+type operation struct {
+	s     *managerStore
+	op    string
+	sid   string
+	start time.Time
+	span  trace.Span
+}
+
+// begin Start measuring and tracing a call of op on sid, returning the context of its
+// database calls
+func (s *managerStore) begin(ctx context.Context, op, sid string) (context.Context, operation) {
+	o := operation{s: s, op: op, sid: sid, start: time.Now()}
+	ctx, o.span = s.startSpan(ctx, op, sid)
+	return ctx, o
+}
+
+// end Report the call that returned err with a value of size, returning err as a *StoreError
+func (o operation) end(size int, err error) error {
+	took := time.Since(o.start)
+	o.s.opts.observe(o.op, took, size, err)
+	o.s.logOperation(o.op, took, err)
+	if o.span != nil {
+		endSpan(o.span, err)
+	}
+	var hash string
+	if o.sid != "" {
+		hash = o.s.sidHash(o.sid)
+	}
+	return storeError(o.op, hash, err)
+}
+
+// valueSize The size of the value loaded by a store
+func valueSize(st interface{}) int {
+	if st, ok := st.(*store); ok && st != nil {
+		return st.size
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestMetrics(t *testing.T) {
+	type observation struct {
+		op   string
+		size int
+		err  error
+	}
+	var observed []observation
+	metrics := MetricsFunc(func(op string, took time.Duration, size int, err error) {
+		observed = append(observed, observation{op, size, err})
+	})
+	mstore := newOfflineStore(t, WithMetrics(metrics), WithOperationTimeout(50*time.Millisecond))
+
+	Convey("Test the measures of the store operations", t, func() {
+		observed = nil
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, "test_metrics", 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldNotBeNil)
+		_, err = mstore.Check(ctx, "test_metrics")
+		So(err, ShouldNotBeNil)
+
+		So(observed, ShouldHaveLength, 3)
+		So(observed[0], ShouldResemble, observation{OpCreate, 0, nil})
+		So(observed[1].op, ShouldEqual, OpSave)
+		So(observed[1].size, ShouldBeGreaterThan, 0)
+		So(observed[1].err, ShouldNotBeNil)
+		So(observed[2].op, ShouldEqual, OpCheck)
+		So(ErrorLabel(observed[2].err), ShouldNotEqual, ErrorLabelNone)
+
+		Convey("loaded value sizes", func() {
+			value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			loaded, err := newLoadedStore(mstore, &sessionItem{Value: value}, newStore(ctx, mstore, "test_metrics", 10, nil))
+			So(err, ShouldBeNil)
+			So(valueSize(loaded), ShouldEqual, len(value.Value))
+			So(valueSize(nil), ShouldEqual, 0)
+		})
+	})
+
+	Convey("Test the error labels", t, func() {
+		So(ErrorLabel(nil), ShouldEqual, ErrorLabelNone)
+		So(ErrorLabel(mongo.ErrNoDocuments), ShouldEqual, ErrorLabelNotFound)
+		So(ErrorLabel(ErrConflict), ShouldEqual, ErrorLabelConflict)
+		So(ErrorLabel(context.DeadlineExceeded), ShouldEqual, ErrorLabelTimeout)
+		So(ErrorLabel(mongo.CommandError{Code: 11000}), ShouldEqual, ErrorLabelDuplicate)
+		So(ErrorLabel(mongo.CommandError{Code: 2}), ShouldEqual, ErrorLabelServer)
+		So(ErrorLabel(ErrInjectedFault), ShouldEqual, ErrorLabelOther)
+	})
+}
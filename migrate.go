@@ -0,0 +1,290 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// WithDualWrite Keep writing the session values as the legacy JSON string of the value field
+// until the deadline, the values in the configured format (codec, compression, encryption or
+// subdocument) going to the value_next field read first, so that the versions of the
+// application predating the format can still read the sessions if rolled back to during the
+// migration, the documents are written in the configured format only after the deadline
+func WithDualWrite(until time.Time) Option {
+	return func(o *options) {
+		o.dualWriteUntil = until
+	}
+}
+
+// dualWrite Whether the values are written in both formats
+func (s *managerStore) dualWrite() bool {
+	return !s.opts.dualWriteUntil.IsZero() && s.now().Before(s.opts.dualWriteUntil)
+}
+
+// legacyValue Encode the session values as the legacy JSON string
+func legacyValue(values map[string]interface{}) (bson.RawValue, error) {
+	if len(values) == 0 {
+		return rawValue("")
+	}
+	return marshalJSON(values, func(data []byte) (bson.RawValue, error) {
+		return stringValue(data), nil
+	})
+}
+
+// currentValue The value of the document in the newest format
+func (item *sessionItem) currentValue() bson.RawValue {
+	if !item.ValueNext.IsZero() {
+		return item.ValueNext
+	}
+	return item.Value
+}
+
+// MigrateCheckpoint The position of a migration, to resume it
+type MigrateCheckpoint struct {
+	// Collection The collection being migrated, LastID the id of its last migrated document
+	Collection string
+	LastID     string
+}
+
+// MigrateProgress The progress of a migration
+type MigrateProgress struct {
+	Checkpoint MigrateCheckpoint
+	// Scanned The number of active sessions read, Migrated those rewritten and Failed those
+	// whose value couldn't be decoded, which are logged and left as they are
+	Scanned  int64
+	Migrated int64
+	Failed   int64
+}
+
+// MigrateOptions The options of a migration
+type MigrateOptions struct {
+	// Batch The number of documents written per batch (DefaultBackfillBatch if not positive)
+	Batch int
+	// Force Rewrite the documents already in the configured format, e.g. to re-encrypt
+	// the values with the current key of a rotated cipher
+	Force bool
+	// Resume The checkpoint to resume a migration from, the start if zero
+	Resume MigrateCheckpoint
+	// Progress Called after each batch with the progress so far
+	Progress func(MigrateProgress)
+}
+
+// Migrator Implemented by the stores, to rewrite the values of the existing sessions in the
+// configured format (codec, compression, encryption or subdocument, both formats during
+// WithDualWrite) instead of waiting for the sessions to be saved again
+type Migrator interface {
+	// Migrate Re-encode the values of the active sessions in another format, in the order of
+	// their ids and batch documents at a time, while the store keeps serving them: the sessions
+	// modified meanwhile are skipped, having been written in the configured format, and the
+	// versions of the sessions are kept. The progress reports the checkpoints to resume from
+	Migrate(ctx context.Context, opts MigrateOptions) (MigrateProgress, error)
+}
+
+func (s *managerStore) Migrate(ctx context.Context, opts MigrateOptions) (MigrateProgress, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = DefaultBackfillBatch
+	}
+	var p MigrateProgress
+	if err := s.ready(ctx); err != nil {
+		return p, err
+	}
+
+	stores := []*managerStore{s}
+	if s.anon != nil {
+		stores = append(stores, s.anon)
+	}
+	if opts.Resume.Collection != "" {
+		for i, m := range stores {
+			if m.collectionName() == opts.Resume.Collection {
+				stores = stores[i:]
+				break
+			}
+		}
+	}
+	var err error
+	for _, m := range stores {
+		after := ""
+		if m.collectionName() == opts.Resume.Collection {
+			after = opts.Resume.LastID
+		}
+		if err = m.migrate(ctx, opts, after, &p); err != nil {
+			break
+		}
+	}
+	s.uncacheAll()
+	return p, err
+}
+
+// sameFormat Tell whether the values are encoded in the same format
+func sameFormat(a, b bson.RawValue) bool {
+	if a.Type != b.Type {
+		return false
+	} else if a.Type != bson.TypeBinary {
+		return true
+	}
+	sa, _ := a.Binary()
+	sb, _ := b.Binary()
+	return sa == sb
+}
+
+// migrateValues The value and next value of item in the configured format, false if item is
+// already written this way and force is not set
+func (s *managerStore) migrateValues(item *sessionItem, force bool) (value, next bson.RawValue, ok bool, err error) {
+	values, err := s.decodeValues(item)
+	if err != nil {
+		return value, next, false, err
+	}
+	if value, err = s.encodeValues(values); err != nil {
+		return value, next, false, err
+	}
+	if s.dualWrite() && value.Type != bson.TypeString {
+		next = value
+		if values, err = s.opts.encodeTypes(values); err != nil {
+			return value, next, false, err
+		}
+		if value, err = legacyValue(values); err != nil {
+			return value, next, false, err
+		}
+	}
+	ok = force || !sameFormat(value, item.Value) || item.ValueNext.IsZero() != next.IsZero() ||
+		(!next.IsZero() && !sameFormat(next, item.ValueNext))
+	return value, next, ok, nil
+}
+
+// migrateQuery The query of the document of item as read by the migration
+func migrateQuery(item *sessionItem) bson.M {
+	q := bson.M{"_id": item.ID, "version": item.Version}
+	if item.Version == 0 {
+		q["version"] = bson.M{"$exists": false}
+	}
+	return q
+}
+
+// migrate Migrate the documents of the collection of s after the id after
+func (s *managerStore) migrate(ctx context.Context, opts MigrateOptions, after string, p *MigrateProgress) error {
+	q := s.activeScope()
+	if after != "" {
+		q["_id"] = bson.M{"$gt": after}
+	}
+	cur, err := s.cPrimary.Find(ctx, q, mopts.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(int32(opts.Batch)))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	p.Checkpoint = MigrateCheckpoint{Collection: s.collectionName(), LastID: after}
+	var last string
+	pending := 0
+	models := make([]mongo.WriteModel, 0, opts.Batch)
+	flush := func() error {
+		if len(models) > 0 {
+			res, err := s.c.BulkWrite(ctx, models, mopts.BulkWrite().SetOrdered(false))
+			if res != nil {
+				p.Migrated += res.ModifiedCount
+			}
+			models = models[:0]
+			if err != nil {
+				return err
+			}
+		}
+		pending = 0
+		if last != "" {
+			p.Checkpoint.LastID = last
+		}
+		if opts.Progress != nil {
+			opts.Progress(*p)
+		}
+		return nil
+	}
+
+	for cur.Next(ctx) {
+		var item sessionItem
+		if err := cur.Decode(&item); err != nil {
+			return err
+		}
+		p.Scanned++
+		last = item.ID
+		pending++
+		if err := s.migrateItem(ctx, &item, opts.Force, &models, p); err != nil {
+			return err
+		}
+		if pending >= opts.Batch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// migrateItem Queue the migration of item to models, or migrate it on its own if its value
+// is stored apart, the chunks being written before the document
+func (s *managerStore) migrateItem(ctx context.Context, item *sessionItem, force bool, models *[]mongo.WriteModel, p *MigrateProgress) error {
+	spilled := item.Spill != nil
+	if err := s.unspill(ctx, item); err != nil {
+		return err
+	}
+	value, next, ok, err := s.migrateValues(item, force)
+	if err != nil {
+		if !errors.Is(err, ErrDecode) {
+			return err
+		}
+		p.Failed++
+		s.log(LevelWarn, "session migration skipped", "collection", s.collectionName(), "sid_hash", s.sidHash(item.ID), "error", err)
+		return nil
+	} else if !ok {
+		return nil
+	}
+
+	set := bson.M{"value": value, "size": len(value.Value)}
+	unset := bson.M{}
+	if next.IsZero() {
+		unset["value_next"] = ""
+	} else {
+		set["value_next"] = next
+	}
+	stored, err := s.spill(ctx, &sessionItem{ID: item.ID, Value: value, ExpiredAt: item.ExpiredAt})
+	if err != nil {
+		return err
+	}
+	if stored.Spill != nil {
+		set["value"] = stored.Value
+		set["spill"] = stored.Spill
+	} else if spilled {
+		unset["spill"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if !spilled && stored.Spill == nil {
+		*models = append(*models, mongo.NewUpdateOneModel().SetFilter(migrateQuery(item)).SetUpdate(update))
+		return nil
+	}
+
+	res, err := s.c.UpdateOne(ctx, migrateQuery(item), update)
+	if err != nil || res.ModifiedCount == 0 {
+		if stored.Spill != nil {
+			s.discardChunks(ctx, item.ID, stored.Spill.Gen)
+		}
+		return err
+	}
+	p.Migrated++
+	var keep string
+	if stored.Spill != nil {
+		keep = stored.Spill.Gen
+	}
+	s.cleanChunks(ctx, item.ID, keep)
+	return nil
+}
@@ -0,0 +1,106 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestDualWrite(t *testing.T) {
+	Convey("Test the values written in both formats during a migration", t, func() {
+		mstore := &managerStore{opts: newOptions(WithCodec(GobCodec{}), WithDualWrite(time.Now().Add(time.Hour)))}
+		So(mstore.dualWrite(), ShouldBeTrue)
+
+		values := map[string]interface{}{"foo": "bar"}
+		legacy, err := legacyValue(values)
+		So(err, ShouldBeNil)
+		So(legacy.StringValue(), ShouldEqual, `{"foo":"bar"}`)
+		next, err := mstore.encodeValues(values)
+		So(err, ShouldBeNil)
+		So(next.Type, ShouldEqual, bson.TypeBinary)
+
+		Convey("read from the new format first", func() {
+			item := &sessionItem{Value: legacy, ValueNext: next}
+			raw, err := bson.Marshal(item)
+			So(err, ShouldBeNil)
+			So(bson.Raw(raw).Lookup("value").Type, ShouldEqual, bson.TypeString)
+
+			loaded, err := mstore.decodeValues(item)
+			So(err, ShouldBeNil)
+			So(loaded, ShouldResemble, values)
+
+			legacyStore := &managerStore{opts: newOptions()}
+			loaded, err = legacyStore.decodeValues(&sessionItem{Value: legacy})
+			So(err, ShouldBeNil)
+			So(loaded, ShouldResemble, values)
+		})
+
+		Convey("no partial updates of dual documents", func() {
+			mstore := &managerStore{opts: newOptions(WithDocumentValues(true), WithDualWrite(time.Now().Add(time.Hour)))}
+			doc, err := mstore.encodeValues(values)
+			So(err, ShouldBeNil)
+			st, err := newLoadedStore(mstore, &sessionItem{Value: legacy, ValueNext: doc}, newStore(context.Background(), mstore, "test_dual", 10, nil))
+			So(err, ShouldBeNil)
+			So(st.partial, ShouldBeFalse)
+			foo, _ := st.Get("foo")
+			So(foo, ShouldEqual, "bar")
+		})
+
+		Convey("after the deadline", func() {
+			mstore := &managerStore{opts: newOptions(WithDualWrite(time.Now().Add(-time.Hour)))}
+			So(mstore.dualWrite(), ShouldBeFalse)
+			So((&managerStore{opts: newOptions()}).dualWrite(), ShouldBeFalse)
+		})
+	})
+}
+
+func TestMigrateValues(t *testing.T) {
+	Convey("Test the re-encoding of the values by a migration", t, func() {
+		values := map[string]interface{}{"foo": "bar"}
+		legacy, err := legacyValue(values)
+		So(err, ShouldBeNil)
+		mstore := &managerStore{opts: newOptions(WithCodec(GobCodec{}))}
+
+		value, next, ok, err := mstore.migrateValues(&sessionItem{Value: legacy}, false)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		So(value.Type, ShouldEqual, bson.TypeBinary)
+		So(next.IsZero(), ShouldBeTrue)
+		loaded, err := mstore.decodeValues(&sessionItem{Value: value})
+		So(err, ShouldBeNil)
+		So(loaded, ShouldResemble, values)
+
+		_, _, ok, err = mstore.migrateValues(&sessionItem{Value: value}, false)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+		_, _, ok, err = mstore.migrateValues(&sessionItem{Value: value}, true)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		Convey("to both formats", func() {
+			dual := &managerStore{opts: newOptions(WithCodec(GobCodec{}), WithDualWrite(time.Now().Add(time.Hour)))}
+			value, next, ok, err := dual.migrateValues(&sessionItem{Value: legacy}, false)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(value.Type, ShouldEqual, bson.TypeString)
+			So(next.Type, ShouldEqual, bson.TypeBinary)
+
+			_, _, ok, err = dual.migrateValues(&sessionItem{Value: value, ValueNext: next}, false)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+			_, _, ok, err = mstore.migrateValues(&sessionItem{Value: value, ValueNext: next}, false)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("undecodable values", func() {
+			_, _, ok, err := mstore.migrateValues(&sessionItem{Value: bson.RawValue{Type: bson.TypeInt32, Value: []byte{1, 0, 0, 0}}}, false)
+			So(errors.Is(err, ErrDecode), ShouldBeTrue)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
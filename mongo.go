@@ -2,194 +2,785 @@ package mongo
 
 import (
 	"context"
-	"sync"
+	"errors"
+	"strings"
 	"time"
 
-	"github.com/globalsign/mgo"
-	"github.com/globalsign/mgo/bson"
 	session "github.com/go-session/session/v3"
 	jsoniter "github.com/json-iterator/go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+
+	"github.com/go-session/mongo/v3/decorator"
 )
 
 var (
-	_             session.ManagerStore = &managerStore{}
-	_             session.Store        = &store{}
-	jsonMarshal                        = jsoniter.Marshal
-	jsonUnmarshal                      = jsoniter.Unmarshal
+	_                   session.ManagerStore = &managerStore{}
+	_                   session.Store        = &store{}
+	_                   NamespaceStore       = &managerStore{}
+	_                   DebugTracer          = &store{}
+	_                   Promoter             = &managerStore{}
+	_                   Toucher              = &managerStore{}
+	_                   BatchChecker         = &managerStore{}
+	_                   ManagerStoreExt      = &managerStore{}
+	_                   IndexedStore         = &store{}
+	_                   Indexer              = &managerStore{}
+	_                   HotSessionReporter   = &managerStore{}
+	_                   Verifier             = &managerStore{}
+	_                   Backfiller           = &managerStore{}
+	_                   Migrator             = &managerStore{}
+	_                   Driller              = &managerStore{}
+	_                   Mutator              = &store{}
+	_                   Rememberer           = &managerStore{}
+	_                   BulkDeleter          = &managerStore{}
+	_                   Loader               = &managerStore{}
+	_                   Subscriber           = &managerStore{}
+	_                   CausalRunner         = &managerStore{}
+	_                   Revisioner           = &store{}
+	_                   Accessor             = &store{}
+	_                   Archiver             = &managerStore{}
+	_                   Scratchpad           = &managerStore{}
+	_                   SaveReporter         = &store{}
+	_                   Accessor             = &memorySession{}
+	_                   Revisioner           = &memorySession{}
+	_                   Waiter               = &store{}
+	_                   Waiter               = &memorySession{}
+	_                   AgeReporter          = &managerStore{}
+	_                   Relocator            = &managerStore{}
+	_                   Reconfigurer         = &managerStore{}
+	_                   Releaser             = &store{}
+	_                   decorator.Inspector  = &managerStore{}
+	_                   decorator.Inspector  = &memoryStore{}
+	jsonUnmarshalString                      = jsoniter.UnmarshalFromString
 )
 
-// NewStore Create an instance of a mongo store
-func NewStore(url, dbName, cName string) session.ManagerStore {
-	session, err := mgo.Dial(url)
+// ErrOwnerMismatch The session document belongs to another owner
+var ErrOwnerMismatch = errors.New("session is owned by another store owner")
+
+// ErrNotFound Alias of ErrSessionNotFound
+var ErrNotFound = ErrSessionNotFound
+
+// NewStore Create an instance of a mongo store,
+// url is a mongodb:// or mongodb+srv:// connection string (the scheme may be omitted),
+// whose session parameters (URIParamTTLIndex...) configure the store before opts
+func NewStore(url, dbName, cName string, opts ...Option) session.ManagerStore {
+	s, err := NewStoreWithError(url, dbName, cName, opts...)
 	if err != nil {
 		panic(err)
 	}
-	return newManagerStore(session, dbName, cName)
+	return s
 }
 
-// NewStoreWithSession Create an instance of a mongo store
-func NewStoreWithSession(session *mgo.Session, dbName, cName string) session.ManagerStore {
-	return newManagerStore(session, dbName, cName)
+// NewStoreWithClient Create an instance of a mongo store reusing a connected client,
+// the client is left open when the store is closed
+func NewStoreWithClient(client *mongo.Client, dbName, cName string, opts ...Option) session.ManagerStore {
+	s, err := NewStoreWithClientError(client, dbName, cName, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
 }
 
-func newManagerStore(session *mgo.Session, dbName, cName string) *managerStore {
-	err := session.DB(dbName).C(cName).EnsureIndex(mgo.Index{
-		Key:         []string{"expired_at"},
-		ExpireAfter: time.Second,
-	})
+// NewStoreWithOptions Create an instance of a mongo store configured by options only,
+// the database defaults to the one of the url and the collection to "session"
+func NewStoreWithOptions(url string, opts ...Option) session.ManagerStore {
+	return NewStore(url, "", "", opts...)
+}
+
+// NewStoreWithError Create an instance of a mongo store like NewStore,
+// returning the option, connection and index creation errors instead of panicking
+// (empty dbName and cName keep the defaults of NewStoreWithOptions)
+func NewStoreWithError(url, dbName, cName string, opts ...Option) (session.ManagerStore, error) {
+	if !strings.Contains(url, "://") {
+		url = "mongodb://" + url
+	}
+	if dbName == "" {
+		dbName = uriDatabase(url)
+	}
+	url, uopts, err := uriOptions(url)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	o := newOptions(append(append(nameOptions(dbName, cName), uopts...), opts...)...)
+	if err := o.validate(); err != nil {
+		return nil, err
 	}
 
-	return &managerStore{
-		session: session,
-		dbName:  dbName,
-		cName:   cName,
+	client, err := mongo.Connect(o.clientOptions(url))
+	if err != nil {
+		return nil, err
+	}
+	s, err := newManagerStore(client, o)
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, err
 	}
+	s.ownClient = true
+	return s, nil
 }
 
-type managerStore struct {
-	session *mgo.Session
-	dbName  string
-	cName   string
+// NewStoreWithClientError Create an instance of a mongo store like NewStoreWithClient,
+// returning the option and index creation errors instead of panicking
+func NewStoreWithClientError(client *mongo.Client, dbName, cName string, opts ...Option) (session.ManagerStore, error) {
+	o := newOptions(append(nameOptions(dbName, cName), opts...)...)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	s, err := newManagerStore(client, o)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
-func (s *managerStore) getValue(sid string) (string, error) {
-	session := s.session.Clone()
-	defer session.Close()
-
-	var item sessionItem
-	err := session.DB(s.dbName).C(s.cName).FindId(sid).One(&item)
+func newManagerStore(client *mongo.Client, o options) (*managerStore, error) {
+	s, err := newCollectionStore(client, o.cName, o)
 	if err != nil {
-		if err == mgo.ErrNotFound {
-			return "", nil
+		return nil, err
+	}
+	s.locks = o.lockCollection(s.c)
+	s.remember = o.rememberCollection(s.c)
+	s.archive = o.archiveCollection(s.c)
+	s.scratch = o.scratchCollection(s.c)
+	if o.anonCollection != "" {
+		if s.anon, err = newCollectionStore(client, o.anonCollection, o); err != nil {
+			s.stopCleanup()
+			return nil, err
 		}
-		return "", err
-	} else if item.ExpiredAt.Before(time.Now()) {
-		return "", nil
+		s.anon.auth = s
+		s.anon.archive = s.archive
+	}
+
+	if o.lazy != nil {
+		o.lazy.fn = s.setup
+	} else if err := s.setup(context.Background()); err != nil {
+		s.stopCleanup()
+		if s.anon != nil {
+			s.anon.stopCleanup()
+		}
+		return nil, err
+	}
+	if o.invalidation {
+		s.startInvalidation()
 	}
-	return item.Value, nil
+	s.startAgeCheck()
+	return s, nil
 }
 
-func (s *managerStore) parseValue(value string) (map[string]interface{}, error) {
-	var values map[string]interface{}
-	if len(value) > 0 {
-		err := jsonUnmarshal([]byte(value), &values)
+// setup Prepare the database for the store: shard the new collections, create the indexes and
+// warm up the connections
+func (s *managerStore) setup(ctx context.Context) error {
+	if err := s.shard(ctx); err != nil {
+		return err
+	}
+	if err := s.createIndexes(ctx); err != nil {
+		return err
+	}
+	if s.anon != nil {
+		if err := s.anon.shard(ctx); err != nil {
+			return err
+		}
+		if err := s.anon.createIndexes(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.warmUp(); err != nil {
+		return err
+	}
+	if s.locks != nil && s.opts.indexes {
+		if _, err := s.locks.Indexes().CreateMany(ctx, lockIndexModels()); err != nil {
+			s.log(LevelError, "session lock index creation failed", "collection", s.locks.Name(), "error", err)
+			return err
+		}
+	}
+	if s.remember != nil && s.opts.indexes {
+		if _, err := s.remember.Indexes().CreateMany(ctx, rememberIndexModels()); err != nil {
+			s.log(LevelError, "remember-me index creation failed", "collection", s.remember.Name(), "error", err)
+			return err
+		}
+	}
+	if s.archive != nil && s.opts.indexes {
+		if _, err := s.archive.Indexes().CreateMany(ctx, archiveIndexModels()); err != nil {
+			s.log(LevelError, "session archive index creation failed", "collection", s.archive.Name(), "error", err)
+			return err
+		}
+	}
+	if s.scratch != nil && s.opts.indexes {
+		if _, err := s.scratch.Indexes().CreateMany(ctx, scratchIndexModels()); err != nil {
+			s.log(LevelError, "session scratch index creation failed", "collection", s.scratch.Name(), "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// newCollectionStore Create the store of the session collection cName
+func newCollectionStore(client *mongo.Client, cName string, o options) (*managerStore, error) {
+	c := client.Database(o.dbName).Collection(cName, o.collectionOptions())
+
+	affinity, err := o.affinityCollections(c)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &managerStore{
+		client:   client,
+		c:        c,
+		cPrimary: c.Clone(mopts.Collection().SetReadPreference(readpref.Primary())),
+		affinity: affinity,
+		chunks:   o.chunkCollection(c),
+		opts:     o,
+	}
+	if o.deferredIndexes != nil {
+		s.builds = newIndexBuilds()
+	}
+	s.startCleanup()
+	return s, nil
+}
+
+// createIndexes Create the indexes of the session collection of s
+func (s *managerStore) createIndexes(ctx context.Context) error {
+	indexes := s.opts.indexModels()
+	if len(indexes) == 0 {
+		return nil
+	}
+	if s.builds != nil {
+		if err := s.deferIndexes(ctx, indexes); err != nil {
+			return err
+		}
+	} else {
+		names, err := s.c.Indexes().CreateMany(ctx, indexes)
 		if err != nil {
-			return nil, err
+			s.log(LevelError, "session index creation failed", "collection", s.c.Name(), "error", err)
+			return err
 		}
+		s.log(LevelInfo, "session indexes created", "collection", s.c.Name(), "indexes", names)
 	}
 
-	return values, nil
+	if s.spills() {
+		if _, err := s.chunks.Indexes().CreateMany(ctx, chunkIndexModels()); err != nil {
+			s.log(LevelError, "session chunk index creation failed", "collection", s.chunks.Name(), "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+type managerStore struct {
+	client    *mongo.Client
+	c         *mongo.Collection
+	cPrimary  *mongo.Collection
+	affinity  []*mongo.Collection
+	locks     *mongo.Collection
+	chunks    *mongo.Collection
+	remember  *mongo.Collection
+	archive   *mongo.Collection
+	scratch   *mongo.Collection
+	ownClient bool
+	opts      options
+	namespace string
+	// scoped Whether the store is a namespace view, not routed to the tenants
+	scoped  bool
+	anon    *managerStore
+	auth    *managerStore
+	janitor *janitor
+	// builds The index builds of WithDeferredIndexes
+	builds *indexBuilds
+	// unwatch Stop following the change stream of WithChangeStreamInvalidation
+	unwatch context.CancelFunc
+	// stopAgeCheck Stop the checks of WithAgeAlert and wait for them
+	stopAgeCheck func()
+}
+
+// selector Query matching the session document, restricted to the configured owner
+func (s *managerStore) selector(sid string) bson.M {
+	q := s.scope()
+	q["_id"] = s.idFilter(sid)
+	return q
 }
 
-func (s *managerStore) Check(_ context.Context, sid string) (bool, error) {
-	val, err := s.getValue(sid)
+// upsert Write the session document, refusing to take over a document of another owner
+func (s *managerStore) upsert(ctx context.Context, sid string, item *sessionItem) error {
+	return s.replace(ctx, sid, item, s.selector(sid))
+}
+
+// upsertVersion Write the session document like upsert, refusing to overwrite another version
+// than expect unless the conflict policy is LastWriteWins
+func (s *managerStore) upsertVersion(ctx context.Context, sid string, item *sessionItem, expect int64) error {
+	return s.replace(ctx, sid, item, s.versionSelector(sid, expect))
+}
+
+func (s *managerStore) replace(ctx context.Context, sid string, item *sessionItem, q bson.M) error {
+	item.ID = s.docID(sid)
+	item.Owner = s.opts.owner
+	item.Namespace = s.namespace
+	item.UpdatedAt = s.now()
+	// a document left under the legacy id can't be replaced under the hashed one
+	q["_id"] = item.ID
+	stored, err := s.spill(ctx, item)
 	if err != nil {
-		return false, err
+		return err
+	}
+	_, err = s.c.ReplaceOne(ctx, q, stored, mopts.Replace().SetUpsert(true))
+	if err != nil {
+		if stored.Spill != nil {
+			s.discardChunks(ctx, item.ID, stored.Spill.Gen)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return s.writeConflict(ctx, sid, err)
+		}
+		return err
+	}
+	var keep string
+	if stored.Spill != nil {
+		keep = stored.Spill.Gen
+	}
+	s.cleanChunks(ctx, item.ID, keep)
+	s.pin(ctx, sid, *item)
+	s.cache(sid, *item)
+	return nil
+}
+
+// remove Delete the session document, mongo.ErrNoDocuments is returned if there is none
+func (s *managerStore) remove(ctx context.Context, sid string) error {
+	s.uncache(sid)
+	res, err := s.c.DeleteOne(ctx, s.selector(sid))
+	if err != nil {
+		return err
+	}
+	s.dropChunks(ctx, s.docID(sid))
+	s.dropScratch(ctx, s.docID(sid))
+	s.unpin(ctx, sid)
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
 	}
-	return val != "", nil
+	return nil
 }
 
-func (s *managerStore) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
-	return newStore(ctx, s, sid, expired, nil), nil
+// getItem Load the session document of sid unexpired or expired for less than grace
+// (nil if there is none), the value is left out of the query when withValue is false
+func (s *managerStore) getItem(ctx context.Context, sid string, withValue bool, grace time.Duration) (*sessionItem, error) {
+	if item, ok := s.pinned(ctx, sid); ok && !callOptionsFromContext(ctx).freshRead {
+		if item.ExpiredAt.Before(s.notBefore(grace)) {
+			return nil, nil
+		}
+		return &item, nil
+	}
+	if s.useCache(ctx) {
+		if item, ok := s.cached(sid); ok {
+			if item.ExpiredAt.Before(s.notBefore(grace)) {
+				return nil, nil
+			}
+			return &item, nil
+		}
+	}
+
+	c := s.sessionCollection(ctx, sid)
+	item, err := s.findItem(ctx, c, sid, withValue, grace)
+	if err == nil && s.lagging(c, item) {
+		s.log(LevelDebug, "session possibly stale on the secondary, reading the primary", "sid_hash", s.sidHash(sid))
+		item, err = s.findItem(ctx, s.cPrimary, sid, withValue, grace)
+	}
+	if err == nil && item != nil && withValue && s.opts.cache != nil {
+		s.cache(sid, *item)
+	}
+	return item, err
 }
 
-func (s *managerStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
-	value, err := s.getValue(sid)
+// findItem Query c for the session document of sid like getItem, ignoring the pinned documents
+func (s *managerStore) findItem(ctx context.Context, c *mongo.Collection, sid string, withValue bool, grace time.Duration) (*sessionItem, error) {
+	opts := mopts.FindOne()
+	if !withValue {
+		opts.SetProjection(bson.M{"value": 0})
+	}
+	notBefore := s.notBefore(grace)
+	q := s.selector(sid)
+	q["expired_at"] = bson.M{"$gte": notBefore}
+	var item sessionItem
+	err := c.FindOne(ctx, q, opts).Decode(&item)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
 		return nil, err
-	} else if value == "" {
-		return newStore(ctx, s, sid, expired, nil), nil
+	} else if item.ExpiredAt.Before(notBefore) {
+		return nil, nil
+	}
+	if withValue {
+		if err := s.unspill(ctx, &item); err != nil {
+			return nil, err
+		}
+		if err := s.migrateID(ctx, sid, &item); err != nil {
+			return nil, err
+		}
+	}
+	return &item, nil
+}
+
+func (s *managerStore) Check(ctx context.Context, sid string) (ok bool, err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Check(ctx, sid)
 	}
+	tctx, op := s.begin(ctx, OpCheck, sid)
+	defer func() { err = op.end(0, err) }()
+	s.hit(sid)
+	if err := s.injectFault(ctx, OpCheck); err != nil {
+		return false, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
 
-	session := s.session.Clone()
-	defer session.Close()
-	err = session.DB(s.dbName).C(s.cName).UpdateId(sid, bson.M{
-		"$set": bson.M{
-			"expired_at": time.Now().Add(time.Duration(expired) * time.Second),
-		},
+	var item *sessionItem
+	err = s.retry(dbctx, OpCheck, func() (err error) {
+		_, item, err = s.locate(dbctx, sid, false, 0)
+		return err
 	})
 	if err != nil {
+		return false, err
+	}
+	if item == nil && s.seeksExpired() {
+		s.detectExpired(dbctx, sid)
+	}
+	return item != nil, nil
+}
+
+func (s *managerStore) Create(ctx context.Context, sid string, expired int64) (st session.Store, err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Create(ctx, sid, expired)
+	}
+	tctx, op := s.begin(ctx, OpCreate, sid)
+	defer func() { err = op.end(0, err) }()
+	if err := s.injectFault(ctx, OpCreate); err != nil {
 		return nil, err
 	}
 
-	values, err := s.parseValue(value)
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+	store := newStore(ctx, s, sid, expired, nil)
+	held, err := s.lock(dbctx, sid)
 	if err != nil {
 		return nil, err
 	}
-
-	return newStore(ctx, s, sid, expired, values), nil
+	// admitted once locked, the sessions which can't be created aren't counted
+	if err := s.admitNew(ctx, dbctx, store); err != nil {
+		if held != nil {
+			_ = held.unlock(dbctx)
+		}
+		return nil, err
+	}
+	if s.opts.expiredDeletion {
+		s.detectExpired(dbctx, sid)
+	}
+	store.hold(held)
+	s.fireHook(ctx, EventCreate, s.opts.hooks.OnCreate, SessionEvent{SID: sid, ExpiredAt: s.expiration(expired, store.createdAt)})
+	return store, nil
 }
 
-func (s *managerStore) Delete(_ context.Context, sid string) error {
-	session := s.session.Clone()
-	defer session.Close()
-	return session.DB(s.dbName).C(s.cName).RemoveId(sid)
+func (s *managerStore) Update(ctx context.Context, sid string, expired int64) (st session.Store, err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Update(ctx, sid, expired)
+	}
+	defer trackLoad(ctx, time.Now())
+	tctx, op := s.begin(ctx, OpUpdate, sid)
+	defer func() { err = op.end(valueSize(st), err) }()
+	s.hit(sid)
+	if err := s.injectFault(ctx, OpUpdate); err != nil {
+		return nil, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	held, err := s.lock(dbctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	var store *store
+	err = s.retry(dbctx, OpUpdate, func() (err error) {
+		store, err = s.update(ctx, dbctx, sid, expired)
+		return err
+	})
+	if err != nil {
+		if held != nil {
+			_ = held.unlock(dbctx)
+		}
+		return nil, err
+	}
+	if !store.loaded {
+		if err := s.admitNew(ctx, dbctx, store); err != nil {
+			if held != nil {
+				_ = held.unlock(dbctx)
+			}
+			return nil, err
+		}
+	}
+	store.hold(held)
+	if !store.loaded && s.seeksExpired() {
+		store.stale = s.detectExpired(dbctx, sid)
+	}
+	return store, nil
 }
 
-func (s *managerStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
-	value, err := s.getValue(oldsid)
+// update Load the session sid and extend its expiration, dbctx being the context of the
+// database calls
+func (s *managerStore) update(ctx, dbctx context.Context, sid string, expired int64) (*store, error) {
+	if m, item := s.locateCached(ctx, sid); item != nil {
+		// the stored expiration gets extended once the cache entry expires
+		item.ExpiredAt = m.expiration(expired, item.CreatedAt)
+		m.cacheExpiration(sid, item.ExpiredAt)
+		return m.loadBound(ctx, sid, expired, item)
+	}
+
+	if s.fastUpdate(ctx) {
+		m := s
+		item, err := m.touchItem(dbctx, sid, expired, true)
+		if err == nil && item == nil && s.anon != nil {
+			m = s.anon
+			item, err = m.touchItem(dbctx, sid, expired, true)
+		}
+		if err != nil {
+			return nil, err
+		} else if item == nil {
+			return newStore(ctx, s, sid, expired, nil), nil
+		}
+		m.cache(sid, *item)
+		return m.loadBound(ctx, sid, expired, item)
+	}
+
+	m, item, err := s.locate(dbctx, sid, true, 0)
 	if err != nil {
 		return nil, err
-	} else if value == "" {
+	} else if item == nil {
 		return newStore(ctx, s, sid, expired, nil), nil
 	}
 
-	session := s.session.Clone()
-	defer session.Close()
-	c := session.DB(s.dbName).C(s.cName)
-	_, err = c.UpsertId(sid, &sessionItem{
-		ID:        sid,
-		Value:     value,
-		ExpiredAt: time.Now().Add(time.Duration(expired) * time.Second),
-	})
+	set := bson.M{}
+	if item.CreatedAt.IsZero() {
+		// document written before the creation time was recorded
+		item.CreatedAt = m.now()
+		set["created_at"] = item.CreatedAt
+	}
+	expiredAt := m.expiration(expired, item.CreatedAt)
+	set["expired_at"] = expiredAt
+	_, err = m.c.UpdateOne(dbctx, m.selector(sid), bson.M{"$set": set})
 	if err != nil {
 		return nil, err
 	}
-	err = c.RemoveId(oldsid)
+	item.ExpiredAt = expiredAt
+	if err := m.extendChunks(dbctx, item); err != nil {
+		return nil, err
+	}
+	m.pinExpiration(ctx, sid, expiredAt)
+	m.cacheExpiration(sid, expiredAt)
+
+	return m.loadBound(ctx, sid, expired, item)
+}
+
+func (s *managerStore) Delete(ctx context.Context, sid string) (err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Delete(ctx, sid)
+	}
+	tctx, op := s.begin(ctx, OpDelete, sid)
+	defer func() { err = op.end(0, err) }()
+	if err := s.injectFault(ctx, OpDelete); err != nil {
+		return err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	err = s.retry(dbctx, OpDelete, func() error {
+		err := s.retire(dbctx, sid, ArchiveDeleted, "")
+		if err == mongo.ErrNoDocuments && s.anon != nil {
+			err = s.anon.retire(dbctx, sid, ArchiveDeleted, "")
+		}
+		return err
+	})
+	if err == mongo.ErrNoDocuments && s.opts.idempotentDelete {
+		// already removed, e.g. by the TTL monitor
+		err = nil
+	}
+	if err == nil {
+		s.fireHook(ctx, EventDelete, s.opts.hooks.OnDelete, SessionEvent{SID: sid})
+	}
+	return err
+}
+
+func (s *managerStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (st session.Store, err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Refresh(ctx, oldsid, sid, expired)
+	}
+	defer trackLoad(ctx, time.Now())
+	tctx, op := s.begin(ctx, OpRefresh, oldsid)
+	defer func() { err = op.end(valueSize(st), err) }()
+	s.hit(oldsid)
+	if err := s.injectFault(ctx, OpRefresh); err != nil {
+		return nil, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	st, err = s.retryLoad(dbctx, OpRefresh, func() (*store, error) {
+		return s.refresh(ctx, dbctx, oldsid, sid, expired)
+	})
+	if store, ok := st.(*store); ok && err == nil {
+		if store.loaded {
+			s.fireHook(ctx, EventRefresh, s.opts.hooks.OnRefresh, SessionEvent{SID: sid, OldSID: oldsid, ExpiredAt: s.expiration(expired, store.createdAt)})
+		} else if s.seeksExpired() {
+			store.stale = s.detectExpired(dbctx, oldsid)
+		}
+	}
+	return st, err
+}
+
+// refresh Move the session oldsid to sid, dbctx being the context of the database calls
+func (s *managerStore) refresh(ctx, dbctx context.Context, oldsid, sid string, expired int64) (*store, error) {
+	m, item, err := s.locate(withoutCache(dbctx), oldsid, true, s.opts.refreshGrace)
 	if err != nil {
 		return nil, err
+	} else if item == nil {
+		if replay, err := s.replayedRefresh(ctx, dbctx, oldsid, sid, expired); err != nil || replay != nil {
+			return replay, err
+		}
+		return newStore(ctx, s, sid, expired, nil), nil
+	}
+	if restart, err := m.verifyFingerprint(ctx, oldsid, item); err != nil {
+		return nil, err
+	} else if restart {
+		return newStore(ctx, s, sid, expired, nil), nil
+	}
+	decision, ip := m.verifyIP(ctx, oldsid, item)
+	switch decision {
+	case IPReject:
+		return nil, ErrIPNotAllowed
+	case IPRestart:
+		return newStore(ctx, s, sid, expired, nil), nil
 	}
 
-	values, err := s.parseValue(value)
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = m.now()
+	}
+	err = m.moveItem(dbctx, oldsid, sid, &sessionItem{
+		Value:       item.Value,
+		ValueNext:   item.ValueNext,
+		ExpiredAt:   m.expiration(expired, item.CreatedAt),
+		CreatedAt:   item.CreatedAt,
+		Version:     item.Version,
+		Indexed:     item.Indexed,
+		Size:        item.Size,
+		Fingerprint: item.Fingerprint,
+		ASN:         item.ASN,
+		RefreshKey:  m.refreshKey(oldsid, sid),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return newStore(ctx, s, sid, expired, values), nil
+	st, err := newLoadedStore(m, item, newStore(ctx, m, sid, expired, nil))
+	if err == nil && decision == IPFlag {
+		st.Set(ipFlagKey, ip.String())
+	}
+	return st, err
 }
 
 func (s *managerStore) Close() error {
-	s.session.Close()
-	return nil
+	s.opts.queued.Wait()
+	s.opts.hookCalls.Wait()
+	if s.unwatch != nil {
+		s.unwatch()
+		s.opts.watchers.Wait()
+	}
+	if s.stopAgeCheck != nil {
+		s.stopAgeCheck()
+	}
+	s.stopCleanup()
+	if s.builds != nil {
+		s.builds.stop()
+	}
+	if s.anon != nil {
+		s.anon.stopCleanup()
+		if s.anon.builds != nil {
+			s.anon.builds.stop()
+		}
+	}
+	if !s.ownClient {
+		return nil
+	}
+	return s.client.Disconnect(context.Background())
 }
 
 func newStore(ctx context.Context, s *managerStore, sid string, expired int64, values map[string]interface{}) *store {
 	if values == nil {
-		values = make(map[string]interface{})
+		values = s.newValues()
+	}
+
+	var trace *debugTrace
+	if s.opts.traceOut != nil {
+		trace = &debugTrace{out: s.opts.traceOut}
 	}
 
 	return &store{
-		session: s.session,
-		dbName:  s.dbName,
-		cName:   s.cName,
-		ctx:     ctx,
-		sid:     sid,
-		expired: expired,
-		values:  values,
+		rwLocker:    s.newLocker(),
+		mstore:      s,
+		trace:       trace,
+		ctx:         ctx,
+		diag:        diagnosticsFromContext(ctx),
+		sid:         sid,
+		expired:     expired,
+		createdAt:   s.now(),
+		values:      values,
+		fingerprint: s.callerFingerprint(ctx),
+		asn:         s.callerASN(ctx),
 	}
 }
 
 type store struct {
-	sync.RWMutex
-	ctx     context.Context
-	session *mgo.Session
-	dbName  string
-	cName   string
-	sid     string
-	expired int64
-	values  map[string]interface{}
+	rwLocker
+	ctx       context.Context
+	mstore    *managerStore
+	diag      *diagnostics
+	trace     *debugTrace
+	sid       string
+	expired   int64
+	createdAt time.Time
+	values    map[string]interface{}
+	lazy      []byte
+	lazyValue bson.RawValue
+	lazyErr   error
+	dirty     map[string]struct{}
+	flushed   bool
+	loaded    bool
+	partial   bool
+	version   int64
+	// size The size of the loaded value
+	size int
+	// indexed The indexed fields, indexDirty if modified since the last save
+	indexed    bson.M
+	indexDirty bool
+	// held The session lock taken by the store, released by the save, Release or the end of
+	// the request context
+	held *sessionLock
+	// released Closed when the lock is released, stopping the watch of the request context
+	released chan struct{}
+	// fingerprint The fingerprint hash the session is bound to
+	fingerprint string
+	// asn The autonomous system the session is bound to
+	asn uint32
+	// bound The user the saved session is bound to, for the login hook
+	bound interface{}
+	// stale Whether the session was found expired but still stored (see WasExpired)
+	stale bool
+	// typeErr The error of the first value rejected by WithStrictTypes since the last save
+	typeErr error
+	// filtered The modified keys whose values already went through WithValueFilter
+	filtered map[string]struct{}
+	// saveID The id of the running save, outcome how the last one resolved
+	saveID  string
+	outcome SaveOutcome
 }
 
 func (s *store) Context() context.Context {
@@ -201,65 +792,336 @@ func (s *store) SessionID() string {
 }
 
 func (s *store) Set(key string, value interface{}) {
+	if !s.admitKey(key) || !s.admit(key, value) {
+		return
+	}
 	s.Lock()
+	s.materialize()
 	s.values[key] = value
+	s.markDirty(key)
 	s.Unlock()
+	if s.diag != nil {
+		s.diag.dirty(key)
+	}
+	if s.trace != nil {
+		s.trace.record(1, "set", key, nil)
+	}
 }
 
 func (s *store) Get(key string) (interface{}, bool) {
 	s.RLock()
 	val, ok := s.values[key]
+	lazy := s.lazy != nil
 	s.RUnlock()
+	if !ok && lazy {
+		s.Lock()
+		val, ok = s.lazyGet(key)
+		s.Unlock()
+	}
+	if s.trace != nil {
+		s.trace.record(1, "get", key, nil)
+	}
 	return val, ok
 }
 
 func (s *store) Delete(key string) interface{} {
 	s.RLock()
 	v, ok := s.values[key]
+	lazy := s.lazy != nil
 	s.RUnlock()
-	if ok {
+	if ok || lazy {
 		s.Lock()
+		s.materialize()
+		v, ok = s.values[key]
 		delete(s.values, key)
+		if ok {
+			s.markDirty(key)
+		}
 		s.Unlock()
+		if ok && s.diag != nil {
+			s.diag.dirty(key)
+		}
+	}
+	if s.trace != nil {
+		s.trace.record(1, "delete", key, nil)
 	}
 	return v
 }
 
 func (s *store) Flush() error {
 	s.Lock()
-	s.values = make(map[string]interface{})
+	if s.diag != nil {
+		s.materialize()
+		for key := range s.values {
+			s.diag.dirty(key)
+		}
+	}
+	s.values = s.mstore.newValues()
+	s.lazy = nil
+	s.lazyValue = bson.RawValue{}
+	s.lazyErr = nil
+	s.dirty = nil
+	s.indexed = nil
+	s.typeErr = nil
+	s.flushed = true
 	s.Unlock()
-	return s.Save()
+
+	err := s.save()
+	if s.trace != nil {
+		s.trace.record(1, "flush", "", err)
+		if err != nil {
+			s.trace.dump(s.mstore.sidHash(s.sid))
+		}
+	}
+	return err
 }
 
 func (s *store) Save() error {
-	var value string
+	err := s.takeTypeErr()
+	if err == nil {
+		if s.mstore.opts.saveCancel == SaveQueue && s.ctx != nil && s.ctx.Done() != nil {
+			err = s.queueSave()
+		} else {
+			err = s.save()
+		}
+	}
+	if s.trace != nil {
+		s.trace.record(1, "save", "", err)
+		if err != nil {
+			s.trace.dump(s.mstore.sidHash(s.sid))
+		}
+	}
+	return err
+}
+
+func (s *store) save() (err error) {
+	var size int
+	tctx, op := s.mstore.begin(s.ctx, OpSave, s.sid)
+	defer func() { err = op.end(size, err) }()
+	defer s.unlock()
+	tctx, detach := s.mstore.saveContext(tctx)
+	defer detach()
+	if err := s.mstore.injectFault(s.ctx, OpSave); err != nil {
+		if s.diag != nil {
+			s.diag.save(0, err)
+		}
+		return err
+	}
+	s.stripKeys()
+	s.filterValues()
+	if err := s.validateValues(); err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.outcome = SaveWritten
+	if s.loaded && !s.flushed && len(s.dirty) == 0 && !s.indexDirty {
+		// nothing to write, the expiration was already extended by the load
+		s.Unlock()
+		return nil
+	}
+	dirty, flushed, indexDirty := s.dirty, s.flushed, s.indexDirty
+	s.dirty, s.flushed, s.indexDirty = nil, false, false
+	saveID := s.mstore.newSaveID()
+	s.saveID = saveID
+	moved := s.classify()
+	user, login := s.boundUser(indexDirty)
+	s.Unlock()
+
+	dbctx, cancel := s.mstore.callContext(tctx)
+	defer cancel()
+
+	persist := func(ctx context.Context) error {
+		var err error
+		size, err = s.write(ctx, dirty, flushed)
+		for i := 0; err == ErrConflict && s.mstore.opts.conflictPolicy == ConflictMerge && i < conflictRetries; i++ {
+			s.mstore.log(LevelWarn, "retrying the session save after a conflict", "sid_hash", s.mstore.sidHash(s.sid), "attempt", i+1)
+			if err = s.merge(ctx, dirty, flushed); err == nil {
+				size, err = s.write(ctx, dirty, flushed)
+			}
+		}
+		if err == nil && moved != nil {
+			err = moved.leave(ctx, s.sid)
+		}
+		return err
+	}
 
+	attempt := 0
+	err = s.mstore.retry(dbctx, OpSave, func() error {
+		if attempt++; attempt > 1 {
+			if written, err := s.resolveSave(dbctx, saveID); err != nil || written {
+				return err
+			}
+			s.Lock()
+			s.outcome = SaveRetried
+			s.Unlock()
+		}
+		if moved != nil && moved.loaded {
+			// the document moves to the collection of its new class
+			return s.mstore.inTransaction(dbctx, persist)
+		}
+		return persist(dbctx)
+	})
+	if err != nil {
+		s.Lock()
+		if s.outcome != SaveConflicted {
+			s.outcome = SaveFailed
+		}
+		s.restoreDirty(dirty, flushed)
+		s.mstore.uncache(s.sid)
+		s.indexDirty = s.indexDirty || indexDirty
+		if moved != nil {
+			moved.undo(s)
+		}
+		s.Unlock()
+	}
+	if s.diag != nil {
+		s.diag.save(size, err)
+	}
+	if err == nil && login {
+		s.notifyLogin(dbctx, user)
+	}
+	return err
+}
+
+// write Write the modifications of the store, returning the size of the written value
+func (s *store) write(ctx context.Context, dirty map[string]struct{}, flushed bool) (int, error) {
 	s.RLock()
-	if len(s.values) > 0 {
-		buf, err := jsonMarshal(s.values)
-		if err != nil {
-			s.RUnlock()
-			return err
+	var value bson.RawValue
+	var set, unset bson.M
+	var partial bool
+	indexed, err := s.indexedFields()
+	switch {
+	case err != nil:
+	case s.lazyErr != nil:
+		err = s.lazyErr
+	case s.lazy != nil:
+		value = s.lazyValue
+	default:
+		s.mstore.observeKeys(len(s.values))
+		if s.partial && !flushed && !s.mstore.dualWrite() && s.mstore.opts.maxSize <= 0 && s.mstore.opts.spillThreshold <= 0 {
+			set, unset, partial = keyUpdate(s.values, dirty)
+		}
+		if partial {
+			for k, v := range set {
+				if set[k], err = s.mstore.opts.encodeType(v); err != nil {
+					break
+				}
+			}
+			s.mstore.opts.indexUpdate(indexed, set, unset)
+		}
+		if !partial {
+			value, err = s.mstore.encodeValues(s.values)
 		}
-		value = string(buf)
 	}
+	version := s.version
 	s.RUnlock()
+	if err == nil && !partial {
+		value, err = s.checkSize(value)
+	}
+	if err != nil {
+		return 0, err
+	}
 
-	session := s.session.Clone()
-	defer session.Close()
-	_, err := session.DB(s.dbName).C(s.cName).UpsertId(s.sid, &sessionItem{
-		ID:        s.sid,
-		Value:     value,
-		ExpiredAt: time.Now().Add(time.Duration(s.expired) * time.Second),
-	})
+	expiredAt := s.mstore.expiration(s.expired, s.createdAt)
+	if partial {
+		// the size of the value is only known from the full writes
+		unset["size"] = ""
+		if s.fingerprint != "" {
+			set["fp"] = s.fingerprint
+		}
+		if s.asn != 0 {
+			set["asn"] = s.asn
+		}
+		if s.saveID != "" {
+			set["save_id"] = s.saveID
+		}
+		updatedAt := s.mstore.now()
+		set["updated_at"] = updatedAt
+		ok, err := s.mstore.updateKeys(ctx, s.sid, set, unset, expiredAt, version)
+		if err != nil {
+			return 0, err
+		}
+		// the document is gone or was modified concurrently unless ok,
+		// the full value is also needed to pin the document
+		if !ok || writePinsFromContext(ctx) != nil || s.mstore.opts.cache != nil {
+			s.RLock()
+			value, err = s.mstore.encodeValues(s.values)
+			s.RUnlock()
+			if err != nil {
+				return 0, err
+			}
+		}
+		if ok {
+			item := sessionItem{Value: value, ExpiredAt: expiredAt, CreatedAt: s.createdAt, UpdatedAt: updatedAt, Version: version + 1, Indexed: indexed}
+			s.mstore.pin(ctx, s.sid, item)
+			s.mstore.cache(s.sid, item)
+			s.Lock()
+			s.version = version + 1
+			s.Unlock()
+			return len(value.Value), nil
+		}
+	}
 
-	return err
+	item := &sessionItem{
+		Value:       value,
+		ExpiredAt:   expiredAt,
+		CreatedAt:   s.createdAt,
+		Version:     version + 1,
+		Indexed:     indexed,
+		Size:        len(value.Value),
+		Fingerprint: s.fingerprint,
+		ASN:         s.asn,
+		SaveID:      s.saveID,
+	}
+	if s.mstore.dualWrite() && value.Type != bson.TypeString {
+		s.RLock()
+		var values map[string]interface{}
+		if values, err = s.mstore.opts.encodeTypes(s.values); err == nil {
+			item.Value, err = legacyValue(values)
+		}
+		s.RUnlock()
+		if err != nil {
+			return 0, err
+		}
+		item.ValueNext = value
+	}
+	err = s.mstore.upsertVersion(ctx, s.sid, item, version)
+	if err != nil {
+		return len(value.Value), err
+	}
+	s.Lock()
+	s.loaded = true
+	s.partial = item.Value.Type == bson.TypeEmbeddedDocument
+	s.version = version + 1
+	s.Unlock()
+	return len(value.Value), nil
 }
 
 // Data items stored in mongo
 type sessionItem struct {
-	ID        string    `bson:"_id"`
-	Value     string    `bson:"value"`
-	ExpiredAt time.Time `bson:"expired_at"`
+	ID        string        `bson:"_id"`
+	Value     bson.RawValue `bson:"value"`
+	ValueNext bson.RawValue `bson:"value_next,omitempty"`
+	ExpiredAt time.Time     `bson:"expired_at"`
+	Owner     string        `bson:"owner,omitempty"`
+	Namespace string        `bson:"ns,omitempty"`
+	Version   int64         `bson:"version,omitempty"`
+	CreatedAt time.Time     `bson:"created_at,omitempty"`
+	// UpdatedAt The time of the last write of the values or id of the session, not changed
+	// by the extensions of its expiration
+	UpdatedAt time.Time `bson:"updated_at,omitempty"`
+	// Size The size of the encoded values, unset after a key-level write
+	Size int `bson:"size,omitempty"`
+	// Spill The reference to the chunks of a value stored apart
+	Spill *spillRef `bson:"spill,omitempty"`
+	// Fingerprint The hash of the fingerprint the session is bound to
+	Fingerprint string `bson:"fp,omitempty"`
+	// ASN The autonomous system the session is bound to
+	ASN uint32 `bson:"asn,omitempty"`
+	// RefreshKey The idempotency key of the refresh that moved the session to its id
+	RefreshKey string `bson:"refresh_key,omitempty"`
+	// SaveID The id of the save that wrote the document, with the retries of the saves
+	SaveID  string `bson:"save_id,omitempty"`
+	Indexed bson.M `bson:",inline"`
 }
@@ -2,9 +2,23 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	session "github.com/go-session/session/v3"
 	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+
+	"github.com/go-session/mongo/v3/decorator"
+	"github.com/go-session/mongo/v3/storetest"
 )
 
 const (
@@ -96,3 +110,1208 @@ func TestManagerStore(t *testing.T) {
 		So(err, ShouldBeNil)
 	})
 }
+
+func TestOwner(t *testing.T) {
+	mstoreA := NewStore(url, dbName, cName, WithOwner("service_a"))
+	defer mstoreA.Close()
+	mstoreB := NewStore(url, dbName, cName, WithOwner("service_b"))
+	defer mstoreB.Close()
+
+	Convey("Test owner isolation on a shared collection", t, func() {
+		sid := "test_owner_store"
+		store, err := mstoreA.Create(context.Background(), sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		err = store.Save()
+		So(err, ShouldBeNil)
+
+		exists, err := mstoreB.Check(context.Background(), sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		store, err = mstoreB.Create(context.Background(), sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "baz")
+		err = store.Save()
+		So(errors.Is(err, ErrOwnerMismatch), ShouldBeTrue)
+
+		store, err = mstoreA.Update(context.Background(), sid, 10)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+
+		err = mstoreA.Delete(context.Background(), sid)
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestNamespace(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+	web := mstore.(NamespaceStore).Namespace("web")
+	defer web.Close()
+	mobile := web.Namespace("mobile")
+	defer mobile.Close()
+
+	Convey("Test logical namespaces over one collection", t, func() {
+		ctx := context.Background()
+		sid := "test_namespace_store"
+		store, err := web.Create(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "web")
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mobile.Create(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "mobile")
+		So(store.Save(), ShouldBeNil)
+
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		store, err = web.Update(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "web")
+
+		n, err := mobile.Count(ctx)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+
+		So(mobile.DeleteAll(ctx), ShouldBeNil)
+		n, err = mobile.Count(ctx)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+
+		exists, err = web.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+		So(web.Delete(ctx, sid), ShouldBeNil)
+	})
+}
+
+func TestTenants(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithTenants(nil))
+	defer mstore.Close()
+
+	Convey("Test the sessions isolated per tenant", t, func() {
+		acme := WithTenant(context.Background(), "acme")
+		globex := WithTenant(context.Background(), "globex")
+		sid := "test_tenant_store"
+		store, err := mstore.Create(acme, sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "acme")
+		So(store.Save(), ShouldBeNil)
+
+		exists, err := mstore.Check(globex, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+		exists, err = mstore.Check(context.Background(), sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		store, err = mstore.Update(acme, sid, 10)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "acme")
+		n, err := mstore.(NamespaceStore).Namespace("acme").Count(context.Background())
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+
+		So(errors.Is(mstore.Delete(globex, sid), ErrSessionNotFound), ShouldBeTrue)
+		So(mstore.Delete(acme, sid), ShouldBeNil)
+	})
+}
+
+func TestIdempotentDelete(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+	idempotent := NewStore(url, dbName, cName, WithIdempotentDelete(true))
+	defer idempotent.Close()
+
+	Convey("Test deleting sessions without document", t, func() {
+		ctx := context.Background()
+		sid := "test_idempotent_delete"
+		So(errors.Is(mstore.Delete(ctx, sid), ErrNotFound), ShouldBeTrue)
+		So(idempotent.Delete(ctx, sid), ShouldBeNil)
+	})
+}
+
+func TestCheckMultiStore(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+
+	Convey("Test checking many sessions with one query", t, func() {
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, "test_check_multi", 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		exists, err := mstore.(BatchChecker).CheckMulti(ctx, []string{"test_check_multi", "test_check_multi_missing"})
+		So(err, ShouldBeNil)
+		So(exists, ShouldResemble, map[string]bool{"test_check_multi": true, "test_check_multi_missing": false})
+		So(mstore.Delete(ctx, "test_check_multi"), ShouldBeNil)
+	})
+}
+
+func TestManagerStoreExt(t *testing.T) {
+	mstore := NewStore(url, dbName, cName).(ManagerStoreExt)
+	defer mstore.Close()
+	admin := mstore.(NamespaceStore).Namespace("test_admin").(ManagerStoreExt)
+
+	Convey("Test enumerating the sessions", t, func() {
+		ctx := context.Background()
+		for _, sid := range []string{"a", "b", "c"} {
+			store, err := admin.Create(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+		}
+
+		n, err := admin.Count(ctx)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 3)
+
+		infos, err := admin.List(ctx, 1, 1)
+		So(err, ShouldBeNil)
+		So(infos, ShouldHaveLength, 1)
+		So(infos[0].SID, ShouldEqual, "b")
+		So(infos[0].Size, ShouldBeGreaterThan, 0)
+		So(infos[0].UpdatedAt, ShouldHappenWithin, time.Minute, time.Now())
+
+		var sids []string
+		err = admin.Iterate(ctx, func(info SessionInfo) error {
+			sids = append(sids, info.SID)
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(sids, ShouldHaveLength, 3)
+
+		buckets, err := admin.CreationCounts(ctx, time.Now().Add(-time.Hour), time.Hour)
+		So(err, ShouldBeNil)
+		var created int64
+		for _, b := range buckets {
+			created += b.Count
+		}
+		So(created, ShouldEqual, 3)
+
+		expiring, err := admin.ExpiryCounts(ctx, []time.Duration{time.Minute})
+		So(err, ShouldBeNil)
+		So(expiring, ShouldHaveLength, 2)
+		So(expiring[0].Max, ShouldEqual, time.Minute)
+		So(expiring[0].Count, ShouldEqual, 3)
+		So(expiring[1].Count, ShouldEqual, 0)
+
+		So(admin.(NamespaceStore).DeleteAll(ctx), ShouldBeNil)
+	})
+}
+
+func TestSessionLock(t *testing.T) {
+	mstore := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithSessionLock(time.Minute, 200*time.Millisecond))
+	defer mstore.Close()
+
+	Convey("Test the requests of a session serialized by its lock", t, func() {
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, "test_lock", 10)
+		So(err, ShouldBeNil)
+
+		_, err = mstore.Update(ctx, "test_lock", 10)
+		So(errors.Is(err, ErrLockTimeout), ShouldBeTrue)
+
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mstore.Update(ctx, "test_lock", 10)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "bar")
+		So(store.Save(), ShouldBeNil)
+
+		Convey("released at the end of a request which doesn't save", func() {
+			rctx, cancel := context.WithCancel(ctx)
+			store, err := mstore.Update(rctx, "test_lock", 10)
+			So(err, ShouldBeNil)
+			foo, _ := store.Get("foo")
+			So(foo, ShouldEqual, "bar")
+			cancel()
+
+			store, err = mstore.Update(ctx, "test_lock", 10)
+			So(err, ShouldBeNil)
+			store.(Releaser).Release()
+			store, err = mstore.Update(ctx, "test_lock", 10)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		})
+
+		Convey("timed out with a clock which doesn't move", func() {
+			now := time.Now()
+			fixed := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithSessionLock(time.Minute, 200*time.Millisecond),
+				WithClock(ClockFunc(func() time.Time { return now })))
+			defer fixed.Close()
+			store, err := fixed.Update(ctx, "test_lock", 10)
+			So(err, ShouldBeNil)
+
+			wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			_, err = fixed.Update(wctx, "test_lock", 10)
+			So(errors.Is(err, ErrLockTimeout), ShouldBeTrue)
+			So(store.Save(), ShouldBeNil)
+		})
+
+		So(mstore.Delete(ctx, "test_lock"), ShouldBeNil)
+	})
+}
+
+func TestHashedIDsMigration(t *testing.T) {
+	plain := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName))
+	defer plain.Close()
+	hashed := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithHashedIDs([]byte("secret"), true))
+	defer hashed.Close()
+
+	Convey("Test the sessions moved under their hashed ids", t, func() {
+		ctx := context.Background()
+		store, err := plain.Create(ctx, "test_hashed", 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		ok, err := hashed.Check(ctx, "test_hashed")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		store, err = hashed.Update(ctx, "test_hashed", 10)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "bar")
+
+		ok, err = plain.Check(ctx, "test_hashed")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+		So(hashed.Delete(ctx, "test_hashed"), ShouldBeNil)
+	})
+
+	Convey("Test the spilled sessions moved under their hashed ids", t, func() {
+		plain := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithSpillover(1024))
+		defer plain.Close()
+		hashed := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithSpillover(1024), WithHashedIDs([]byte("secret"), true))
+		defer hashed.Close()
+		ctx := context.Background()
+		large := strings.Repeat("x", 600*1024)
+		store, err := plain.Create(ctx, "test_hashed_spilled", 10)
+		So(err, ShouldBeNil)
+		store.Set("large", large)
+		So(store.Save(), ShouldBeNil)
+
+		store, err = hashed.Update(ctx, "test_hashed_spilled", 10)
+		So(err, ShouldBeNil)
+		v, _ := store.Get("large")
+		So(v, ShouldEqual, large)
+
+		m := hashed.(*managerStore)
+		var item sessionItem
+		So(m.c.FindOne(ctx, bson.M{"_id": m.docID("test_hashed_spilled")}).Decode(&item), ShouldBeNil)
+		So(item.Spill, ShouldNotBeNil)
+		So(item.Value.Type, ShouldEqual, bson.TypeNull)
+
+		// loaded again from the moved chunks
+		store, err = hashed.Update(ctx, "test_hashed_spilled", 10)
+		So(err, ShouldBeNil)
+		v, _ = store.Get("large")
+		So(v, ShouldEqual, large)
+		So(hashed.Delete(ctx, "test_hashed_spilled"), ShouldBeNil)
+	})
+}
+
+func TestSpilloverStore(t *testing.T) {
+	mstore := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithSpillover(64*1024))
+	defer mstore.Close()
+
+	Convey("Test the sessions larger than the spillover threshold", t, func() {
+		ctx := context.Background()
+		big := strings.Repeat("x", 600*1024)
+		store, err := mstore.Create(ctx, "test_spill", 10)
+		So(err, ShouldBeNil)
+		store.Set("big", big)
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mstore.Update(ctx, "test_spill", 10)
+		So(err, ShouldBeNil)
+		v, _ := store.Get("big")
+		So(v, ShouldEqual, big)
+		store.Set("big", "small")
+		So(store.Save(), ShouldBeNil)
+		So(mstore.(Toucher).Touch(ctx, "test_spill", 10), ShouldBeNil)
+
+		store, err = mstore.Update(ctx, "test_spill", 10)
+		So(err, ShouldBeNil)
+		v, _ = store.Get("big")
+		So(v, ShouldEqual, "small")
+		So(mstore.Delete(ctx, "test_spill"), ShouldBeNil)
+	})
+}
+
+func TestRememberMe(t *testing.T) {
+	mstore := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithRememberMe(time.Hour))
+	defer mstore.Close()
+	r := mstore.(Rememberer)
+
+	Convey("Test logging back in with a remember-me token", t, func() {
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, "test_remember", 10)
+		So(err, ShouldBeNil)
+		store.Set("uid", "u1")
+		store.Set("cart", "c1")
+		So(store.Save(), ShouldBeNil)
+
+		token, err := r.Remember(ctx, "test_remember", "uid")
+		So(err, ShouldBeNil)
+
+		store, next, err := r.Recall(ctx, token, "test_remembered", 10)
+		So(err, ShouldBeNil)
+		So(next, ShouldNotEqual, token)
+		uid, _ := store.Get("uid")
+		So(uid, ShouldEqual, "u1")
+		_, ok := store.Get("cart")
+		So(ok, ShouldBeFalse)
+
+		_, _, err = r.Recall(ctx, token, "test_remembered2", 10)
+		So(err, ShouldEqual, ErrInvalidRememberToken)
+
+		So(r.Forget(ctx, next), ShouldBeNil)
+		_, _, err = r.Recall(ctx, next, "test_remembered2", 10)
+		So(err, ShouldEqual, ErrInvalidRememberToken)
+
+		So(mstore.Delete(ctx, "test_remember"), ShouldBeNil)
+		So(mstore.Delete(ctx, "test_remembered"), ShouldBeNil)
+	})
+}
+
+func TestIndexer(t *testing.T) {
+	mstore := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithIndexedFields("uid"))
+	defer mstore.Close()
+	idx := mstore.(NamespaceStore).Namespace("test_indexer")
+
+	Convey("Test finding and deleting the sessions by user", t, func() {
+		ctx := context.Background()
+		for _, sid := range []string{"a", "b", "c"} {
+			store, err := idx.Create(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			if sid != "c" {
+				store.(IndexedStore).SetIndexed("uid", "u1")
+			}
+			So(store.Save(), ShouldBeNil)
+		}
+
+		store, err := idx.Update(ctx, "a", 10)
+		So(err, ShouldBeNil)
+		uid, ok := store.(IndexedStore).GetIndexed("uid")
+		So(ok, ShouldBeTrue)
+		So(uid, ShouldEqual, "u1")
+
+		infos, err := idx.(Indexer).FindByIndex(ctx, "uid", "u1")
+		So(err, ShouldBeNil)
+		So(infos, ShouldHaveLength, 2)
+
+		n, err := idx.(Indexer).DeleteByIndex(ctx, "uid", "u1")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 2)
+
+		ok, err = idx.Check(ctx, "c")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		n, err = idx.(Backfiller).Backfill(ctx, 10, KeyFields(map[string]string{"uid": "foo"}))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+		infos, err = idx.(Indexer).FindByIndex(ctx, "uid", "bar")
+		So(err, ShouldBeNil)
+		So(infos, ShouldHaveLength, 1)
+
+		So(idx.(NamespaceStore).DeleteAll(ctx), ShouldBeNil)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+
+	Convey("Test the startup self-check", t, func() {
+		r, err := mstore.(Verifier).Verify(context.Background())
+		So(err, ShouldBeNil)
+		So(r.ServerVersion, ShouldNotBeEmpty)
+		So(r.TTLIndex, ShouldBeTrue)
+	})
+}
+
+// newOfflineStore Create a store whose server can't be reached, for the tests of the code
+// paths that never get a reply from the database
+func newOfflineStore(t *testing.T, opts ...Option) *managerStore {
+	client, err := mongo.Connect(mopts.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	c := client.Database(dbName).Collection(cName)
+	return &managerStore{client: client, c: c, cPrimary: c.Clone(), opts: newOptions(opts...)}
+}
+
+func TestContextCancel(t *testing.T) {
+	mstore := newOfflineStore(t)
+
+	Convey("Test canceled contexts abort the database calls", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := mstore.Check(ctx, "test_context_cancel")
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+
+		_, err = mstore.Update(ctx, "test_context_cancel", 10)
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+
+		err = mstore.Delete(ctx, "test_context_cancel")
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+
+		store, err := mstore.Create(ctx, "test_context_cancel", 10)
+		So(err, ShouldBeNil)
+		err = store.Save()
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+	})
+}
+
+func TestNewStoreWithError(t *testing.T) {
+	Convey("Test constructors returning errors instead of panicking", t, func() {
+		mstore, err := NewStoreWithError("mongodb://127.0.0.1:27017/?connectTimeoutMS=abc", dbName, cName)
+		So(err, ShouldNotBeNil)
+		So(mstore, ShouldBeNil)
+
+		So(func() { NewStore("mongodb://127.0.0.1:27017/?connectTimeoutMS=abc", dbName, cName) }, ShouldPanic)
+	})
+}
+
+func TestBulkDelete(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+
+	Convey("Test the bulk deletes of sessions", t, func() {
+		ctx := context.Background()
+		bulk := mstore.(BulkDeleter)
+		sids := []string{"test_bulk_1", "test_bulk_2", "test_bulk_3"}
+		for _, sid := range sids {
+			store, err := mstore.Create(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			store.Set("foo", sid)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		n, err := bulk.DeleteMany(ctx, []string{sids[0], sids[1], "test_bulk_missing"})
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 2)
+		exists, err := mstore.Check(ctx, sids[0])
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+		exists, err = mstore.Check(ctx, sids[2])
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		Convey("expired", func() {
+			expired := NewStore(url, dbName, cName, WithClock(ClockFunc(func() time.Time {
+				return time.Now().Add(time.Minute)
+			})))
+			defer expired.Close()
+			n, err := expired.(BulkDeleter).DeleteExpired(ctx)
+			So(err, ShouldBeNil)
+			So(n, ShouldBeGreaterThanOrEqualTo, 1)
+			exists, err := mstore.Check(ctx, sids[2])
+			So(err, ShouldBeNil)
+			So(exists, ShouldBeFalse)
+		})
+	})
+}
+
+func TestLoginNotification(t *testing.T) {
+	var events []LoginEvent
+	mstore := NewStore(url, dbName, cName, WithIndexedFields("uid"), WithLoginHook("uid", func(ctx context.Context, event LoginEvent) {
+		events = append(events, event)
+	}))
+	defer mstore.Close()
+
+	Convey("Test the notification of the concurrent logins", t, func() {
+		ctx := context.Background()
+		login := func(sid string) {
+			store, err := mstore.Create(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			store.(IndexedStore).SetIndexed("uid", "test_login_user")
+			So(store.Save(), ShouldBeNil)
+		}
+		login("test_login_1")
+		So(events, ShouldBeEmpty)
+		login("test_login_2")
+		So(events, ShouldHaveLength, 1)
+		So(events[0].SIDHash, ShouldEqual, SHA256SIDHasher("test_login_2"))
+		So(events[0].Others, ShouldHaveLength, 1)
+		So(events[0].Others[0].SID, ShouldEqual, "test_login_1")
+
+		n, err := mstore.(Indexer).DeleteByIndex(ctx, "uid", "test_login_user")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 2)
+	})
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+
+	Convey("Test the snapshots loaded by the background jobs", t, func() {
+		ctx := context.Background()
+		sid := "test_load_snapshot"
+		store, err := mstore.Create(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		snap, err := mstore.(Loader).Load(ctx, sid)
+		So(err, ShouldBeNil)
+		So(snap.SessionID(), ShouldEqual, sid)
+		foo, _ := snap.Get("foo")
+		So(foo, ShouldEqual, "bar")
+
+		_, err = mstore.(Loader).Load(ctx, "test_load_missing")
+		So(errors.Is(err, ErrSessionNotFound), ShouldBeTrue)
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+	})
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string][]SessionEvent{}
+	record := func(name string) func(context.Context, SessionEvent) {
+		return func(ctx context.Context, event SessionEvent) {
+			mu.Lock()
+			seen[name] = append(seen[name], event)
+			mu.Unlock()
+		}
+	}
+	hooks := LifecycleHooks{OnCreate: record("create"), OnRefresh: record("refresh"), OnDelete: record("delete"), OnExpireDetected: record("expired")}
+	mstore := NewStore(url, dbName, cName, WithLifecycleHooks(hooks))
+	defer mstore.Close()
+
+	Convey("Test the lifecycle events of the sessions", t, func() {
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, "test_events_1", 10)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+		_, err = mstore.Refresh(ctx, "test_events_1", "test_events_2", 10)
+		So(err, ShouldBeNil)
+
+		store, err = mstore.Create(ctx, "test_events_3", 10)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+		later := NewStore(url, dbName, cName, WithLifecycleHooks(hooks), WithClock(ClockFunc(func() time.Time {
+			return time.Now().Add(time.Minute)
+		})))
+		_, err = later.Update(ctx, "test_events_3", 10)
+		So(err, ShouldBeNil)
+		So(later.Close(), ShouldBeNil)
+
+		So(mstore.Delete(ctx, "test_events_2"), ShouldBeNil)
+		So(mstore.Delete(ctx, "test_events_3"), ShouldBeNil)
+		mstore.(*managerStore).opts.hookCalls.Wait()
+
+		So(seen["create"], ShouldHaveLength, 2)
+		So(seen["refresh"], ShouldHaveLength, 1)
+		So(seen["refresh"][0].OldSID, ShouldEqual, "test_events_1")
+		So(seen["expired"], ShouldHaveLength, 1)
+		So(seen["expired"][0].SID, ShouldEqual, "test_events_3")
+		So(seen["delete"], ShouldHaveLength, 2)
+	})
+}
+
+func TestChangeStream(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+
+	Convey("Test the events of the sessions changed by the other instances", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		events := mstore.(Subscriber).Subscribe(ctx)
+		time.Sleep(500 * time.Millisecond)
+
+		other := NewStore(url, dbName, cName)
+		defer other.Close()
+		sid := "test_change_stream"
+		store, err := other.Create(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+		So(other.Delete(ctx, sid), ShouldBeNil)
+
+		var kinds []EventKind
+		for event := range events {
+			if event.SID == sid {
+				kinds = append(kinds, event.Kind)
+			}
+			if len(kinds) == 2 {
+				break
+			}
+		}
+		So(kinds, ShouldResemble, []EventKind{EventCreate, EventDelete})
+	})
+}
+
+func TestCausalChain(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithReadPreference(readpref.SecondaryPreferred()))
+	defer mstore.Close()
+
+	Convey("Test a causal chain of session operations", t, func() {
+		err := mstore.(CausalRunner).WithSession(context.Background(), func(ctx context.Context) error {
+			store, err := mstore.Update(ctx, "test_causal_1", 10)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+
+			store, err = mstore.Refresh(ctx, "test_causal_1", "test_causal_2", 10)
+			So(err, ShouldBeNil)
+			foo, _ := store.Get("foo")
+			So(foo, ShouldEqual, "bar")
+			return mstore.Delete(ctx, "test_causal_2")
+		})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestExportImport(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithIndexedFields("uid"))
+	defer mstore.Close()
+
+	Convey("Test the export and import of the sessions of a subject", t, func() {
+		ctx := context.Background()
+		sid := "test_export_session"
+		store, err := mstore.Create(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		store.(IndexedStore).SetIndexed("uid", "test_export_user")
+		So(store.Save(), ShouldBeNil)
+
+		r, err := mstore.(Exporter).Export(ctx, ExportFilter{Field: "uid", Value: "test_export_user"})
+		So(err, ShouldBeNil)
+		var buf strings.Builder
+		_, err = io.Copy(&buf, r)
+		So(err, ShouldBeNil)
+		So(r.Close(), ShouldBeNil)
+		So(strings.Count(buf.String(), "\n"), ShouldEqual, 1)
+		So(buf.String(), ShouldContainSubstring, `"sid":"test_export_session"`)
+
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+		n, err := mstore.(Exporter).Import(ctx, strings.NewReader(buf.String()))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+
+		store, err = mstore.Update(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+	})
+
+	Convey("Test the import of the anonymous sessions to their collection", t, func() {
+		mstore := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName),
+			WithAnonymousCollection(cName+"_anon", time.Hour, KeyClassifier("uid")), WithOperationTimeout(time.Second))
+		defer mstore.Close()
+		ctx := context.Background()
+		for sid, values := range map[string]map[string]interface{}{
+			"test_export_anon": {"cart": "c1"},
+			"test_export_auth": {"uid": "u1"},
+		} {
+			store, err := mstore.Create(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			for k, v := range values {
+				store.Set(k, v)
+			}
+			So(store.Save(), ShouldBeNil)
+		}
+
+		r, err := mstore.(Exporter).Export(ctx, ExportFilter{SIDs: []string{"test_export_anon", "test_export_auth"}})
+		So(err, ShouldBeNil)
+		var buf strings.Builder
+		_, err = io.Copy(&buf, r)
+		So(err, ShouldBeNil)
+		So(r.Close(), ShouldBeNil)
+		So(buf.String(), ShouldContainSubstring, `"anonymous":true`)
+
+		m := mstore.(*managerStore)
+		for _, sid := range []string{"test_export_anon", "test_export_auth"} {
+			So(mstore.Delete(ctx, sid), ShouldBeNil)
+		}
+		n, err := mstore.(Exporter).Import(ctx, strings.NewReader(buf.String()))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 2)
+		count, err := m.anon.c.CountDocuments(ctx, bson.M{"_id": "test_export_anon"})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+		count, err = m.c.CountDocuments(ctx, bson.M{"_id": "test_export_auth"})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+		for _, sid := range []string{"test_export_anon", "test_export_auth"} {
+			So(mstore.Delete(ctx, sid), ShouldBeNil)
+		}
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	legacyStore := NewStore(url, dbName, cName)
+	defer legacyStore.Close()
+	mstore := NewStore(url, dbName, cName, WithCompression(GzipCompression, 1))
+	defer mstore.Close()
+
+	Convey("Test the migration of the session values to another format", t, func() {
+		ctx := context.Background()
+		sids := []string{"test_migrate_1", "test_migrate_2", "test_migrate_3"}
+		for _, sid := range sids {
+			store, err := legacyStore.Create(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			store.Set("foo", sid)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		var batches []MigrateProgress
+		p, err := mstore.(Migrator).Migrate(ctx, MigrateOptions{Batch: 2, Progress: func(p MigrateProgress) {
+			batches = append(batches, p)
+		}})
+		So(err, ShouldBeNil)
+		So(p.Migrated, ShouldBeGreaterThanOrEqualTo, 3)
+		So(p.Failed, ShouldEqual, 0)
+		So(len(batches), ShouldBeGreaterThanOrEqualTo, 2)
+		So(p.Checkpoint.Collection, ShouldEqual, cName)
+
+		var item sessionItem
+		err = mstore.(*managerStore).c.FindOne(ctx, bson.M{"_id": sids[0]}).Decode(&item)
+		So(err, ShouldBeNil)
+		So(item.Value.Type, ShouldEqual, bson.TypeBinary)
+
+		p, err = mstore.(Migrator).Migrate(ctx, MigrateOptions{Resume: p.Checkpoint})
+		So(err, ShouldBeNil)
+		So(p.Migrated, ShouldEqual, 0)
+
+		for _, sid := range sids {
+			store, err := legacyStore.Update(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			foo, _ := store.Get("foo")
+			So(foo, ShouldEqual, sid)
+		}
+		n, err := mstore.(BulkDeleter).DeleteMany(ctx, sids)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 3)
+	})
+}
+
+func TestStoreIsolation(t *testing.T) {
+	mstore := newOfflineStore(t)
+
+	Convey("Test the stores loaded from a document never share their values", t, func() {
+		value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+		So(err, ShouldBeNil)
+		item := &sessionItem{Value: value}
+
+		stores := make([]*store, 8)
+		var wg sync.WaitGroup
+		for i := range stores {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				st, err := newLoadedStore(mstore, item, newStore(context.Background(), mstore, "test_isolation", 10, nil))
+				if err != nil {
+					return
+				}
+				st.Set("foo", i)
+				st.Set("n", i)
+				stores[i] = st
+			}(i)
+		}
+		wg.Wait()
+		for i, st := range stores {
+			So(st, ShouldNotBeNil)
+			foo, _ := st.Get("foo")
+			So(foo, ShouldEqual, i)
+		}
+	})
+}
+
+func TestExpiredDeletion(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithExpiredDeletion())
+	defer mstore.Close()
+	skewed := NewStore(url, dbName, cName, WithExpirySkew(time.Minute))
+	defer skewed.Close()
+
+	Convey("Test the expired documents found by the reads", t, func() {
+		ctx := context.Background()
+		c := mstore.(*managerStore).c
+		expire := func(sid string) {
+			_, err := c.InsertOne(ctx, &sessionItem{ID: sid, ExpiredAt: time.Now().Add(-time.Second)})
+			So(err, ShouldBeNil)
+		}
+
+		expire("test_expired_skew")
+		ok, err := skewed.Check(ctx, "test_expired_skew")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		ok, err = mstore.Check(ctx, "test_expired_skew")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+		n, err := c.CountDocuments(ctx, bson.M{"_id": "test_expired_skew"})
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+
+		expire("test_expired_update")
+		st, err := mstore.Update(ctx, "test_expired_update", 10)
+		So(err, ShouldBeNil)
+		So(WasExpired(st), ShouldBeTrue)
+
+		expire("test_expired_load")
+		_, err = mstore.(Loader).Load(ctx, "test_expired_load")
+		So(errors.Is(err, ErrExpired), ShouldBeTrue)
+		So(errors.Is(err, ErrSessionNotFound), ShouldBeTrue)
+		_, err = mstore.(Loader).Load(ctx, "test_expired_load")
+		So(errors.Is(err, ErrExpired), ShouldBeFalse)
+	})
+}
+
+func TestArchive(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithArchive(time.Hour))
+	defer mstore.Close()
+
+	Convey("Test the archive of the removed sessions", t, func() {
+		ctx := context.Background()
+		st, err := mstore.Create(ctx, "test_archive", 10)
+		So(err, ShouldBeNil)
+		st.Set("user", "u1")
+		So(st.Save(), ShouldBeNil)
+
+		_, err = mstore.Refresh(ctx, "test_archive", "test_archive_next", 10)
+		So(err, ShouldBeNil)
+		So(mstore.Delete(ctx, "test_archive_next"), ShouldBeNil)
+
+		archived, err := mstore.(Archiver).Archived(ctx, "test_archive")
+		So(err, ShouldBeNil)
+		So(archived, ShouldHaveLength, 1)
+		So(archived[0].Reason, ShouldEqual, ArchiveRefreshed)
+		So(archived[0].NextSID, ShouldEqual, "test_archive_next")
+		So(archived[0].Values["user"], ShouldEqual, "u1")
+
+		archived, err = mstore.(Archiver).Archived(ctx, "test_archive_next")
+		So(err, ShouldBeNil)
+		So(archived, ShouldHaveLength, 1)
+		So(archived[0].Reason, ShouldEqual, ArchiveDeleted)
+
+		c := mstore.(*managerStore).c
+		_, err = c.InsertOne(ctx, &sessionItem{ID: "test_archive_expired", ExpiredAt: time.Now().Add(-time.Second)})
+		So(err, ShouldBeNil)
+		n, err := mstore.(BulkDeleter).DeleteExpired(ctx)
+		So(err, ShouldBeNil)
+		So(n, ShouldBeGreaterThanOrEqualTo, 1)
+		archived, err = mstore.(Archiver).Archived(ctx, "test_archive_expired")
+		So(err, ShouldBeNil)
+		So(archived, ShouldHaveLength, 1)
+		So(archived[0].Reason, ShouldEqual, ArchiveExpired)
+
+		_, err = mstore.(*managerStore).archive.DeleteMany(ctx, bson.M{"sid": bson.M{"$in": bson.A{"test_archive", "test_archive_next", "test_archive_expired"}}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Test the archive of the spilled sessions", t, func() {
+		mstore := NewStoreWithOptions(url, WithDatabase(dbName), WithCollection(cName), WithArchive(time.Hour), WithSpillover(1024))
+		defer mstore.Close()
+		ctx := context.Background()
+		st, err := mstore.Create(ctx, "test_archive_spilled", 10)
+		So(err, ShouldBeNil)
+		large := strings.Repeat("x", 600*1024)
+		st.Set("large", large)
+		So(st.Save(), ShouldBeNil)
+		So(mstore.Delete(ctx, "test_archive_spilled"), ShouldBeNil)
+
+		archived, err := mstore.(Archiver).Archived(ctx, "test_archive_spilled")
+		So(err, ShouldBeNil)
+		So(archived, ShouldHaveLength, 1)
+		So(archived[0].Values["large"], ShouldEqual, large)
+
+		m := mstore.(*managerStore)
+		var doc archiveDoc
+		So(m.archive.FindOne(ctx, bson.M{"sid": "test_archive_spilled"}).Decode(&doc), ShouldBeNil)
+		So(doc.Session.Spill, ShouldNotBeNil)
+		So(doc.Session.Value.Type, ShouldEqual, bson.TypeNull)
+		_, err = m.chunks.DeleteMany(ctx, bson.M{"doc": doc.Chunks})
+		So(err, ShouldBeNil)
+		_, err = m.archive.DeleteMany(ctx, bson.M{"sid": "test_archive_spilled"})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestDeferredIndexes(t *testing.T) {
+	builds := make(chan IndexBuild, 16)
+	mstore := NewStore(url, dbName, cName, WithIndexedFields("test_deferred"), WithDeferredIndexes(time.Second, func(b IndexBuild) {
+		builds <- b
+	}))
+	defer mstore.Close()
+
+	Convey("Test the index builds in the background", t, func() {
+		var build IndexBuild
+		for !build.Finished {
+			select {
+			case build = <-builds:
+			case <-time.After(30 * time.Second):
+				t.Fatal("index build not finished")
+			}
+		}
+		So(build.Err, ShouldBeNil)
+		So(build.Keys, ShouldEqual, "test_deferred_1")
+
+		missing, err := mstore.(*managerStore).missingIndexes(context.Background(), mstore.(*managerStore).opts.indexModels())
+		So(err, ShouldBeNil)
+		So(missing, ShouldBeEmpty)
+		err = mstore.(*managerStore).c.Indexes().DropOne(context.Background(), "test_deferred_1")
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestWaitForChangeStream(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithWaitInterval(100*time.Millisecond))
+	defer mstore.Close()
+	ctx := context.Background()
+
+	Convey("Test waiting for the changes of a stored session", t, func() {
+		st, err := mstore.Create(ctx, "test_wait", 10)
+		So(err, ShouldBeNil)
+		So(st.Save(), ShouldBeNil)
+		waiting, err := mstore.Update(ctx, "test_wait", 10)
+		So(err, ShouldBeNil)
+
+		done := make(chan error, 1)
+		go func() { done <- waiting.(Waiter).WaitForChange(ctx) }()
+		_, err = mstore.Update(ctx, "test_wait", 10)
+		So(err, ShouldBeNil)
+		other, err := mstore.Update(ctx, "test_wait", 10)
+		So(err, ShouldBeNil)
+		other.Set("approved", true)
+		So(other.Save(), ShouldBeNil)
+		So(<-done, ShouldBeNil)
+
+		go func() { done <- other.(Waiter).WaitForChange(ctx) }()
+		So(mstore.Delete(ctx, "test_wait"), ShouldBeNil)
+		So(<-done, ShouldEqual, ErrSessionNotFound)
+	})
+}
+
+func TestScratch(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithScratch())
+	defer mstore.Close()
+	ctx := context.Background()
+	pad := mstore.(Scratchpad)
+
+	Convey("Test the scratch entries of the sessions", t, func() {
+		err := pad.PutScratch(ctx, "test_scratch", "upload", []byte("token"), time.Minute)
+		So(err, ShouldEqual, ErrSessionNotFound)
+
+		st, err := mstore.Create(ctx, "test_scratch", 10)
+		So(err, ShouldBeNil)
+		So(st.Save(), ShouldBeNil)
+		So(pad.PutScratch(ctx, "test_scratch", "upload", []byte("token"), time.Minute), ShouldBeNil)
+		So(pad.PutScratch(ctx, "test_scratch", "wizard", []byte("step2"), time.Minute), ShouldBeNil)
+		value, err := pad.GetScratch(ctx, "test_scratch", "upload")
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "token")
+
+		So(pad.DeleteScratch(ctx, "test_scratch", "upload"), ShouldBeNil)
+		_, err = pad.GetScratch(ctx, "test_scratch", "upload")
+		So(err, ShouldEqual, ErrScratchNotFound)
+
+		_, err = mstore.Refresh(ctx, "test_scratch", "test_scratch_next", 10)
+		So(err, ShouldBeNil)
+		value, err = pad.GetScratch(ctx, "test_scratch_next", "wizard")
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "step2")
+
+		So(mstore.Delete(ctx, "test_scratch_next"), ShouldBeNil)
+		n, err := mstore.(*managerStore).scratch.CountDocuments(ctx, bson.M{"sid": "test_scratch_next"})
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+	})
+}
+
+func TestRefreshReplay(t *testing.T) {
+	mstore := NewStore(url, dbName, cName)
+	defer mstore.Close()
+	ctx := context.Background()
+
+	Convey("Test the refreshes retried after they went through", t, func() {
+		st, err := mstore.Create(ctx, "test_replay", 10)
+		So(err, ShouldBeNil)
+		st.Set("user", "u1")
+		So(st.Save(), ShouldBeNil)
+
+		_, err = mstore.Refresh(ctx, "test_replay", "test_replay_next", 10)
+		So(err, ShouldBeNil)
+		st, err = mstore.Refresh(ctx, "test_replay", "test_replay_next", 10)
+		So(err, ShouldBeNil)
+		user, ok := st.Get("user")
+		So(ok, ShouldBeTrue)
+		So(user, ShouldEqual, "u1")
+
+		st, err = mstore.Refresh(ctx, "test_replay", "test_replay_other", 10)
+		So(err, ShouldBeNil)
+		_, ok = st.Get("user")
+		So(ok, ShouldBeFalse)
+		So(mstore.Delete(ctx, "test_replay_next"), ShouldBeNil)
+	})
+}
+
+func TestIPPinningRefresh(t *testing.T) {
+	_, office, _ := net.ParseCIDR("10.0.0.0/8")
+	mstore := NewStore(url, dbName, cName, WithIPPinning(IPPinning{Ranges: []*net.IPNet{office}}))
+	defer mstore.Close()
+	inside := WithClientIP(context.Background(), net.ParseIP("10.0.0.1"))
+	outside := WithClientIP(context.Background(), net.ParseIP("192.0.2.1"))
+
+	Convey("Test the refreshes of the sessions pinned to IP ranges", t, func() {
+		st, err := mstore.Create(inside, "test_ip_refresh", 10)
+		So(err, ShouldBeNil)
+		st.Set("user", "u1")
+		So(st.Save(), ShouldBeNil)
+
+		_, err = mstore.Refresh(outside, "test_ip_refresh", "test_ip_refresh_next", 10)
+		So(errors.Is(err, ErrIPNotAllowed), ShouldBeTrue)
+		ok, err := mstore.Check(inside, "test_ip_refresh")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		st, err = mstore.Refresh(inside, "test_ip_refresh", "test_ip_refresh_next", 10)
+		So(err, ShouldBeNil)
+		user, _ := st.Get("user")
+		So(user, ShouldEqual, "u1")
+		So(mstore.Delete(inside, "test_ip_refresh_next"), ShouldBeNil)
+	})
+}
+
+func TestSaveReplay(t *testing.T) {
+	mstore := NewStore(url, dbName, cName, WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+	defer mstore.Close()
+	ctx := context.Background()
+
+	Convey("Test the retries of the saves that went through", t, func() {
+		sess, err := mstore.Create(ctx, "test_save_replay", 10)
+		So(err, ShouldBeNil)
+		sess.Set("n", 1)
+		So(sess.Save(), ShouldBeNil)
+		So(sess.(SaveReporter).SaveOutcome(), ShouldEqual, SaveWritten)
+
+		// the reply of the save was lost
+		st := sess.(*store)
+		st.version--
+		written, err := st.resolveSave(ctx, st.saveID)
+		So(err, ShouldBeNil)
+		So(written, ShouldBeTrue)
+		So(st.SaveOutcome(), ShouldEqual, SaveReplayed)
+		So(st.version, ShouldEqual, 1)
+
+		other, err := mstore.Update(ctx, "test_save_replay", 10)
+		So(err, ShouldBeNil)
+		other.Set("n", 2)
+		So(other.Save(), ShouldBeNil)
+		_, err = st.resolveSave(ctx, "lost")
+		So(err, ShouldEqual, ErrConflict)
+		So(st.SaveOutcome(), ShouldEqual, SaveConflicted)
+		So(mstore.Delete(ctx, "test_save_replay"), ShouldBeNil)
+	})
+}
+
+func TestOldestSessionAge(t *testing.T) {
+	now := time.Now()
+	mstore := NewStore(url, dbName, cName, WithClock(ClockFunc(func() time.Time { return now })))
+	defer mstore.Close()
+	ctx := context.Background()
+
+	Convey("Test the age of the oldest session", t, func() {
+		sess, err := mstore.Create(ctx, "test_oldest", 3600)
+		So(err, ShouldBeNil)
+		So(sess.Save(), ShouldBeNil)
+		now = now.Add(time.Hour / 2)
+
+		age, err := mstore.(AgeReporter).OldestSessionAge(ctx)
+		So(err, ShouldBeNil)
+		So(age, ShouldBeGreaterThanOrEqualTo, time.Hour/2)
+
+		var alerts []AgeAlert
+		m := mstore.(*managerStore)
+		m.opts.ageBound = time.Minute
+		m.opts.ageAlert = func(a AgeAlert) { alerts = append(alerts, a) }
+		m.checkAge(ctx)
+		So(alerts, ShouldHaveLength, 1)
+		So(alerts[0].Bound, ShouldEqual, time.Minute)
+		So(mstore.Delete(ctx, "test_oldest"), ShouldBeNil)
+	})
+}
+
+func TestRelocate(t *testing.T) {
+	source := NewStore(url, dbName, cName)
+	defer source.Close()
+	target := NewStore(url, dbName, cName+"_relocated")
+	defer target.Close()
+	ctx := context.Background()
+
+	Convey("Test the relocation of the sessions to another collection", t, func() {
+		for _, sid := range []string{"test_relocate_1", "test_relocate_2"} {
+			sess, err := source.Create(ctx, sid, 10)
+			So(err, ShouldBeNil)
+			sess.Set("sid", sid)
+			So(sess.Save(), ShouldBeNil)
+		}
+		dual := NewDualStore(source, target, nil)
+		sess, err := dual.Update(ctx, "test_relocate_2", 10)
+		So(err, ShouldBeNil)
+		sess.Set("n", 1)
+		So(sess.Save(), ShouldBeNil)
+
+		var p MigrateProgress
+		p, err = source.(Relocator).Relocate(ctx, target, RelocateOptions{Batch: 1})
+		So(err, ShouldBeNil)
+		So(p.Migrated, ShouldBeGreaterThanOrEqualTo, 1)
+
+		report, err := dual.(decorator.Standby).Promote(ctx, decorator.PromoteOptions{})
+		So(err, ShouldBeNil)
+		So(report.Promoted, ShouldBeTrue)
+		sess, err = target.Update(ctx, "test_relocate_2", 10)
+		So(err, ShouldBeNil)
+		n, _ := sess.Get("n")
+		So(n, ShouldEqual, 1)
+
+		for _, sid := range []string{"test_relocate_1", "test_relocate_2"} {
+			So(dual.Delete(ctx, sid), ShouldBeNil)
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	cipher, err := NewAESGCMCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := map[string][]Option{
+		"json":      nil,
+		"documents": {WithDocumentValues(true)},
+		"encrypted": {WithCipher(cipher), WithCompression(GzipCompression, 0)},
+		"cached":    {WithCache(100, 500*time.Millisecond)},
+		// the suite calls with contexts which can be canceled, queueing the saves
+		"write-behind": {WithSaveCancelPolicy(SaveQueue, 0)},
+	}
+	for name, opts := range modes {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) session.ManagerStore {
+				return NewStoreWithOptions(url, append([]Option{WithDatabase(dbName), WithCollection(cName)}, opts...)...)
+			})
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package mongo
+
+import (
+	"reflect"
+)
+
+// Mutator Implemented by the session stores, to change many values at once
+type Mutator interface {
+	// Mutate Call fn with a copy of the values under the lock of the store and apply the
+	// changes it made unless it returns an error, which is returned; other goroutines never
+	// see part of the changes, and the next Save writes them all in a single update
+	Mutate(fn func(values map[string]interface{}) error) error
+}
+
+func (s *store) Mutate(fn func(values map[string]interface{}) error) error {
+	s.Lock()
+	s.materialize()
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	if err := fn(values); err != nil {
+		s.Unlock()
+		if s.trace != nil {
+			s.trace.record(1, "mutate", "", err)
+		}
+		return err
+	}
+
+	var refused []string
+	for k := range values {
+		if no, _ := s.mstore.opts.refuseKey(k); no {
+			delete(values, k)
+			refused = append(refused, k)
+		}
+	}
+	var changed []string
+	for k, v := range values {
+		if old, ok := s.values[k]; !ok || mutated(old, v) {
+			if err := s.mstore.opts.checkType(k, v); err != nil {
+				s.Unlock()
+				if s.mstore.opts.strictTypes == StrictTypesPanic {
+					panic("mongo: " + err.Error())
+				}
+				if s.trace != nil {
+					s.trace.record(1, "mutate", "", err)
+				}
+				return err
+			}
+			changed = append(changed, k)
+		}
+	}
+	for k := range s.values {
+		if _, ok := values[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	s.values = values
+	for _, k := range changed {
+		s.markDirty(k)
+	}
+	s.Unlock()
+
+	if s.diag != nil {
+		for _, k := range changed {
+			s.diag.dirty(k)
+		}
+	}
+	for _, k := range refused {
+		_, denied := s.mstore.opts.refuseKey(k)
+		s.mstore.keyViolated(s.sid, k, denied, false)
+	}
+	if s.trace != nil {
+		s.trace.record(1, "mutate", "", nil)
+	}
+	return nil
+}
+
+// mutated Tell whether the value may have changed, the maps and slices shared with
+// the copy given to Mutate may have been modified in place
+func mutated(old, v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+		return true
+	}
+	return !reflect.DeepEqual(old, v)
+}
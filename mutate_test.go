@@ -0,0 +1,50 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMutate(t *testing.T) {
+	mstore := newOfflineStore(t)
+
+	Convey("Test the changes of many values at once", t, func() {
+		store := newStore(context.Background(), mstore, "test_mutate", 10, map[string]interface{}{"a": 1, "b": "x", "c": true})
+
+		Convey("applied with the modified keys", func() {
+			err := store.Mutate(func(values map[string]interface{}) error {
+				values["a"] = 2
+				values["b"] = "x"
+				delete(values, "c")
+				values["d"] = []string{"y"}
+				return nil
+			})
+			So(err, ShouldBeNil)
+			a, _ := store.Get("a")
+			So(a, ShouldEqual, 2)
+			_, ok := store.Get("c")
+			So(ok, ShouldBeFalse)
+			So(store.dirty, ShouldHaveLength, 3)
+			So(store.dirty, ShouldContainKey, "d")
+			So(store.dirty, ShouldNotContainKey, "b")
+		})
+
+		Convey("discarded on error", func() {
+			errAbort := errors.New("abort")
+			err := store.Mutate(func(values map[string]interface{}) error {
+				values["a"] = 2
+				delete(values, "b")
+				return errAbort
+			})
+			So(err, ShouldEqual, errAbort)
+			a, _ := store.Get("a")
+			So(a, ShouldEqual, 1)
+			_, ok := store.Get("b")
+			So(ok, ShouldBeTrue)
+			So(store.dirty, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package mongo
+
+import (
+	"context"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// NamespaceStore A manager store scoped to one logical namespace (e.g. "web", "mobile", "admin")
+// of the physical collection, all namespaces share the connection of the store they come from
+type NamespaceStore interface {
+	session.ManagerStore
+	// Namespace Get a store scoped to the namespace name of the same collection
+	// (the empty name is the default namespace of documents written without one)
+	Namespace(name string) NamespaceStore
+	// Count the active sessions of the namespace
+	Count(ctx context.Context) (int64, error)
+	// DeleteAll Delete all sessions of the namespace
+	DeleteAll(ctx context.Context) error
+}
+
+func (s *managerStore) Namespace(name string) NamespaceStore {
+	return s.namespaceView(name)
+}
+
+func (s *managerStore) namespaceView(name string) *managerStore {
+	view := &managerStore{
+		client:    s.client,
+		c:         s.c,
+		cPrimary:  s.cPrimary,
+		affinity:  s.affinity,
+		locks:     s.locks,
+		chunks:    s.chunks,
+		remember:  s.remember,
+		archive:   s.archive,
+		scratch:   s.scratch,
+		opts:      s.opts,
+		namespace: name,
+		scoped:    true,
+	}
+	if s.anon != nil {
+		view.anon = s.anon.namespaceView(name)
+		view.anon.auth = view
+	}
+	return view
+}
+
+// WithTenants Isolate the sessions of the tenants sharing the collection: each operation
+// (Create, Update, Refresh, Delete, Check, Touch) is done in the namespace of the tenant that
+// resolve returns for its context, the default namespace if empty; with a nil resolve, the
+// tenant is the one given with WithTenant. The namespace stores are not routed
+func WithTenants(resolve func(ctx context.Context) string) Option {
+	return func(o *options) {
+		if resolve == nil {
+			resolve = tenantFromContext
+		}
+		o.tenant = resolve
+	}
+}
+
+// WithTenant Returns a context making the operations done with it use the namespace of tenant
+// with WithTenants
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return withCallOptions(ctx, func(o *callOptions) {
+		o.tenant = tenant
+	})
+}
+
+// tenantFromContext The tenant given with WithTenant
+func tenantFromContext(ctx context.Context) string {
+	return callOptionsFromContext(ctx).tenant
+}
+
+// tenantView The namespace view of the tenant of ctx, s itself for the default namespace
+func (s *managerStore) tenantView(ctx context.Context) *managerStore {
+	if s.opts.tenant == nil || s.scoped {
+		return s
+	}
+	if name := s.opts.tenant(ctx); name != "" {
+		return s.namespaceView(name)
+	}
+	return s
+}
+
+// docID The document id of sid within the namespace
+func (s *managerStore) docID(sid string) string {
+	if len(s.opts.idSecret) > 0 {
+		sid = s.opts.hashID(sid)
+	}
+	if s.namespace == "" {
+		return sid
+	}
+	return s.namespace + ":" + sid
+}
+
+// scope Query matching all documents visible to the store
+func (s *managerStore) scope() bson.M {
+	q := bson.M{}
+	if s.opts.owner != "" {
+		q["owner"] = s.opts.owner
+	}
+	if s.namespace != "" {
+		q["ns"] = s.namespace
+	} else {
+		q["ns"] = bson.M{"$exists": false}
+	}
+	return q
+}
+
+func (s *managerStore) Count(ctx context.Context) (int64, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	q := s.activeScope()
+	n, err := s.adminCollection().CountDocuments(dbctx, q)
+	if err != nil || s.anon == nil {
+		return n, err
+	}
+	m, err := s.anon.adminCollection().CountDocuments(dbctx, q)
+	return n + m, err
+}
+
+func (s *managerStore) DeleteAll(ctx context.Context) error {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	_, err := s.c.DeleteMany(dbctx, s.scope())
+	if err == nil && s.anon != nil {
+		_, err = s.anon.c.DeleteMany(dbctx, s.scope())
+	}
+	if err == nil && s.scratch != nil {
+		_, err = s.scratch.DeleteMany(dbctx, s.scope())
+	}
+	s.uncacheAll()
+	return err
+}
@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTenantRouting(t *testing.T) {
+	Convey("Test the routing of the operations to the tenants", t, func() {
+		acme := WithTenant(context.Background(), "acme")
+
+		Convey("from the context", func() {
+			mstore := newOfflineStore(t, WithTenants(nil))
+			So(mstore.tenantView(context.Background()), ShouldEqual, mstore)
+			view := mstore.tenantView(acme)
+			So(view.namespace, ShouldEqual, "acme")
+			So(view.docID("sid"), ShouldEqual, "acme:sid")
+			So(view.scope()["ns"], ShouldEqual, "acme")
+			So(view.tenantView(WithTenant(acme, "globex")), ShouldEqual, view)
+
+			st, err := mstore.Create(acme, "sid", 10)
+			So(err, ShouldBeNil)
+			So(st.(*store).mstore.namespace, ShouldEqual, "acme")
+		})
+
+		Convey("with a resolver", func() {
+			type hostKey struct{}
+			mstore := newOfflineStore(t, WithTenants(func(ctx context.Context) string {
+				host, _ := ctx.Value(hostKey{}).(string)
+				return host
+			}))
+			So(mstore.tenantView(acme), ShouldEqual, mstore)
+			So(mstore.tenantView(context.WithValue(acme, hostKey{}, "initech")).namespace, ShouldEqual, "initech")
+		})
+
+		Convey("unless scoped", func() {
+			view := newOfflineStore(t, WithTenants(nil)).namespaceView("")
+			So(view.tenantView(acme), ShouldEqual, view)
+			So(newOfflineStore(t).tenantView(acme).namespace, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,338 @@
+package mongo
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math/rand"
+	neturl "net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/v2/tag"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Default names of the session database and collection
+const (
+	DefaultDatabase   = "session"
+	DefaultCollection = "session"
+)
+
+// DefaultAppName Application name reported to the server by the clients of the stores
+// (shown in the server logs, currentOp and the profiler) unless set by the connection
+// string or WithAppName
+const DefaultAppName = "go-session-mongo"
+
+// driverName Name of the library reported in the driver metadata of the handshake
+const driverName = "go-session/mongo"
+
+// Option A mongo store parameter option
+type Option func(*options)
+
+type options struct {
+	dbName            string
+	cName             string
+	dialTimeout       time.Duration
+	poolLimit         uint64
+	writeConcern      *writeconcern.WriteConcern
+	readPref          *readpref.ReadPref
+	ttlIndex          bool
+	owner             string
+	traceOut          io.Writer
+	faults            []Fault
+	clock             Clock
+	rand              *lockedRand
+	timeout           time.Duration
+	codec             Codec
+	lazyDecode        bool
+	keyStats          *keyStats
+	documents         bool
+	noLock            bool
+	conflictPolicy    ConflictPolicy
+	affinity          []tag.Set
+	cipher            Cipher
+	nilCipher         bool
+	hedged            bool
+	compression       Compression
+	compressionMin    int
+	refreshTxn        bool
+	txn               *txnSupport
+	anonCollection    string
+	anonMaxAge        time.Duration
+	classifier        SessionClassifier
+	indexes           bool
+	ttlName           string
+	ttlExpireAfter    time.Duration
+	cleanupInterval   time.Duration
+	refreshGrace      time.Duration
+	expirySkew        time.Duration
+	expiredDeletion   bool
+	valueTypes        map[reflect.Type]ValueType
+	valueTypeNames    map[string]ValueType
+	strictTypes       StrictTypesMode
+	archiveRetention  time.Duration
+	scratch           bool
+	maxReadLag        time.Duration
+	capacity          *capacityLimiter
+	createGuard       func(ctx context.Context, req CreateRequest) CreateDecision
+	deferredIndexes   *deferredIndexes
+	sharding          bool
+	shardChunks       int
+	sidHasher         SIDHasher
+	waitInterval      time.Duration
+	expirationMode    ExpirationMode
+	maxLifetime       time.Duration
+	idempotentDelete  bool
+	appName           string
+	driverInfo        *mopts.DriverInfo
+	indexFields       []string
+	hot               *hotTracker
+	metrics           Metrics
+	tracer            trace.Tracer
+	logger            LogFunc
+	slowThreshold     time.Duration
+	cache             *itemCache
+	dualWriteUntil    time.Time
+	retry             RetryPolicy
+	opRetry           map[string]RetryPolicy
+	saveCancel        SaveCancelPolicy
+	detachTimeout     time.Duration
+	queued            *sync.WaitGroup
+	tlsConfig         *tls.Config
+	clientCerts       []tls.Certificate
+	username          string
+	password          string
+	authMechanism     string
+	authSource        string
+	warmup            int
+	lockTTL           time.Duration
+	lockWait          time.Duration
+	lazy              *lazySetup
+	idSecret          []byte
+	legacyIDs         bool
+	maxSize           int
+	oversize          OversizePolicy
+	spillThreshold    int
+	rememberMaxAge    time.Duration
+	fingerprints      bool
+	fingerprintPolicy FingerprintPolicy
+	ipPinning         *IPPinning
+	tenant            func(ctx context.Context) string
+	loginField        string
+	loginHook         func(ctx context.Context, event LoginEvent)
+	hooks             LifecycleHooks
+	cleanups          []func(ctx context.Context, c SessionCleanup)
+	hookCalls         *sync.WaitGroup
+	invalidation      bool
+	watchers          *sync.WaitGroup
+	env               string
+	ageBound          time.Duration
+	ageInterval       time.Duration
+	ageAlert          func(AgeAlert)
+	allowedKeys       []string
+	deniedKeys        []string
+	keyViolation      func(KeyViolation)
+	valueFilters      []ValueFilter
+	tuned             *tunedOptions
+	validateValues    ValueValidator
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		dbName:     DefaultDatabase,
+		cName:      DefaultCollection,
+		ttlIndex:   true,
+		refreshTxn: true,
+		indexes:    true,
+		txn:        &txnSupport{},
+		queued:     &sync.WaitGroup{},
+		hookCalls:  &sync.WaitGroup{},
+		watchers:   &sync.WaitGroup{},
+		tuned:      &tunedOptions{},
+		codec:      JSONCodec{},
+		clock:      ClockFunc(time.Now),
+		sidHasher:  SHA256SIDHasher,
+		rand:       newLockedRand(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.applyEnv()
+	return o
+}
+
+// nameOptions Options of the positional constructor arguments, empty names keep the defaults
+func nameOptions(dbName, cName string) []Option {
+	var opts []Option
+	if dbName != "" {
+		opts = append(opts, WithDatabase(dbName))
+	}
+	if cName != "" {
+		opts = append(opts, WithCollection(cName))
+	}
+	return opts
+}
+
+// uriDatabase The database in the path of a connection string, if any
+func uriDatabase(uri string) string {
+	if i := strings.Index(uri, "://"); i >= 0 {
+		uri = uri[i+3:]
+	}
+	i := strings.Index(uri, "/")
+	if i < 0 {
+		return ""
+	}
+	db := uri[i+1:]
+	if j := strings.Index(db, "?"); j >= 0 {
+		db = db[:j]
+	}
+	if unescaped, err := neturl.PathUnescape(db); err == nil {
+		db = unescaped
+	}
+	return db
+}
+
+// clientOptions Options of the client connecting to uri
+func (o *options) clientOptions(uri string) *mopts.ClientOptions {
+	opts := mopts.Client().ApplyURI(uri)
+	if o.appName != "" {
+		opts.SetAppName(o.appName)
+	} else if opts.AppName == nil {
+		opts.SetAppName(DefaultAppName)
+	}
+	if o.driverInfo != nil {
+		opts.SetDriverInfo(o.driverInfo)
+	} else {
+		opts.SetDriverInfo(&mopts.DriverInfo{Name: driverName})
+	}
+	if o.dialTimeout > 0 {
+		opts.SetConnectTimeout(o.dialTimeout)
+		opts.SetServerSelectionTimeout(o.dialTimeout)
+	}
+	if o.poolLimit > 0 {
+		opts.SetMaxPoolSize(o.poolLimit)
+	}
+	if n := uint64(o.warmup); n > 0 && (opts.MinPoolSize == nil || *opts.MinPoolSize < n) {
+		opts.SetMinPoolSize(n)
+	}
+	o.applyTLS(opts)
+	o.applyAuth(opts)
+	return opts
+}
+
+// collectionOptions Options of the session collection handle
+func (o *options) collectionOptions() *mopts.CollectionOptionsBuilder {
+	opts := mopts.Collection()
+	if o.writeConcern != nil {
+		opts.SetWriteConcern(o.writeConcern)
+	}
+	if rp := o.sessionReadPref(); rp != nil {
+		opts.SetReadPreference(rp)
+	}
+	return opts
+}
+
+// WithDatabase Set the name of the session database
+func WithDatabase(name string) Option {
+	return func(o *options) {
+		o.dbName = name
+	}
+}
+
+// WithCollection Set the name of the session collection
+func WithCollection(name string) Option {
+	return func(o *options) {
+		o.cName = name
+	}
+}
+
+// WithDialTimeout Set the timeout to connect and select a server
+// (only for the stores creating their client)
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithAppName Set the application name reported to the server, overriding the one of the
+// connection string (only for the stores creating their client)
+func WithAppName(name string) Option {
+	return func(o *options) {
+		o.appName = name
+	}
+}
+
+// WithDriverInfo Set the metadata of the library wrapping the driver sent in the connection
+// handshake, it describes the go-session/mongo library by default
+// (only for the stores creating their client)
+func WithDriverInfo(info *mopts.DriverInfo) Option {
+	return func(o *options) {
+		o.driverInfo = info
+	}
+}
+
+// WithPoolLimit Set the maximum number of connections of the pool
+// (only for the stores creating their client)
+func WithPoolLimit(limit uint64) Option {
+	return func(o *options) {
+		o.poolLimit = limit
+	}
+}
+
+// WithWriteConcern Set the write concern of the session writes of Save, Refresh, Delete
+// and the other operations (the client one by default), e.g. &writeconcern.WriteConcern{W: 1,
+// Journal: &journal}; the write timeout is the one of WithOperationTimeout and WithCallTimeout
+func WithWriteConcern(wc *writeconcern.WriteConcern) Option {
+	return func(o *options) {
+		o.writeConcern = wc
+	}
+}
+
+// WithReadPreference Set the read preference of the session reads of Get, Check and the other
+// lookups (the client one by default), e.g. readpref.Nearest(readpref.WithTags("zone", "a")),
+// the writes and the administrative reads going to the primary
+func WithReadPreference(rp *readpref.ReadPref) Option {
+	return func(o *options) {
+		o.readPref = rp
+	}
+}
+
+// WithTTLIndex Set whether to create the TTL index expiring the session documents
+// when the store is created (true by default)
+func WithTTLIndex(create bool) Option {
+	return func(o *options) {
+		o.ttlIndex = create
+	}
+}
+
+// WithOwner Set the owner stamped on every session document written by the store,
+// documents owned by someone else can't be loaded or overwritten
+// (useful when several services share one collection)
+func WithOwner(owner string) Option {
+	return func(o *options) {
+		o.owner = owner
+	}
+}
+
+// WithRefreshGrace Set how long after its expiration the values of a session are still
+// carried over to the new session by Refresh (0 by default: an expired session is always
+// refreshed into an empty one), the documents removed by the TTL index are gone anyway
+func WithRefreshGrace(window time.Duration) Option {
+	return func(o *options) {
+		o.refreshGrace = window
+	}
+}
+
+// WithIdempotentDelete Set whether Delete succeeds for the sessions without document
+// (e.g. already removed by the TTL monitor) instead of returning ErrNotFound
+func WithIdempotentDelete(idempotent bool) Option {
+	return func(o *options) {
+		o.idempotentDelete = idempotent
+	}
+}
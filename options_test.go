@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+)
+
+func TestOptions(t *testing.T) {
+	Convey("Test store options", t, func() {
+		Convey("database of the connection string", func() {
+			So(uriDatabase("mongodb://127.0.0.1:27017"), ShouldEqual, "")
+			So(uriDatabase("mongodb://u:p@h1:27017,h2:27017/app?replicaSet=rs0"), ShouldEqual, "app")
+			So(uriDatabase("mongodb+srv://cluster0.example.net/app"), ShouldEqual, "app")
+			So(uriDatabase("mongodb+srv://u:p@cluster0.example.net/?retryWrites=true"), ShouldEqual, "")
+			So(uriDatabase("mongodb://127.0.0.1/my%20app?tls=false"), ShouldEqual, "my app")
+		})
+
+		Convey("options of the connection string", func() {
+			o := newOptions()
+			co := o.clientOptions("mongodb://u:p@h1:27017,h2:27017/app?authSource=admin&replicaSet=rs0&tls=true&readPreference=secondary&w=majority&appName=web")
+			So(co.Validate(), ShouldBeNil)
+			So(co.Auth.AuthSource, ShouldEqual, "admin")
+			So(*co.ReplicaSet, ShouldEqual, "rs0")
+			So(co.TLSConfig, ShouldNotBeNil)
+			So(co.ReadPreference.Mode(), ShouldEqual, readpref.SecondaryMode)
+			So(co.WriteConcern.W, ShouldEqual, "majority")
+			So(*co.AppName, ShouldEqual, "web")
+		})
+
+		Convey("positional names and defaults", func() {
+			o := newOptions(nameOptions("", "")...)
+			So(o.dbName, ShouldEqual, DefaultDatabase)
+			So(o.cName, ShouldEqual, DefaultCollection)
+			So(o.ttlIndex, ShouldBeTrue)
+
+			o = newOptions(append(nameOptions("db", "c"), WithCollection("c2"), WithTTLIndex(false))...)
+			So(o.dbName, ShouldEqual, "db")
+			So(o.cName, ShouldEqual, "c2")
+			So(o.ttlIndex, ShouldBeFalse)
+		})
+
+		Convey("client and collection options", func() {
+			o := newOptions(WithDialTimeout(time.Second), WithPoolLimit(10), WithWriteConcern(writeconcern.Majority()))
+			co := o.clientOptions("mongodb://127.0.0.1:27017")
+			So(*co.ConnectTimeout, ShouldEqual, time.Second)
+			So(*co.ServerSelectionTimeout, ShouldEqual, time.Second)
+			So(*co.MaxPoolSize, ShouldEqual, 10)
+			So(co.MinPoolSize, ShouldBeNil)
+			So(o.collectionOptions(), ShouldNotBeNil)
+		})
+
+		Convey("read preference and write concern of the sessions", func() {
+			journal := true
+			wc := &writeconcern.WriteConcern{W: 1, Journal: &journal}
+			rp := readpref.Nearest(readpref.WithTags("zone", "a"))
+			o := newOptions(WithWriteConcern(wc), WithReadPreference(rp))
+
+			var co mopts.CollectionOptions
+			for _, set := range o.collectionOptions().List() {
+				So(set(&co), ShouldBeNil)
+			}
+			So(co.WriteConcern, ShouldEqual, wc)
+			So(co.ReadPreference, ShouldEqual, rp)
+
+			o = newOptions()
+			co = mopts.CollectionOptions{}
+			for _, set := range o.collectionOptions().List() {
+				So(set(&co), ShouldBeNil)
+			}
+			So(co.WriteConcern, ShouldBeNil)
+			So(co.ReadPreference, ShouldBeNil)
+		})
+
+		Convey("connection metadata", func() {
+			o := newOptions()
+			co := o.clientOptions("mongodb://127.0.0.1:27017")
+			So(*co.AppName, ShouldEqual, DefaultAppName)
+			So(co.DriverInfo.Name, ShouldEqual, "go-session/mongo")
+
+			co = o.clientOptions("mongodb://127.0.0.1:27017/?appName=billing")
+			So(*co.AppName, ShouldEqual, "billing")
+
+			o = newOptions(WithAppName("checkout"), WithDriverInfo(&mopts.DriverInfo{Name: "app", Version: "1.2"}))
+			co = o.clientOptions("mongodb://127.0.0.1:27017/?appName=billing")
+			So(*co.AppName, ShouldEqual, "checkout")
+			So(co.DriverInfo.Version, ShouldEqual, "1.2")
+		})
+
+		Convey("TLS and credentials", func() {
+			o := newOptions()
+			co := o.clientOptions("mongodb://127.0.0.1:27017")
+			So(co.TLSConfig, ShouldBeNil)
+			So(co.Auth, ShouldBeNil)
+
+			cfg := &tls.Config{ServerName: "db.example.net"}
+			o = newOptions(WithTLSConfig(cfg), WithClientCertificate(tls.Certificate{}), WithCredentials("app", "secret"))
+			co = o.clientOptions("mongodb://u:p@127.0.0.1:27017/app?authSource=users")
+			So(co.Validate(), ShouldBeNil)
+			So(co.TLSConfig.ServerName, ShouldEqual, "db.example.net")
+			So(co.TLSConfig.Certificates, ShouldHaveLength, 1)
+			So(cfg.Certificates, ShouldBeEmpty)
+			So(co.Auth.Username, ShouldEqual, "app")
+			So(co.Auth.Password, ShouldEqual, "secret")
+			So(co.Auth.AuthSource, ShouldEqual, "users")
+
+			o = newOptions(WithCredentials("app", "secret"), WithAuthMechanism(AuthSCRAMSHA256), WithAuthSource("admin"))
+			co = o.clientOptions("mongodb://127.0.0.1:27017")
+			So(co.Validate(), ShouldBeNil)
+			So(co.Auth.AuthMechanism, ShouldEqual, "SCRAM-SHA-256")
+			So(co.Auth.AuthSource, ShouldEqual, "admin")
+
+			o = newOptions(WithClientCertificate(tls.Certificate{}), WithAuthMechanism(AuthX509))
+			co = o.clientOptions("mongodb://127.0.0.1:27017")
+			So(co.Validate(), ShouldBeNil)
+			So(co.TLSConfig.MinVersion, ShouldEqual, tls.VersionTLS12)
+			So(co.Auth.AuthSource, ShouldEqual, "$external")
+		})
+
+		Convey("hedged reads", func() {
+			o := newOptions()
+			So(o.sessionReadPref(), ShouldBeNil)
+
+			o = newOptions(WithHedgedReads(true))
+			rp := o.sessionReadPref()
+			So(rp.Mode(), ShouldEqual, readpref.NearestMode)
+			So(*rp.HedgeEnabled(), ShouldBeTrue)
+
+			o = newOptions(WithHedgedReads(true), WithReadPreference(readpref.SecondaryPreferred(readpref.WithTags("zone", "a"))))
+			rp = o.sessionReadPref()
+			So(rp.Mode(), ShouldEqual, readpref.SecondaryPreferredMode)
+			So(rp.TagSets(), ShouldHaveLength, 1)
+			So(*rp.HedgeEnabled(), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package mongo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracerProvider Record an OpenTelemetry span for each operation of the stores (Check,
+// Create, Update, Refresh, Delete, Touch, Promote and Save) with tp, carrying the operation,
+// a hash of the session id and the collection, the spans of the database calls of the
+// operation are its children when the client is instrumented
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = nil
+		if tp != nil {
+			o.tracer = tp.Tracer(driverName)
+		}
+	}
+}
+
+// Attributes of the operation spans
+const (
+	attrOperation  = attribute.Key("session.operation")
+	attrSIDHash    = attribute.Key("session.id_hash")
+	attrNamespace  = attribute.Key("session.namespace")
+	attrSystem     = attribute.Key("db.system")
+	attrDatabase   = attribute.Key("db.name")
+	attrCollection = attribute.Key("db.mongodb.collection")
+)
+
+// SIDHasher Obfuscates the session ids in the logs, metrics, spans, errors and events of the
+// stores, identifying the sessions without revealing them
+type SIDHasher func(sid string) string
+
+// SHA256SIDHasher The default SIDHasher, the first 8 bytes of the SHA-256 of sid in hex
+func SHA256SIDHasher(sid string) string {
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:8])
+}
+
+// WithSIDHasher Set how the session ids are obfuscated in the logs, metrics, spans, errors and
+// events of the store (SHA256SIDHasher by default), e.g. with a keyed hash
+func WithSIDHasher(hasher SIDHasher) Option {
+	return func(o *options) {
+		if hasher == nil {
+			hasher = SHA256SIDHasher
+		}
+		o.sidHasher = hasher
+	}
+}
+
+// sidHash The obfuscated sid
+func (s *managerStore) sidHash(sid string) string {
+	return s.opts.sidHasher(sid)
+}
+
+// startSpan Start the span of op on the session sid (empty for many sessions)
+func (s *managerStore) startSpan(ctx context.Context, op, sid string) (context.Context, trace.Span) {
+	if s.opts.tracer == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs := []attribute.KeyValue{
+		attrOperation.String(op),
+		attrSystem.String("mongodb"),
+	}
+	if s.c != nil {
+		attrs = append(attrs, attrDatabase.String(s.c.Database().Name()), attrCollection.String(s.c.Name()))
+	}
+	if s.namespace != "" {
+		attrs = append(attrs, attrNamespace.String(s.namespace))
+	}
+	if s.opts.env != "" {
+		attrs = append(attrs, attrEnvironment.String(s.opts.env))
+	}
+	if sid != "" {
+		attrs = append(attrs, attrSIDHash.String(s.sidHash(sid)))
+	}
+	return s.opts.tracer.Start(ctx, "session."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan End the span of an operation that returned err
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,98 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// testSpan A span recording what the store reports
+type testSpan struct {
+	noop.Span
+	name   string
+	attrs  map[attribute.Key]string
+	status codes.Code
+	ended  bool
+}
+
+func (s *testSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+func (s *testSpan) End(...trace.SpanEndOption)          { s.ended = true }
+
+type testTracer struct {
+	noop.Tracer
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &testSpan{name: name, attrs: make(map[attribute.Key]string)}
+	config := trace.NewSpanStartConfig(opts...)
+	for _, kv := range config.Attributes() {
+		span.attrs[kv.Key] = kv.Value.Emit()
+	}
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type testTracerProvider struct {
+	noop.TracerProvider
+	tracer *testTracer
+}
+
+func (p testTracerProvider) Tracer(string, ...trace.TracerOption) trace.Tracer { return p.tracer }
+
+func TestTracing(t *testing.T) {
+	tracer := &testTracer{}
+	mstore := newOfflineStore(t, WithTracerProvider(testTracerProvider{tracer: tracer}), WithOperationTimeout(50*time.Millisecond))
+
+	Convey("Test the spans of the store operations", t, func() {
+		tracer.spans = nil
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, "test_tracing", 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldNotBeNil)
+
+		So(tracer.spans, ShouldHaveLength, 2)
+		create := tracer.spans[0]
+		So(create.name, ShouldEqual, "session.create")
+		So(create.attrs[attrSIDHash], ShouldEqual, SHA256SIDHasher("test_tracing"))
+		So(create.attrs[attrCollection], ShouldEqual, mstore.c.Name())
+		So(create.status, ShouldEqual, codes.Unset)
+		So(create.ended, ShouldBeTrue)
+
+		save := tracer.spans[1]
+		So(save.name, ShouldEqual, "session.save")
+		So(save.status, ShouldEqual, codes.Error)
+		So(save.ended, ShouldBeTrue)
+
+		So(SHA256SIDHasher("test_tracing"), ShouldNotContainSubstring, "test_tracing")
+	})
+}
+
+func TestSIDHasher(t *testing.T) {
+	Convey("Test the obfuscation of the session ids", t, func() {
+		hasher := WithSIDHasher(func(sid string) string { return "h:" + sid[len(sid)-1:] })
+		events := make(chan SessionEvent, 1)
+		mstore := newOfflineStore(t, hasher, WithFaultInjection(Fault{Op: OpDelete, Rate: 1, Err: mongo.CommandError{Code: 189}}),
+			WithLifecycleHooks(LifecycleHooks{OnCreate: func(_ context.Context, e SessionEvent) { events <- e }}))
+
+		err := mstore.Delete(context.Background(), "test_hasher")
+		var se *StoreError
+		So(errors.As(err, &se), ShouldBeTrue)
+		So(se.SIDHash, ShouldEqual, "h:r")
+
+		_, err = mstore.Create(context.Background(), "test_hasher", 10)
+		So(err, ShouldBeNil)
+		So((<-events).SIDHash, ShouldEqual, "h:r")
+
+		So(newOptions(WithSIDHasher(nil)).sidHasher("sid"), ShouldEqual, SHA256SIDHasher("sid"))
+	})
+}
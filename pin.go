@@ -0,0 +1,87 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type writePinsKey struct{}
+
+// writePins Session documents saved within one context
+type writePins struct {
+	sync.Mutex
+	items map[string]sessionItem
+}
+
+// WithReadAfterWrite Returns a context (typically the one of a request) remembering the sessions
+// saved with it, so that later loads of these sessions within the same context see the saved
+// state instead of what a cache or a lagging secondary may still return
+func WithReadAfterWrite(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, writePinsKey{}, &writePins{items: make(map[string]sessionItem)})
+}
+
+func writePinsFromContext(ctx context.Context) *writePins {
+	p, _ := contextValue(ctx, writePinsKey{}).(*writePins)
+	return p
+}
+
+// pinKey Identify the document of sid among all the stores that may share a context
+func (s *managerStore) pinKey(sid string) string {
+	return s.docKey(s.docID(sid))
+}
+
+// docKey Identify the document id among all the stores that may share a context
+func (s *managerStore) docKey(id string) string {
+	return s.c.Database().Name() + "." + s.c.Name() + "|" + s.opts.owner + "|" + id
+}
+
+// pinned Get the session document of sid saved with ctx
+func (s *managerStore) pinned(ctx context.Context, sid string) (sessionItem, bool) {
+	p := writePinsFromContext(ctx)
+	if p == nil {
+		return sessionItem{}, false
+	}
+	p.Lock()
+	item, ok := p.items[s.pinKey(sid)]
+	p.Unlock()
+	return item, ok
+}
+
+// pin Remember the session document of sid written with ctx
+func (s *managerStore) pin(ctx context.Context, sid string, item sessionItem) {
+	if p := writePinsFromContext(ctx); p != nil {
+		p.Lock()
+		p.items[s.pinKey(sid)] = item
+		p.Unlock()
+	}
+}
+
+// pinExpiration Update the expiration of the pinned document of sid, if any
+func (s *managerStore) pinExpiration(ctx context.Context, sid string, expiredAt time.Time) {
+	if p := writePinsFromContext(ctx); p != nil {
+		p.Lock()
+		if item, ok := p.items[s.pinKey(sid)]; ok {
+			item.ExpiredAt = expiredAt
+			p.items[s.pinKey(sid)] = item
+		}
+		p.Unlock()
+	}
+}
+
+// unpin Forget the document of sid, after it has been deleted
+func (s *managerStore) unpin(ctx context.Context, sid string) {
+	s.unpinID(ctx, s.docID(sid))
+}
+
+// unpinID Forget the document of id, after it has been deleted
+func (s *managerStore) unpinID(ctx context.Context, id string) {
+	if p := writePinsFromContext(ctx); p != nil {
+		p.Lock()
+		delete(p.items, s.docKey(id))
+		p.Unlock()
+	}
+}
@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReadAfterWrite(t *testing.T) {
+	mstore := newOfflineStore(t)
+	other := mstore.Namespace("other").(*managerStore)
+
+	Convey("Test saved sessions are pinned to the context", t, func() {
+		ctx := WithReadAfterWrite(context.Background())
+		value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+		So(err, ShouldBeNil)
+		mstore.pin(ctx, "test_pin", sessionItem{Value: value, ExpiredAt: time.Now().Add(time.Minute)})
+
+		item, err := mstore.getItem(ctx, "test_pin", true, 0)
+		So(err, ShouldBeNil)
+		values, err := mstore.decodeValues(item)
+		So(err, ShouldBeNil)
+		So(values["foo"], ShouldEqual, "bar")
+
+		_, ok := other.pinned(ctx, "test_pin")
+		So(ok, ShouldBeFalse)
+
+		mstore.pinExpiration(ctx, "test_pin", time.Now().Add(-time.Second))
+		exists, err := mstore.Check(ctx, "test_pin")
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		item, err = mstore.getItem(ctx, "test_pin", true, 0)
+		So(err, ShouldBeNil)
+		So(item, ShouldBeNil)
+		item, err = mstore.getItem(ctx, "test_pin", true, time.Minute)
+		So(err, ShouldBeNil)
+		So(item, ShouldNotBeNil)
+		So(newOptions(WithRefreshGrace(time.Minute)).refreshGrace, ShouldEqual, time.Minute)
+
+		mstore.unpin(ctx, "test_pin")
+		_, ok = mstore.pinned(ctx, "test_pin")
+		So(ok, ShouldBeFalse)
+	})
+}
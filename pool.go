@@ -0,0 +1,79 @@
+package mongo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Pools of the values reused across the encodings of the session values, the clients of
+// the driver pooling the connections themselves
+var (
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+	gzipReaders sync.Pool
+	buffers     = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+)
+
+// marshalJSON Serialize v as JSON into a pooled stream, calling fn with the data, which must
+// not be kept once it returns
+func marshalJSON(v interface{}, fn func(data []byte) (bson.RawValue, error)) (bson.RawValue, error) {
+	stream := jsoniter.ConfigDefault.BorrowStream(nil)
+	defer jsoniter.ConfigDefault.ReturnStream(stream)
+	stream.WriteVal(v)
+	if stream.Error != nil {
+		return bson.RawValue{}, stream.Error
+	}
+	return fn(stream.Buffer())
+}
+
+// stringValue The BSON string of data, built in a single allocation
+func stringValue(data []byte) bson.RawValue {
+	value := make([]byte, 4, len(data)+5)
+	binary.LittleEndian.PutUint32(value, uint32(len(data)+1))
+	value = append(value, data...)
+	return bson.RawValue{Type: bson.TypeString, Value: append(value, 0)}
+}
+
+// gzipCompress Compress data with a pooled gzip writer
+func gzipCompress(data []byte) ([]byte, error) {
+	buf := buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer buffers.Put(buf)
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// gzipDecompress Decompress data with a pooled gzip reader
+func gzipDecompress(data []byte) ([]byte, error) {
+	r, ok := gzipReaders.Get().(*gzip.Reader)
+	var err error
+	if ok {
+		err = r.Reset(bytes.NewReader(data))
+	} else {
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReaders.Put(r)
+
+	buf := buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer buffers.Put(buf)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
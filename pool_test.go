@@ -0,0 +1,30 @@
+package mongo
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPools(t *testing.T) {
+	Convey("Test the encodings with the pooled buffers", t, func() {
+		for _, s := range []string{"", `{"foo":"bar"}`, "é"} {
+			value, err := rawValue(s)
+			So(err, ShouldBeNil)
+			So(stringValue([]byte(s)), ShouldResemble, value)
+		}
+
+		data := bytes.Repeat([]byte("session"), 512)
+		for i := 0; i < 3; i++ {
+			compressed, err := gzipCompress(data)
+			So(err, ShouldBeNil)
+			So(len(compressed), ShouldBeLessThan, len(data))
+			plain, err := gzipDecompress(compressed)
+			So(err, ShouldBeNil)
+			So(plain, ShouldResemble, data)
+		}
+		_, err := gzipDecompress([]byte("not gzip"))
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"sync/atomic"
+)
+
+// WithMapPresizing Set whether to track the typical number of keys of the sessions of the
+// collection and to allocate the values maps at that size, which saves the rehashing of
+// the maps filled on load for workloads with consistently large sessions
+func WithMapPresizing(enabled bool) Option {
+	return func(o *options) {
+		o.keyStats = nil
+		if enabled {
+			o.keyStats = &keyStats{}
+		}
+	}
+}
+
+// keyStatsShift Weight (1/2^shift) of the last observation in the moving average
+const keyStatsShift = 3
+
+// keyStats Exponential moving average of the key counts of the sessions, in 1/256 keys
+type keyStats struct {
+	avg int64
+}
+
+// observe Account a session of n keys
+func (k *keyStats) observe(n int) {
+	for {
+		old := atomic.LoadInt64(&k.avg)
+		avg := old + (int64(n)<<8-old)>>keyStatsShift
+		if old == 0 {
+			avg = int64(n) << 8
+		}
+		if atomic.CompareAndSwapInt64(&k.avg, old, avg) {
+			return
+		}
+	}
+}
+
+// hint The size to allocate the values maps with
+func (k *keyStats) hint() int {
+	return int((atomic.LoadInt64(&k.avg) + 255) >> 8)
+}
+
+// newValues Allocate the values map of a session
+func (s *managerStore) newValues() map[string]interface{} {
+	if s.opts.keyStats == nil {
+		return make(map[string]interface{})
+	}
+	return make(map[string]interface{}, s.opts.keyStats.hint())
+}
+
+// observeKeys Account the key count of a loaded or saved session
+func (s *managerStore) observeKeys(n int) {
+	if s.opts.keyStats != nil {
+		s.opts.keyStats.observe(n)
+	}
+}
@@ -0,0 +1,45 @@
+package mongo
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMapPresizing(t *testing.T) {
+	Convey("Test values maps sized from the key counts of the sessions", t, func() {
+		mstore := &managerStore{opts: newOptions(WithMapPresizing(true))}
+		So(mstore.opts.keyStats.hint(), ShouldEqual, 0)
+
+		values := map[string]interface{}{}
+		for i := 0; i < 40; i++ {
+			values[string(rune('a'+i))] = i
+		}
+		value, err := mstore.encodeValues(values)
+		So(err, ShouldBeNil)
+		So(mstore.opts.keyStats.hint(), ShouldEqual, 0)
+
+		loaded, err := mstore.decodeValues(&sessionItem{Value: value})
+		So(err, ShouldBeNil)
+		So(loaded, ShouldHaveLength, 40)
+		So(mstore.opts.keyStats.hint(), ShouldEqual, 40)
+
+		for i := 0; i < 100; i++ {
+			mstore.observeKeys(10)
+		}
+		So(mstore.opts.keyStats.hint(), ShouldEqual, 10)
+
+		Convey("shared by the namespaces", func() {
+			ns := mstore.Namespace("ns").(*managerStore)
+			ns.observeKeys(1000)
+			So(mstore.opts.keyStats.hint(), ShouldBeGreaterThan, 10)
+		})
+
+		Convey("disabled", func() {
+			mstore := &managerStore{opts: newOptions()}
+			mstore.observeKeys(10)
+			So(mstore.opts.keyStats, ShouldBeNil)
+			So(mstore.newValues(), ShouldBeEmpty)
+		})
+	})
+}
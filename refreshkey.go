@@ -0,0 +1,26 @@
+package mongo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// refreshKey The idempotency key of the move of oldsid to sid, recorded in the document of sid
+// so that a Refresh retried after an ambiguous failure (the first attempt went through but its
+// reply was lost) finds the session it moved instead of restarting it
+func (s *managerStore) refreshKey(oldsid, sid string) string {
+	sum := sha256.Sum256([]byte(s.docID(oldsid) + "\x00" + s.docID(sid)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// replayedRefresh The store of sid if its document was moved from oldsid by an earlier
+// attempt of the refresh, nil if there is none
+func (s *managerStore) replayedRefresh(ctx, dbctx context.Context, oldsid, sid string, expired int64) (*store, error) {
+	m, item, err := s.locate(withoutCache(dbctx), sid, true, 0)
+	if err != nil || item == nil || item.RefreshKey != s.refreshKey(oldsid, sid) {
+		return nil, err
+	}
+	s.log(LevelDebug, "session refresh replayed", "sid_hash", s.sidHash(sid))
+	return newLoadedStore(m, item, newStore(ctx, m, sid, expired, nil))
+}
@@ -0,0 +1,18 @@
+package mongo
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRefreshKey(t *testing.T) {
+	Convey("Test the idempotency keys of the refreshes", t, func() {
+		mstore := newOfflineStore(t)
+		key := mstore.refreshKey("test_old", "test_new")
+		So(key, ShouldHaveLength, 32)
+		So(mstore.refreshKey("test_old", "test_new"), ShouldEqual, key)
+		So(mstore.refreshKey("test_new", "test_old"), ShouldNotEqual, key)
+		So(mstore.namespaceView("tenant").refreshKey("test_old", "test_new"), ShouldNotEqual, key)
+	})
+}
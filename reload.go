@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// TunablesLoader Read the tunables to apply with UpdateOptions, e.g. from a configuration
+// service
+type TunablesLoader func() (Tunables, error)
+
+// tunablesConfig The tunables of a configuration file
+type tunablesConfig struct {
+	CacheSize      *int    `json:"cache_size"`
+	SlowThreshold  *string `json:"slow_threshold"`
+	RetryAttempts  *int    `json:"retry_attempts"`
+	ReadPreference *string `json:"read_preference"`
+}
+
+// TunablesFile Load the tunables from the JSON file at path, e.g. written by the configuration
+// management: {"cache_size": 5000, "slow_threshold": "500ms", "retry_attempts": 1,
+// "read_preference": "secondaryPreferred"}, the missing keys leaving the tunables as they are
+// (removing a key doesn't restore the option of the creation)
+func TunablesFile(path string) TunablesLoader {
+	return func() (Tunables, error) {
+		var t Tunables
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return t, err
+		}
+		var c tunablesConfig
+		if err := json.Unmarshal(data, &c); err != nil {
+			return t, fmt.Errorf("%s: %w", path, err)
+		}
+		t.CacheSize, t.RetryAttempts = c.CacheSize, c.RetryAttempts
+		if c.SlowThreshold != nil {
+			d, err := time.ParseDuration(*c.SlowThreshold)
+			if err != nil {
+				return t, fmt.Errorf("%s: slow_threshold: %w", path, err)
+			}
+			t.SlowThreshold = &d
+		}
+		if c.ReadPreference != nil {
+			mode, err := readpref.ModeFromString(*c.ReadPreference)
+			if err != nil {
+				return t, fmt.Errorf("%s: read_preference: %w", path, err)
+			}
+			if t.ReadPreference, err = readpref.New(mode); err != nil {
+				return t, fmt.Errorf("%s: read_preference: %w", path, err)
+			}
+		}
+		return t, nil
+	}
+}
+
+// reloadTunables Load the tunables and apply them to r
+func reloadTunables(r Reconfigurer, load TunablesLoader) error {
+	t, err := load()
+	if err != nil {
+		return fmt.Errorf("load the tunables: %w", err)
+	}
+	return r.UpdateOptions(t)
+}
+
+// ReloadOptions Apply the tunables of load to r, then again on each of the signals (SIGHUP if
+// none) until ctx is done, for the fleets configured by files rather than by redeploys; the
+// error is the one of the first reload, the watch isn't started if it fails, and the errors
+// of the later reloads, which leave the tunables as they were, are passed to report (if not
+// nil)
+func ReloadOptions(ctx context.Context, r Reconfigurer, load TunablesLoader, report func(error), signals ...os.Signal) error {
+	if err := reloadTunables(r, load); err != nil {
+		return err
+	}
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				if err := reloadTunables(r, load); err != nil && report != nil {
+					report(err)
+				}
+			}
+		}
+	}()
+	return nil
+}
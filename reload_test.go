@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func TestReloadOptions(t *testing.T) {
+	Convey("Test the reload of the tunables from a file", t, func() {
+		path := filepath.Join(t.TempDir(), "tunables.json")
+		write := func(config string) {
+			if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write(`{"slow_threshold": "250ms", "retry_attempts": 2, "read_preference": "secondaryPreferred"}`)
+		tunables, err := TunablesFile(path)()
+		So(err, ShouldBeNil)
+		So(*tunables.SlowThreshold, ShouldEqual, 250*time.Millisecond)
+		So(*tunables.RetryAttempts, ShouldEqual, 2)
+		So(tunables.ReadPreference.Mode(), ShouldEqual, readpref.SecondaryPreferredMode)
+		So(tunables.CacheSize, ShouldBeNil)
+
+		write(`{"slow_threshold": "soon"}`)
+		_, err = TunablesFile(path)()
+		So(err, ShouldNotBeNil)
+
+		Convey("The tunables are applied at the start and on the signals", func() {
+			mstore := newOfflineStore(t)
+			write(`{"retry_attempts": 2}`)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			reported := make(chan error, 1)
+			So(ReloadOptions(ctx, mstore, TunablesFile(path), func(err error) { reported <- err }, syscall.SIGHUP), ShouldBeNil)
+			So(mstore.opts.retryPolicy(OpSave).MaxAttempts, ShouldEqual, 2)
+
+			p, _ := os.FindProcess(os.Getpid())
+			write(`{"retry_attempts": 50}`)
+			So(p.Signal(syscall.SIGHUP), ShouldBeNil)
+			select {
+			case err := <-reported:
+				So(err, ShouldNotBeNil)
+			case <-time.After(5 * time.Second):
+				t.Fatal("no reload reported")
+			}
+			So(mstore.opts.retryPolicy(OpSave).MaxAttempts, ShouldEqual, 2)
+
+			write(`{"retry_attempts": 3}`)
+			So(p.Signal(syscall.SIGHUP), ShouldBeNil)
+			deadline := time.Now().Add(5 * time.Second)
+			for mstore.opts.retryPolicy(OpSave).MaxAttempts != 3 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(mstore.opts.retryPolicy(OpSave).MaxAttempts, ShouldEqual, 3)
+		})
+
+		Convey("The watch isn't started when the first reload fails", func() {
+			mstore := newOfflineStore(t)
+			So(ReloadOptions(context.Background(), mstore, TunablesFile(filepath.Join(t.TempDir(), "missing.json")), nil), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,165 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// RelocateOptions The options of a relocation
+type RelocateOptions struct {
+	// Batch The number of documents written per batch (DefaultBackfillBatch if not positive)
+	Batch int
+	// Resume The checkpoint to resume a relocation from, the start if zero
+	Resume MigrateCheckpoint
+	// Progress Called after each batch with the progress so far, Migrated counting the
+	// sessions copied
+	Progress func(MigrateProgress)
+}
+
+// Relocator Implemented by the stores, to move their sessions to another collection or
+// database without logging the users out
+type Relocator interface {
+	// Relocate Copy the active sessions of all the namespaces and owners to the collections of
+	// target, a mongo store configured like the source one but for its names, in the order of
+	// their ids and batch documents at a time; the sessions target already has are kept as
+	// they are. The move goes: serve the sessions from NewDualStore(source, target), which
+	// writes them to both stores, relocate the existing ones, then cut over with the Promote
+	// of decorator.Standby, which verifies target and flips the reads to it, the source store
+	// still getting the writes until it's dropped. The scratch entries, archives and
+	// remember-me tokens are not copied
+	Relocate(ctx context.Context, target session.ManagerStore, opts RelocateOptions) (MigrateProgress, error)
+}
+
+func (s *managerStore) Relocate(ctx context.Context, target session.ManagerStore, opts RelocateOptions) (MigrateProgress, error) {
+	var p MigrateProgress
+	t, ok := target.(*managerStore)
+	if !ok {
+		return p, fmt.Errorf("relocation target %T is not a mongo store", target)
+	}
+	s, t = s.root(), t.root()
+	if s.anon != nil && t.anon == nil {
+		return p, errors.New("relocation target has no anonymous collection")
+	}
+	if t.collectionName() == s.collectionName() && t.c.Database().Name() == s.c.Database().Name() {
+		return p, errors.New("relocation target is the source collection")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = DefaultBackfillBatch
+	}
+	if err := s.ready(ctx); err != nil {
+		return p, err
+	}
+	if err := t.ready(ctx); err != nil {
+		return p, err
+	}
+
+	pairs := [][2]*managerStore{{s, t}}
+	if s.anon != nil {
+		pairs = append(pairs, [2]*managerStore{s.anon, t.anon})
+	}
+	if opts.Resume.Collection != "" {
+		for i, pair := range pairs {
+			if pair[0].collectionName() == opts.Resume.Collection {
+				pairs = pairs[i:]
+				break
+			}
+		}
+	}
+	for _, pair := range pairs {
+		after := ""
+		if pair[0].collectionName() == opts.Resume.Collection {
+			after = opts.Resume.LastID
+		}
+		if err := pair[0].relocate(ctx, pair[1], opts, after, &p); err != nil {
+			return p, err
+		}
+	}
+	t.uncacheAll()
+	return p, nil
+}
+
+// relocate Copy the documents of the collection of s after the id after to the one of t
+func (s *managerStore) relocate(ctx context.Context, t *managerStore, opts RelocateOptions, after string, p *MigrateProgress) error {
+	q := bson.M{"expired_at": bson.M{"$gt": s.notBefore(0)}}
+	if after != "" {
+		q["_id"] = bson.M{"$gt": after}
+	}
+	cur, err := s.cPrimary.Find(ctx, q, mopts.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(int32(opts.Batch)))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	p.Checkpoint = MigrateCheckpoint{Collection: s.collectionName(), LastID: after}
+	var last string
+	docs := make([]interface{}, 0, opts.Batch)
+	flush := func() error {
+		if len(docs) > 0 {
+			// the sessions target already has were written since by the dual writes
+			_, err := t.c.InsertMany(ctx, docs, mopts.InsertMany().SetOrdered(false))
+			copied := len(docs)
+			docs = docs[:0]
+			var bwe mongo.BulkWriteException
+			if errors.As(err, &bwe) && bwe.WriteConcernError == nil && onlyDuplicates(bwe) {
+				copied -= len(bwe.WriteErrors)
+				err = nil
+			}
+			if err != nil {
+				return err
+			}
+			p.Migrated += int64(copied)
+		}
+		if last != "" {
+			p.Checkpoint.LastID = last
+		}
+		if opts.Progress != nil {
+			opts.Progress(*p)
+		}
+		return nil
+	}
+
+	for cur.Next(ctx) {
+		var item sessionItem
+		if err := cur.Decode(&item); err != nil {
+			return err
+		}
+		p.Scanned++
+		last = item.ID
+		if err := s.unspill(ctx, &item); err != nil {
+			return err
+		}
+		stored, err := t.spill(ctx, &item)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, stored)
+		if len(docs) >= opts.Batch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// onlyDuplicates Tell whether the write errors of e are all duplicate keys
+func onlyDuplicates(e mongo.BulkWriteException) bool {
+	for _, we := range e.WriteErrors {
+		if we.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
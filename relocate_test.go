@@ -0,0 +1,27 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRelocateTargets(t *testing.T) {
+	Convey("Test the targets of the relocations", t, func() {
+		ctx := context.Background()
+		mstore := newOfflineStore(t)
+
+		_, err := mstore.Relocate(ctx, NewMemoryStore(), RelocateOptions{})
+		So(err, ShouldNotBeNil)
+		_, err = mstore.Relocate(ctx, newOfflineStore(t), RelocateOptions{})
+		So(err, ShouldNotBeNil)
+
+		anon := newOfflineStore(t)
+		anon.anon = newOfflineStore(t, WithCollection("session_anon"))
+		other := newOfflineStore(t)
+		other.c = other.c.Database().Collection("session_next")
+		_, err = anon.Relocate(ctx, other, RelocateOptions{})
+		So(err, ShouldNotBeNil)
+	})
+}
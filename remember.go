@@ -0,0 +1,213 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	session "github.com/go-session/session/v3"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// ErrRememberDisabled The remember-me tokens are not enabled with WithRememberMe
+var ErrRememberDisabled = errors.New("remember-me tokens are not enabled")
+
+// ErrInvalidRememberToken The remember-me token is unknown, expired or forged
+var ErrInvalidRememberToken = errors.New("invalid remember-me token")
+
+// Sizes in bytes of the random parts of the remember-me tokens
+const (
+	rememberSelectorSize  = 12
+	rememberValidatorSize = 32
+)
+
+// WithRememberMe Enable the remember-me tokens of Rememberer, valid for maxAge, stored in the
+// collection named after the session one with a "_remember" suffix
+func WithRememberMe(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.rememberMaxAge = maxAge
+	}
+}
+
+// Rememberer Implemented by the stores, to log the users back in with long-lived tokens
+// (e.g. a "remember me" cookie) once their session has expired; a token is a selector,
+// identifying its document, and a validator, of which only a hash is stored
+type Rememberer interface {
+	// Remember Issue a remember-me token keeping the values of keys (all of them if none)
+	// of the session sid, the error wraps ErrNotFound if there is no such session
+	Remember(ctx context.Context, sid string, keys ...string) (string, error)
+	// Recall Validate the token, create the session sid with the values it keeps, and return
+	// the session with the token replacing the validated one, which can't be used again;
+	// a known selector with a wrong validator revokes the token, which may have been stolen
+	Recall(ctx context.Context, token, sid string, expired int64) (session.Store, string, error)
+	// Forget Revoke the token
+	Forget(ctx context.Context, token string) error
+}
+
+// rememberDoc A remember-me token
+type rememberDoc struct {
+	ID        string        `bson:"_id"`
+	Validator []byte        `bson:"validator"`
+	Value     bson.RawValue `bson:"value"`
+	Owner     string        `bson:"owner,omitempty"`
+	Namespace string        `bson:"ns,omitempty"`
+	ExpiredAt time.Time     `bson:"expired_at"`
+}
+
+// rememberCollection The collection of the remember-me tokens of the session collection c
+func (o *options) rememberCollection(c *mongo.Collection) *mongo.Collection {
+	if o.rememberMaxAge <= 0 {
+		return nil
+	}
+	return c.Database().Collection(c.Name()+"_remember", o.collectionOptions().SetReadPreference(readpref.Primary()))
+}
+
+// rememberIndexModels The indexes to create on the remember-me collection
+func rememberIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "expired_at", Value: 1}},
+		Options: mopts.Index().SetExpireAfterSeconds(1),
+	}}
+}
+
+// parseRememberToken Split token into its selector and the hash of its validator
+func parseRememberToken(token string) (string, []byte, bool) {
+	i := strings.IndexByte(token, '.')
+	if i <= 0 {
+		return "", nil, false
+	}
+	validator, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || len(validator) != rememberValidatorSize {
+		return "", nil, false
+	}
+	hash := sha256.Sum256(validator)
+	return token[:i], hash[:], true
+}
+
+// issueRemember Store a new token keeping value until expiredAt and return it
+func (s *managerStore) issueRemember(ctx context.Context, value bson.RawValue, expiredAt time.Time) (string, error) {
+	b := make([]byte, rememberSelectorSize+rememberValidatorSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	selector := base64.RawURLEncoding.EncodeToString(b[:rememberSelectorSize])
+	validator := b[rememberSelectorSize:]
+	hash := sha256.Sum256(validator)
+
+	_, err := s.root().remember.InsertOne(ctx, &rememberDoc{
+		ID:        selector,
+		Validator: hash[:],
+		Value:     value,
+		Owner:     s.opts.owner,
+		Namespace: s.namespace,
+		ExpiredAt: expiredAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return selector + "." + base64.RawURLEncoding.EncodeToString(validator), nil
+}
+
+func (s *managerStore) Remember(ctx context.Context, sid string, keys ...string) (string, error) {
+	if s.root().remember == nil {
+		return "", ErrRememberDisabled
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	_, item, err := s.root().locate(dbctx, sid, true, 0)
+	if err != nil {
+		return "", err
+	} else if item == nil {
+		return "", ErrNotFound
+	}
+	values, err := s.decodeValues(item)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) > 0 {
+		kept := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			if v, ok := values[key]; ok {
+				kept[key] = v
+			}
+		}
+		values = kept
+	}
+	value, err := s.encodeValues(values)
+	if err != nil {
+		return "", err
+	}
+	return s.issueRemember(dbctx, value, s.now().Add(s.opts.rememberMaxAge))
+}
+
+func (s *managerStore) Recall(ctx context.Context, token, sid string, expired int64) (session.Store, string, error) {
+	remember := s.root().remember
+	if remember == nil {
+		return nil, "", ErrRememberDisabled
+	}
+	selector, hash, ok := parseRememberToken(token)
+	if !ok {
+		return nil, "", ErrInvalidRememberToken
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	q := s.scope()
+	q["_id"] = selector
+	var doc rememberDoc
+	if err := remember.FindOneAndDelete(dbctx, q).Decode(&doc); err == mongo.ErrNoDocuments {
+		return nil, "", ErrInvalidRememberToken
+	} else if err != nil {
+		return nil, "", err
+	}
+	if subtle.ConstantTimeCompare(doc.Validator, hash) != 1 {
+		s.log(LevelWarn, "remember-me token revoked after a wrong validator", "selector", selector)
+		return nil, "", ErrInvalidRememberToken
+	} else if !doc.ExpiredAt.After(s.now()) {
+		return nil, "", ErrInvalidRememberToken
+	}
+
+	values, err := s.decodeValues(&sessionItem{Value: doc.Value})
+	if err != nil {
+		return nil, "", err
+	}
+	st := newStore(ctx, s, sid, expired, values)
+	for key := range values {
+		st.markDirty(key)
+	}
+	if err := st.Save(); err != nil {
+		return nil, "", err
+	}
+	next, err := s.issueRemember(dbctx, doc.Value, doc.ExpiredAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return st, next, nil
+}
+
+func (s *managerStore) Forget(ctx context.Context, token string) error {
+	remember := s.root().remember
+	if remember == nil {
+		return ErrRememberDisabled
+	}
+	selector, _, ok := parseRememberToken(token)
+	if !ok {
+		return ErrInvalidRememberToken
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	q := s.scope()
+	q["_id"] = selector
+	_, err := remember.DeleteOne(dbctx, q)
+	return err
+}
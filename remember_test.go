@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRememberTokens(t *testing.T) {
+	Convey("Test the remember-me tokens", t, func() {
+		Convey("disabled by default", func() {
+			mstore := newOfflineStore(t)
+			So(mstore.opts.rememberCollection(mstore.c), ShouldBeNil)
+			_, err := mstore.Remember(context.Background(), "sid")
+			So(err, ShouldEqual, ErrRememberDisabled)
+			_, _, err = mstore.Recall(context.Background(), "a.b", "sid", 10)
+			So(err, ShouldEqual, ErrRememberDisabled)
+			So(mstore.Forget(context.Background(), "a.b"), ShouldEqual, ErrRememberDisabled)
+		})
+
+		Convey("token format", func() {
+			validator := strings.Repeat("A", 43)
+			selector, hash, ok := parseRememberToken("sel." + validator)
+			So(ok, ShouldBeTrue)
+			So(selector, ShouldEqual, "sel")
+			So(hash, ShouldHaveLength, 32)
+
+			for _, token := range []string{"", "sel", ".", "." + validator, "sel.short", "sel.!" + validator[1:]} {
+				_, _, ok := parseRememberToken(token)
+				So(ok, ShouldBeFalse)
+			}
+
+			mstore := newOfflineStore(t, WithRememberMe(time.Hour))
+			mstore.remember = mstore.opts.rememberCollection(mstore.c)
+			So(mstore.remember.Name(), ShouldEqual, cName+"_remember")
+			_, _, err := mstore.Recall(context.Background(), "forged", "sid", 10)
+			So(err, ShouldEqual, ErrInvalidRememberToken)
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	session "github.com/go-session/session/v3"
+
+	"github.com/go-session/mongo/v3/decorator"
+)
+
+// RetryPolicy The retries of the session operations failing with a transient error, the
+// policy of decorator.Retry: the zero values aren't defaulted (a MaxAttempts of 0 or 1
+// disables the retries) and Ops is ignored, WithOperationRetryPolicy picking the policy of
+// each operation
+type RetryPolicy = decorator.RetryPolicy
+
+// WithRetryPolicy Retry the session operations (Check, Update, Refresh, Delete, Touch,
+// Promote, CheckMulti and Save) failing with a transient error following policy, within
+// the operation timeout and the deadline of the context: the backoffs are shortened, and the
+// retries given up, so that another attempt as long as the last one still ends in time. The
+// operations are not retried by default
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithOperationRetryPolicy Retry the operation op (OpRefresh, OpSave, OpCheck...) following
+// policy instead of the one of WithRetryPolicy, e.g. to retry the reads eagerly but not
+// Refresh, which is not idempotent: a retried Refresh whose first attempt went through
+// finds its old session gone
+func WithOperationRetryPolicy(op string, policy RetryPolicy) Option {
+	return func(o *options) {
+		if o.opRetry == nil {
+			o.opRetry = make(map[string]RetryPolicy)
+		}
+		o.opRetry[op] = policy
+	}
+}
+
+// retryPolicy The retry policy of the operation op
+func (o *options) retryPolicy(op string) RetryPolicy {
+	p, ok := o.opRetry[op]
+	if !ok {
+		p = o.retry
+	}
+	if n := o.tuned.retryAttempts.Load(); n > 0 {
+		p.MaxAttempts = int(n)
+	}
+	return p
+}
+
+// IsTransientError Whether err is a network error or a server error due to a replica set
+// state change, that an immediate retry may not hit (decorator.IsTransientError)
+func IsTransientError(err error) bool {
+	return decorator.IsTransientError(err)
+}
+
+// backoff The delay before the retry of p following attempt (from 1)
+func (s *managerStore) backoff(p RetryPolicy, attempt int) time.Duration {
+	return p.Backoff(attempt, s.opts.rand.Int63n)
+}
+
+// retry Call fn of the operation op following its policy (RetryPolicy.Do), logging the retries
+func (s *managerStore) retry(ctx context.Context, op string, fn func() error) error {
+	return s.opts.retryPolicy(op).Do(ctx, s.opts.rand.Int63n, func(n decorator.RetryNotice) {
+		if n.GivenUp {
+			s.log(LevelWarn, "session operation not retried, its deadline is too close", "op", op, "attempt", n.Attempt, "error", n.Err)
+			return
+		}
+		s.log(LevelWarn, "retrying the session operation", "op", op, "attempt", n.Attempt+1, "backoff", n.Backoff, "error", n.Err)
+	}, fn)
+}
+
+// retryLoad Load a session with fn of the operation op like retry
+func (s *managerStore) retryLoad(ctx context.Context, op string, fn func() (*store, error)) (session.Store, error) {
+	var st *store
+	err := s.retry(ctx, op, func() (err error) {
+		st, err = fn()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("Test the retries of the transient failures", t, func() {
+		policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 3 * time.Millisecond}
+		mstore := &managerStore{opts: newOptions(WithRetryPolicy(policy))}
+		transient := mongo.CommandError{Code: 10107, Name: "NotWritablePrimary"}
+
+		calls := 0
+		err := mstore.retry(context.Background(), OpCheck, func() error {
+			if calls++; calls < 3 {
+				return transient
+			}
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(calls, ShouldEqual, 3)
+
+		Convey("attempts exhausted", func() {
+			calls := 0
+			err := mstore.retry(context.Background(), OpCheck, func() error {
+				calls++
+				return transient
+			})
+			So(err, ShouldResemble, transient)
+			So(calls, ShouldEqual, 3)
+		})
+
+		Convey("errors not retryable", func() {
+			calls := 0
+			err := mstore.retry(context.Background(), OpCheck, func() error {
+				calls++
+				return ErrConflict
+			})
+			So(err, ShouldEqual, ErrConflict)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("within the deadline", func() {
+			mstore := &managerStore{opts: newOptions(WithRetryPolicy(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}))}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			calls := 0
+			err := mstore.retry(ctx, OpCheck, func() error {
+				if calls++; calls == 1 {
+					return transient
+				}
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(calls, ShouldEqual, 2)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+
+			// no room for another attempt as long as the first one
+			ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			calls = 0
+			err = mstore.retry(ctx, OpCheck, func() error {
+				calls++
+				time.Sleep(30 * time.Millisecond)
+				return transient
+			})
+			So(err, ShouldResemble, transient)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("off by default", func() {
+			mstore := &managerStore{opts: newOptions()}
+			calls := 0
+			_ = mstore.retry(context.Background(), OpCheck, func() error {
+				calls++
+				return transient
+			})
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("per operation", func() {
+			mstore := &managerStore{opts: newOptions(WithRetryPolicy(policy), WithOperationRetryPolicy(OpRefresh, RetryPolicy{MaxAttempts: 1}))}
+			calls := 0
+			_ = mstore.retry(context.Background(), OpRefresh, func() error {
+				calls++
+				return transient
+			})
+			So(calls, ShouldEqual, 1)
+			calls = 0
+			_ = mstore.retry(context.Background(), OpCheck, func() error {
+				calls++
+				return transient
+			})
+			So(calls, ShouldEqual, 3)
+		})
+
+		Convey("exponential backoff with jitter", func() {
+			So(mstore.backoff(mstore.opts.retry, 1), ShouldEqual, time.Millisecond)
+			So(mstore.backoff(mstore.opts.retry, 2), ShouldEqual, 2*time.Millisecond)
+			So(mstore.backoff(mstore.opts.retry, 5), ShouldEqual, 3*time.Millisecond)
+
+			policy.Jitter = 0.5
+			mstore := &managerStore{opts: newOptions(WithRetryPolicy(policy), WithRandSource(rand.NewSource(1)))}
+			for i := 0; i < 10; i++ {
+				d := mstore.backoff(mstore.opts.retry, 2)
+				So(d, ShouldBeGreaterThanOrEqualTo, time.Millisecond)
+				So(d, ShouldBeLessThanOrEqualTo, 2*time.Millisecond)
+			}
+		})
+	})
+
+	Convey("Test the transient errors", t, func() {
+		So(IsTransientError(mongo.CommandError{Code: 189}), ShouldBeTrue)
+		So(IsTransientError(mongo.CommandError{Code: 2}), ShouldBeFalse)
+		So(IsTransientError(mongo.CommandError{Labels: []string{"RetryableWriteError"}}), ShouldBeTrue)
+		So(IsTransientError(context.DeadlineExceeded), ShouldBeFalse)
+		So(IsTransientError(errors.New("boom")), ShouldBeFalse)
+		So(IsTransientError(nil), ShouldBeFalse)
+	})
+}
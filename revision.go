@@ -0,0 +1,17 @@
+package mongo
+
+import "strconv"
+
+// Revisioner Implemented by the session stores, to tell the saved states of a session apart,
+// e.g. as the ETag of an API mirroring the session to its clients
+type Revisioner interface {
+	// Revision An opaque revision of the session, changing with each save that writes it
+	// ("0" until the first one), the modifications not saved yet keep the revision
+	Revision() string
+}
+
+func (s *store) Revision() string {
+	s.RLock()
+	defer s.RUnlock()
+	return strconv.FormatInt(s.version, 10)
+}
@@ -0,0 +1,24 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRevision(t *testing.T) {
+	Convey("Test the revisions of the sessions", t, func() {
+		mstore := newOfflineStore(t)
+		store := newStore(context.Background(), mstore, "sid", 10, nil)
+		So(store.Revision(), ShouldEqual, "0")
+		store.Set("foo", "bar")
+		So(store.Revision(), ShouldEqual, "0")
+
+		value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+		So(err, ShouldBeNil)
+		loaded, err := newLoadedStore(mstore, &sessionItem{Value: value, Version: 7}, newStore(context.Background(), mstore, "sid", 10, nil))
+		So(err, ShouldBeNil)
+		So(loaded.Revision(), ShouldEqual, "7")
+	})
+}
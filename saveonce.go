@@ -0,0 +1,94 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// SaveOutcome How the last Save of a session store resolved
+type SaveOutcome int
+
+// Outcomes of the saves
+const (
+	// SaveWritten The first attempt wrote the session, or there was nothing to write
+	SaveWritten SaveOutcome = iota
+	// SaveRetried A retry wrote the session after the failed attempts wrote nothing
+	SaveRetried
+	// SaveReplayed A failed attempt turned out to have written the session, which the retry
+	// didn't write again
+	SaveReplayed
+	// SaveConflicted A retry found the session written by another request since the failed
+	// attempt and didn't overwrite it, the error is ErrConflict
+	SaveConflicted
+	// SaveFailed The save failed
+	SaveFailed
+)
+
+// SaveReporter Implemented by the session stores, to tell how their last Save resolved. With
+// the retries of WithRetryPolicy, each save writes an id of its own with the session, so that
+// a retry after an ambiguous failure (the write went through but its reply was lost) finds it
+// instead of writing again, and doesn't overwrite the concurrent writes made in between, even
+// with LastWriteWins
+type SaveReporter interface {
+	SaveOutcome() SaveOutcome
+}
+
+func (s *store) SaveOutcome() SaveOutcome {
+	s.RLock()
+	defer s.RUnlock()
+	return s.outcome
+}
+
+// newSaveID The id of a save, empty when the saves are not retried
+func (s *managerStore) newSaveID() string {
+	if s.opts.retryPolicy(OpSave).MaxAttempts <= 1 {
+		return ""
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// resolveSave Find out before a retry of the save saveID whether a failed attempt wrote the
+// session, returning true if it did; the error is ErrConflict if another request wrote the
+// session since the store loaded it, unless the conflicts are merged
+func (s *store) resolveSave(ctx context.Context, saveID string) (bool, error) {
+	if saveID == "" {
+		return false, nil
+	}
+	s.RLock()
+	expect := s.version
+	s.RUnlock()
+
+	var doc struct {
+		Version int64  `bson:"version"`
+		SaveID  string `bson:"save_id"`
+	}
+	opts := mopts.FindOne().SetProjection(bson.M{"version": 1, "save_id": 1})
+	err := s.mstore.cPrimary.FindOne(ctx, s.mstore.selector(s.sid), opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	switch {
+	case doc.SaveID == saveID:
+		s.version = doc.Version
+		s.outcome = SaveReplayed
+		return true, nil
+	case doc.Version != expect && s.mstore.opts.conflictPolicy != ConflictMerge:
+		s.outcome = SaveConflicted
+		return false, ErrConflict
+	}
+	return false, nil
+}
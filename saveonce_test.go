@@ -0,0 +1,25 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSaveID(t *testing.T) {
+	Convey("Test the ids of the retried saves", t, func() {
+		mstore := newOfflineStore(t)
+		So(mstore.newSaveID(), ShouldBeEmpty)
+		st := newStore(context.Background(), mstore, "test_save_id", 10, nil)
+		written, err := st.resolveSave(context.Background(), "")
+		So(err, ShouldBeNil)
+		So(written, ShouldBeFalse)
+		So(st.SaveOutcome(), ShouldEqual, SaveWritten)
+
+		mstore = newOfflineStore(t, WithOperationRetryPolicy(OpSave, RetryPolicy{MaxAttempts: 3}))
+		id := mstore.newSaveID()
+		So(id, ShouldHaveLength, 16)
+		So(mstore.newSaveID(), ShouldNotEqual, id)
+	})
+}
@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidValues The session values don't pass the validation of WithValueValidation
+var ErrInvalidValues = errors.New("session values rejected by the validation")
+
+// ValueValidator Check the values of a session against the contract of the applications
+// sharing the collection (required keys, types, formats...), e.g. with a JSON Schema
+// validator, the values must not be modified
+type ValueValidator func(ctx context.Context, values map[string]interface{}) error
+
+// WithValueValidation Check the values of the sessions with validate before they're written,
+// after the filters of WithValueFilter: the saves of the values it rejects fail with its
+// error wrapped with ErrInvalidValues and write nothing, the store keeping its values and
+// modifications to be fixed and saved again
+func WithValueValidation(validate ValueValidator) Option {
+	return func(o *options) {
+		o.validateValues = validate
+	}
+}
+
+// validateValues Check the values of the store before a save
+func (s *store) validateValues() error {
+	validate := s.mstore.opts.validateValues
+	if validate == nil {
+		return nil
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.loaded && !s.flushed && len(s.dirty) == 0 && !s.indexDirty {
+		return nil
+	}
+	s.materialize()
+	if err := validate(s.ctx, s.values); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidValues, err)
+	}
+	return nil
+}
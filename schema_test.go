@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValueValidation(t *testing.T) {
+	Convey("Test the rejection of the values breaking the session contract", t, func() {
+		var validated int
+		validate := func(ctx context.Context, values map[string]interface{}) error {
+			validated++
+			if _, ok := values["user_id"].(string); !ok {
+				return errors.New("user_id must be a string")
+			}
+			return nil
+		}
+		mstore := newOfflineStore(t, WithOperationTimeout(time.Millisecond), WithValueValidation(validate))
+		store := newStore(context.Background(), mstore, "test_schema", 10, nil)
+
+		store.Set("user_id", 42.0)
+		err := store.Save()
+		So(errors.Is(err, ErrInvalidValues), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "user_id must be a string")
+		So(store.dirty, ShouldContainKey, "user_id")
+		v, _ := store.Get("user_id")
+		So(v, ShouldEqual, 42.0)
+
+		store.Set("user_id", "u-1")
+		err = store.Save()
+		So(err, ShouldNotBeNil)
+		So(errors.Is(err, ErrInvalidValues), ShouldBeFalse)
+		So(validated, ShouldEqual, 2)
+	})
+}
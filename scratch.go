@@ -0,0 +1,198 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// ErrScratchDisabled The scratch entries are not enabled with WithScratch
+var ErrScratchDisabled = errors.New("session scratch entries are not enabled")
+
+// ErrScratchNotFound The scratch entry is missing, expired or its session is gone
+var ErrScratchNotFound = errors.New("session scratch entry not found")
+
+// WithScratch Enable the scratch entries of Scratchpad, stored in the collection named after
+// the session one with a "_scratch" suffix
+func WithScratch() Option {
+	return func(o *options) {
+		o.scratch = true
+	}
+}
+
+// Scratchpad Implemented by the stores, to keep small blobs tied to a session next to it with
+// their own expirations (upload tokens, wizard states), without loading them with the session
+// values. The entries are removed with their session by Delete and the removals of the store,
+// moved with it by Refresh, and those of the sessions removed by the TTL monitor are no longer
+// found and expire on their own
+type Scratchpad interface {
+	// PutScratch Store value as the entry name of the session sid for ttl, replacing the
+	// entry of the same name, the error is ErrSessionNotFound if there is no such session
+	PutScratch(ctx context.Context, sid, name string, value []byte, ttl time.Duration) error
+	// GetScratch The value of the entry name of the session sid, ErrScratchNotFound if it
+	// expired or the session is gone
+	GetScratch(ctx context.Context, sid, name string) ([]byte, error)
+	// DeleteScratch Remove the entry name of the session sid, if any
+	DeleteScratch(ctx context.Context, sid, name string) error
+}
+
+// scratchDoc A scratch entry
+type scratchDoc struct {
+	SID       string    `bson:"sid"`
+	Name      string    `bson:"name"`
+	Value     []byte    `bson:"value"`
+	Owner     string    `bson:"owner,omitempty"`
+	Namespace string    `bson:"ns,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at"`
+	ExpiredAt time.Time `bson:"expired_at"`
+}
+
+// scratchCollection The collection of the scratch entries of the session collection c
+func (o *options) scratchCollection(c *mongo.Collection) *mongo.Collection {
+	if !o.scratch {
+		return nil
+	}
+	return c.Database().Collection(c.Name()+"_scratch", o.collectionOptions().SetReadPreference(readpref.Primary()))
+}
+
+// scratchIndexModels The indexes to create on the scratch collection
+func scratchIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sid", Value: 1}, {Key: "name", Value: 1}},
+			Options: mopts.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expired_at", Value: 1}},
+			Options: mopts.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+// scratchSelector Query matching the entry name of the session sid
+func (s *managerStore) scratchSelector(sid, name string) bson.M {
+	q := s.scope()
+	q["sid"] = s.docID(sid)
+	q["name"] = name
+	return q
+}
+
+// dropScratch Remove the scratch entries of the documents ids, after they have been deleted
+func (s *managerStore) dropScratch(ctx context.Context, ids ...string) {
+	sc := s.root().scratch
+	if sc == nil || len(ids) == 0 {
+		return
+	}
+	if _, err := sc.DeleteMany(ctx, bson.M{"sid": bson.M{"$in": ids}}); err != nil {
+		s.log(LevelWarn, "session scratch cleanup failed", "collection", sc.Name(), "error", err)
+	}
+}
+
+// moveScratch Move the scratch entries of oldsid to sid
+func (s *managerStore) moveScratch(ctx context.Context, oldsid, sid string) error {
+	sc := s.root().scratch
+	if sc == nil || oldsid == sid {
+		return nil
+	}
+	_, err := sc.UpdateMany(ctx, bson.M{"sid": s.docID(oldsid)}, bson.M{"$set": bson.M{"sid": s.docID(sid)}})
+	return err
+}
+
+func (s *managerStore) PutScratch(ctx context.Context, sid, name string, value []byte, ttl time.Duration) error {
+	if t := s.tenantView(ctx); t != s {
+		return t.PutScratch(ctx, sid, name, value, ttl)
+	}
+	sc := s.root().scratch
+	if sc == nil {
+		return ErrScratchDisabled
+	} else if ttl <= 0 {
+		return fmt.Errorf("invalid scratch ttl %s", ttl)
+	}
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	return s.retry(dbctx, OpSave, func() error {
+		_, item, err := s.locate(dbctx, sid, false, 0)
+		if err != nil {
+			return err
+		} else if item == nil {
+			return ErrSessionNotFound
+		}
+		now := s.now()
+		set := bson.M{"value": value, "updated_at": now, "expired_at": now.Add(ttl)}
+		_, err = sc.UpdateOne(dbctx, s.scratchSelector(sid, name), bson.M{"$set": set}, mopts.UpdateOne().SetUpsert(true))
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrOwnerMismatch
+		}
+		return err
+	})
+}
+
+func (s *managerStore) GetScratch(ctx context.Context, sid, name string) ([]byte, error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.GetScratch(ctx, sid, name)
+	}
+	sc := s.root().scratch
+	if sc == nil {
+		return nil, ErrScratchDisabled
+	}
+	if err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	var doc scratchDoc
+	err := s.retry(dbctx, OpLoad, func() error {
+		q := s.scratchSelector(sid, name)
+		q["expired_at"] = bson.M{"$gt": s.now()}
+		err := sc.FindOne(dbctx, q).Decode(&doc)
+		if err == mongo.ErrNoDocuments {
+			return ErrScratchNotFound
+		} else if err != nil {
+			return err
+		}
+		// the session may have been removed by the TTL monitor
+		_, item, err := s.locate(dbctx, sid, false, 0)
+		if err != nil {
+			return err
+		} else if item == nil {
+			s.dropScratch(dbctx, s.docID(sid))
+			return ErrScratchNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc.Value, nil
+}
+
+func (s *managerStore) DeleteScratch(ctx context.Context, sid, name string) error {
+	if t := s.tenantView(ctx); t != s {
+		return t.DeleteScratch(ctx, sid, name)
+	}
+	sc := s.root().scratch
+	if sc == nil {
+		return ErrScratchDisabled
+	}
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	return s.retry(dbctx, OpDelete, func() error {
+		_, err := sc.DeleteOne(dbctx, s.scratchSelector(sid, name))
+		return err
+	})
+}
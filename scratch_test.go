@@ -0,0 +1,33 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestScratchOptions(t *testing.T) {
+	Convey("Test the scratch collection", t, func() {
+		ctx := context.Background()
+		mstore := newOfflineStore(t)
+		So(mstore.opts.scratchCollection(mstore.c), ShouldBeNil)
+		So(mstore.PutScratch(ctx, "test_scratch", "upload", []byte("token"), time.Minute), ShouldEqual, ErrScratchDisabled)
+		_, err := mstore.GetScratch(ctx, "test_scratch", "upload")
+		So(err, ShouldEqual, ErrScratchDisabled)
+		So(mstore.DeleteScratch(ctx, "test_scratch", "upload"), ShouldEqual, ErrScratchDisabled)
+
+		o := newOptions(WithScratch())
+		c := o.scratchCollection(mstore.c)
+		So(c, ShouldNotBeNil)
+		So(c.Name(), ShouldEqual, mstore.c.Name()+"_scratch")
+
+		mstore.scratch = c
+		So(mstore.PutScratch(ctx, "test_scratch", "upload", []byte("token"), 0), ShouldNotBeNil)
+		So(mstore.scratchSelector("test_scratch", "upload"), ShouldResemble, bson.M{
+			"sid": "test_scratch", "name": "upload", "ns": bson.M{"$exists": false},
+		})
+	})
+}
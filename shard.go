@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// WithHashedSharding Shard the session collection on a hashed _id when the store creates it,
+// pre-split into chunks chunks (the server default if not positive) so that the first sessions
+// spread over the shards instead of filling a single chunk; the existing collections are left
+// as they are. The store must be connected to the mongos of a sharded cluster, whose user is
+// allowed to enable the sharding; the servers ignoring numInitialChunks split the chunks
+// themselves
+func WithHashedSharding(chunks int) Option {
+	return func(o *options) {
+		o.sharding = true
+		o.shardChunks = chunks
+	}
+}
+
+// shardCommand The command sharding the collection cName of dbName on a hashed _id
+func shardCommand(dbName, cName string, chunks int) bson.D {
+	cmd := bson.D{
+		{Key: "shardCollection", Value: dbName + "." + cName},
+		{Key: "key", Value: bson.D{{Key: "_id", Value: "hashed"}}},
+	}
+	if chunks > 0 {
+		cmd = append(cmd, bson.E{Key: "numInitialChunks", Value: chunks})
+	}
+	return cmd
+}
+
+// shard Shard the collection of s if it doesn't exist yet
+func (s *managerStore) shard(ctx context.Context) error {
+	if !s.opts.sharding {
+		return nil
+	}
+	db := s.c.Database()
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": s.c.Name()})
+	if err != nil {
+		return err
+	} else if len(names) > 0 {
+		return nil
+	}
+
+	admin := s.client.Database("admin")
+	// the databases are sharded by default from 6.0
+	err = admin.RunCommand(ctx, bson.D{{Key: "enableSharding", Value: db.Name()}}).Err()
+	var ce mongo.CommandError
+	if err != nil && !(errors.As(err, &ce) && ce.Code == 23) {
+		s.log(LevelError, "session database sharding failed", "database", db.Name(), "error", err)
+		return err
+	}
+	if err := admin.RunCommand(ctx, shardCommand(db.Name(), s.c.Name(), s.opts.shardChunks)).Err(); err != nil {
+		s.log(LevelError, "session collection sharding failed", "collection", s.c.Name(), "error", err)
+		return err
+	}
+	s.log(LevelInfo, "session collection sharded", "collection", s.c.Name(), "chunks", s.opts.shardChunks)
+	return nil
+}
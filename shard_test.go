@@ -0,0 +1,23 @@
+package mongo
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestShardCommand(t *testing.T) {
+	Convey("Test the sharding of the session collection", t, func() {
+		So(shardCommand("app", "session", 0), ShouldResemble, bson.D{
+			{Key: "shardCollection", Value: "app.session"},
+			{Key: "key", Value: bson.D{{Key: "_id", Value: "hashed"}}},
+		})
+		cmd := shardCommand("app", "session", 64)
+		So(cmd[len(cmd)-1], ShouldResemble, bson.E{Key: "numInitialChunks", Value: 64})
+
+		o := newOptions(WithHashedSharding(64))
+		So(o.sharding, ShouldBeTrue)
+		So(o.shardChunks, ShouldEqual, 64)
+	})
+}
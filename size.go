@@ -0,0 +1,113 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrSessionTooLarge Matched by the SizeError of the sessions exceeding the maximum size
+var ErrSessionTooLarge = errors.New("session exceeds the maximum size")
+
+// SizeError The error of Save for a session exceeding the maximum size with OversizeReject
+// (test with errors.Is(err, ErrSessionTooLarge))
+type SizeError struct {
+	// Size The size of the encoded values
+	Size int
+	// Max The maximum size of the store
+	Max int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("session of %d bytes exceeds the maximum size of %d bytes", e.Size, e.Max)
+}
+
+// Is Match ErrSessionTooLarge
+func (e *SizeError) Is(target error) bool {
+	return target == ErrSessionTooLarge
+}
+
+// OversizePolicy How Save handles the sessions exceeding the maximum size
+type OversizePolicy int
+
+// Oversize policies
+const (
+	// OversizeReject Fail the Save with a SizeError, keeping the stored session (default)
+	OversizeReject OversizePolicy = iota
+	// OversizeTruncate Remove the largest values of the session until it fits and save it,
+	// logging the removed keys
+	OversizeTruncate
+	// OversizeAllow Log the oversized session and save it
+	OversizeAllow
+)
+
+// WithMaxSessionSize Set the maximum size in bytes of the encoded values of a session and how
+// Save handles the larger ones, rather than failing at the 16MB limit of the documents; the
+// size is measured on the whole encoded value, so the key-level writes of WithDocumentValues
+// are disabled
+func WithMaxSessionSize(max int, policy OversizePolicy) Option {
+	return func(o *options) {
+		o.maxSize = max
+		o.oversize = policy
+	}
+}
+
+// checkSize Apply the oversize policy to the encoded value of the store, returning the value to write
+func (s *store) checkSize(value bson.RawValue) (bson.RawValue, error) {
+	max := s.mstore.opts.maxSize
+	size := len(value.Value)
+	if max <= 0 || size <= max {
+		return value, nil
+	}
+
+	switch s.mstore.opts.oversize {
+	case OversizeAllow:
+		s.mstore.log(LevelWarn, "oversized session saved", "sid_hash", s.mstore.sidHash(s.sid), "size", size, "max", max)
+		return value, nil
+	case OversizeTruncate:
+		return s.truncate(value)
+	default:
+		return bson.RawValue{}, &SizeError{Size: size, Max: max}
+	}
+}
+
+// truncate Remove the largest values of the store until its encoded value fits the maximum size
+func (s *store) truncate(value bson.RawValue) (bson.RawValue, error) {
+	max := s.mstore.opts.maxSize
+	s.Lock()
+	defer s.Unlock()
+	s.materialize()
+
+	type keySize struct {
+		key  string
+		size int
+	}
+	sizes := make([]keySize, 0, len(s.values))
+	for k, v := range s.values {
+		b, _ := jsoniter.Marshal(v)
+		sizes = append(sizes, keySize{k, len(k) + len(b)})
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].size > sizes[j].size })
+
+	var removed []string
+	for len(value.Value) > max && len(sizes) > 0 {
+		// remove at least the estimated excess before encoding again
+		excess := len(value.Value) - max
+		for excess > 0 && len(sizes) > 0 {
+			delete(s.values, sizes[0].key)
+			s.markDirty(sizes[0].key)
+			removed = append(removed, sizes[0].key)
+			excess -= sizes[0].size
+			sizes = sizes[1:]
+		}
+		var err error
+		if value, err = s.mstore.encodeValues(s.values); err != nil {
+			return bson.RawValue{}, err
+		}
+	}
+	s.mstore.log(LevelWarn, "oversized session truncated", "sid_hash", s.mstore.sidHash(s.sid), "max", max, "removed", removed)
+	return value, nil
+}
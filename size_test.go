@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaxSessionSize(t *testing.T) {
+	Convey("Test the sessions exceeding the maximum size", t, func() {
+		values := func() map[string]interface{} {
+			return map[string]interface{}{"big": strings.Repeat("x", 1000), "medium": strings.Repeat("y", 100), "small": "z"}
+		}
+
+		Convey("rejected by default", func() {
+			mstore := newOfflineStore(t, WithMaxSessionSize(500, OversizeReject))
+			store := newStore(context.Background(), mstore, "test_size", 10, values())
+			value, err := mstore.encodeValues(store.values)
+			So(err, ShouldBeNil)
+
+			_, err = store.checkSize(value)
+			So(errors.Is(err, ErrSessionTooLarge), ShouldBeTrue)
+			var se *SizeError
+			So(errors.As(err, &se), ShouldBeTrue)
+			So(se.Size, ShouldEqual, len(value.Value))
+			So(se.Max, ShouldEqual, 500)
+
+			err = store.Save()
+			So(errors.Is(err, ErrSessionTooLarge), ShouldBeTrue)
+		})
+
+		Convey("truncated", func() {
+			mstore := newOfflineStore(t, WithMaxSessionSize(500, OversizeTruncate))
+			store := newStore(context.Background(), mstore, "test_size", 10, values())
+			value, err := mstore.encodeValues(store.values)
+			So(err, ShouldBeNil)
+
+			value, err = store.checkSize(value)
+			So(err, ShouldBeNil)
+			So(len(value.Value), ShouldBeLessThanOrEqualTo, 500)
+			So(store.values, ShouldNotContainKey, "big")
+			So(store.values, ShouldContainKey, "medium")
+			So(store.dirty, ShouldContainKey, "big")
+		})
+
+		Convey("allowed", func() {
+			mstore := newOfflineStore(t, WithMaxSessionSize(500, OversizeAllow))
+			store := newStore(context.Background(), mstore, "test_size", 10, values())
+			value, err := mstore.encodeValues(store.values)
+			So(err, ShouldBeNil)
+
+			checked, err := store.checkSize(value)
+			So(err, ShouldBeNil)
+			So(checked.Value, ShouldResemble, value.Value)
+		})
+
+		Convey("key-level writes disabled", func() {
+			mstore := newOfflineStore(t, WithDocumentValues(true), WithMaxSessionSize(500, OversizeReject))
+			value, err := mstore.encodeValues(values())
+			So(err, ShouldBeNil)
+			store, err := newLoadedStore(mstore, &sessionItem{Value: value}, newStore(context.Background(), mstore, "test_size", 10, nil))
+			So(err, ShouldBeNil)
+			store.Set("small", "zz")
+			So(errors.Is(store.Save(), ErrSessionTooLarge), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package mongo
+
+import (
+	"context"
+	"time"
+)
+
+// Loader Implemented by the stores, to read the sessions from background jobs (cron, queue
+// workers) without extending their expiration nor risking to overwrite the live sessions
+type Loader interface {
+	// Load Get a read-only snapshot of the unexpired session sid,
+	// the error wraps ErrSessionNotFound if there is no such session, and also ErrExpired if
+	// its document is expired but still stored
+	Load(ctx context.Context, sid string) (*Snapshot, error)
+}
+
+// Snapshot The read-only copy of a session at the time it was loaded
+type Snapshot struct {
+	sid       string
+	values    map[string]interface{}
+	indexed   map[string]interface{}
+	createdAt time.Time
+	updatedAt time.Time
+	expiredAt time.Time
+}
+
+// SessionID The id of the session
+func (s *Snapshot) SessionID() string {
+	return s.sid
+}
+
+// Get Get the value of key, shared by the callers of the snapshot
+func (s *Snapshot) Get(key string) (interface{}, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Keys The keys of the values of the session, unordered
+func (s *Snapshot) Keys() []string {
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// GetIndexed Get the indexed field (see IndexedStore)
+func (s *Snapshot) GetIndexed(field string) (interface{}, bool) {
+	v, ok := s.indexed[field]
+	return v, ok
+}
+
+// CreatedAt The creation time of the session, zero if unknown
+func (s *Snapshot) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// UpdatedAt The time of the last write of the session, zero if unknown
+func (s *Snapshot) UpdatedAt() time.Time {
+	return s.updatedAt
+}
+
+// ExpiredAt The expiration time of the session
+func (s *Snapshot) ExpiredAt() time.Time {
+	return s.expiredAt
+}
+
+func (s *managerStore) Load(ctx context.Context, sid string) (snap *Snapshot, err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Load(ctx, sid)
+	}
+	var size int
+	tctx, op := s.begin(ctx, OpLoad, sid)
+	defer func() { err = op.end(size, err) }()
+	if err := s.injectFault(ctx, OpLoad); err != nil {
+		return nil, err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	var m *managerStore
+	var item *sessionItem
+	err = s.retry(dbctx, OpLoad, func() (err error) {
+		m, item, err = s.locate(dbctx, sid, true, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if item == nil {
+		return nil, s.missing(dbctx, sid)
+	}
+	size = len(item.Value.Value)
+
+	values, err := m.decodeValues(item)
+	if err != nil {
+		return nil, err
+	}
+	snap = &Snapshot{sid: sid, values: values, createdAt: item.CreatedAt, updatedAt: item.UpdatedAt, expiredAt: item.ExpiredAt}
+	for field, value := range item.Indexed {
+		if m.opts.isIndexed(field) {
+			if snap.indexed == nil {
+				snap.indexed = make(map[string]interface{})
+			}
+			snap.indexed[field] = value
+		}
+	}
+	return snap, nil
+}
@@ -0,0 +1,46 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshot(t *testing.T) {
+	Convey("Test the read-only snapshots of the sessions", t, func() {
+		now := time.Now()
+		snap := &Snapshot{
+			sid:       "sid",
+			values:    map[string]interface{}{"foo": "bar"},
+			indexed:   map[string]interface{}{"uid": 42},
+			createdAt: now,
+			updatedAt: now.Add(time.Minute),
+			expiredAt: now.Add(time.Hour),
+		}
+		So(snap.SessionID(), ShouldEqual, "sid")
+		foo, ok := snap.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+		_, ok = snap.Get("missing")
+		So(ok, ShouldBeFalse)
+		So(snap.Keys(), ShouldResemble, []string{"foo"})
+		uid, _ := snap.GetIndexed("uid")
+		So(uid, ShouldEqual, 42)
+		So(snap.CreatedAt(), ShouldEqual, now)
+		So(snap.UpdatedAt(), ShouldEqual, now.Add(time.Minute))
+		So(snap.ExpiredAt(), ShouldEqual, now.Add(time.Hour))
+
+		Convey("with faults", func() {
+			fault := errors.New("fault")
+			mstore := newOfflineStore(t, WithFaultInjection(Fault{Op: OpLoad, Rate: 1, Err: fault}))
+			_, err := mstore.Load(context.Background(), "sid")
+			So(errors.Is(err, fault), ShouldBeTrue)
+			var se *StoreError
+			So(errors.As(err, &se), ShouldBeTrue)
+			So(se.Op, ShouldEqual, OpLoad)
+		})
+	})
+}
@@ -0,0 +1,202 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ErrChunksMissing The chunks of a session value stored apart are missing or incomplete
+var ErrChunksMissing = errors.New("session value chunks are missing")
+
+const (
+	// spillChunkSize The size of the chunks of the values stored apart, the one of GridFS
+	spillChunkSize = 255 * 1024
+	// chunkGrace The time the chunks outlive the expiration of the session document
+	// and the chunks replaced by a concurrent write are kept with LastWriteWins
+	chunkGrace = time.Hour
+)
+
+// WithSpillover Store the encoded values larger than threshold bytes apart from the session
+// documents, as chunks in the collection named after the session one with a "_chunks" suffix,
+// reassembled when the sessions are loaded; for the sessions that can't fit the 16MB limit of
+// the documents. The size is measured on the whole encoded value, so the key-level writes of
+// WithDocumentValues are disabled, and the writes of the sessions also remove the replaced chunks
+func WithSpillover(threshold int) Option {
+	return func(o *options) {
+		o.spillThreshold = threshold
+	}
+}
+
+// spillRef The reference of a session document to the chunks of its value
+type spillRef struct {
+	// Gen The generation of the chunks, unique to each write
+	Gen    string `bson:"gen"`
+	Chunks int    `bson:"n"`
+	Size   int    `bson:"size"`
+	Type   int32  `bson:"type"`
+}
+
+// chunkDoc A chunk of a session value
+type chunkDoc struct {
+	Doc       string      `bson:"doc"`
+	Gen       string      `bson:"gen"`
+	N         int         `bson:"n"`
+	Data      bson.Binary `bson:"data"`
+	CreatedAt time.Time   `bson:"created_at"`
+	ExpiredAt time.Time   `bson:"expired_at"`
+}
+
+// spills Tell whether the large values are stored apart
+func (s *managerStore) spills() bool {
+	return s.opts.spillThreshold > 0 && s.chunks != nil
+}
+
+// chunkCollection The collection of the value chunks of the session collection c
+func (o *options) chunkCollection(c *mongo.Collection) *mongo.Collection {
+	return c.Database().Collection(c.Name()+"_chunks", o.collectionOptions())
+}
+
+// chunkIndexModels The indexes to create on the chunk collection
+func chunkIndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "doc", Value: 1}, {Key: "gen", Value: 1}, {Key: "n", Value: 1}},
+			Options: mopts.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expired_at", Value: 1}},
+			Options: mopts.Index().SetExpireAfterSeconds(1),
+		},
+	}
+}
+
+// spill Write the value of item as chunks if it's over the threshold, returning the document
+// to write in place of item
+func (s *managerStore) spill(ctx context.Context, item *sessionItem) (*sessionItem, error) {
+	if !s.spills() || len(item.Value.Value) <= s.opts.spillThreshold {
+		return item, nil
+	}
+
+	gen := make([]byte, 8)
+	if _, err := rand.Read(gen); err != nil {
+		return nil, err
+	}
+	ref := &spillRef{Gen: hex.EncodeToString(gen), Size: len(item.Value.Value), Type: int32(item.Value.Type)}
+	now := s.now()
+	data := item.Value.Value
+	var chunks []interface{}
+	for off := 0; off < len(data); off += spillChunkSize {
+		end := off + spillChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chunks = append(chunks, chunkDoc{
+			Doc:       item.ID,
+			Gen:       ref.Gen,
+			N:         len(chunks),
+			Data:      bson.Binary{Data: data[off:end]},
+			CreatedAt: now,
+			ExpiredAt: item.ExpiredAt.Add(chunkGrace),
+		})
+	}
+	ref.Chunks = len(chunks)
+	if _, err := s.chunks.InsertMany(ctx, chunks); err != nil {
+		return nil, err
+	}
+
+	stored := *item
+	stored.Value = bson.RawValue{Type: bson.TypeNull}
+	stored.Spill = ref
+	return &stored, nil
+}
+
+// unspill Reassemble the value of item from its chunks if it's stored apart
+func (s *managerStore) unspill(ctx context.Context, item *sessionItem) error {
+	ref := item.Spill
+	if ref == nil {
+		return nil
+	} else if s.chunks == nil {
+		return ErrChunksMissing
+	}
+
+	cur, err := s.chunks.Find(ctx, bson.M{"doc": item.ID, "gen": ref.Gen}, mopts.Find().SetSort(bson.D{{Key: "n", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	data := make([]byte, 0, ref.Size)
+	n := 0
+	for cur.Next(ctx) {
+		var chunk chunkDoc
+		if err := cur.Decode(&chunk); err != nil {
+			return err
+		}
+		if chunk.N != n {
+			return ErrChunksMissing
+		}
+		data = append(data, chunk.Data.Data...)
+		n++
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	if n != ref.Chunks || len(data) != ref.Size {
+		return ErrChunksMissing
+	}
+	item.Value = bson.RawValue{Type: bson.Type(ref.Type), Value: data}
+	return nil
+}
+
+// cleanChunks Remove the chunks of the document id other than those of the generation keep
+// (all of them if empty), sparing those of the concurrent writes with LastWriteWins
+func (s *managerStore) cleanChunks(ctx context.Context, id, keep string) {
+	if !s.spills() {
+		return
+	}
+	q := bson.M{"doc": id}
+	if keep != "" {
+		q["gen"] = bson.M{"$ne": keep}
+	}
+	if s.opts.conflictPolicy == LastWriteWins {
+		q["created_at"] = bson.M{"$lt": s.now().Add(-chunkGrace)}
+	}
+	if _, err := s.chunks.DeleteMany(ctx, q); err != nil {
+		s.log(LevelWarn, "session chunk cleanup failed", "collection", s.chunks.Name(), "error", err)
+	}
+}
+
+// extendChunks Make the chunks of item outlive its expiration
+func (s *managerStore) extendChunks(ctx context.Context, item *sessionItem) error {
+	if item.Spill == nil || s.chunks == nil {
+		return nil
+	}
+	_, err := s.chunks.UpdateMany(ctx,
+		bson.M{"doc": item.ID, "gen": item.Spill.Gen},
+		bson.M{"$set": bson.M{"expired_at": item.ExpiredAt.Add(chunkGrace)}})
+	return err
+}
+
+// dropChunks Remove all the chunks of the document id, after it has been deleted
+func (s *managerStore) dropChunks(ctx context.Context, id string) {
+	if !s.spills() {
+		return
+	}
+	if _, err := s.chunks.DeleteMany(ctx, bson.M{"doc": id}); err != nil {
+		s.log(LevelWarn, "session chunk cleanup failed", "collection", s.chunks.Name(), "error", err)
+	}
+}
+
+// discardChunks Remove the chunks of the generation gen of the document id, after its write failed
+func (s *managerStore) discardChunks(ctx context.Context, id, gen string) {
+	if _, err := s.chunks.DeleteMany(ctx, bson.M{"doc": id, "gen": gen}); err != nil {
+		s.log(LevelWarn, "session chunk cleanup failed", "collection", s.chunks.Name(), "error", err)
+	}
+}
@@ -0,0 +1,52 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSpillover(t *testing.T) {
+	Convey("Test the values stored apart from the session documents", t, func() {
+		mstore := newOfflineStore(t, WithDocumentValues(true), WithSpillover(1024))
+		So(mstore.spills(), ShouldBeFalse)
+		mstore.chunks = mstore.opts.chunkCollection(mstore.c)
+		So(mstore.spills(), ShouldBeTrue)
+		So(mstore.chunks.Name(), ShouldEqual, cName+"_chunks")
+		So(mstore.Namespace("ns").(*managerStore).chunks, ShouldEqual, mstore.chunks)
+
+		Convey("small values stay in the documents", func() {
+			item := &sessionItem{ID: "sid", Value: bson.RawValue{Type: bson.TypeString, Value: []byte("small")}}
+			stored, err := mstore.spill(context.Background(), item)
+			So(err, ShouldBeNil)
+			So(stored, ShouldEqual, item)
+			So(mstore.unspill(context.Background(), item), ShouldBeNil)
+		})
+
+		Convey("references to the chunks", func() {
+			value, err := mstore.encodeValues(map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			ref := &spillRef{Gen: "g", Chunks: 2, Size: 300 * 1024, Type: int32(bson.TypeString)}
+			b, err := bson.Marshal(&sessionItem{ID: "sid", Value: bson.RawValue{Type: bson.TypeNull}, Spill: ref})
+			So(err, ShouldBeNil)
+			var item sessionItem
+			So(bson.Unmarshal(b, &item), ShouldBeNil)
+			So(item.Value.Type, ShouldEqual, bson.TypeNull)
+			So(item.Spill, ShouldResemble, ref)
+			So(item.Indexed, ShouldBeEmpty)
+
+			item.Value = value
+			store, err := newLoadedStore(mstore, &item, newStore(context.Background(), mstore, "sid", 10, nil))
+			So(err, ShouldBeNil)
+			So(store.partial, ShouldBeFalse)
+		})
+
+		Convey("missing chunks", func() {
+			mstore := newOfflineStore(t)
+			item := &sessionItem{ID: "sid", Spill: &spillRef{Gen: "g", Chunks: 1, Size: 10}}
+			So(mstore.unspill(context.Background(), item), ShouldEqual, ErrChunksMissing)
+		})
+	})
+}
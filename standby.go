@@ -0,0 +1,108 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/go-session/mongo/v3/decorator"
+)
+
+// errHashedSample The session ids hashed with WithHashedIDs can't be sampled
+var errHashedSample = errors.New("hashed session ids can't be sampled")
+
+// SampleSessions The ids of n active sessions at most, chosen randomly among the authenticated
+// sessions then the anonymous ones, for the promotions of decorator.Dual; the ids hashed with
+// WithHashedIDs can't be sampled
+func (s *managerStore) SampleSessions(ctx context.Context, n int) ([]string, error) {
+	if len(s.opts.idSecret) > 0 {
+		return nil, errHashedSample
+	}
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	var sids []string
+	for _, m := range []*managerStore{s, s.anon} {
+		if m == nil || len(sids) >= n {
+			continue
+		}
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: m.activeScope()}},
+			{{Key: "$sample", Value: bson.M{"size": n - len(sids)}}},
+			{{Key: "$project", Value: bson.M{"_id": 1}}},
+		}
+		cur, err := m.adminCollection().Aggregate(dbctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var docs []sessionInfoDoc
+		if err := cur.All(dbctx, &docs); err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			sids = append(sids, m.info(doc).SID)
+		}
+	}
+	return sids, nil
+}
+
+// PeekSession The state of the active session sid read with Load, nil if there is no such
+// session
+func (s *managerStore) PeekSession(ctx context.Context, sid string) (*decorator.SessionState, error) {
+	snap, err := s.Load(ctx, sid)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &decorator.SessionState{Values: snap.values, ExpiredAt: snap.expiredAt}, nil
+}
+
+func (s *memoryStore) Count(context.Context) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+	var n int64
+	notBefore := s.m.notBefore(0)
+	for _, doc := range s.docs {
+		if !doc.expiredAt.Before(notBefore) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryStore) SampleSessions(_ context.Context, n int) ([]string, error) {
+	if len(s.m.opts.idSecret) > 0 {
+		return nil, errHashedSample
+	}
+	s.Lock()
+	defer s.Unlock()
+	var sids []string
+	notBefore := s.m.notBefore(0)
+	// the order of the map is random
+	for id, doc := range s.docs {
+		if len(sids) >= n {
+			break
+		}
+		if !doc.expiredAt.Before(notBefore) {
+			sids = append(sids, id)
+		}
+	}
+	return sids, nil
+}
+
+func (s *memoryStore) PeekSession(_ context.Context, sid string) (*decorator.SessionState, error) {
+	s.Lock()
+	doc := s.get(sid, 0)
+	s.Unlock()
+	if doc == nil {
+		return nil, nil
+	}
+	values, err := s.m.decodeValues(&sessionItem{Value: doc.value})
+	if err != nil {
+		return nil, err
+	}
+	return &decorator.SessionState{Values: values, ExpiredAt: doc.expiredAt}, nil
+}
@@ -0,0 +1,42 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-session/mongo/v3/decorator"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPromoteMemoryStores(t *testing.T) {
+	Convey("Test the promotion of a memory store", t, func() {
+		ctx := context.Background()
+		primary, secondary := NewMemoryStore(), NewMemoryStore()
+		st, err := primary.Create(ctx, "primary_only", 10)
+		So(err, ShouldBeNil)
+		st.Set("foo", "bar")
+		So(st.Save(), ShouldBeNil)
+
+		standby := NewDualStore(primary, secondary, nil).(decorator.Standby)
+		report, err := standby.Promote(ctx, decorator.PromoteOptions{})
+		So(err, ShouldEqual, decorator.ErrIncomplete)
+		So(report.PrimaryCount, ShouldEqual, 1)
+		So(report.SecondaryCount, ShouldEqual, 0)
+		So(report.Missing, ShouldResemble, []string{SHA256SIDHasher("primary_only")})
+
+		st, err = secondary.Create(ctx, "primary_only", 10)
+		So(err, ShouldBeNil)
+		st.Set("foo", "bar")
+		So(st.Save(), ShouldBeNil)
+		report, err = standby.Promote(ctx, decorator.PromoteOptions{})
+		So(err, ShouldBeNil)
+		So(report.Sampled, ShouldEqual, 1)
+		So(report.Promoted, ShouldBeTrue)
+
+		Convey("not with the hashed ids", func() {
+			store := NewMemoryStore(WithHashedIDs([]byte("secret"), false))
+			_, err := store.(decorator.Inspector).SampleSessions(ctx, 1)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,182 @@
+package mongo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// BucketCount The number of sessions created in a time bucket
+type BucketCount struct {
+	// Start Start of the bucket
+	Start time.Time
+	Count int64
+}
+
+func (s *managerStore) CreationCounts(ctx context.Context, since time.Time, bucket time.Duration) ([]BucketCount, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	counts := make(map[int64]int64)
+	if err := s.creationCounts(dbctx, since, bucket, counts); err != nil {
+		return nil, err
+	}
+	if s.anon != nil {
+		if err := s.anon.creationCounts(dbctx, since, bucket, counts); err != nil {
+			return nil, err
+		}
+	}
+
+	buckets := make([]BucketCount, 0, len(counts))
+	for start, n := range counts {
+		buckets = append(buckets, BucketCount{Start: time.UnixMilli(start).UTC(), Count: n})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+	return buckets, nil
+}
+
+// creationPipeline The aggregation counting the sessions created since, per bucket
+// (keyed by the start of the bucket in Unix milliseconds)
+func (s *managerStore) creationPipeline(since time.Time, bucket time.Duration) mongo.Pipeline {
+	q := s.scope()
+	q["created_at"] = bson.M{"$gte": since}
+	created := bson.M{"$toLong": "$created_at"}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: q}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$subtract": bson.A{
+				created,
+				bson.M{"$mod": bson.A{created, bucket.Milliseconds()}},
+			}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+}
+
+// creationCounts Add the creation counts of the collection of s to counts
+func (s *managerStore) creationCounts(ctx context.Context, since time.Time, bucket time.Duration, counts map[int64]int64) error {
+	cur, err := s.adminCollection().Aggregate(ctx, s.creationPipeline(since, bucket))
+	if err != nil {
+		return err
+	}
+	var docs []struct {
+		Start int64 `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		counts[doc.Start] += doc.Count
+	}
+	return nil
+}
+
+// DefaultExpiryBounds The bounds of the remaining lifetimes bucketing the sessions by default
+// in ExpiryCounts: less than an hour, up to a day and more
+var DefaultExpiryBounds = []time.Duration{time.Hour, 24 * time.Hour}
+
+// ExpiryCount The number of active sessions expiring in a range of remaining lifetimes
+type ExpiryCount struct {
+	// Min The shortest remaining lifetime of the range, Max the end of the range
+	// (excluded), zero for the last range
+	Min   time.Duration
+	Max   time.Duration
+	Count int64
+}
+
+// expiryBounds The positive bounds in ascending order
+func expiryBounds(bounds []time.Duration) []time.Duration {
+	if len(bounds) == 0 {
+		bounds = DefaultExpiryBounds
+	}
+	sorted := make([]time.Duration, 0, len(bounds))
+	for _, b := range bounds {
+		if b > 0 {
+			sorted = append(sorted, b)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	uniq := sorted[:0]
+	for i, b := range sorted {
+		if i == 0 || b != sorted[i-1] {
+			uniq = append(uniq, b)
+		}
+	}
+	return uniq
+}
+
+func (s *managerStore) ExpiryCounts(ctx context.Context, bounds []time.Duration) ([]ExpiryCount, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	bounds = expiryBounds(bounds)
+	now := time.UnixMilli(s.now().UnixMilli())
+	counts := make([]ExpiryCount, len(bounds)+1)
+	for i := range counts {
+		if i > 0 {
+			counts[i].Min = bounds[i-1]
+		}
+		if i < len(bounds) {
+			counts[i].Max = bounds[i]
+		}
+	}
+	if err := s.expiryCounts(dbctx, now, bounds, counts); err != nil {
+		return nil, err
+	}
+	if s.anon != nil {
+		if err := s.anon.expiryCounts(dbctx, now, bounds, counts); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// expiryPipeline The aggregation counting the sessions active at now per range of remaining
+// lifetimes up to bounds, keyed by the start of the range, the last one by "beyond"
+func (s *managerStore) expiryPipeline(now time.Time, bounds []time.Duration) mongo.Pipeline {
+	q := s.scope()
+	q["expired_at"] = bson.M{"$gt": now}
+	boundaries := bson.A{now}
+	for _, b := range bounds {
+		boundaries = append(boundaries, now.Add(b))
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: q}},
+		{{Key: "$bucket", Value: bson.M{
+			"groupBy":    "$expired_at",
+			"boundaries": boundaries,
+			"default":    "beyond",
+			"output":     bson.M{"count": bson.M{"$sum": 1}},
+		}}},
+	}
+}
+
+// expiryCounts Add the expiry counts of the collection of s to counts
+func (s *managerStore) expiryCounts(ctx context.Context, now time.Time, bounds []time.Duration, counts []ExpiryCount) error {
+	cur, err := s.adminCollection().Aggregate(ctx, s.expiryPipeline(now, bounds))
+	if err != nil {
+		return err
+	}
+	var docs []struct {
+		Start bson.RawValue `bson:"_id"`
+		Count int64         `bson:"count"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		i := len(bounds)
+		if start, ok := doc.Start.DateTimeOK(); ok {
+			for i = 0; i < len(bounds) && now.Add(bounds[i]).UnixMilli() <= start; i++ {
+			}
+		}
+		counts[i].Count += doc.Count
+	}
+	return nil
+}
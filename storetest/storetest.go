@@ -0,0 +1,309 @@
+// Package storetest A conformance suite of the session.ManagerStore behaviors the session
+// manager relies on, to check the stores of the mongo package in each of their modes, their
+// decorators and the other implementations against the same contract
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	session "github.com/go-session/session/v3"
+)
+
+// Factory Create the store under test, closed by the suite; each call may return a new store
+// or the same backend, the sessions of the suite having ids of their own
+type Factory func(t *testing.T) session.ManagerStore
+
+// expired The lifetime in seconds of the sessions of the suite
+const expired = 60
+
+// Run Check the store of factory: the values saved and loaded back, the sessions missing
+// until saved and after their deletion or expiration, the moves of Refresh, including the
+// concurrent refreshes of a session, and the concurrent uses of the sessions. The values are
+// strings and float64, which every codec round-trips, and the calls are made with contexts
+// which can be canceled, as the ones of the requests; the expiration test, which waits for the
+// sessions to expire, is skipped with -short
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+	prefix := newPrefix(t)
+	n := 0
+	sid := func(name string) string {
+		n++
+		return fmt.Sprintf("%s_%s_%d", prefix, name, n)
+	}
+	open := func(t *testing.T) session.ManagerStore {
+		store := factory(t)
+		t.Cleanup(func() {
+			if err := store.Close(); err != nil {
+				t.Errorf("close: %v", err)
+			}
+		})
+		return store
+	}
+
+	t.Run("Persistence", func(t *testing.T) { testPersistence(t, open(t), sid("persist")) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, open(t), sid("delete")) })
+	t.Run("Flush", func(t *testing.T) { testFlush(t, open(t), sid("flush")) })
+	t.Run("Refresh", func(t *testing.T) { testRefresh(t, open(t), sid("old"), sid("new"), sid("missing")) })
+	t.Run("RefreshRace", func(t *testing.T) { testRefreshRace(t, open(t), sid("old"), sid("a"), sid("b")) })
+	t.Run("Concurrency", func(t *testing.T) {
+		sids := make([]string, 8)
+		for i := range sids {
+			sids[i] = sid("concurrent")
+		}
+		testConcurrency(t, open(t), sids)
+	})
+	t.Run("Expiration", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("waits for the sessions to expire")
+		}
+		testExpiration(t, open(t), sid("expire"))
+	})
+}
+
+// request The context of a request, done at the end of the test, so that the stores take the
+// paths of the requests which can be canceled (e.g. the queued saves of mongo.SaveQueue)
+func request(t *testing.T) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// newPrefix A prefix of the session ids unique to the run
+func newPrefix(t *testing.T) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return "storetest_" + hex.EncodeToString(b)
+}
+
+// exists Fail t unless sid exists as expected
+func exists(t *testing.T, store session.ManagerStore, sid string, want bool) {
+	t.Helper()
+	ok, err := store.Check(request(t), sid)
+	if err != nil {
+		t.Fatalf("check %s: %v", sid, err)
+	} else if ok != want {
+		t.Fatalf("check %s: %v, want %v", sid, ok, want)
+	}
+}
+
+// value Fail t unless the session store holds v for key, or nothing if v is nil
+func value(t *testing.T, st session.Store, key string, v interface{}) {
+	t.Helper()
+	got, ok := st.Get(key)
+	switch {
+	case v == nil && ok:
+		t.Fatalf("get %s of %s: %v, want none", key, st.SessionID(), got)
+	case v != nil && (!ok || got != v):
+		t.Fatalf("get %s of %s: %v (%v), want %v", key, st.SessionID(), got, ok, v)
+	}
+}
+
+// saved Create the session sid holding values
+func saved(t *testing.T, store session.ManagerStore, sid string, values map[string]interface{}) {
+	t.Helper()
+	st, err := store.Create(request(t), sid, expired)
+	if err != nil {
+		t.Fatalf("create %s: %v", sid, err)
+	}
+	for k, v := range values {
+		st.Set(k, v)
+	}
+	if err := st.Save(); err != nil {
+		t.Fatalf("save %s: %v", sid, err)
+	}
+}
+
+// update Load the session sid
+func update(t *testing.T, store session.ManagerStore, sid string) session.Store {
+	t.Helper()
+	st, err := store.Update(request(t), sid, expired)
+	if err != nil {
+		t.Fatalf("update %s: %v", sid, err)
+	}
+	return st
+}
+
+func testPersistence(t *testing.T, store session.ManagerStore, sid string) {
+	st, err := store.Create(request(t), sid, expired)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if st.SessionID() != sid {
+		t.Fatalf("session id %q, want %q", st.SessionID(), sid)
+	}
+	st.Set("user", "u1")
+	st.Set("count", 2.0)
+	value(t, st, "user", "u1")
+	exists(t, store, sid, false)
+	if err := st.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	exists(t, store, sid, true)
+
+	st = update(t, store, sid)
+	value(t, st, "user", "u1")
+	value(t, st, "count", 2.0)
+	st.Set("count", 3.0)
+	if v := st.Delete("user"); v != "u1" {
+		t.Fatalf("delete user: %v, want u1", v)
+	}
+	// the changes are only kept by Save
+	st = update(t, store, sid)
+	value(t, st, "count", 2.0)
+	st.Set("count", 3.0)
+	st.Delete("user")
+	if err := st.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	st = update(t, store, sid)
+	value(t, st, "count", 3.0)
+	value(t, st, "user", nil)
+
+	// a session loaded without saving isn't persisted
+	st = update(t, store, sid+"_unsaved")
+	value(t, st, "user", nil)
+	exists(t, store, sid+"_unsaved", false)
+}
+
+func testDelete(t *testing.T, store session.ManagerStore, sid string) {
+	saved(t, store, sid, map[string]interface{}{"user": "u1"})
+	if err := store.Delete(request(t), sid); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	exists(t, store, sid, false)
+	value(t, update(t, store, sid), "user", nil)
+}
+
+func testFlush(t *testing.T, store session.ManagerStore, sid string) {
+	saved(t, store, sid, map[string]interface{}{"user": "u1", "lang": "fr"})
+	st := update(t, store, sid)
+	if err := st.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	value(t, st, "user", nil)
+	st = update(t, store, sid)
+	value(t, st, "user", nil)
+	value(t, st, "lang", nil)
+}
+
+func testRefresh(t *testing.T, store session.ManagerStore, oldsid, sid, missing string) {
+	ctx := request(t)
+	saved(t, store, oldsid, map[string]interface{}{"user": "u1"})
+	st, err := store.Refresh(ctx, oldsid, sid, expired)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if st.SessionID() != sid {
+		t.Fatalf("refreshed session id %q, want %q", st.SessionID(), sid)
+	}
+	value(t, st, "user", "u1")
+	st.Set("step", "2")
+	if err := st.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	exists(t, store, oldsid, false)
+	st = update(t, store, sid)
+	value(t, st, "user", "u1")
+	value(t, st, "step", "2")
+
+	// the refresh of a missing session starts a new one
+	st, err = store.Refresh(ctx, missing, missing+"_new", expired)
+	if err != nil {
+		t.Fatalf("refresh missing: %v", err)
+	}
+	value(t, st, "user", nil)
+}
+
+func testRefreshRace(t *testing.T, store session.ManagerStore, oldsid, a, b string) {
+	saved(t, store, oldsid, map[string]interface{}{"user": "u1"})
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, sid := range []string{a, b} {
+		wg.Add(1)
+		go func(i int, sid string) {
+			defer wg.Done()
+			st, err := store.Refresh(request(t), oldsid, sid, expired)
+			if err == nil {
+				err = st.Save()
+			}
+			errs[i] = err
+		}(i, sid)
+	}
+	wg.Wait()
+
+	exists(t, store, oldsid, false)
+	moved := 0
+	for i, sid := range []string{a, b} {
+		if errs[i] != nil {
+			// the store may tell the refresh which lost
+			continue
+		}
+		if v, ok := update(t, store, sid).Get("user"); ok && v == "u1" {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatalf("no refresh moved the values (errors %v)", errs)
+	}
+}
+
+func testConcurrency(t *testing.T, store session.ManagerStore, sids []string) {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(sids))
+	for i, sid := range sids {
+		wg.Add(1)
+		go func(i int, sid string) {
+			defer wg.Done()
+			ctx := request(t)
+			for round := 0; round < 5; round++ {
+				st, err := store.Update(ctx, sid, expired)
+				if err != nil {
+					errs <- fmt.Errorf("update %s: %w", sid, err)
+					return
+				}
+				st.Set("owner", fmt.Sprint(i))
+				st.Set("round", float64(round))
+				if err := st.Save(); err != nil {
+					errs <- fmt.Errorf("save %s: %w", sid, err)
+					return
+				}
+			}
+		}(i, sid)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	for i, sid := range sids {
+		st := update(t, store, sid)
+		value(t, st, "owner", fmt.Sprint(i))
+		value(t, st, "round", 4.0)
+	}
+}
+
+func testExpiration(t *testing.T, store session.ManagerStore, sid string) {
+	ctx := request(t)
+	st, err := store.Create(ctx, sid, 1)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	st.Set("user", "u1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	exists(t, store, sid, true)
+
+	time.Sleep(2 * time.Second)
+	exists(t, store, sid, false)
+	value(t, update(t, store, sid), "user", nil)
+}
@@ -0,0 +1,24 @@
+package storetest_test
+
+import (
+	"testing"
+	"time"
+
+	session "github.com/go-session/session/v3"
+
+	"github.com/go-session/mongo/v3"
+	"github.com/go-session/mongo/v3/decorator"
+	"github.com/go-session/mongo/v3/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) session.ManagerStore {
+		return mongo.NewMemoryStore()
+	})
+}
+
+func TestCachedMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) session.ManagerStore {
+		return decorator.Chain(mongo.NewMemoryStore(), decorator.Cache(100, 500*time.Millisecond))
+	})
+}
@@ -0,0 +1,119 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrValueType The session value is of a type the store doesn't decode back as is
+// (e.g. an int64 loaded as a float64 with JSONCodec)
+var ErrValueType = errors.New("session value type doesn't round-trip")
+
+// StrictTypesMode How the stores handle the values of the types that don't round-trip
+type StrictTypesMode int
+
+// Strict types modes
+const (
+	// StrictTypesOff Store the values of any type (default)
+	StrictTypesOff StrictTypesMode = iota
+	// StrictTypesError Reject the values, the next Save of the store failing with the
+	// error (wrapping ErrValueType) and Mutate returning it
+	StrictTypesError
+	// StrictTypesPanic Panic on the values, to catch them during the development
+	StrictTypesPanic
+)
+
+// WithStrictTypes Set how the stores handle the session values which wouldn't be decoded
+// back with their type (StrictTypesOff by default): those other than strings, bools, float64,
+// nil, map[string]interface{} and []interface{} of them with JSONCodec; int32, int64 and the
+// bson types are accepted as well with WithDocumentValues, and the types registered with
+// WithValueType always are. The values aren't checked with the other codecs
+func WithStrictTypes(mode StrictTypesMode) Option {
+	return func(o *options) {
+		o.strictTypes = mode
+	}
+}
+
+// checkType Check that the value v of key round-trips with the codec of o
+func (o *options) checkType(key string, v interface{}) error {
+	if o.strictTypes == StrictTypesOff {
+		return nil
+	}
+	documents := o.documents && o.cipher == nil
+	if _, ok := o.codec.(JSONCodec); !ok && !documents {
+		return nil
+	}
+	if t := o.unfaithful(v, documents); t != nil {
+		return fmt.Errorf("%w: %q of type %s", ErrValueType, key, t)
+	}
+	return nil
+}
+
+// unfaithful The type within v which doesn't round-trip, nil if none
+func (o *options) unfaithful(v interface{}, documents bool) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	if _, ok := o.valueTypes[reflect.TypeOf(v)]; ok {
+		return nil
+	}
+	switch v := v.(type) {
+	case string, bool, float64:
+		return nil
+	case map[string]interface{}:
+		for _, e := range v {
+			if t := o.unfaithful(e, documents); t != nil {
+				return t
+			}
+		}
+		return nil
+	case []interface{}:
+		for _, e := range v {
+			if t := o.unfaithful(e, documents); t != nil {
+				return t
+			}
+		}
+		return nil
+	}
+	if documents {
+		switch v := v.(type) {
+		case int32, int64, bson.Binary, bson.DateTime, bson.ObjectID, bson.Decimal128:
+			return nil
+		case bson.M:
+			return o.unfaithful(map[string]interface{}(v), documents)
+		case bson.A:
+			return o.unfaithful([]interface{}(v), documents)
+		}
+	}
+	return reflect.TypeOf(v)
+}
+
+// admit Tell whether the value v of key can be set, recording or panicking on the error
+// of a rejected one
+func (s *store) admit(key string, v interface{}) bool {
+	err := s.mstore.opts.checkType(key, v)
+	if err == nil {
+		return true
+	}
+	if s.mstore.opts.strictTypes == StrictTypesPanic {
+		panic("mongo: " + err.Error())
+	}
+	s.Lock()
+	if s.typeErr == nil {
+		s.typeErr = err
+	}
+	s.Unlock()
+	return false
+}
+
+// takeTypeErr The error of the first value rejected since the last save, cleared
+func (s *store) takeTypeErr() error {
+	s.Lock()
+	defer s.Unlock()
+	err := s.typeErr
+	s.typeErr = nil
+	return err
+}
@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestStrictTypes(t *testing.T) {
+	Convey("Test the rejection of the values which don't round-trip", t, func() {
+		mstore := newOfflineStore(t, append(testValueTypes(), WithStrictTypes(StrictTypesError))...)
+		store := newStore(context.Background(), mstore, "test_strict", 10, nil)
+
+		store.Set("name", "x")
+		store.Set("nested", map[string]interface{}{"ids": []interface{}{1.0, testOrderID("o-1")}})
+		store.Set("count", int64(1))
+		store.Set("at", time.Now())
+		_, ok := store.Get("count")
+		So(ok, ShouldBeFalse)
+		So(store.Len(), ShouldEqual, 2)
+
+		err := store.Save()
+		So(errors.Is(err, ErrValueType), ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, `"count" of type int64`)
+
+		store.SetMulti(map[string]interface{}{"a": "x", "b": []interface{}{map[string]interface{}{"n": 2}}})
+		So(store.Len(), ShouldEqual, 2)
+		So(errors.Is(store.takeTypeErr(), ErrValueType), ShouldBeTrue)
+
+		err = store.Mutate(func(values map[string]interface{}) error {
+			values["n"] = 3
+			return nil
+		})
+		So(errors.Is(err, ErrValueType), ShouldBeTrue)
+		So(store.Len(), ShouldEqual, 2)
+		So(store.takeTypeErr(), ShouldBeNil)
+
+		Convey("with the types of the documents", func() {
+			dstore := newOfflineStore(t, WithDocumentValues(true), WithStrictTypes(StrictTypesError))
+			store := newStore(context.Background(), dstore, "test_strict", 10, nil)
+			store.Set("count", int64(1))
+			store.Set("doc", bson.M{"n": int32(2)})
+			So(store.Len(), ShouldEqual, 2)
+			store.Set("n", 3)
+			So(errors.Is(store.takeTypeErr(), ErrValueType), ShouldBeTrue)
+		})
+
+		Convey("panicking", func() {
+			pstore := newOfflineStore(t, WithStrictTypes(StrictTypesPanic))
+			store := newStore(context.Background(), pstore, "test_strict", 10, nil)
+			So(func() { store.Set("n", 3) }, ShouldPanic)
+			So(func() { store.Set("n", 3.0) }, ShouldNotPanic)
+		})
+
+		Convey("unchecked with the other codecs", func() {
+			gstore := newOfflineStore(t, WithCodec(GobCodec{}), WithStrictTypes(StrictTypesError))
+			store := newStore(context.Background(), gstore, "test_strict", 10, nil)
+			store.Set("n", 3)
+			So(store.Len(), ShouldEqual, 1)
+		})
+	})
+}
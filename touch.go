@@ -0,0 +1,152 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// Toucher Implemented by the stores, to keep sessions alive without loading them
+type Toucher interface {
+	// Touch Extend the expiration of the unexpired session sid to expired seconds
+	// with a single update, the error wraps ErrSessionNotFound if there is no such session
+	// (ErrExpired if it has expired but is still stored)
+	Touch(ctx context.Context, sid string, expired int64) error
+}
+
+func (s *managerStore) Touch(ctx context.Context, sid string, expired int64) (err error) {
+	if t := s.tenantView(ctx); t != s {
+		return t.Touch(ctx, sid, expired)
+	}
+	tctx, op := s.begin(ctx, OpTouch, sid)
+	defer func() { err = op.end(0, err) }()
+	s.hit(sid)
+	if err := s.injectFault(ctx, OpTouch); err != nil {
+		return err
+	}
+
+	dbctx, cancel := s.callContext(tctx)
+	defer cancel()
+
+	var ok bool
+	err = s.retry(dbctx, OpTouch, func() (err error) {
+		ok, err = s.touch(dbctx, sid, expired)
+		if err == nil && !ok && s.anon != nil {
+			ok, err = s.anon.touch(dbctx, sid, expired)
+		}
+		return err
+	})
+	if err == nil && !ok {
+		return s.missing(dbctx, sid)
+	}
+	return err
+}
+
+// touch Extend the expiration of the unexpired document of sid, false if there is none
+func (s *managerStore) touch(ctx context.Context, sid string, expired int64) (bool, error) {
+	if s.spills() {
+		// the chunks of the value follow the expiration of the document
+		item, err := s.touchItem(ctx, sid, expired, false)
+		if err != nil || item == nil {
+			return false, err
+		}
+		s.pinExpiration(ctx, sid, item.ExpiredAt)
+		s.cacheExpiration(sid, item.ExpiredAt)
+		return true, nil
+	}
+	res, err := s.c.UpdateOne(ctx, s.unexpiredSelector(sid), s.touchPipeline(expired))
+	if err != nil {
+		return false, err
+	}
+	if item, ok := s.pinned(ctx, sid); ok && res.MatchedCount > 0 {
+		s.pinExpiration(ctx, sid, s.expiration(expired, item.CreatedAt))
+	}
+	if item, ok := s.cached(sid); ok && res.MatchedCount > 0 {
+		s.cacheExpiration(sid, s.expiration(expired, item.CreatedAt))
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// unexpiredSelector Query matching the session document if it is not expired
+func (s *managerStore) unexpiredSelector(sid string) bson.M {
+	q := s.selector(sid)
+	q["expired_at"] = bson.M{"$gte": s.notBefore(0)}
+	return q
+}
+
+// touchPipeline The update extending the expiration of a session document to expired
+// seconds, computed by the server from its creation time (recorded if missing)
+func (s *managerStore) touchPipeline(expired int64) mongo.Pipeline {
+	now := s.now()
+	ttl := time.Duration(expired) * time.Second
+	lifetime := func(d time.Duration) bson.M {
+		return bson.M{"$add": bson.A{"$created_at", d.Milliseconds()}}
+	}
+
+	var expiredAt interface{} = now.Add(ttl)
+	switch s.opts.expirationMode {
+	case AbsoluteExpiration:
+		if s.opts.maxLifetime > 0 {
+			ttl = s.opts.maxLifetime
+		}
+		expiredAt = lifetime(ttl)
+	case SlidingAbsoluteExpiration:
+		if s.opts.maxLifetime > 0 {
+			expiredAt = bson.M{"$min": bson.A{now.Add(ttl), lifetime(s.opts.maxLifetime)}}
+		}
+	}
+	if s.auth != nil && s.opts.anonMaxAge > 0 {
+		expiredAt = bson.M{"$min": bson.A{expiredAt, now.Add(s.opts.anonMaxAge)}}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"created_at": bson.M{"$ifNull": bson.A{"$created_at", now}}}}},
+		{{Key: "$set", Value: bson.M{"expired_at": expiredAt}}},
+	}
+}
+
+// fastUpdate Whether Update can load and extend the session in a single round trip to the
+// primary, i.e. the reads aren't meant to go elsewhere and ctx pins no session
+func (s *managerStore) fastUpdate(ctx context.Context) bool {
+	if len(s.affinity) > 0 || s.opts.hedged || writePinsFromContext(ctx) != nil {
+		return false
+	}
+	rp := s.opts.readPref
+	if tuned := s.opts.tunedReadPref(); tuned != nil {
+		rp = tuned
+	}
+	return rp == nil || rp.Mode() == readpref.PrimaryMode
+}
+
+// touchItem Extend the expiration of the unexpired document of sid and return it,
+// nil if there is none, the value is left out when withValue is false
+func (s *managerStore) touchItem(ctx context.Context, sid string, expired int64, withValue bool) (*sessionItem, error) {
+	opts := mopts.FindOneAndUpdate().SetReturnDocument(mopts.After)
+	if !withValue {
+		opts.SetProjection(bson.M{"value": 0, "value_next": 0})
+	}
+	var item sessionItem
+	err := s.c.FindOneAndUpdate(ctx, s.unexpiredSelector(sid), s.touchPipeline(expired), opts).Decode(&item)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := s.extendChunks(ctx, &item); err != nil {
+		return nil, err
+	}
+	if !withValue {
+		return &item, nil
+	}
+	if err := s.unspill(ctx, &item); err != nil {
+		return nil, err
+	}
+	if err := s.migrateID(ctx, sid, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func TestTouch(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+
+	Convey("Test the expiration updates done without loading the sessions", t, func() {
+		mstore := &managerStore{opts: newOptions(clock)}
+		p := mstore.touchPipeline(60)
+		So(p, ShouldHaveLength, 2)
+		So(p[1][0].Value, ShouldResemble, bson.M{"expired_at": now.Add(time.Minute)})
+
+		mstore = &managerStore{opts: newOptions(clock, WithExpiration(SlidingAbsoluteExpiration, time.Hour))}
+		p = mstore.touchPipeline(60)
+		So(p[1][0].Value, ShouldResemble, bson.M{"expired_at": bson.M{"$min": bson.A{
+			now.Add(time.Minute),
+			bson.M{"$add": bson.A{"$created_at", int64(3600000)}},
+		}}})
+
+		Convey("fast path of Update", func() {
+			ctx := context.Background()
+			So((&managerStore{opts: newOptions()}).fastUpdate(ctx), ShouldBeTrue)
+			So((&managerStore{opts: newOptions()}).fastUpdate(WithReadAfterWrite(ctx)), ShouldBeFalse)
+			So((&managerStore{opts: newOptions(WithReadPreference(readpref.Secondary()))}).fastUpdate(ctx), ShouldBeFalse)
+			So((&managerStore{opts: newOptions(WithHedgedReads(true))}).fastUpdate(ctx), ShouldBeFalse)
+		})
+
+		Convey("with faults", func() {
+			var toucher Toucher = newOfflineStore(t, WithFaultInjection(Fault{Op: OpTouch, Rate: 1, Err: ErrInjectedFault}))
+			So(errors.Is(toucher.Touch(context.Background(), "test_touch", 10), ErrInjectedFault), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,91 @@
+package mongo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// TraceRecord A store operation recorded in debug trace mode
+type TraceRecord struct {
+	Time   time.Time
+	Op     string
+	Key    string
+	Caller string
+	Err    error
+}
+
+func (r TraceRecord) String() string {
+	s := r.Time.Format("15:04:05.000000") + " " + r.Op
+	if r.Key != "" {
+		s += " " + r.Key
+	}
+	if r.Err != nil {
+		s += " error=" + r.Err.Error()
+	}
+	return s + " (" + r.Caller + ")"
+}
+
+// DebugTracer Implemented by stores created with the debug trace enabled
+type DebugTracer interface {
+	// DebugTrace Get the operations recorded on the store so far
+	DebugTrace() []TraceRecord
+}
+
+// WithDebugTrace Record every Get/Set/Delete/Flush/Save on the stores together with the
+// caller, the trace is written to out (os.Stderr if nil) when Save fails and can be
+// read any time through the DebugTracer interface, intended for debugging only
+func WithDebugTrace(out io.Writer) Option {
+	return func(o *options) {
+		if out == nil {
+			out = os.Stderr
+		}
+		o.traceOut = out
+	}
+}
+
+type debugTrace struct {
+	sync.Mutex
+	out     io.Writer
+	records []TraceRecord
+}
+
+// record Append an operation, skip is the number of frames between the caller and record
+func (t *debugTrace) record(skip int, op, key string, err error) {
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		caller = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	t.Lock()
+	t.records = append(t.records, TraceRecord{
+		Time:   time.Now(),
+		Op:     op,
+		Key:    key,
+		Caller: caller,
+		Err:    err,
+	})
+	t.Unlock()
+}
+
+func (t *debugTrace) dump(sidHash string) {
+	t.Lock()
+	defer t.Unlock()
+
+	fmt.Fprintf(t.out, "session %s trace:\n", sidHash)
+	for _, r := range t.records {
+		fmt.Fprintf(t.out, "\t%s\n", r)
+	}
+}
+
+func (s *store) DebugTrace() []TraceRecord {
+	if s.trace == nil {
+		return nil
+	}
+	s.trace.Lock()
+	defer s.trace.Unlock()
+	return append([]TraceRecord(nil), s.trace.records...)
+}
@@ -0,0 +1,29 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDebugTrace(t *testing.T) {
+	Convey("Test debug trace records store operations with callers", t, func() {
+		mstore := &managerStore{opts: newOptions(WithDebugTrace(new(bytes.Buffer)))}
+		store := newStore(context.Background(), mstore, "test_trace", 10, nil)
+
+		store.Set("foo", "bar")
+		store.Get("foo")
+		store.Delete("foo")
+
+		records := store.DebugTrace()
+		So(records, ShouldHaveLength, 3)
+		So(records[0].Op, ShouldEqual, "set")
+		So(records[1].Op, ShouldEqual, "get")
+		So(records[2].Op, ShouldEqual, "delete")
+		So(records[2].Key, ShouldEqual, "foo")
+		So(strings.Contains(records[0].Caller, "trace_test.go"), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// WithIndexCreation Set whether to create the indexes of the session collection when the
+// store is created (true by default), disable it when the indexes are managed by migrations
+func WithIndexCreation(create bool) Option {
+	return func(o *options) {
+		o.indexes = create
+	}
+}
+
+// WithTTLIndexOptions Set the name of the TTL index (the server default if empty) and the
+// delay after the expiration at which the server removes the documents (1s by default)
+func WithTTLIndexOptions(name string, expireAfter time.Duration) Option {
+	return func(o *options) {
+		o.ttlName = name
+		o.ttlExpireAfter = expireAfter
+	}
+}
+
+// WithCleanupInterval Remove the expired session documents every interval from the store
+// rather than with a TTL index (not created then), for the servers on which the TTL monitor
+// is missing or behaves differently, the cleanup stops when the store is closed
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.cleanupInterval = interval
+	}
+}
+
+// indexModels The indexes to create on the session collection
+func (o *options) indexModels() []mongo.IndexModel {
+	if !o.indexes {
+		return nil
+	}
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "ns", Value: 1}},
+			Options: mopts.Index().SetSparse(true),
+		},
+	}
+	switch {
+	case o.cleanupInterval > 0:
+		indexes = append(indexes, mongo.IndexModel{
+			Keys: bson.D{{Key: "expired_at", Value: 1}},
+		})
+	case o.ttlIndex:
+		expireAfter := int32(o.ttlExpireAfter / time.Second)
+		if o.ttlExpireAfter <= 0 {
+			expireAfter = 1
+		}
+		ttl := mopts.Index().SetExpireAfterSeconds(expireAfter)
+		if o.ttlName != "" {
+			ttl.SetName(o.ttlName)
+		}
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expired_at", Value: 1}},
+			Options: ttl,
+		})
+	}
+	return append(indexes, o.indexFieldModels()...)
+}
+
+// janitor The goroutine removing the expired documents of a store
+type janitor struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// startCleanup Start removing the expired documents of the collection of s periodically
+func (s *managerStore) startCleanup() {
+	if s.opts.cleanupInterval <= 0 {
+		return
+	}
+
+	j := &janitor{stop: make(chan struct{}), done: make(chan struct{})}
+	s.janitor = j
+	go func() {
+		defer close(j.done)
+		t := time.NewTicker(s.opts.cleanupInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				// failures are retried at the next tick
+				if _, err := s.cleanup(context.Background()); err != nil {
+					s.log(LevelWarn, "session cleanup failed", "collection", s.collectionName(), "error", err)
+				}
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// cleanup Remove the expired documents of the collection of s, of any namespace or owner
+func (s *managerStore) cleanup(ctx context.Context) (int64, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	q := bson.M{"expired_at": bson.M{"$lt": s.notBefore(0)}}
+	if s.keepsRemoved(ArchiveExpired) {
+		return s.retireExpired(dbctx, q)
+	}
+	res, err := s.c.DeleteMany(dbctx, q)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// stopCleanup Stop the cleanup goroutine of s and wait for it
+func (s *managerStore) stopCleanup() {
+	if j := s.janitor; j != nil {
+		j.once.Do(func() { close(j.stop) })
+		<-j.done
+	}
+}
@@ -0,0 +1,46 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestExpirationStrategy(t *testing.T) {
+	Convey("Test the indexes and cleanup expiring the sessions", t, func() {
+		o := newOptions()
+		So(o.indexModels(), ShouldHaveLength, 2)
+
+		o = newOptions(WithIndexCreation(false))
+		So(o.indexModels(), ShouldBeEmpty)
+
+		o = newOptions(WithTTLIndex(false))
+		So(o.indexModels(), ShouldHaveLength, 1)
+
+		o = newOptions(WithTTLIndexOptions("session_ttl", time.Hour))
+		indexes := o.indexModels()
+		ttl := indexes[1].Options
+		var io mopts.IndexOptions
+		for _, set := range ttl.List() {
+			So(set(&io), ShouldBeNil)
+		}
+		So(*io.Name, ShouldEqual, "session_ttl")
+		So(*io.ExpireAfterSeconds, ShouldEqual, 3600)
+
+		o = newOptions(WithCleanupInterval(time.Minute))
+		indexes = o.indexModels()
+		So(indexes, ShouldHaveLength, 2)
+		So(indexes[1].Options, ShouldBeNil)
+
+		Convey("cleanup goroutine", func() {
+			mstore := newOfflineStore(t, WithCleanupInterval(time.Millisecond), WithOperationTimeout(time.Millisecond))
+			mstore.startCleanup()
+			So(mstore.janitor, ShouldNotBeNil)
+			time.Sleep(5 * time.Millisecond)
+			So(mstore.Close(), ShouldBeNil)
+			So(mstore.Close(), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,157 @@
+package mongo
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// Bounds of the tunables of UpdateOptions
+const (
+	MaxTunedCacheSize     = 1 << 20
+	MaxTunedRetryAttempts = 10
+)
+
+// Tunables The options changed at runtime by UpdateOptions, the nil fields being left as is
+type Tunables struct {
+	// CacheSize The number of session documents of WithCache (1 to MaxTunedCacheSize), the
+	// least recently used ones beyond it being evicted; the cache must be enabled
+	CacheSize *int
+	// SlowThreshold The threshold of WithSlowThreshold, 0 disabling the warnings
+	SlowThreshold *time.Duration
+	// RetryAttempts The MaxAttempts of the policies of WithRetryPolicy and
+	// WithOperationRetryPolicy (1 to MaxTunedRetryAttempts), 1 disabling the retries
+	RetryAttempts *int
+	// ReadPreference The read preference of the session reads, instead of the one of
+	// WithReadPreference; not with WithSessionAffinity or WithHedgedReads
+	ReadPreference *readpref.ReadPref
+}
+
+// Reconfigurer Implemented by the stores, to change the tunables at runtime, e.g. to shed the
+// retries or move the reads away from a struggling member during an incident without
+// restarting the instances
+type Reconfigurer interface {
+	// UpdateOptions Apply the non nil tunables of t to the store and its views, all or none:
+	// the error is an *OptionsError listing the rejected ones
+	UpdateOptions(t Tunables) error
+}
+
+// tunedOptions The tunables changed by UpdateOptions, shared by the views of a store
+type tunedOptions struct {
+	// updating Serializes the updates
+	updating      sync.Mutex
+	slowThreshold atomic.Pointer[time.Duration]
+	retryAttempts atomic.Int32
+	reads         atomic.Pointer[tunedReads]
+}
+
+// tunedReads The read preference of UpdateOptions with the handles of the collections reading
+// with it, replaced together
+type tunedReads struct {
+	rp    *readpref.ReadPref
+	colls sync.Map
+}
+
+// slowOperation The threshold of the slow operations
+func (o *options) slowOperation() time.Duration {
+	if t := o.tuned.slowThreshold.Load(); t != nil {
+		return *t
+	}
+	return o.slowThreshold
+}
+
+// tunedReadPref The read preference set by UpdateOptions, nil if none
+func (o *options) tunedReadPref() *readpref.ReadPref {
+	if r := o.tuned.reads.Load(); r != nil {
+		return r.rp
+	}
+	return nil
+}
+
+// tunedCollection The handle of c reading with the read preference set by UpdateOptions,
+// c itself if none is
+func (o *options) tunedCollection(c *mongo.Collection) *mongo.Collection {
+	r := o.tuned.reads.Load()
+	if r == nil {
+		return c
+	}
+	if tc, ok := r.colls.Load(c); ok {
+		return tc.(*mongo.Collection)
+	}
+	tc, _ := r.colls.LoadOrStore(c, c.Clone(mopts.Collection().SetReadPreference(r.rp)))
+	return tc.(*mongo.Collection)
+}
+
+// resize Change the number of documents of the cache, evicting the least recently used ones
+func (c *itemCache) resize(size int) {
+	c.Lock()
+	defer c.Unlock()
+	c.size = size
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (s *managerStore) UpdateOptions(t Tunables) error {
+	tuned := s.opts.tuned
+	tuned.updating.Lock()
+	defer tuned.updating.Unlock()
+
+	var problems []OptionsProblem
+	reject := func(msg string, opts ...string) {
+		problems = append(problems, OptionsProblem{Options: opts, Message: msg})
+	}
+	if t.CacheSize != nil {
+		if s.opts.cache == nil {
+			reject("the cache isn't enabled, it can only be resized", "CacheSize", "WithCache")
+		} else if *t.CacheSize < 1 || *t.CacheSize > MaxTunedCacheSize {
+			reject(fmt.Sprintf("the cache size %d isn't between 1 and %d", *t.CacheSize, MaxTunedCacheSize), "CacheSize")
+		}
+	}
+	if t.SlowThreshold != nil && *t.SlowThreshold < 0 {
+		reject(fmt.Sprintf("the slow threshold %s is negative", *t.SlowThreshold), "SlowThreshold")
+	}
+	if t.RetryAttempts != nil && (*t.RetryAttempts < 1 || *t.RetryAttempts > MaxTunedRetryAttempts) {
+		reject(fmt.Sprintf("the retry attempts %d aren't between 1 and %d", *t.RetryAttempts, MaxTunedRetryAttempts), "RetryAttempts")
+	}
+	if rp := t.ReadPreference; rp != nil {
+		if len(s.affinity) > 0 || s.opts.hedged {
+			reject("the read preference is derived from the affinity or the hedging set at the creation", "ReadPreference", "WithSessionAffinity", "WithHedgedReads")
+		} else if rp.Mode() == readpref.PrimaryMode && s.opts.maxReadLag > 0 {
+			reject("the lag guard is for the secondary reads, the primary ones are never stale", "ReadPreference", "WithReadLagGuard")
+		}
+	}
+	if len(problems) > 0 {
+		return &OptionsError{Problems: problems}
+	}
+
+	var changed []interface{}
+	if t.CacheSize != nil {
+		s.opts.cache.resize(*t.CacheSize)
+		changed = append(changed, "cache_size", *t.CacheSize)
+	}
+	if t.SlowThreshold != nil {
+		threshold := *t.SlowThreshold
+		tuned.slowThreshold.Store(&threshold)
+		changed = append(changed, "slow_threshold", threshold)
+	}
+	if t.RetryAttempts != nil {
+		tuned.retryAttempts.Store(int32(*t.RetryAttempts))
+		changed = append(changed, "retry_attempts", *t.RetryAttempts)
+	}
+	if t.ReadPreference != nil {
+		tuned.reads.Store(&tunedReads{rp: t.ReadPreference})
+		changed = append(changed, "read_preference", t.ReadPreference.Mode().String())
+	}
+	if len(changed) > 0 {
+		s.log(LevelInfo, "session store options updated", append(changed, "collection", s.collectionName())...)
+	}
+	return nil
+}
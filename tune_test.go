@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func TestUpdateOptions(t *testing.T) {
+	Convey("Test the changes of the tunables at runtime", t, func() {
+		var entries []string
+		logger := func(level LogLevel, msg string, kv ...interface{}) { entries = append(entries, msg) }
+		mstore := newOfflineStore(t, WithCache(3, time.Minute), WithLogger(logger),
+			WithSlowThreshold(time.Second), WithRetryPolicy(RetryPolicy{MaxAttempts: 4}))
+		for _, sid := range []string{"a", "b", "c"} {
+			mstore.cache(sid, sessionItem{ID: sid, ExpiredAt: time.Now().Add(time.Hour)})
+		}
+
+		size, slow, attempts := 2, time.Duration(0), 1
+		err := mstore.UpdateOptions(Tunables{CacheSize: &size, SlowThreshold: &slow, RetryAttempts: &attempts, ReadPreference: readpref.SecondaryPreferred()})
+		So(err, ShouldBeNil)
+		_, ok := mstore.cached("a")
+		So(ok, ShouldBeFalse)
+		_, ok = mstore.cached("c")
+		So(ok, ShouldBeTrue)
+		So(mstore.opts.slowOperation(), ShouldEqual, 0)
+		So(mstore.opts.retryPolicy(OpSave).MaxAttempts, ShouldEqual, 1)
+		So(mstore.newSaveID(), ShouldBeEmpty)
+		So(entries, ShouldContain, "session store options updated")
+
+		c := mstore.readCollection(context.Background())
+		So(c, ShouldNotEqual, mstore.c)
+		So(mstore.readCollection(context.Background()), ShouldEqual, c)
+		So(mstore.readCollection(WithPrimaryRead(context.Background())), ShouldEqual, mstore.cPrimary)
+		So(mstore.fastUpdate(context.Background()), ShouldBeFalse)
+
+		Convey("The views follow the changes", func() {
+			view := mstore.Namespace("web").(*managerStore)
+			So(view.opts.retryPolicy(OpCheck).MaxAttempts, ShouldEqual, 1)
+		})
+
+		Convey("The unsafe changes are rejected as a whole", func() {
+			size, attempts = 0, 50
+			err := mstore.UpdateOptions(Tunables{CacheSize: &size, RetryAttempts: &attempts})
+			var oe *OptionsError
+			So(errors.As(err, &oe), ShouldBeTrue)
+			So(oe.Problems, ShouldHaveLength, 2)
+			So(mstore.opts.retryPolicy(OpSave).MaxAttempts, ShouldEqual, 1)
+
+			other := newOfflineStore(t, WithHedgedReads(true))
+			So(other.UpdateOptions(Tunables{CacheSize: &attempts, ReadPreference: readpref.Nearest()}), ShouldNotBeNil)
+		})
+	})
+}
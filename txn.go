@@ -0,0 +1,99 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// WithRefreshTransactions Set whether Refresh writes the new session document and removes
+// the old one in a transaction (true by default), so that a failure in between can't leave
+// both sessions alive or neither, the same goes for the moves of sessions between the
+// anonymous and authenticated collections; standalone servers not supporting transactions
+// are detected and get the two separate writes
+func WithRefreshTransactions(enabled bool) Option {
+	return func(o *options) {
+		o.refreshTxn = enabled
+	}
+}
+
+// txnSupport Whether the server of a store turned out not to support transactions
+type txnSupport struct {
+	unsupported int32
+}
+
+func (t *txnSupport) supported() bool {
+	return atomic.LoadInt32(&t.unsupported) == 0
+}
+
+func (t *txnSupport) setUnsupported() {
+	atomic.StoreInt32(&t.unsupported, 1)
+}
+
+// isTxnUnsupported Whether err is the rejection of a transaction by a standalone server
+func isTxnUnsupported(err error) bool {
+	var ce mongo.CommandError
+	if errors.As(err, &ce) && ce.Code == 20 {
+		return true
+	}
+	return err != nil && strings.Contains(err.Error(), "Transaction numbers are only allowed")
+}
+
+// moveItem Write the document of sid and remove the one of oldsid,
+// in a transaction when the server supports it
+func (s *managerStore) moveItem(ctx context.Context, oldsid, sid string, item *sessionItem) error {
+	return s.transfer(ctx, s, oldsid, sid, item)
+}
+
+// transfer Write the document of sid and remove the one of oldsid from the collection of from,
+// in a transaction when the server supports it
+func (s *managerStore) transfer(ctx context.Context, from *managerStore, oldsid, sid string, item *sessionItem) error {
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.upsert(ctx, sid, item); err != nil {
+			return err
+		}
+		if from == s && oldsid == sid {
+			return nil
+		}
+		if err := s.moveScratch(ctx, oldsid, sid); err != nil {
+			return err
+		}
+		return from.retire(ctx, oldsid, ArchiveRefreshed, sid)
+	})
+	if err != nil {
+		// the writes may have been rolled back
+		s.uncache(sid)
+		from.uncache(oldsid)
+	}
+	return err
+}
+
+// inTransaction Run fn in a transaction when enabled and supported by the server, else directly,
+// the transaction joins the session of ctx if any (see CausalRunner)
+func (s *managerStore) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !s.opts.refreshTxn || !s.opts.txn.supported() {
+		return fn(ctx)
+	}
+
+	sess := mongo.SessionFromContext(ctx)
+	if sess == nil {
+		var err error
+		if sess, err = s.client.StartSession(); err != nil {
+			return err
+		}
+		defer sess.EndSession(context.Background())
+	}
+
+	_, err := sess.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+	if isTxnUnsupported(err) {
+		s.log(LevelInfo, "transactions unsupported by the server, moving the sessions without")
+		s.opts.txn.setUnsupported()
+		err = fn(ctx)
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestRefreshTransactions(t *testing.T) {
+	Convey("Test the detection of the servers without transactions", t, func() {
+		So(isTxnUnsupported(nil), ShouldBeFalse)
+		So(isTxnUnsupported(ErrInjectedFault), ShouldBeFalse)
+		So(isTxnUnsupported(mongo.CommandError{Code: 20, Message: "Transaction numbers are only allowed on a replica set member or mongos"}), ShouldBeTrue)
+		So(isTxnUnsupported(errors.New("(IllegalOperation) Transaction numbers are only allowed on a replica set member or mongos")), ShouldBeTrue)
+
+		o := newOptions()
+		So(o.refreshTxn, ShouldBeTrue)
+		So(o.txn.supported(), ShouldBeTrue)
+		o.txn.setUnsupported()
+		So(o.txn.supported(), ShouldBeFalse)
+
+		mstore := &managerStore{opts: o}
+		So(mstore.Namespace("ns").(*managerStore).opts.txn.supported(), ShouldBeFalse)
+		So(newOptions(WithRefreshTransactions(false)).refreshTxn, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,129 @@
+package mongo
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters of the connection strings configuring the store rather than the client
+const (
+	// URIParamTTLIndex "true" or "false", as WithTTLIndex
+	URIParamTTLIndex = "sessionTTLIndex"
+	// URIParamCodec "json", "gob" or "documents" (WithDocumentValues)
+	URIParamCodec = "sessionCodec"
+	// URIParamCompression "gzip", "snappy" or "none", as WithCompression with the minimum
+	// size of URIParamCompressionMin
+	URIParamCompression    = "sessionCompression"
+	URIParamCompressionMin = "sessionCompressionMin"
+	// URIParamCacheSize The size of WithCache, with the ttl of URIParamCacheTTL (e.g. "5s")
+	URIParamCacheSize = "sessionCacheSize"
+	URIParamCacheTTL  = "sessionCacheTTL"
+)
+
+// uriParamPrefix The prefix of the query parameters of the store, which no client option has
+const uriParamPrefix = "session"
+
+// uriOptions Split the query parameters of the store off uri, returning the connection string
+// of the client and the options of the parameters
+func uriOptions(uri string) (string, []Option, error) {
+	i := strings.Index(uri, "?")
+	if i < 0 {
+		return uri, nil, nil
+	}
+	var kept []string
+	params := make(map[string]string)
+	for _, pair := range strings.Split(uri[i+1:], "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		if !strings.HasPrefix(strings.ToLower(key), uriParamPrefix) {
+			kept = append(kept, pair)
+			continue
+		}
+		v, err := neturl.QueryUnescape(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("connection string parameter %s: %w", key, err)
+		}
+		params[strings.ToLower(key)] = v
+	}
+	if len(params) == 0 {
+		return uri, nil, nil
+	}
+	if uri = uri[:i]; len(kept) > 0 {
+		uri += "?" + strings.Join(kept, "&")
+	}
+
+	param := func(name string) (string, bool) {
+		v, ok := params[strings.ToLower(name)]
+		delete(params, strings.ToLower(name))
+		return v, ok
+	}
+	invalid := func(name, value string) error {
+		return fmt.Errorf("invalid connection string parameter %s=%q", name, value)
+	}
+	var opts []Option
+	if v, ok := param(URIParamTTLIndex); ok {
+		create, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", nil, invalid(URIParamTTLIndex, v)
+		}
+		opts = append(opts, WithTTLIndex(create))
+	}
+	if v, ok := param(URIParamCodec); ok {
+		switch strings.ToLower(v) {
+		case "json":
+			opts = append(opts, WithCodec(JSONCodec{}))
+		case "gob":
+			opts = append(opts, WithCodec(GobCodec{}))
+		case "documents":
+			opts = append(opts, WithDocumentValues(true))
+		default:
+			return "", nil, invalid(URIParamCodec, v)
+		}
+	}
+	minSize, hasMin := param(URIParamCompressionMin)
+	if v, ok := param(URIParamCompression); ok {
+		var algo Compression
+		switch strings.ToLower(v) {
+		case "none":
+			algo = NoCompression
+		case "gzip":
+			algo = GzipCompression
+		case "snappy":
+			algo = SnappyCompression
+		default:
+			return "", nil, invalid(URIParamCompression, v)
+		}
+		n := 0
+		if hasMin {
+			var err error
+			if n, err = strconv.Atoi(minSize); err != nil || n < 0 {
+				return "", nil, invalid(URIParamCompressionMin, minSize)
+			}
+		}
+		opts = append(opts, WithCompression(algo, n))
+	} else if hasMin {
+		return "", nil, fmt.Errorf("connection string parameter %s without %s", URIParamCompressionMin, URIParamCompression)
+	}
+	ttl, hasTTL := param(URIParamCacheTTL)
+	if v, ok := param(URIParamCacheSize); ok {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 0 {
+			return "", nil, invalid(URIParamCacheSize, v)
+		}
+		var d time.Duration
+		if hasTTL {
+			if d, err = time.ParseDuration(ttl); err != nil {
+				return "", nil, invalid(URIParamCacheTTL, ttl)
+			}
+		}
+		opts = append(opts, WithCache(size, d))
+	} else if hasTTL {
+		return "", nil, fmt.Errorf("connection string parameter %s without %s", URIParamCacheTTL, URIParamCacheSize)
+	}
+	for key := range params {
+		return "", nil, fmt.Errorf("unknown connection string parameter %s", key)
+	}
+	return uri, opts, nil
+}
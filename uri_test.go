@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestURIOptions(t *testing.T) {
+	Convey("Test the store parameters of the connection strings", t, func() {
+		uri, opts, err := uriOptions("mongodb://u:p@h1,h2/app?replicaSet=rs0&sessionTTLIndex=false&sessionCodec=documents&sessioncompression=snappy&sessionCompressionMin=64&sessionCacheSize=500&sessionCacheTTL=2s&w=majority")
+		So(err, ShouldBeNil)
+		So(uri, ShouldEqual, "mongodb://u:p@h1,h2/app?replicaSet=rs0&w=majority")
+		o := newOptions(opts...)
+		So(o.ttlIndex, ShouldBeFalse)
+		So(o.documents, ShouldBeTrue)
+		So(o.compression, ShouldEqual, SnappyCompression)
+		So(o.compressionMin, ShouldEqual, 64)
+		So(o.cache, ShouldNotBeNil)
+		So(o.cache.size, ShouldEqual, 500)
+		So(o.cache.ttl, ShouldEqual, 2*time.Second)
+
+		uri, opts, err = uriOptions("mongodb://h1/?sessionCodec=gob")
+		So(err, ShouldBeNil)
+		So(uri, ShouldEqual, "mongodb://h1/")
+		So(newOptions(opts...).codec, ShouldResemble, GobCodec{})
+
+		uri, opts, err = uriOptions("mongodb://h1/app?tls=true")
+		So(err, ShouldBeNil)
+		So(uri, ShouldEqual, "mongodb://h1/app?tls=true")
+		So(opts, ShouldBeEmpty)
+
+		Convey("The explicit options win", func() {
+			_, uopts, err := uriOptions("mongodb://h1/?sessionTTLIndex=false")
+			So(err, ShouldBeNil)
+			So(newOptions(append(uopts, WithTTLIndex(true))...).ttlIndex, ShouldBeTrue)
+		})
+
+		Convey("The invalid parameters are rejected", func() {
+			for _, uri := range []string{
+				"mongodb://h1/?sessionTTLIndex=maybe",
+				"mongodb://h1/?sessionCodec=xml",
+				"mongodb://h1/?sessionCompression=lz4",
+				"mongodb://h1/?sessionCompressionMin=10",
+				"mongodb://h1/?sessionCacheSize=-1",
+				"mongodb://h1/?sessionCacheSize=10&sessionCacheTTL=soon",
+				"mongodb://h1/?sessionCacheTTL=1s",
+				"mongodb://h1/?sessionTTL=1h",
+			} {
+				_, _, err := uriOptions(uri)
+				So(err, ShouldNotBeNil)
+			}
+			_, err := NewStoreWithError("mongodb://127.0.0.1:1/?sessionCodec=xml", "", "")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
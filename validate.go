@@ -0,0 +1,85 @@
+package mongo
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// OptionsProblem An incoherent combination of options
+type OptionsProblem struct {
+	// Options The names of the options combined (e.g. "WithHedgedReads")
+	Options []string
+	// Message What is wrong and how to fix it
+	Message string
+}
+
+// OptionsError The incoherent combinations of options rejected by ValidateOptions and the
+// constructors of the stores returning an error
+type OptionsError struct {
+	Problems []OptionsProblem
+}
+
+func (e *OptionsError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = strings.Join(p.Options, " + ") + ": " + p.Message
+	}
+	return "invalid session store options: " + strings.Join(msgs, "; ")
+}
+
+// ValidateOptions Check that opts can be combined, the error is an *OptionsError listing the
+// incoherent combinations; NewStore and the other constructors validate their options
+func ValidateOptions(opts ...Option) error {
+	o := newOptions(opts...)
+	return o.validate()
+}
+
+// validate Check the combinations of the options
+func (o *options) validate() error {
+	var problems []OptionsProblem
+	reject := func(msg string, opts ...string) {
+		problems = append(problems, OptionsProblem{Options: opts, Message: msg})
+	}
+
+	primary := o.readPref != nil && o.readPref.Mode() == readpref.PrimaryMode
+	if o.hedged && primary {
+		reject("hedged reads go to the secondaries, drop one of the options or set a secondary read preference", "WithHedgedReads", "WithReadPreference")
+	}
+	if o.maxReadLag > 0 && primary {
+		reject("the reads from the primary don't lag, drop WithReadLagGuard", "WithReadLagGuard", "WithReadPreference")
+	}
+	if o.invalidation && o.cache == nil {
+		reject("the change stream invalidates the cache, enable it with WithCache or drop WithChangeStreamInvalidation", "WithChangeStreamInvalidation")
+	}
+	if o.cleanupInterval > 0 && (o.ttlName != "" || o.ttlExpireAfter > 0) {
+		reject("the periodic cleanup replaces the TTL index, drop one of the options", "WithCleanupInterval", "WithTTLIndexOptions")
+	}
+	if !o.ttlIndex && (o.ttlName != "" || o.ttlExpireAfter > 0) {
+		reject("the TTL index isn't created, drop WithTTLIndexOptions or WithTTLIndex(false)", "WithTTLIndex", "WithTTLIndexOptions")
+	}
+	if o.anonCollection != "" && o.anonCollection == o.cName {
+		reject(fmt.Sprintf("the anonymous sessions need another collection than %q", o.cName), "WithAnonymousCollection", "WithCollection")
+	}
+	if o.spillThreshold > 0 && o.maxSize > 0 && o.spillThreshold >= o.maxSize && o.oversize != OversizeAllow {
+		reject(fmt.Sprintf("the values larger than the max size of %d bytes are never spilled, lower the spillover threshold %d", o.maxSize, o.spillThreshold), "WithSpillover", "WithMaxSessionSize")
+	}
+	if o.saveCancel == SaveQueue && o.lockTTL > 0 {
+		reject("the queued saves outlive their request, so the lock would be released before the write lands; use SaveDetach or drop WithSessionLock", "WithSaveCancelPolicy(SaveQueue)", "WithSessionLock")
+	}
+	if o.saveCancel == SaveQueue && o.conflictPolicy != LastWriteWins {
+		reject("the conflicts of the queued saves can't be returned to their request, use SaveDetach or the LastWriteWins conflict policy", "WithSaveCancelPolicy(SaveQueue)", "WithConflictPolicy")
+	}
+	if o.nilCipher {
+		reject("encryption without key provider, pass a cipher such as NewAESGCMCipher(key) or drop WithCipher", "WithCipher")
+	}
+	if o.keyViolation != nil && !o.keyPolicy() {
+		reject("no key is refused, add WithAllowedKeys or WithDeniedKeys", "WithKeyViolation")
+	}
+
+	if len(problems) > 0 {
+		return &OptionsError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func TestValidateOptions(t *testing.T) {
+	Convey("Test the validation of the options", t, func() {
+		So(ValidateOptions(), ShouldBeNil)
+		So(ValidateOptions(WithCache(10, time.Second), WithChangeStreamInvalidation(), WithHedgedReads(true)), ShouldBeNil)
+
+		err := ValidateOptions(
+			WithHedgedReads(true),
+			WithReadPreference(readpref.Primary()),
+			WithChangeStreamInvalidation(),
+			WithCleanupInterval(time.Minute),
+			WithTTLIndexOptions("ttl", 0),
+		)
+		var oerr *OptionsError
+		So(errors.As(err, &oerr), ShouldBeTrue)
+		So(oerr.Problems, ShouldHaveLength, 3)
+		So(oerr.Problems[0].Options, ShouldResemble, []string{"WithHedgedReads", "WithReadPreference"})
+		So(err.Error(), ShouldContainSubstring, "WithCleanupInterval + WithTTLIndexOptions: ")
+
+		So(ValidateOptions(WithAnonymousCollection("session", time.Minute, nil)), ShouldNotBeNil)
+		So(ValidateOptions(WithSpillover(1024), WithMaxSessionSize(1024, OversizeReject)), ShouldNotBeNil)
+		So(ValidateOptions(WithSpillover(1024), WithMaxSessionSize(4096, OversizeReject)), ShouldBeNil)
+
+		Convey("the queued saves with the session locks", func() {
+			err := ValidateOptions(WithSaveCancelPolicy(SaveQueue, 0), WithSessionLock(time.Minute, time.Second))
+			So(errors.As(err, &oerr), ShouldBeTrue)
+			So(oerr.Problems, ShouldHaveLength, 1)
+			So(oerr.Problems[0].Options, ShouldResemble, []string{"WithSaveCancelPolicy(SaveQueue)", "WithSessionLock"})
+			So(ValidateOptions(WithSaveCancelPolicy(SaveDetach, 0), WithSessionLock(time.Minute, time.Second)), ShouldBeNil)
+		})
+
+		Convey("the queued saves with the conflict errors", func() {
+			for _, policy := range []ConflictPolicy{ConflictError, ConflictMerge} {
+				err := ValidateOptions(WithSaveCancelPolicy(SaveQueue, 0), WithConflictPolicy(policy))
+				So(errors.As(err, &oerr), ShouldBeTrue)
+				So(oerr.Problems, ShouldHaveLength, 1)
+				So(oerr.Problems[0].Options, ShouldResemble, []string{"WithSaveCancelPolicy(SaveQueue)", "WithConflictPolicy"})
+			}
+			So(ValidateOptions(WithSaveCancelPolicy(SaveQueue, 0), WithConflictPolicy(LastWriteWins)), ShouldBeNil)
+		})
+
+		Convey("the encryption without cipher", func() {
+			err := ValidateOptions(WithCipher(nil))
+			So(errors.As(err, &oerr), ShouldBeTrue)
+			So(oerr.Problems, ShouldHaveLength, 1)
+			So(err.Error(), ShouldContainSubstring, "encryption without key provider")
+		})
+
+		Convey("by the constructors", func() {
+			_, err := NewStoreWithError("mongodb://127.0.0.1:1", dbName, cName, WithChangeStreamInvalidation())
+			So(errors.As(err, &oerr), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,142 @@
+package mongo
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Keys of the stored values of the registered types
+const (
+	typeKey      = "__type"
+	typeValueKey = "__value"
+)
+
+// ValueType The conversion of the session values of a Go type to and from storable values
+type ValueType struct {
+	// Name The name of the type in the stored values
+	Name string
+	// Encode Convert a value of the type into a value the codec stores as is (string,
+	// number, bool, []interface{}, map[string]interface{}...)
+	Encode func(v interface{}) (interface{}, error)
+	// Decode Convert the stored value as decoded by the codec (e.g. float64 for the numbers
+	// of JSONCodec) back into a value of the type
+	Decode func(stored interface{}) (interface{}, error)
+}
+
+// WithValueType Store the session values of the type of sample (e.g. a custom id or a
+// decimal type) with the conversions of t, as a {"__type": t.Name, "__value": ...} map
+// decoded back into the type, for the values of the sessions and of their
+// map[string]interface{} and []interface{} values; the stored values of the types no
+// longer registered are loaded as their maps
+func WithValueType(sample interface{}, t ValueType) Option {
+	return func(o *options) {
+		if o.valueTypes == nil {
+			o.valueTypes = make(map[reflect.Type]ValueType)
+			o.valueTypeNames = make(map[string]ValueType)
+		}
+		o.valueTypes[reflect.TypeOf(sample)] = t
+		o.valueTypeNames[t.Name] = t
+	}
+}
+
+// encodeTypes The session values with the values of the registered types encoded,
+// values itself if no type is registered
+func (o *options) encodeTypes(values map[string]interface{}) (map[string]interface{}, error) {
+	if len(o.valueTypes) == 0 || values == nil {
+		return values, nil
+	}
+	encoded := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		var err error
+		if encoded[k], err = o.encodeType(v); err != nil {
+			return nil, fmt.Errorf("session value %q: %w", k, err)
+		}
+	}
+	return encoded, nil
+}
+
+// encodeType The value v with the values of the registered types encoded
+func (o *options) encodeType(v interface{}) (interface{}, error) {
+	if len(o.valueTypes) == 0 || v == nil {
+		return v, nil
+	}
+	if t, ok := o.valueTypes[reflect.TypeOf(v)]; ok {
+		stored, err := t.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{typeKey: t.Name, typeValueKey: stored}, nil
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return o.encodeTypes(v)
+	case []interface{}:
+		encoded := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if encoded[i], err = o.encodeType(e); err != nil {
+				return nil, err
+			}
+		}
+		return encoded, nil
+	}
+	return v, nil
+}
+
+// decodeTypes Decode the values of the registered types among the decoded session values
+func (o *options) decodeTypes(values map[string]interface{}) error {
+	if len(o.valueTypes) == 0 {
+		return nil
+	}
+	for k, v := range values {
+		var err error
+		if values[k], err = o.decodeType(v); err != nil {
+			return fmt.Errorf("session value %q: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// decodeType The decoded value v with the values of the registered types decoded,
+// the maps and slices of v are modified in place
+func (o *options) decodeType(v interface{}) (interface{}, error) {
+	if len(o.valueTypes) == 0 {
+		return v, nil
+	}
+
+	var m map[string]interface{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m = v
+	case bson.M:
+		m = v
+	case []interface{}:
+		return v, o.decodeSlice(v)
+	case bson.A:
+		return v, o.decodeSlice(v)
+	default:
+		return v, nil
+	}
+	if name, ok := m[typeKey].(string); ok && len(m) == 2 {
+		if t, ok := o.valueTypeNames[name]; ok {
+			if stored, ok := m[typeValueKey]; ok {
+				return t.Decode(stored)
+			}
+		}
+	}
+	return v, o.decodeTypes(m)
+}
+
+// decodeSlice Decode the values of the registered types among the elements of v in place
+func (o *options) decodeSlice(v []interface{}) error {
+	for i, e := range v {
+		var err error
+		if v[i], err = o.decodeType(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
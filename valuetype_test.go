@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type testOrderID string
+
+// testMoney Amount in cents
+type testMoney struct {
+	cents int64
+}
+
+func testValueTypes() []Option {
+	return []Option{
+		WithValueType(testOrderID(""), ValueType{
+			Name:   "order_id",
+			Encode: func(v interface{}) (interface{}, error) { return string(v.(testOrderID)), nil },
+			Decode: func(stored interface{}) (interface{}, error) {
+				s, ok := stored.(string)
+				if !ok {
+					return nil, errors.New("not a string")
+				}
+				return testOrderID(s), nil
+			},
+		}),
+		WithValueType(testMoney{}, ValueType{
+			Name:   "money",
+			Encode: func(v interface{}) (interface{}, error) { return fmt.Sprint(v.(testMoney).cents), nil },
+			Decode: func(stored interface{}) (interface{}, error) {
+				var m testMoney
+				_, err := fmt.Sscan(stored.(string), &m.cents)
+				return m, err
+			},
+		}),
+	}
+}
+
+func TestValueTypes(t *testing.T) {
+	Convey("Test the registered value types", t, func() {
+		values := map[string]interface{}{
+			"order": testOrderID("o-1"),
+			"cart":  []interface{}{testMoney{cents: 1250}, "note"},
+			"meta":  map[string]interface{}{"last": testOrderID("o-0")},
+			"plain": "text",
+		}
+
+		for _, opts := range map[string][]Option{
+			"json":      testValueTypes(),
+			"gob":       append(testValueTypes(), WithCodec(GobCodec{})),
+			"documents": append(testValueTypes(), WithDocumentValues(true)),
+		} {
+			mstore := &managerStore{opts: newOptions(opts...)}
+			value, err := mstore.encodeValues(values)
+			So(err, ShouldBeNil)
+			decoded, err := mstore.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(decoded["order"], ShouldEqual, testOrderID("o-1"))
+			So(decoded["plain"], ShouldEqual, "text")
+
+			var cart []interface{}
+			switch v := decoded["cart"].(type) {
+			case []interface{}:
+				cart = v
+			case bson.A:
+				cart = v
+			}
+			So(cart, ShouldResemble, []interface{}{testMoney{cents: 1250}, "note"})
+
+			var meta map[string]interface{}
+			switch v := decoded["meta"].(type) {
+			case map[string]interface{}:
+				meta = v
+			case bson.M:
+				meta = v
+			}
+			So(meta["last"], ShouldEqual, testOrderID("o-0"))
+		}
+
+		Convey("decoded lazily", func() {
+			mstore := &managerStore{opts: newOptions(append(testValueTypes(), WithLazyDecode(true))...)}
+			value, err := mstore.encodeValues(values)
+			So(err, ShouldBeNil)
+			st, err := newLoadedStore(mstore, &sessionItem{Value: value}, newStore(context.Background(), mstore, "sid", 10, nil))
+			So(err, ShouldBeNil)
+			order, ok := st.Get("order")
+			So(ok, ShouldBeTrue)
+			So(order, ShouldEqual, testOrderID("o-1"))
+			st.materialize()
+			So(st.lazyErr, ShouldBeNil)
+			So(st.values["meta"].(map[string]interface{})["last"], ShouldEqual, testOrderID("o-0"))
+		})
+
+		Convey("unregistered and failing types", func() {
+			mstore := &managerStore{opts: newOptions(testValueTypes()...)}
+			value, err := mstore.encodeValues(values)
+			So(err, ShouldBeNil)
+			plain := &managerStore{opts: newOptions()}
+			decoded, err := plain.decodeValues(&sessionItem{Value: value})
+			So(err, ShouldBeNil)
+			So(decoded["order"], ShouldResemble, map[string]interface{}{typeKey: "order_id", typeValueKey: "o-1"})
+
+			bad := map[string]interface{}{"order": map[string]interface{}{typeKey: "order_id", typeValueKey: 42.0}}
+			value, err = plain.encodeValues(bad)
+			So(err, ShouldBeNil)
+			_, err = mstore.decodeValues(&sessionItem{Value: value})
+			So(errors.Is(err, ErrDecode), ShouldBeTrue)
+
+			failing := &managerStore{opts: newOptions(WithValueType(testMoney{}, ValueType{
+				Name:   "money",
+				Encode: func(v interface{}) (interface{}, error) { return nil, errors.New("no encoding") },
+			}))}
+			_, err = failing.encodeValues(map[string]interface{}{"price": testMoney{}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,230 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MaxClockSkew The clock difference with the server above which Verify reports a problem,
+// the TTL monitor of the server removing the sessions by the expirations of the store
+const MaxClockSkew = 5 * time.Second
+
+// VerifyReport The capabilities of the server checked by Verify
+type VerifyReport struct {
+	ServerVersion string
+	// ReplicaSet Whether the server is a replica set member or a mongos,
+	// which transactions and change streams require
+	ReplicaSet    bool
+	Transactions  bool
+	ChangeStreams bool
+	// TTLIndex Whether the TTL index of the collection exists
+	TTLIndex       bool
+	TTLExpireAfter time.Duration
+	// ClockSkew The time of the server minus the time of the store
+	ClockSkew time.Duration
+	// MissingActions The actions the store needs that the user isn't granted on the collection
+	MissingActions []string
+	// Problems The misconfigurations found, empty if none
+	Problems []string
+}
+
+// OK Whether no problem was found
+func (r *VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verifier Implemented by the stores, to check at startup that the server and the
+// configuration of the store suit each other
+type Verifier interface {
+	// Verify Check the server version, the TTL index (or the cleanup), the clock skew and
+	// the privileges the configured features need, an error is returned if the checks
+	// themselves fail
+	Verify(ctx context.Context) (*VerifyReport, error)
+}
+
+func (s *managerStore) Verify(ctx context.Context) (*VerifyReport, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	r := &VerifyReport{}
+	db := s.c.Database()
+
+	var build struct {
+		Version string `bson:"version"`
+	}
+	if err := db.RunCommand(dbctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&build); err != nil {
+		return nil, err
+	}
+	r.ServerVersion = build.Version
+
+	var hello helloReply
+	start := s.now()
+	if err := db.RunCommand(dbctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, err
+	}
+	r.verifyHello(hello, start, s.now())
+
+	for _, m := range []*managerStore{s, s.anon} {
+		if m == nil {
+			continue
+		}
+		cur, err := m.c.Indexes().List(dbctx)
+		if err != nil {
+			return nil, err
+		}
+		var indexes []indexSpec
+		if err := cur.All(dbctx, &indexes); err != nil {
+			return nil, err
+		}
+		r.verifyIndexes(m.c.Name(), indexes, m.opts)
+	}
+
+	var status connectionStatus
+	err := db.RunCommand(dbctx, bson.D{{Key: "connectionStatus", Value: 1}, {Key: "showPrivileges", Value: true}}).Decode(&status)
+	if err != nil {
+		return nil, err
+	}
+	r.verifyPrivileges(status, db.Name(), s.c.Name(), s.requiredActions())
+
+	if s.opts.refreshTxn && !r.Transactions {
+		r.Problems = append(r.Problems, "transactions are unsupported: the refreshes and promotions are not atomic")
+	}
+	return r, nil
+}
+
+// requiredActions The privilege actions on the collection the store needs
+func (s *managerStore) requiredActions() []string {
+	actions := []string{"find", "insert", "update", "remove", "listIndexes"}
+	if s.opts.indexes {
+		actions = append(actions, "createIndex")
+	}
+	return actions
+}
+
+// helloReply The fields of the hello reply checked by Verify
+type helloReply struct {
+	SetName   string    `bson:"setName"`
+	Msg       string    `bson:"msg"`
+	LocalTime time.Time `bson:"localTime"`
+}
+
+// verifyHello Record the topology and the clock skew told by the hello reply received
+// between sent and received
+func (r *VerifyReport) verifyHello(hello helloReply, sent, received time.Time) {
+	r.ReplicaSet = hello.SetName != "" || hello.Msg == "isdbgrid"
+	r.Transactions = r.ReplicaSet
+	r.ChangeStreams = r.ReplicaSet
+
+	if !hello.LocalTime.IsZero() {
+		r.ClockSkew = hello.LocalTime.Sub(sent.Add(received.Sub(sent) / 2))
+		if skew := r.ClockSkew; skew > MaxClockSkew || skew < -MaxClockSkew {
+			r.Problems = append(r.Problems, fmt.Sprintf("the clock of the server is off by %s", skew))
+		}
+	}
+}
+
+// indexSpec The fields of an index description checked by Verify
+type indexSpec struct {
+	Key                bson.D      `bson:"key"`
+	ExpireAfterSeconds interface{} `bson:"expireAfterSeconds"`
+}
+
+// verifyIndexes Record the TTL index of collection among indexes
+func (r *VerifyReport) verifyIndexes(collection string, indexes []indexSpec, o options) {
+	for _, index := range indexes {
+		if len(index.Key) != 1 || index.Key[0].Key != "expired_at" {
+			continue
+		}
+		expireAfter, ok := asInt64(index.ExpireAfterSeconds)
+		if !ok {
+			continue
+		}
+		r.TTLIndex = true
+		r.TTLExpireAfter = time.Duration(expireAfter) * time.Second
+
+		want := o.ttlExpireAfter
+		if want <= 0 {
+			want = time.Second
+		}
+		if o.cleanupInterval <= 0 && r.TTLExpireAfter/time.Second != want/time.Second {
+			r.Problems = append(r.Problems, fmt.Sprintf("the TTL index of %s removes the sessions %s after their expiration instead of %s",
+				collection, r.TTLExpireAfter, want))
+		}
+	}
+	if !r.TTLIndex && o.cleanupInterval <= 0 {
+		r.Problems = append(r.Problems, fmt.Sprintf("the collection %s has no TTL index nor cleanup: expired sessions are never removed", collection))
+	}
+}
+
+// connectionStatus The fields of the connection status checked by Verify
+type connectionStatus struct {
+	AuthInfo struct {
+		AuthenticatedUsers          []bson.Raw  `bson:"authenticatedUsers"`
+		AuthenticatedUserPrivileges []privilege `bson:"authenticatedUserPrivileges"`
+	} `bson:"authInfo"`
+}
+
+type privilege struct {
+	Resource struct {
+		DB          *string `bson:"db"`
+		Collection  *string `bson:"collection"`
+		AnyResource bool    `bson:"anyResource"`
+	} `bson:"resource"`
+	Actions []string `bson:"actions"`
+}
+
+// covers Whether the privilege applies to the collection of db
+func (p privilege) covers(db, collection string) bool {
+	res := p.Resource
+	if res.AnyResource {
+		return true
+	}
+	if res.DB == nil || res.Collection == nil {
+		// cluster resource
+		return false
+	}
+	return (*res.DB == "" || *res.DB == db) && (*res.Collection == "" || *res.Collection == collection)
+}
+
+// verifyPrivileges Record the actions missing from the privileges of the connection status on
+// the collection, the servers without access control granting all
+func (r *VerifyReport) verifyPrivileges(status connectionStatus, db, collection string, actions []string) {
+	if len(status.AuthInfo.AuthenticatedUsers) == 0 {
+		return
+	}
+
+	granted := make(map[string]bool)
+	for _, p := range status.AuthInfo.AuthenticatedUserPrivileges {
+		if !p.covers(db, collection) {
+			continue
+		}
+		for _, a := range p.Actions {
+			granted[a] = true
+		}
+	}
+
+	for _, a := range actions {
+		if !granted[a] {
+			r.MissingActions = append(r.MissingActions, a)
+		}
+	}
+	if len(r.MissingActions) > 0 {
+		r.Problems = append(r.Problems, fmt.Sprintf("the user lacks the actions %v on %s.%s", r.MissingActions, db, collection))
+	}
+}
+
+// asInt64 The value of a numeric BSON field
+func asInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestVerifyReport(t *testing.T) {
+	Convey("Test the checks of the server capabilities", t, func() {
+		now := time.Now()
+
+		Convey("topology and clock", func() {
+			r := &VerifyReport{}
+			r.verifyHello(helloReply{SetName: "rs0", LocalTime: now.Add(time.Second)}, now, now)
+			So(r.Transactions, ShouldBeTrue)
+			So(r.ClockSkew, ShouldEqual, time.Second)
+			So(r.OK(), ShouldBeTrue)
+
+			r = &VerifyReport{}
+			r.verifyHello(helloReply{LocalTime: now.Add(-time.Minute)}, now, now)
+			So(r.ReplicaSet, ShouldBeFalse)
+			So(r.Problems, ShouldHaveLength, 1)
+		})
+
+		Convey("TTL index", func() {
+			ttl := indexSpec{Key: bson.D{{Key: "expired_at", Value: 1}}, ExpireAfterSeconds: int32(1)}
+			r := &VerifyReport{}
+			r.verifyIndexes("session", []indexSpec{{Key: bson.D{{Key: "_id", Value: 1}}}, ttl}, newOptions())
+			So(r.TTLIndex, ShouldBeTrue)
+			So(r.OK(), ShouldBeTrue)
+
+			r = &VerifyReport{}
+			r.verifyIndexes("session", []indexSpec{ttl}, newOptions(WithTTLIndexOptions("", time.Hour)))
+			So(r.Problems, ShouldHaveLength, 1)
+
+			r = &VerifyReport{}
+			r.verifyIndexes("session", nil, newOptions())
+			So(r.TTLIndex, ShouldBeFalse)
+			So(r.Problems, ShouldHaveLength, 1)
+
+			r = &VerifyReport{}
+			r.verifyIndexes("session", nil, newOptions(WithCleanupInterval(time.Minute)))
+			So(r.OK(), ShouldBeTrue)
+		})
+
+		Convey("privileges", func() {
+			raw, err := bson.Marshal(bson.M{"authInfo": bson.M{
+				"authenticatedUsers": bson.A{bson.M{"user": "app", "db": "admin"}},
+				"authenticatedUserPrivileges": bson.A{
+					bson.M{"resource": bson.M{"db": "app", "collection": ""}, "actions": bson.A{"find", "insert", "update"}},
+					bson.M{"resource": bson.M{"db": "other", "collection": ""}, "actions": bson.A{"remove"}},
+					bson.M{"resource": bson.M{"cluster": true}, "actions": bson.A{"remove"}},
+				},
+			}})
+			So(err, ShouldBeNil)
+			var status connectionStatus
+			So(bson.Unmarshal(raw, &status), ShouldBeNil)
+
+			r := &VerifyReport{}
+			r.verifyPrivileges(status, "app", "session", []string{"find", "insert", "update", "remove"})
+			So(r.MissingActions, ShouldResemble, []string{"remove"})
+			So(r.OK(), ShouldBeFalse)
+
+			r = &VerifyReport{}
+			r.verifyPrivileges(connectionStatus{}, "app", "session", []string{"find"})
+			So(r.OK(), ShouldBeTrue)
+		})
+	})
+}
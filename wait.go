@@ -0,0 +1,153 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// DefaultWaitInterval The interval between the reads of a session waited for by
+// WaitForChange when no change stream can be followed
+const DefaultWaitInterval = time.Second
+
+// Waiter Implemented by the session stores, to wait for the changes made to a session by the
+// other requests, e.g. a login approved on a phone and continued on a desktop
+type Waiter interface {
+	// WaitForChange Block until the session is saved with another revision than the one of
+	// the store (see Revisioner), returning nil, until it's removed or expires, returning
+	// ErrSessionNotFound, or until ctx is done. The changes are followed with a change stream
+	// of the session document, or by reading it every interval of WithWaitInterval on the
+	// deployments without change streams (standalone servers); the store keeps its values,
+	// the session is updated again to read the new ones
+	WaitForChange(ctx context.Context) error
+}
+
+// WithWaitInterval Set the interval between the reads of a session waited for by
+// WaitForChange without change stream (DefaultWaitInterval by default)
+func WithWaitInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.waitInterval = interval
+	}
+}
+
+// waitPeriod The interval between the reads of a waited session
+func (o *options) waitPeriod() time.Duration {
+	if o.waitInterval <= 0 {
+		return DefaultWaitInterval
+	}
+	return o.waitInterval
+}
+
+// waitPipeline The pipeline of the change stream of the document of sid
+func (s *managerStore) waitPipeline(sid string) mongo.Pipeline {
+	match := bson.M{
+		"operationType":   bson.M{"$in": bson.A{"update", "replace", "delete"}},
+		"documentKey._id": s.idFilter(sid),
+	}
+	return mongo.Pipeline{{{Key: "$match", Value: match}}}
+}
+
+// changedSince Tell whether the session document of sid has another version than version,
+// ErrSessionNotFound if it's missing or expired
+func (s *managerStore) changedSince(ctx context.Context, sid string, version int64) (bool, error) {
+	dbctx, cancel := s.callContext(ctx)
+	defer cancel()
+	var item *sessionItem
+	err := s.retry(dbctx, OpLoad, func() (err error) {
+		item, err = s.findItem(dbctx, s.cPrimary, sid, false, 0)
+		return err
+	})
+	if err != nil {
+		return false, err
+	} else if item == nil {
+		return false, ErrSessionNotFound
+	}
+	return item.Version != version, nil
+}
+
+func (s *store) WaitForChange(ctx context.Context) error {
+	if ctx == nil {
+		ctx = s.ctx
+	}
+	s.RLock()
+	version := s.version
+	s.RUnlock()
+	m := s.mstore
+	if err := m.ready(ctx); err != nil {
+		return err
+	}
+
+	// the stream is opened before the first read, so that no change falls in between
+	cs, err := m.cPrimary.Watch(ctx, m.waitPipeline(s.sid), mopts.ChangeStream())
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.log(LevelDebug, "session change stream unavailable, polling the session", "collection", m.collectionName(), "sid_hash", m.sidHash(s.sid), "error", err)
+		cs = nil
+	}
+	defer func() {
+		if cs != nil {
+			cs.Close(context.Background())
+		}
+	}()
+
+	interval := m.opts.waitPeriod()
+	for {
+		if changed, err := m.changedSince(ctx, s.sid, version); err != nil || changed {
+			return err
+		}
+		if cs != nil {
+			if cs.Next(ctx) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.log(LevelWarn, "session change stream failed, polling the session", "collection", m.collectionName(), "sid_hash", m.sidHash(s.sid), "error", cs.Err())
+			cs.Close(context.Background())
+			cs = nil
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *memorySession) WaitForChange(ctx context.Context) error {
+	if ctx == nil {
+		ctx = s.ctx
+	}
+	s.RLock()
+	version := s.version
+	s.RUnlock()
+	interval := s.s.m.opts.waitPeriod()
+	for {
+		s.s.Lock()
+		doc := s.s.get(s.sid, 0)
+		s.s.Unlock()
+		if doc == nil {
+			return ErrSessionNotFound
+		} else if doc.version != version {
+			return nil
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext Wait for d, or for ctx to be done, returning its error
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
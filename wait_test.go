@@ -0,0 +1,52 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWaitForChange(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Test waiting for the changes of a session", t, func() {
+		mstore := NewMemoryStore(WithWaitInterval(10 * time.Millisecond))
+		st, err := mstore.Create(ctx, "test_wait", 10)
+		So(err, ShouldBeNil)
+		So(st.Save(), ShouldBeNil)
+		waiting, err := mstore.Update(ctx, "test_wait", 10)
+		So(err, ShouldBeNil)
+
+		Convey("unchanged until ctx is done", func() {
+			wctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			err := waiting.(Waiter).WaitForChange(wctx)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		})
+
+		Convey("saved by another request", func() {
+			done := make(chan error, 1)
+			go func() { done <- waiting.(Waiter).WaitForChange(ctx) }()
+			other, err := mstore.Update(ctx, "test_wait", 10)
+			So(err, ShouldBeNil)
+			other.Set("approved", true)
+			So(other.Save(), ShouldBeNil)
+			So(<-done, ShouldBeNil)
+
+			st, err := mstore.Update(ctx, "test_wait", 10)
+			So(err, ShouldBeNil)
+			approved, _ := st.Get("approved")
+			So(approved, ShouldEqual, true)
+		})
+
+		Convey("removed by another request", func() {
+			done := make(chan error, 1)
+			go func() { done <- waiting.(Waiter).WaitForChange(ctx) }()
+			So(mstore.Delete(ctx, "test_wait"), ShouldBeNil)
+			So(<-done, ShouldEqual, ErrSessionNotFound)
+		})
+	})
+}
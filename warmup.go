@@ -0,0 +1,61 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// DefaultWarmupTimeout Bound of the warm-up of the connections unless set by WithDialTimeout
+const DefaultWarmupTimeout = 10 * time.Second
+
+// WithWarmup Establish conns connections to the primary and ping it when creating the store,
+// failing the creation if the server is unreachable, so that the first requests don't pay the
+// connection setup; the stores creating their client also keep conns idle connections in the pool
+func WithWarmup(conns int) Option {
+	return func(o *options) {
+		o.warmup = conns
+	}
+}
+
+// warmUp Open the warm-up connections of the client by pinging the primary on each of them
+func (s *managerStore) warmUp() error {
+	if s.opts.warmup <= 0 {
+		return nil
+	}
+	timeout := s.opts.dialTimeout
+	if timeout <= 0 {
+		timeout = DefaultWarmupTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.client.Ping(ctx, readpref.Primary()); err != nil {
+		s.log(LevelError, "session connection warm-up failed", "error", err)
+		return err
+	}
+
+	// concurrent pings check out distinct connections of the pool
+	var wg sync.WaitGroup
+	errs := make(chan error, s.opts.warmup)
+	for i := 1; i < s.opts.warmup; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.client.Ping(ctx, readpref.Primary())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			s.log(LevelError, "session connection warm-up failed", "error", err)
+			return err
+		}
+	}
+	s.log(LevelInfo, "session connections warmed up", "connections", s.opts.warmup, "took", time.Since(start))
+	return nil
+}
@@ -0,0 +1,31 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWarmup(t *testing.T) {
+	Convey("Test the warm-up of the connections", t, func() {
+		Convey("idle connections kept by the pool", func() {
+			o := newOptions(WithWarmup(8))
+			co := o.clientOptions("mongodb://127.0.0.1:27017")
+			So(*co.MinPoolSize, ShouldEqual, 8)
+
+			co = o.clientOptions("mongodb://127.0.0.1:27017/?minPoolSize=20")
+			So(*co.MinPoolSize, ShouldEqual, 20)
+		})
+
+		Convey("disabled by default", func() {
+			mstore := newOfflineStore(t)
+			So(mstore.warmUp(), ShouldBeNil)
+		})
+
+		Convey("unreachable servers fail the creation", func() {
+			mstore := newOfflineStore(t, WithWarmup(4), WithDialTimeout(50*time.Millisecond))
+			So(mstore.warmUp(), ShouldNotBeNil)
+		})
+	})
+}
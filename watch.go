@@ -0,0 +1,204 @@
+package mongo
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mopts "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Bounds of the delay before reopening a failed change stream
+const (
+	watchRetryMin = 100 * time.Millisecond
+	watchRetryMax = 5 * time.Second
+)
+
+// Subscriber Implemented by the stores, to learn about the changes of the sessions made by
+// the other instances sharing the collection (invalidation, forced logouts), from a change
+// stream of MongoDB, which needs a replica set or a sharded cluster
+type Subscriber interface {
+	// Subscribe Get the events of the sessions written (EventCreate, EventUpdate) or removed
+	// (EventDelete, including the refreshed sessions and those removed by the TTL monitor)
+	// until ctx is done, when the channel is closed; the stream resumes after its failures,
+	// which are logged, and the events invalidate the cached documents as they are received.
+	// The SID of the events is hashed with WithHashedIDs, the deletions of the sessions of
+	// the other owners are also received
+	Subscribe(ctx context.Context) <-chan SessionEvent
+}
+
+// WithChangeStreamInvalidation Keep the cache of WithCache consistent across the instances
+// sharing the collection: the store follows its change stream until closed, removing the
+// cached documents modified or removed by the other instances
+func WithChangeStreamInvalidation() Option {
+	return func(o *options) {
+		o.invalidation = true
+	}
+}
+
+// changeDoc The change stream event of a session document
+type changeDoc struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID string `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *struct {
+		ExpiredAt time.Time `bson:"expired_at"`
+	} `bson:"fullDocument"`
+	ClusterTime bson.Timestamp `bson:"clusterTime"`
+	WallTime    time.Time      `bson:"wallTime"`
+}
+
+// event The session event of the change
+func (s *managerStore) event(change *changeDoc) (SessionEvent, bool) {
+	event := SessionEvent{
+		SID:       s.info(sessionInfoDoc{ID: change.DocumentKey.ID}).SID,
+		Namespace: s.namespace,
+		At:        change.WallTime,
+	}
+	if event.At.IsZero() {
+		event.At = time.Unix(int64(change.ClusterTime.T), 0)
+	}
+	if change.FullDocument != nil {
+		event.ExpiredAt = change.FullDocument.ExpiredAt
+	}
+	switch change.OperationType {
+	case "insert":
+		event.Kind = EventCreate
+	case "update", "replace":
+		event.Kind = EventUpdate
+	case "delete":
+		event.Kind = EventDelete
+	default:
+		return event, false
+	}
+	return event, true
+}
+
+// watchPipeline The pipeline of the change stream of the sessions of the namespace of s
+func (s *managerStore) watchPipeline() mongo.Pipeline {
+	match := bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}
+	if s.namespace != "" {
+		match["documentKey._id"] = bson.M{"$regex": "^" + regexp.QuoteMeta(s.namespace+":")}
+	}
+	return mongo.Pipeline{{{Key: "$match", Value: match}}}
+}
+
+func (s *managerStore) Subscribe(ctx context.Context) <-chan SessionEvent {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return s.subscribe(ctx, nil)
+}
+
+// subscribe Follow the change streams of s until ctx is done, counting the watchers in wg if set
+func (s *managerStore) subscribe(ctx context.Context, wg *sync.WaitGroup) <-chan SessionEvent {
+	events := make(chan SessionEvent, 64)
+	stores := []*managerStore{s}
+	if s.anon != nil {
+		stores = append(stores, s.anon)
+	}
+
+	done := make(chan struct{}, len(stores))
+	for _, m := range stores {
+		if wg != nil {
+			wg.Add(1)
+		}
+		go func(m *managerStore) {
+			if wg != nil {
+				defer wg.Done()
+			}
+			defer func() { done <- struct{}{} }()
+			m.watch(ctx, events)
+		}(m)
+	}
+	go func() {
+		for range stores {
+			<-done
+		}
+		close(events)
+	}()
+	return events
+}
+
+// watch Send the events of the collection of s to events until ctx is done
+func (s *managerStore) watch(ctx context.Context, events chan<- SessionEvent) {
+	var token bson.Raw
+	delay := watchRetryMin
+	for {
+		err := s.ready(ctx)
+		if err == nil {
+			err = s.follow(ctx, &token, events, &delay)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		s.log(LevelWarn, "session change stream failed", "collection", s.collectionName(), "retry_in", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if delay *= 2; delay > watchRetryMax {
+			delay = watchRetryMax
+		}
+	}
+}
+
+// follow Follow the change stream of the collection of s from token until it fails,
+// updating token and resetting delay as the events are received
+func (s *managerStore) follow(ctx context.Context, token *bson.Raw, events chan<- SessionEvent, delay *time.Duration) error {
+	opts := mopts.ChangeStream().SetFullDocument(mopts.UpdateLookup)
+	if *token != nil {
+		opts.SetResumeAfter(*token)
+	}
+	cs, err := s.cPrimary.Watch(ctx, s.watchPipeline(), opts)
+	if err != nil {
+		return err
+	}
+	defer cs.Close(context.Background())
+
+	for cs.Next(ctx) {
+		*token = cs.ResumeToken()
+		*delay = watchRetryMin
+		var change changeDoc
+		if err := cs.Decode(&change); err != nil {
+			return err
+		}
+		event, ok := s.event(&change)
+		if !ok {
+			continue
+		}
+		s.uncacheID(change.DocumentKey.ID)
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return cs.Err()
+}
+
+// uncacheID Forget the cached document id
+func (s *managerStore) uncacheID(id string) {
+	if s.opts.cache != nil {
+		s.opts.cache.remove(s.docKey(id))
+	}
+}
+
+// startInvalidation Follow the change stream of the collection to invalidate the cache until
+// closed, Close waiting for its watchers only, not for those of the subscriptions
+func (s *managerStore) startInvalidation() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.unwatch = cancel
+	events := s.subscribe(ctx, s.opts.watchers)
+	go func() {
+		for range events {
+		}
+	}()
+}
@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestChangeEvents(t *testing.T) {
+	Convey("Test the session events of the change stream", t, func() {
+		mstore := newOfflineStore(t)
+		at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		change := &changeDoc{OperationType: "delete", ClusterTime: bson.Timestamp{T: uint32(at.Unix())}}
+		change.DocumentKey.ID = "sid"
+		event, ok := mstore.event(change)
+		So(ok, ShouldBeTrue)
+		So(event.Kind, ShouldEqual, EventDelete)
+		So(event.SID, ShouldEqual, "sid")
+		So(event.At.Equal(at), ShouldBeTrue)
+
+		change.OperationType = "insert"
+		change.WallTime = at.Add(time.Second)
+		event, _ = mstore.event(change)
+		So(event.Kind, ShouldEqual, EventCreate)
+		So(event.At, ShouldEqual, at.Add(time.Second))
+		change.OperationType = "replace"
+		event, _ = mstore.event(change)
+		So(event.Kind, ShouldEqual, EventUpdate)
+		change.OperationType = "invalidate"
+		_, ok = mstore.event(change)
+		So(ok, ShouldBeFalse)
+
+		Convey("of a namespace", func() {
+			web := mstore.namespaceView("web")
+			change.OperationType = "update"
+			change.DocumentKey.ID = "web:sid"
+			event, _ := web.event(change)
+			So(event.SID, ShouldEqual, "sid")
+			So(event.Namespace, ShouldEqual, "web")
+
+			match := web.watchPipeline()[0][0].Value.(bson.M)
+			So(match["documentKey._id"], ShouldResemble, bson.M{"$regex": "^web:"})
+			_, ok := mstore.watchPipeline()[0][0].Value.(bson.M)["documentKey._id"]
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("invalidating the cache", func() {
+			mstore := newOfflineStore(t, WithCache(10, time.Minute))
+			mstore.cache("sid", sessionItem{ID: "sid", ExpiredAt: time.Now().Add(time.Hour)})
+			_, ok := mstore.cached("sid")
+			So(ok, ShouldBeTrue)
+			mstore.uncacheID(mstore.docID("sid"))
+			_, ok = mstore.cached("sid")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("closed with the context", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			events := newOfflineStore(t).Subscribe(ctx)
+			select {
+			case _, ok := <-events:
+				So(ok, ShouldBeFalse)
+			case <-time.After(5 * time.Second):
+				So("the channel is not closed", ShouldBeEmpty)
+			}
+		})
+
+		Convey("closed without waiting for the subscriptions", func() {
+			mstore := newOfflineStore(t, WithCache(10, time.Minute), WithChangeStreamInvalidation())
+			mstore.startInvalidation()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events := mstore.Subscribe(ctx)
+			closed := make(chan error, 1)
+			go func() { closed <- mstore.Close() }()
+			select {
+			case err := <-closed:
+				So(err, ShouldBeNil)
+			case <-time.After(5 * time.Second):
+				So("the store is not closed", ShouldBeEmpty)
+			}
+			cancel()
+			for range events {
+			}
+		})
+	})
+}